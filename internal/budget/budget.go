@@ -0,0 +1,51 @@
+package budget
+
+import (
+	"github/shaolim/momon/internal/budget/model"
+	transactionmodel "github/shaolim/momon/internal/transaction/model"
+	"github/shaolim/momon/pkg/money"
+)
+
+// Status is how much of a budget was spent in a month.
+type Status struct {
+	Budget *model.Budget
+	// Spent is in the budget's currency.
+	Spent money.Money
+}
+
+// Percent is the share of the budget spent, rounded down.
+func (s *Status) Percent() int {
+	return int(s.Spent.Amount * 100 / s.Budget.Amount)
+}
+
+// Remaining is what is left of the budget, negative once it is overspent.
+func (s *Status) Remaining() money.Money {
+	return money.New(s.Budget.Amount-s.Spent.Amount, s.Budget.Currency)
+}
+
+// Evaluate totals the expenses among transactions that count towards each
+// budget. transactions should be the month's.
+func Evaluate(budgets []*model.Budget, transactions []*transactionmodel.Transaction) []*Status {
+	statuses := make([]*Status, 0, len(budgets))
+	for _, b := range budgets {
+		var spent int64
+		for _, t := range transactions {
+			if t.Type == transactionmodel.TransactionTypeExpense && t.Currency == b.Currency && b.Covers(t.Category) {
+				spent += t.Amount
+			}
+		}
+		statuses = append(statuses, &Status{Budget: b, Spent: money.New(spent, b.Currency)})
+	}
+	return statuses
+}
+
+// Crossed returns the highest of thresholds, in ascending order, that percent
+// reached.
+func Crossed(percent int, thresholds []int) (int, bool) {
+	for i := len(thresholds) - 1; i >= 0; i-- {
+		if percent >= thresholds[i] {
+			return thresholds[i], true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,56 @@
+package budget
+
+import (
+	"github/shaolim/momon/internal/budget/model"
+	transactionmodel "github/shaolim/momon/internal/transaction/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluate(t *testing.T) {
+	overall := &model.Budget{Amount: 100000, Currency: "JPY"}
+	food := &model.Budget{Category: "Food", Amount: 30000, Currency: "JPY"}
+
+	transactions := []*transactionmodel.Transaction{
+		{Amount: 24000, Currency: "JPY", Type: transactionmodel.TransactionTypeExpense, Category: "food"},
+		{Amount: 3400, Currency: "JPY", Type: transactionmodel.TransactionTypeExpense, Category: "Transport"},
+		{Amount: 600, Currency: "JPY", Type: transactionmodel.TransactionTypeExpense},
+		{Amount: 50000, Currency: "JPY", Type: transactionmodel.TransactionTypeIncome, Category: "Food"},
+		// Other currencies don't count until they can be converted.
+		{Amount: 1200, Currency: "USD", Type: transactionmodel.TransactionTypeExpense, Category: "Food"},
+	}
+
+	statuses := Evaluate([]*model.Budget{overall, food}, transactions)
+	if assert.Len(t, statuses, 2) {
+		assert.Equal(t, "28,000 JPY", statuses[0].Spent.String())
+		assert.Equal(t, 28, statuses[0].Percent())
+		assert.Equal(t, "72,000 JPY", statuses[0].Remaining().String())
+
+		assert.Equal(t, "24,000 JPY", statuses[1].Spent.String())
+		assert.Equal(t, 80, statuses[1].Percent())
+	}
+}
+
+func TestCrossed(t *testing.T) {
+	thresholds := []int{80, 100}
+
+	tests := []struct {
+		percent int
+		want    int
+		wantOK  bool
+	}{
+		{percent: 0},
+		{percent: 79},
+		{percent: 80, want: 80, wantOK: true},
+		{percent: 99, want: 80, wantOK: true},
+		{percent: 100, want: 100, wantOK: true},
+		{percent: 250, want: 100, wantOK: true},
+	}
+
+	for _, tt := range tests {
+		got, ok := Crossed(tt.percent, thresholds)
+		assert.Equal(t, tt.want, got, "Crossed(%d)", tt.percent)
+		assert.Equal(t, tt.wantOK, ok, "Crossed(%d)", tt.percent)
+	}
+}
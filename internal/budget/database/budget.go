@@ -0,0 +1,143 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"github/shaolim/momon/internal/budget/model"
+	"github/shaolim/momon/pkg/database"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type BudgetDB interface {
+	SetBudget(ctx context.Context, budget *model.Budget) error
+	DeleteBudget(ctx context.Context, userID int64, category string) error
+	ListBudgets(ctx context.Context, userID int64) ([]*model.Budget, error)
+	MarkAlerted(ctx context.Context, budgetID int64, month time.Time, threshold int, now time.Time) (bool, error)
+}
+
+type budgetDB struct {
+	db *database.DB
+}
+
+func New(db *database.DB) BudgetDB {
+	return &budgetDB{
+		db: db,
+	}
+}
+
+const budgetColumns = `id, user_id, category, amount, currency, created_at, updated_at`
+
+func scanBudget(row pgx.Row) (*model.Budget, error) {
+	var b model.Budget
+	if err := row.Scan(&b.ID, &b.UserID, &b.Category, &b.Amount, &b.Currency, &b.CreatedAt, &b.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// SetBudget saves the budget, replacing the user's budget for the same
+// category, ignoring case. Alerts already sent for the replaced budget are
+// forgotten so the new amount warns again.
+func (db *budgetDB) SetBudget(ctx context.Context, budget *model.Budget) error {
+	budget.Category = strings.TrimSpace(budget.Category)
+	if err := budget.Validate(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	budget.CreatedAt = now
+	budget.UpdatedAt = now
+
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, `
+			INSERT INTO budgets (user_id, category, amount, currency, created_at, updated_at)
+			VALUES($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (user_id, LOWER(category)) DO UPDATE
+			SET amount = EXCLUDED.amount, currency = EXCLUDED.currency, updated_at = EXCLUDED.updated_at
+			RETURNING `+budgetColumns,
+			budget.UserID, budget.Category, budget.Amount, budget.Currency, budget.CreatedAt, budget.UpdatedAt)
+
+		saved, err := scanBudget(row)
+		if err != nil {
+			return fmt.Errorf("insert budgets: %w", err)
+		}
+		*budget = *saved
+
+		if _, err := tx.Exec(ctx, `DELETE FROM budget_alerts WHERE budget_id = $1`, budget.ID); err != nil {
+			return fmt.Errorf("delete budget_alerts: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// DeleteBudget removes the user's budget for category, ignoring case. An
+// empty category is the overall budget.
+func (db *budgetDB) DeleteBudget(ctx context.Context, userID int64, category string) error {
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+			DELETE FROM budgets WHERE user_id = $1 AND LOWER(category) = LOWER($2)
+		`, userID, strings.TrimSpace(category))
+		if err != nil {
+			return fmt.Errorf("delete budgets: %w", err)
+		}
+
+		if result.RowsAffected() == 0 {
+			return database.ErrNotFound
+		}
+
+		return nil
+	})
+}
+
+// ListBudgets returns the user's budgets, the overall one first and the rest
+// by category.
+func (db *budgetDB) ListBudgets(ctx context.Context, userID int64) ([]*model.Budget, error) {
+	rows, err := db.db.Pool.Query(ctx, `
+		SELECT `+budgetColumns+` FROM budgets
+		WHERE user_id = $1
+		ORDER BY category <> '', LOWER(category)
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("select budgets: %w", err)
+	}
+	defer rows.Close()
+
+	var budgets []*model.Budget
+	for rows.Next() {
+		budget, err := scanBudget(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan budgets: %w", err)
+		}
+		budgets = append(budgets, budget)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate budgets: %w", err)
+	}
+
+	return budgets, nil
+}
+
+// MarkAlerted records that the budget's threshold was reached in month, which
+// is the first day of the month. It returns false when that was recorded
+// already, so concurrent saves alert once.
+func (db *budgetDB) MarkAlerted(ctx context.Context, budgetID int64, month time.Time, threshold int, now time.Time) (bool, error) {
+	var marked bool
+	err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+			INSERT INTO budget_alerts (budget_id, month, threshold, sent_at)
+			VALUES($1, $2, $3, $4)
+			ON CONFLICT DO NOTHING
+		`, budgetID, month.Format(time.DateOnly), threshold, now)
+		if err != nil {
+			return fmt.Errorf("insert budget_alerts: %w", err)
+		}
+
+		marked = result.RowsAffected() == 1
+		return nil
+	})
+	return marked, err
+}
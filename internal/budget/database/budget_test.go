@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"github/shaolim/momon/internal/budget/model"
+	usermodel "github/shaolim/momon/internal/user/model"
+	"github/shaolim/momon/internal/user/usertest"
+	"github/shaolim/momon/pkg/database"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBudgets(t *testing.T) {
+	t.Parallel()
+
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	budgetDB := New(testDB)
+	ctx := context.Background()
+	user := usertest.MustAddUser(t, testDB, &usermodel.User{LineUserID: "line123"})
+
+	food := &model.Budget{UserID: user.ID, Category: "Food", Amount: 30000, Currency: "JPY"}
+	assert.NoError(t, budgetDB.SetBudget(ctx, food))
+	assert.NotZero(t, food.ID)
+	overall := &model.Budget{UserID: user.ID, Amount: 100000, Currency: "JPY"}
+	assert.NoError(t, budgetDB.SetBudget(ctx, overall))
+
+	// Setting a budget for the same category replaces it.
+	again := &model.Budget{UserID: user.ID, Category: "FOOD", Amount: 40000, Currency: "JPY"}
+	assert.NoError(t, budgetDB.SetBudget(ctx, again))
+	assert.Equal(t, food.ID, again.ID)
+	assert.Equal(t, "Food", again.Category)
+
+	budgets, err := budgetDB.ListBudgets(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("failed to list budgets: %v", err)
+	}
+	if assert.Len(t, budgets, 2) {
+		assert.Equal(t, overall.ID, budgets[0].ID)
+		assert.Equal(t, int64(40000), budgets[1].Amount)
+	}
+
+	assert.Error(t, budgetDB.SetBudget(ctx, &model.Budget{UserID: user.ID, Amount: 0, Currency: "JPY"}))
+
+	assert.NoError(t, budgetDB.DeleteBudget(ctx, user.ID, "food"))
+	err = budgetDB.DeleteBudget(ctx, user.ID, "food")
+	assert.True(t, errors.Is(err, database.ErrNotFound), "got %v", err)
+
+	budgets, _ = budgetDB.ListBudgets(ctx, user.ID)
+	assert.Len(t, budgets, 1)
+}
+
+func TestMarkAlerted(t *testing.T) {
+	t.Parallel()
+
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	budgetDB := New(testDB)
+	ctx := context.Background()
+	user := usertest.MustAddUser(t, testDB, &usermodel.User{LineUserID: "line123"})
+	now := time.Now()
+	october := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	budget := &model.Budget{UserID: user.ID, Amount: 100000, Currency: "JPY"}
+	if err := budgetDB.SetBudget(ctx, budget); err != nil {
+		t.Fatalf("failed to set budget: %v", err)
+	}
+
+	marked, err := budgetDB.MarkAlerted(ctx, budget.ID, october, 80, now)
+	assert.NoError(t, err)
+	assert.True(t, marked)
+
+	marked, err = budgetDB.MarkAlerted(ctx, budget.ID, october, 80, now)
+	assert.NoError(t, err)
+	assert.False(t, marked)
+
+	// Other thresholds and months are separate.
+	marked, _ = budgetDB.MarkAlerted(ctx, budget.ID, october, 100, now)
+	assert.True(t, marked)
+	marked, _ = budgetDB.MarkAlerted(ctx, budget.ID, october.AddDate(0, 1, 0), 80, now)
+	assert.True(t, marked)
+
+	// Changing the budget resets its alerts.
+	budget.Amount = 120000
+	assert.NoError(t, budgetDB.SetBudget(ctx, budget))
+	marked, _ = budgetDB.MarkAlerted(ctx, budget.ID, october, 80, now)
+	assert.True(t, marked)
+}
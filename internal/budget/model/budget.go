@@ -0,0 +1,70 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"github/shaolim/momon/pkg/money"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxCategoryLength matches the longest category name.
+const MaxCategoryLength = 50
+
+// Budget limits a user's spending per calendar month, in one category or,
+// when Category is empty, overall.
+type Budget struct {
+	ID       int64
+	UserID   int64
+	Category string
+	// Amount is in the currency's minor unit. Only expenses in the same
+	// currency count towards it.
+	Amount    int64
+	Currency  money.Currency
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (b *Budget) Validate() error {
+	if b.UserID == 0 {
+		return errors.New("user id must not be empty")
+	}
+
+	if utf8.RuneCountInString(b.Category) > MaxCategoryLength {
+		return fmt.Errorf("category must be at most %d characters", MaxCategoryLength)
+	}
+
+	if b.Amount <= 0 {
+		return errors.New("amount must be greater than zero")
+	}
+
+	if len(b.Currency) != 3 {
+		return errors.New("currency must be a 3-letter ISO 4217 code")
+	}
+
+	return nil
+}
+
+// Money returns the amount together with its currency.
+func (b *Budget) Money() money.Money {
+	return money.New(b.Amount, b.Currency)
+}
+
+// IsOverall reports whether the budget covers all spending.
+func (b *Budget) IsOverall() bool {
+	return b.Category == ""
+}
+
+// Covers reports whether an expense in category counts towards the budget.
+func (b *Budget) Covers(category string) bool {
+	return b.IsOverall() || strings.EqualFold(b.Category, category)
+}
+
+// Name is how the budget is referred to in messages.
+func (b *Budget) Name() string {
+	if b.IsOverall() {
+		return "overall"
+	}
+	return b.Category
+}
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBudget_Validate(t *testing.T) {
+	valid := func() *Budget {
+		return &Budget{UserID: 1, Category: "Food", Amount: 30000, Currency: "JPY"}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(b *Budget)
+		wantErr string
+	}{
+		{name: "valid", modify: func(*Budget) {}},
+		{name: "overall", modify: func(b *Budget) { b.Category = "" }},
+		{name: "no user", modify: func(b *Budget) { b.UserID = 0 }, wantErr: "user id must not be empty"},
+		{name: "long category", modify: func(b *Budget) { b.Category = strings.Repeat("a", 51) }, wantErr: "category must be at most 50 characters"},
+		{name: "zero amount", modify: func(b *Budget) { b.Amount = 0 }, wantErr: "amount must be greater than zero"},
+		{name: "bad currency", modify: func(b *Budget) { b.Currency = "YEN!" }, wantErr: "currency must be a 3-letter ISO 4217 code"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := valid()
+			tt.modify(b)
+
+			err := b.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBudget_Covers(t *testing.T) {
+	food := &Budget{Category: "Food"}
+	assert.True(t, food.Covers("food"))
+	assert.False(t, food.Covers("Transport"))
+	assert.False(t, food.Covers(""))
+	assert.Equal(t, "Food", food.Name())
+
+	overall := &Budget{}
+	assert.True(t, overall.Covers("Transport"))
+	assert.True(t, overall.Covers(""))
+	assert.Equal(t, "overall", overall.Name())
+}
@@ -0,0 +1,152 @@
+package category
+
+import (
+	"context"
+	"fmt"
+	categorydb "github/shaolim/momon/internal/category/database"
+	"github/shaolim/momon/internal/category/model"
+	"log/slog"
+	"strings"
+)
+
+// Expense is what an expense is categorized by.
+type Expense struct {
+	Merchant    string
+	Description string
+	// Items are the names of the items bought, e.g. from a receipt.
+	Items []string
+}
+
+func (e *Expense) texts() []string {
+	return append([]string{e.Merchant, e.Description}, e.Items...)
+}
+
+// Keyword is what a correction of the expense's category is remembered by:
+// the merchant, or the description when there is no merchant.
+func (e *Expense) Keyword() string {
+	if keyword := model.NormalizeKeyword(e.Merchant); keyword != "" {
+		return keyword
+	}
+	return model.NormalizeKeyword(e.Description)
+}
+
+// Suggester picks the category of an expense no rule knows. It returns "" when
+// none of categories fits.
+type Suggester interface {
+	Suggest(ctx context.Context, expense *Expense, categories []string) (string, error)
+}
+
+// Categorizer files expenses under the user's categories. Rules learned from
+// the user's corrections come first, then DefaultRules, then the suggester.
+type Categorizer struct {
+	db        categorydb.CategoryDB
+	suggester Suggester
+}
+
+// NewCategorizer returns a Categorizer. suggester may be nil, in which case
+// expenses no rule matches stay uncategorized.
+func NewCategorizer(db categorydb.CategoryDB, suggester Suggester) *Categorizer {
+	return &Categorizer{
+		db:        db,
+		suggester: suggester,
+	}
+}
+
+// Categories returns the user's categories, giving them the defaults the first
+// time.
+func (c *Categorizer) Categories(ctx context.Context, userID int64) ([]*model.Category, error) {
+	categories, err := c.db.ListCategories(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list categories: %w", err)
+	}
+	if len(categories) > 0 {
+		return categories, nil
+	}
+
+	if err := c.db.AddDefaultCategories(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to add default categories: %w", err)
+	}
+
+	categories, err = c.db.ListCategories(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list categories: %w", err)
+	}
+	return categories, nil
+}
+
+// Categorize returns the name of the user's category the expense belongs to,
+// or "" when it can't tell. A failing suggester is logged rather than
+// returned, since an uncategorized expense is better than none.
+func (c *Categorizer) Categorize(ctx context.Context, userID int64, expense *Expense) (string, error) {
+	categories, err := c.Categories(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	learned, err := c.db.ListRules(ctx, userID)
+	if err != nil {
+		return "", fmt.Errorf("failed to list rules: %w", err)
+	}
+	if name, ok := Match(learned, expense.texts()...); ok {
+		return name, nil
+	}
+
+	if name, ok := Match(DefaultRules, expense.texts()...); ok {
+		if category := find(categories, name); category != nil {
+			return category.Name, nil
+		}
+	}
+
+	if c.suggester == nil || expense.Keyword() == "" && len(expense.Items) == 0 {
+		return "", nil
+	}
+
+	names := make([]string, 0, len(categories))
+	for _, category := range categories {
+		names = append(names, category.Name)
+	}
+
+	name, err := c.suggester.Suggest(ctx, expense, names)
+	if err != nil {
+		slog.Warn("failed to suggest category", slog.Any("error", err))
+		return "", nil
+	}
+	// Only trust the model with categories the user has.
+	if category := find(categories, name); category != nil {
+		return category.Name, nil
+	}
+	return "", nil
+}
+
+// Learn files the expense, and later ones with the same keyword, under name.
+// The category is added when the user doesn't have it yet. It returns the
+// category's name as the user first spelled it.
+func (c *Categorizer) Learn(ctx context.Context, userID int64, expense *Expense, name string) (string, error) {
+	if _, err := c.Categories(ctx, userID); err != nil {
+		return "", err
+	}
+
+	category := &model.Category{UserID: userID, Name: name}
+	if err := c.db.AddCategory(ctx, category); err != nil {
+		return "", fmt.Errorf("failed to add category: %w", err)
+	}
+
+	if keyword := expense.Keyword(); keyword != "" {
+		rule := &model.Rule{UserID: userID, CategoryID: category.ID, Keyword: keyword}
+		if err := c.db.LearnRule(ctx, rule); err != nil {
+			return "", fmt.Errorf("failed to learn rule: %w", err)
+		}
+	}
+
+	return category.Name, nil
+}
+
+func find(categories []*model.Category, name string) *model.Category {
+	name = strings.TrimSpace(name)
+	for _, category := range categories {
+		if strings.EqualFold(category.Name, name) {
+			return category
+		}
+	}
+	return nil
+}
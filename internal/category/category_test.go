@@ -0,0 +1,148 @@
+package category
+
+import (
+	"context"
+	"errors"
+	"github/shaolim/momon/internal/category/categorytest"
+	"github/shaolim/momon/internal/category/model"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type suggesterFunc func(ctx context.Context, expense *Expense, categories []string) (string, error)
+
+func (f suggesterFunc) Suggest(ctx context.Context, expense *Expense, categories []string) (string, error) {
+	return f(ctx, expense, categories)
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		texts []string
+		want  string
+	}{
+		{name: "description", texts: []string{"", "Lunch with Bob"}, want: "Food"},
+		{name: "merchant", texts: []string{"LAWSON Shibuya", ""}, want: "Groceries"},
+		{name: "plural", texts: []string{"", "snacks"}, want: "Food"},
+		{name: "possessive", texts: []string{"McDonald's", ""}, want: "Food"},
+		{name: "japanese", texts: []string{"ローソン渋谷店", ""}, want: "Groceries"},
+		{name: "item", texts: []string{"", "", "Pizza Margherita"}, want: "Food"},
+		{name: "longest keyword wins", texts: []string{"", "gas bill"}, want: "Utilities"},
+		{name: "not part of a word", texts: []string{"", "business trip"}},
+		{name: "unknown", texts: []string{"", "haircut"}},
+		{name: "empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Match(DefaultRules, tt.texts...)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.want != "", ok)
+		})
+	}
+}
+
+func TestDefaultRules(t *testing.T) {
+	// Every rule files under a category users get.
+	for _, r := range DefaultRules {
+		assert.Contains(t, model.DefaultCategories, r.Category)
+		assert.Equal(t, model.NormalizeKeyword(r.Keyword), r.Keyword)
+	}
+}
+
+func TestExpense_Keyword(t *testing.T) {
+	assert.Equal(t, "grocery market", (&Expense{Merchant: " Grocery  Market", Description: "milk"}).Keyword())
+	assert.Equal(t, "haircut", (&Expense{Description: "Haircut"}).Keyword())
+	assert.Equal(t, "", (&Expense{Items: []string{"Milk"}}).Keyword())
+}
+
+func TestCategorizer(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("defaults and default rules", func(t *testing.T) {
+		db := categorytest.NewDB()
+		c := NewCategorizer(db, nil)
+
+		got, err := c.Categorize(ctx, 1, &Expense{Description: "taxi"})
+		assert.NoError(t, err)
+		assert.Equal(t, "Transport", got)
+
+		categories, _ := db.ListCategories(ctx, 1)
+		assert.Len(t, categories, len(model.DefaultCategories))
+
+		got, err = c.Categorize(ctx, 1, &Expense{Description: "haircut"})
+		assert.NoError(t, err)
+		assert.Empty(t, got)
+	})
+
+	t.Run("learns from corrections", func(t *testing.T) {
+		db := categorytest.NewDB()
+		c := NewCategorizer(db, nil)
+
+		// Lawson is groceries by default; this user buys lunch there.
+		name, err := c.Learn(ctx, 1, &Expense{Merchant: "LAWSON"}, "food")
+		assert.NoError(t, err)
+		assert.Equal(t, "Food", name)
+
+		got, err := c.Categorize(ctx, 1, &Expense{Merchant: "Lawson", Items: []string{"Onigiri"}})
+		assert.NoError(t, err)
+		assert.Equal(t, "Food", got)
+
+		// Other users are not affected.
+		got, err = c.Categorize(ctx, 2, &Expense{Merchant: "Lawson"})
+		assert.NoError(t, err)
+		assert.Equal(t, "Groceries", got)
+	})
+
+	t.Run("learning a new category", func(t *testing.T) {
+		db := categorytest.NewDB()
+		c := NewCategorizer(db, nil)
+
+		name, err := c.Learn(ctx, 1, &Expense{Description: "haircut"}, "Beauty")
+		assert.NoError(t, err)
+		assert.Equal(t, "Beauty", name)
+
+		categories, _ := db.ListCategories(ctx, 1)
+		assert.Len(t, categories, len(model.DefaultCategories)+1)
+
+		got, err := c.Categorize(ctx, 1, &Expense{Description: "haircut and shave"})
+		assert.NoError(t, err)
+		assert.Equal(t, "Beauty", got)
+
+		_, err = c.Learn(ctx, 1, &Expense{Description: "haircut"}, strings.Repeat("a", 51))
+		assert.Error(t, err)
+	})
+
+	t.Run("suggester for unknown expenses", func(t *testing.T) {
+		var offered []string
+		c := NewCategorizer(categorytest.NewDB(), suggesterFunc(func(_ context.Context, e *Expense, categories []string) (string, error) {
+			offered = categories
+			if e.Description == "haircut" {
+				return "health", nil
+			}
+			return "Travel", nil
+		}))
+
+		got, err := c.Categorize(ctx, 1, &Expense{Description: "haircut"})
+		assert.NoError(t, err)
+		assert.Equal(t, "Health", got)
+		assert.Equal(t, model.DefaultCategories, offered)
+
+		// Made-up categories are ignored.
+		got, err = c.Categorize(ctx, 1, &Expense{Description: "souvenirs"})
+		assert.NoError(t, err)
+		assert.Empty(t, got)
+	})
+
+	t.Run("failing suggester", func(t *testing.T) {
+		c := NewCategorizer(categorytest.NewDB(), suggesterFunc(func(context.Context, *Expense, []string) (string, error) {
+			return "", errors.New("rate limited")
+		}))
+
+		got, err := c.Categorize(ctx, 1, &Expense{Description: "haircut"})
+		assert.NoError(t, err)
+		assert.Empty(t, got)
+	})
+}
@@ -0,0 +1,101 @@
+// Package categorytest provides an in-memory CategoryDB for tests.
+package categorytest
+
+import (
+	"context"
+	categorydb "github/shaolim/momon/internal/category/database"
+	"github/shaolim/momon/internal/category/model"
+	"github/shaolim/momon/pkg/database"
+	"strings"
+)
+
+// DB is an in-memory categorydb.CategoryDB.
+type DB struct {
+	categories []*model.Category
+	rules      []*model.Rule
+}
+
+var _ categorydb.CategoryDB = (*DB)(nil)
+
+func NewDB() *DB {
+	return &DB{}
+}
+
+func (f *DB) AddDefaultCategories(ctx context.Context, userID int64) error {
+	categories, _ := f.ListCategories(ctx, userID)
+	if len(categories) > 0 {
+		return nil
+	}
+	for _, name := range model.DefaultCategories {
+		if err := f.AddCategory(ctx, &model.Category{UserID: userID, Name: name}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *DB) AddCategory(ctx context.Context, category *model.Category) error {
+	category.Name = strings.TrimSpace(category.Name)
+	if err := category.Validate(); err != nil {
+		return err
+	}
+	if existing, err := f.GetCategory(ctx, category.UserID, category.Name); err == nil {
+		*category = *existing
+		return nil
+	}
+	category.ID = int64(len(f.categories) + 1)
+	f.categories = append(f.categories, category)
+	return nil
+}
+
+func (f *DB) GetCategory(_ context.Context, userID int64, name string) (*model.Category, error) {
+	for _, c := range f.categories {
+		if c.UserID == userID && strings.EqualFold(c.Name, strings.TrimSpace(name)) {
+			return c, nil
+		}
+	}
+	return nil, database.ErrNotFound
+}
+
+func (f *DB) ListCategories(_ context.Context, userID int64) ([]*model.Category, error) {
+	var result []*model.Category
+	for _, c := range f.categories {
+		if c.UserID == userID {
+			result = append(result, c)
+		}
+	}
+	return result, nil
+}
+
+func (f *DB) LearnRule(_ context.Context, rule *model.Rule) error {
+	rule.Keyword = model.NormalizeKeyword(rule.Keyword)
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	for _, r := range f.rules {
+		if r.UserID == rule.UserID && r.Keyword == rule.Keyword {
+			r.CategoryID = rule.CategoryID
+			return nil
+		}
+	}
+	rule.ID = int64(len(f.rules) + 1)
+	f.rules = append(f.rules, rule)
+	return nil
+}
+
+func (f *DB) ListRules(_ context.Context, userID int64) ([]*model.Rule, error) {
+	var result []*model.Rule
+	for _, r := range f.rules {
+		if r.UserID != userID {
+			continue
+		}
+		copied := *r
+		for _, c := range f.categories {
+			if c.ID == r.CategoryID {
+				copied.Category = c.Name
+			}
+		}
+		result = append(result, &copied)
+	}
+	return result, nil
+}
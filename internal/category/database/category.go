@@ -0,0 +1,191 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github/shaolim/momon/internal/category/model"
+	"github/shaolim/momon/pkg/database"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type CategoryDB interface {
+	AddDefaultCategories(ctx context.Context, userID int64) error
+	AddCategory(ctx context.Context, category *model.Category) error
+	GetCategory(ctx context.Context, userID int64, name string) (*model.Category, error)
+	ListCategories(ctx context.Context, userID int64) ([]*model.Category, error)
+	LearnRule(ctx context.Context, rule *model.Rule) error
+	ListRules(ctx context.Context, userID int64) ([]*model.Rule, error)
+}
+
+type categoryDB struct {
+	db *database.DB
+}
+
+func New(db *database.DB) CategoryDB {
+	return &categoryDB{
+		db: db,
+	}
+}
+
+const categoryColumns = `id, user_id, name, created_at`
+
+func scanCategory(row pgx.Row) (*model.Category, error) {
+	var c model.Category
+	if err := row.Scan(&c.ID, &c.UserID, &c.Name, &c.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// AddDefaultCategories gives the user model.DefaultCategories unless they
+// have categories already.
+func (db *categoryDB) AddDefaultCategories(ctx context.Context, userID int64) error {
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO categories (user_id, name, created_at)
+			SELECT $1, name, $3 FROM UNNEST($2::TEXT[]) AS defaults(name)
+			WHERE NOT EXISTS (SELECT 1 FROM categories WHERE user_id = $1)
+			ON CONFLICT DO NOTHING
+		`, userID, model.DefaultCategories, time.Now()); err != nil {
+			return fmt.Errorf("insert categories: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// AddCategory saves the category. When the user has a category with the same
+// name, ignoring case, that one is kept and category is filled in from it.
+func (db *categoryDB) AddCategory(ctx context.Context, category *model.Category) error {
+	category.Name = strings.TrimSpace(category.Name)
+	if err := category.Validate(); err != nil {
+		return err
+	}
+
+	category.CreatedAt = time.Now()
+
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		// The no-op update makes RETURNING yield the existing row.
+		row := tx.QueryRow(ctx, `
+			INSERT INTO categories (user_id, name, created_at)
+			VALUES($1, $2, $3)
+			ON CONFLICT (user_id, LOWER(name)) DO UPDATE SET name = categories.name
+			RETURNING `+categoryColumns,
+			category.UserID, category.Name, category.CreatedAt)
+
+		saved, err := scanCategory(row)
+		if err != nil {
+			return fmt.Errorf("insert categories: %w", err)
+		}
+		*category = *saved
+
+		return nil
+	})
+}
+
+// GetCategory returns the user's category with the given name, ignoring case.
+func (db *categoryDB) GetCategory(ctx context.Context, userID int64, name string) (*model.Category, error) {
+	row := db.db.Pool.QueryRow(ctx, `
+		SELECT `+categoryColumns+` FROM categories
+		WHERE user_id = $1 AND LOWER(name) = LOWER($2)
+	`, userID, strings.TrimSpace(name))
+
+	category, err := scanCategory(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, database.ErrNotFound
+		}
+		return nil, fmt.Errorf("select categories: %w", err)
+	}
+
+	return category, nil
+}
+
+// ListCategories returns the user's categories in the order they were added.
+func (db *categoryDB) ListCategories(ctx context.Context, userID int64) ([]*model.Category, error) {
+	rows, err := db.db.Pool.Query(ctx, `
+		SELECT `+categoryColumns+` FROM categories
+		WHERE user_id = $1
+		ORDER BY id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("select categories: %w", err)
+	}
+	defer rows.Close()
+
+	var categories []*model.Category
+	for rows.Next() {
+		category, err := scanCategory(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan categories: %w", err)
+		}
+		categories = append(categories, category)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate categories: %w", err)
+	}
+
+	return categories, nil
+}
+
+// LearnRule saves the rule, replacing the category of an existing rule for
+// the same keyword.
+func (db *categoryDB) LearnRule(ctx context.Context, rule *model.Rule) error {
+	rule.Keyword = model.NormalizeKeyword(rule.Keyword)
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	rule.CreatedAt = now
+	rule.UpdatedAt = now
+
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, `
+			INSERT INTO category_rules (user_id, category_id, keyword, created_at, updated_at)
+			VALUES($1, $2, $3, $4, $5)
+			ON CONFLICT (user_id, keyword) DO UPDATE SET category_id = EXCLUDED.category_id, updated_at = EXCLUDED.updated_at
+			RETURNING id, created_at
+		`, rule.UserID, rule.CategoryID, rule.Keyword, rule.CreatedAt, rule.UpdatedAt)
+
+		if err := row.Scan(&rule.ID, &rule.CreatedAt); err != nil {
+			return fmt.Errorf("insert category_rules: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// ListRules returns the user's rules with their category names, most recently
+// learned first.
+func (db *categoryDB) ListRules(ctx context.Context, userID int64) ([]*model.Rule, error) {
+	rows, err := db.db.Pool.Query(ctx, `
+		SELECT r.id, r.user_id, r.category_id, c.name, r.keyword, r.created_at, r.updated_at
+		FROM category_rules r
+		JOIN categories c ON c.id = r.category_id
+		WHERE r.user_id = $1
+		ORDER BY r.updated_at DESC, r.id DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("select category_rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []*model.Rule
+	for rows.Next() {
+		var r model.Rule
+		if err := rows.Scan(&r.ID, &r.UserID, &r.CategoryID, &r.Category, &r.Keyword, &r.CreatedAt, &r.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan category_rules: %w", err)
+		}
+		rules = append(rules, &r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate category_rules: %w", err)
+	}
+
+	return rules, nil
+}
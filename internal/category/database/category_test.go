@@ -0,0 +1,112 @@
+package database
+
+import (
+	"context"
+	"github/shaolim/momon/internal/category/model"
+	usermodel "github/shaolim/momon/internal/user/model"
+	"github/shaolim/momon/internal/user/usertest"
+	"github/shaolim/momon/pkg/database"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCategories(t *testing.T) {
+	t.Parallel()
+
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	categoryDB := New(testDB)
+	ctx := context.Background()
+	user := usertest.MustAddUser(t, testDB, &usermodel.User{LineUserID: "line123"})
+
+	assert.NoError(t, categoryDB.AddDefaultCategories(ctx, user.ID))
+
+	categories, err := categoryDB.ListCategories(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("failed to list categories: %v", err)
+	}
+	var names []string
+	for _, c := range categories {
+		names = append(names, c.Name)
+	}
+	assert.Equal(t, model.DefaultCategories, names)
+
+	// A user's own category is added next to the defaults.
+	pets := &model.Category{UserID: user.ID, Name: " Pets "}
+	assert.NoError(t, categoryDB.AddCategory(ctx, pets))
+	assert.NotZero(t, pets.ID)
+	assert.Equal(t, "Pets", pets.Name)
+
+	// Adding the same name in another case returns the existing category.
+	again := &model.Category{UserID: user.ID, Name: "PETS"}
+	assert.NoError(t, categoryDB.AddCategory(ctx, again))
+	assert.Equal(t, pets.ID, again.ID)
+	assert.Equal(t, "Pets", again.Name)
+
+	got, err := categoryDB.GetCategory(ctx, user.ID, "pets")
+	if assert.NoError(t, err) {
+		assert.Equal(t, pets.ID, got.ID)
+	}
+	_, err = categoryDB.GetCategory(ctx, user.ID, "Travel")
+	assert.ErrorIs(t, err, database.ErrNotFound)
+
+	// Defaults are only given once.
+	assert.NoError(t, categoryDB.AddDefaultCategories(ctx, user.ID))
+	categories, err = categoryDB.ListCategories(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("failed to list categories: %v", err)
+	}
+	assert.Len(t, categories, len(model.DefaultCategories)+1)
+
+	// Categories are per user.
+	other := usertest.MustAddUser(t, testDB, &usermodel.User{LineUserID: "line456"})
+	categories, err = categoryDB.ListCategories(ctx, other.ID)
+	if err != nil {
+		t.Fatalf("failed to list categories: %v", err)
+	}
+	assert.Empty(t, categories)
+
+	assert.EqualError(t, categoryDB.AddCategory(ctx, &model.Category{UserID: user.ID}), "name must not be empty")
+}
+
+func TestRules(t *testing.T) {
+	t.Parallel()
+
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	categoryDB := New(testDB)
+	ctx := context.Background()
+	user := usertest.MustAddUser(t, testDB, &usermodel.User{LineUserID: "line123"})
+
+	food := &model.Category{UserID: user.ID, Name: "Food"}
+	groceries := &model.Category{UserID: user.ID, Name: "Groceries"}
+	for _, c := range []*model.Category{food, groceries} {
+		if err := categoryDB.AddCategory(ctx, c); err != nil {
+			t.Fatalf("failed to add category: %v", err)
+		}
+	}
+
+	rule := &model.Rule{UserID: user.ID, CategoryID: food.ID, Keyword: "  LAWSON "}
+	assert.NoError(t, categoryDB.LearnRule(ctx, rule))
+	assert.NotZero(t, rule.ID)
+	assert.Equal(t, "lawson", rule.Keyword)
+
+	// Correcting the same keyword again replaces its category.
+	corrected := &model.Rule{UserID: user.ID, CategoryID: groceries.ID, Keyword: "lawson"}
+	assert.NoError(t, categoryDB.LearnRule(ctx, corrected))
+	assert.Equal(t, rule.ID, corrected.ID)
+
+	assert.NoError(t, categoryDB.LearnRule(ctx, &model.Rule{UserID: user.ID, CategoryID: food.ID, Keyword: "lunch"}))
+
+	rules, err := categoryDB.ListRules(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("failed to list rules: %v", err)
+	}
+	if assert.Len(t, rules, 2) {
+		assert.Equal(t, "lunch", rules[0].Keyword)
+		assert.Equal(t, "Food", rules[0].Category)
+		assert.Equal(t, "lawson", rules[1].Keyword)
+		assert.Equal(t, "Groceries", rules[1].Category)
+	}
+
+	assert.EqualError(t, categoryDB.LearnRule(ctx, &model.Rule{UserID: user.ID, CategoryID: food.ID, Keyword: " "}), "keyword must not be empty")
+}
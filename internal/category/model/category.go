@@ -0,0 +1,87 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxNameLength is the longest category name in characters.
+const MaxNameLength = 50
+
+// DefaultCategories are given to every user the first time their expenses are
+// categorized.
+var DefaultCategories = []string{
+	"Food",
+	"Groceries",
+	"Transport",
+	"Utilities",
+	"Housing",
+	"Shopping",
+	"Entertainment",
+	"Health",
+	"Other",
+}
+
+// Category groups a user's expenses, e.g. Food or Transport. Transactions
+// keep the category's name.
+type Category struct {
+	ID        int64
+	UserID    int64
+	Name      string
+	CreatedAt time.Time
+}
+
+func (c *Category) Validate() error {
+	if c.UserID == 0 {
+		return errors.New("user id must not be empty")
+	}
+
+	if strings.TrimSpace(c.Name) == "" {
+		return errors.New("name must not be empty")
+	}
+
+	if utf8.RuneCountInString(c.Name) > MaxNameLength {
+		return fmt.Errorf("name must be at most %d characters", MaxNameLength)
+	}
+
+	return nil
+}
+
+// Rule files expenses whose merchant, description or items contain Keyword
+// under a category. Users get rules by correcting a category.
+type Rule struct {
+	ID         int64
+	UserID     int64
+	CategoryID int64
+	// Category is the category's name. It is read with the rule and ignored
+	// when saving it.
+	Category  string
+	Keyword   string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (r *Rule) Validate() error {
+	if r.UserID == 0 {
+		return errors.New("user id must not be empty")
+	}
+
+	if r.CategoryID == 0 {
+		return errors.New("category id must not be empty")
+	}
+
+	if r.Keyword == "" {
+		return errors.New("keyword must not be empty")
+	}
+
+	return nil
+}
+
+// NormalizeKeyword lowercases s and collapses its whitespace, so "LAWSON
+// Shibuya" and "lawson  shibuya" are the same keyword.
+func NormalizeKeyword(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCategory_Validate(t *testing.T) {
+	assert.NoError(t, (&Category{UserID: 1, Name: "Food"}).Validate())
+	assert.EqualError(t, (&Category{Name: "Food"}).Validate(), "user id must not be empty")
+	assert.EqualError(t, (&Category{UserID: 1, Name: "  "}).Validate(), "name must not be empty")
+	assert.EqualError(t, (&Category{UserID: 1, Name: strings.Repeat("a", 51)}).Validate(), "name must be at most 50 characters")
+	// Length is counted in characters, not bytes.
+	assert.NoError(t, (&Category{UserID: 1, Name: strings.Repeat("食", 50)}).Validate())
+}
+
+func TestRule_Validate(t *testing.T) {
+	assert.NoError(t, (&Rule{UserID: 1, CategoryID: 2, Keyword: "lawson"}).Validate())
+	assert.EqualError(t, (&Rule{UserID: 1, Keyword: "lawson"}).Validate(), "category id must not be empty")
+	assert.EqualError(t, (&Rule{UserID: 1, CategoryID: 2}).Validate(), "keyword must not be empty")
+}
+
+func TestNormalizeKeyword(t *testing.T) {
+	assert.Equal(t, "lawson shibuya", NormalizeKeyword("  LAWSON \t Shibuya "))
+	assert.Equal(t, "ローソン", NormalizeKeyword("ローソン"))
+	assert.Equal(t, "", NormalizeKeyword("   "))
+}
@@ -0,0 +1,77 @@
+package category
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/openai/openai-go/v3"
+)
+
+const openAIPrompt = `You file expenses recorded in a money tracking bot under the user's categories.
+
+The user's categories are: %s
+
+You get the merchant, the description and the items bought, any of which may be empty or in another language.
+
+Return ONLY a JSON object with this field:
+{
+    "category": "One of the user's categories, spelled exactly as listed"
+}
+
+If none of the categories fits, or the expense is too vague to tell, return {"category": ""}`
+
+type openAISuggestion struct {
+	Category string `json:"category"`
+}
+
+// OpenAI asks the model to categorize expenses no rule knows, such as
+// "haircut" or an unfamiliar shop.
+type OpenAI struct {
+	client *openai.Client
+}
+
+func NewOpenAI(client *openai.Client) *OpenAI {
+	return &OpenAI{
+		client: client,
+	}
+}
+
+func (s *OpenAI) Suggest(ctx context.Context, expense *Expense, categories []string) (string, error) {
+	req := openai.ChatCompletionNewParams{
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.SystemMessage(fmt.Sprintf(openAIPrompt, strings.Join(categories, ", "))),
+			openai.UserMessage(describeExpense(expense)),
+		},
+		Model: openai.ChatModelGPT4oMini,
+		ResponseFormat: openai.ChatCompletionNewParamsResponseFormatUnion{
+			OfJSONObject: &openai.ResponseFormatJSONObjectParam{},
+		},
+	}
+
+	resp, err := s.client.Chat.Completions.New(ctx, req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get response from OpenAI: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("no choices returned from OpenAI")
+	}
+	content := resp.Choices[0].Message.Content
+
+	var result openAISuggestion
+	if err := json.Unmarshal([]byte(content), &result); err != nil {
+		return "", fmt.Errorf("failed to unmarshal suggestion: %w, content: %s", err, content)
+	}
+
+	return strings.TrimSpace(result.Category), nil
+}
+
+// describeExpense lays the expense out for the model, one field per line.
+func describeExpense(e *Expense) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Merchant: %s\n", e.Merchant)
+	fmt.Fprintf(&b, "Description: %s\n", e.Description)
+	fmt.Fprintf(&b, "Items: %s", strings.Join(e.Items, ", "))
+	return b.String()
+}
@@ -0,0 +1,113 @@
+package category
+
+import (
+	"github/shaolim/momon/internal/category/model"
+	"strings"
+)
+
+// defaultKeywords maps each of model.DefaultCategories to words that give an
+// expense away, e.g. a chain's name or what was bought.
+var defaultKeywords = map[string][]string{
+	"Food": {
+		"breakfast", "brunch", "lunch", "dinner", "snack", "coffee", "cafe", "café", "restaurant",
+		"bakery", "pizza", "burger", "sushi", "ramen", "udon", "bento", "starbucks", "mcdonald",
+		"doutor", "yoshinoya", "sukiya", "ランチ", "弁当", "カフェ", "スターバックス",
+	},
+	"Groceries": {
+		"grocery", "groceries", "supermarket", "market", "lawson", "familymart", "family mart",
+		"7-eleven", "seven-eleven", "aeon", "seiyu", "costco", "ローソン", "ファミリーマート",
+		"セブンイレブン", "スーパー", "コンビニ",
+	},
+	"Transport": {
+		"taxi", "uber", "train", "bus", "subway", "metro", "suica", "pasmo", "shinkansen",
+		"parking", "toll", "gasoline", "fuel", "flight", "タクシー", "電車", "バス", "新幹線",
+	},
+	"Utilities": {
+		"electricity", "electric bill", "water bill", "gas bill", "internet", "wifi",
+		"phone bill", "docomo", "softbank", "電気", "水道", "ガス代",
+	},
+	"Housing": {
+		"rent", "mortgage", "家賃",
+	},
+	"Shopping": {
+		"amazon", "uniqlo", "muji", "ikea", "daiso", "don quijote", "clothes", "shoes", "ユニクロ", "ダイソー",
+	},
+	"Entertainment": {
+		"movie", "cinema", "netflix", "spotify", "concert", "karaoke", "game", "映画", "カラオケ",
+	},
+	"Health": {
+		"pharmacy", "drugstore", "clinic", "hospital", "dentist", "doctor", "medicine", "gym",
+		"薬局", "病院",
+	},
+}
+
+// DefaultRules file expenses under model.DefaultCategories until the user
+// teaches the bot otherwise.
+var DefaultRules = defaultRules()
+
+func defaultRules() []*model.Rule {
+	var rules []*model.Rule
+	for _, category := range model.DefaultCategories {
+		for _, keyword := range defaultKeywords[category] {
+			rules = append(rules, &model.Rule{Category: category, Keyword: keyword})
+		}
+	}
+	return rules
+}
+
+// Match returns the category of the rule whose keyword appears in any of
+// texts, ignoring case. When several match, the longest keyword wins, so
+// "gas bill" beats "gas"; ties go to the earlier rule.
+func Match(rules []*model.Rule, texts ...string) (string, bool) {
+	var best *model.Rule
+	for _, text := range texts {
+		text = model.NormalizeKeyword(text)
+		if text == "" {
+			continue
+		}
+		for _, r := range rules {
+			if best != nil && len(r.Keyword) <= len(best.Keyword) {
+				continue
+			}
+			if containsWord(text, model.NormalizeKeyword(r.Keyword)) {
+				best = r
+			}
+		}
+	}
+
+	if best == nil {
+		return "", false
+	}
+	return best.Category, true
+}
+
+// containsWord reports whether keyword appears in text as whole words, so
+// "bus" is found in "bus fare" but not in "business". A trailing "s" is
+// allowed for plurals. Scripts without spaces, such as Japanese, match
+// anywhere.
+func containsWord(text, keyword string) bool {
+	if keyword == "" {
+		return false
+	}
+
+	for start := 0; ; {
+		i := strings.Index(text[start:], keyword)
+		if i < 0 {
+			return false
+		}
+		i += start
+		end := i + len(keyword)
+		if end < len(text) && text[end] == 's' {
+			end++
+		}
+
+		if (i == 0 || !isWordByte(text[i-1])) && (end == len(text) || !isWordByte(text[end])) {
+			return true
+		}
+		start = i + 1
+	}
+}
+
+func isWordByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= '0' && b <= '9'
+}
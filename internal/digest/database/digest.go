@@ -0,0 +1,159 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"github/shaolim/momon/internal/digest/model"
+	"github/shaolim/momon/pkg/database"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type DigestDB interface {
+	SetDigest(ctx context.Context, digest *model.Digest) error
+	DeleteDigest(ctx context.Context, userID int64, period model.Period) error
+	ListDigests(ctx context.Context, userID int64) ([]*model.Digest, error)
+	ListDueDigests(ctx context.Context, now time.Time, limit int) ([]*model.Digest, error)
+	MarkSent(ctx context.Context, digest *model.Digest, next time.Time) (bool, error)
+}
+
+type digestDB struct {
+	db *database.DB
+}
+
+func New(db *database.DB) DigestDB {
+	return &digestDB{
+		db: db,
+	}
+}
+
+const digestColumns = `d.id, d.user_id, d.period, d.weekday, d.hour, d.minute, d.next_run_at, u.timezone, d.created_at, d.updated_at`
+
+func scanDigest(row pgx.Row) (*model.Digest, error) {
+	var (
+		d       model.Digest
+		weekday int
+	)
+	if err := row.Scan(&d.ID, &d.UserID, &d.Period, &weekday, &d.Hour, &d.Minute, &d.NextRunAt, &d.Timezone,
+		&d.CreatedAt, &d.UpdatedAt); err != nil {
+		return nil, err
+	}
+	d.Weekday = time.Weekday(weekday)
+	return &d, nil
+}
+
+// SetDigest saves the digest, replacing the user's digest for the same
+// period.
+func (db *digestDB) SetDigest(ctx context.Context, digest *model.Digest) error {
+	if err := digest.Validate(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	digest.CreatedAt = now
+	digest.UpdatedAt = now
+
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, `
+			INSERT INTO digests (user_id, period, weekday, hour, minute, next_run_at, created_at, updated_at)
+			VALUES($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (user_id, period) DO UPDATE
+			SET weekday = EXCLUDED.weekday, hour = EXCLUDED.hour, minute = EXCLUDED.minute,
+				next_run_at = EXCLUDED.next_run_at, updated_at = EXCLUDED.updated_at
+			RETURNING id, created_at
+		`, digest.UserID, digest.Period, int(digest.Weekday), digest.Hour, digest.Minute, digest.NextRunAt,
+			digest.CreatedAt, digest.UpdatedAt)
+
+		if err := row.Scan(&digest.ID, &digest.CreatedAt); err != nil {
+			return fmt.Errorf("insert digests: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// DeleteDigest stops the user's digest for period.
+func (db *digestDB) DeleteDigest(ctx context.Context, userID int64, period model.Period) error {
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `DELETE FROM digests WHERE user_id = $1 AND period = $2`, userID, period)
+		if err != nil {
+			return fmt.Errorf("delete digests: %w", err)
+		}
+
+		if result.RowsAffected() == 0 {
+			return database.ErrNotFound
+		}
+
+		return nil
+	})
+}
+
+// ListDigests returns the user's digests, the daily one first.
+func (db *digestDB) ListDigests(ctx context.Context, userID int64) ([]*model.Digest, error) {
+	return db.listDigests(ctx, `
+		SELECT `+digestColumns+`
+		FROM digests d
+		JOIN users u ON u.id = d.user_id
+		WHERE d.user_id = $1
+		ORDER BY d.period
+	`, userID)
+}
+
+// ListDueDigests returns up to limit digests whose next run is at or before
+// now, the longest overdue first.
+func (db *digestDB) ListDueDigests(ctx context.Context, now time.Time, limit int) ([]*model.Digest, error) {
+	return db.listDigests(ctx, `
+		SELECT `+digestColumns+`
+		FROM digests d
+		JOIN users u ON u.id = d.user_id
+		WHERE d.next_run_at <= $1
+		ORDER BY d.next_run_at, d.id
+		LIMIT $2
+	`, now, limit)
+}
+
+func (db *digestDB) listDigests(ctx context.Context, query string, args ...any) ([]*model.Digest, error) {
+	rows, err := db.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("select digests: %w", err)
+	}
+	defer rows.Close()
+
+	var digests []*model.Digest
+	for rows.Next() {
+		digest, err := scanDigest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan digests: %w", err)
+		}
+		digests = append(digests, digest)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate digests: %w", err)
+	}
+
+	return digests, nil
+}
+
+// MarkSent moves the digest due at digest.NextRunAt on to next, before it is
+// pushed. It returns false when the digest is no longer due then: another
+// instance is sending it, or the user changed its schedule since it was
+// listed. A digest marked sent whose push then fails is not sent again.
+func (db *digestDB) MarkSent(ctx context.Context, digest *model.Digest, next time.Time) (bool, error) {
+	var marked bool
+	now := time.Now()
+	err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+			UPDATE digests
+			SET next_run_at = $3, updated_at = $4
+			WHERE id = $1 AND next_run_at = $2
+		`, digest.ID, digest.NextRunAt, next, now)
+		if err != nil {
+			return fmt.Errorf("update digests: %w", err)
+		}
+
+		marked = result.RowsAffected() == 1
+		return nil
+	})
+	return marked, err
+}
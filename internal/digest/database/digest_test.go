@@ -0,0 +1,66 @@
+package database
+
+import (
+	"context"
+	"github/shaolim/momon/internal/digest/model"
+	usermodel "github/shaolim/momon/internal/user/model"
+	"github/shaolim/momon/internal/user/usertest"
+	"github/shaolim/momon/pkg/database"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDigests(t *testing.T) {
+	t.Parallel()
+
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	digestDB := New(testDB)
+	ctx := context.Background()
+	user := usertest.MustAddUser(t, testDB, &usermodel.User{LineUserID: "line123", Timezone: "Asia/Tokyo"})
+	first := time.Date(2025, 10, 15, 12, 0, 0, 0, time.UTC)
+
+	weekly := &model.Digest{UserID: user.ID, Period: model.PeriodWeekly, Weekday: time.Sunday, Hour: 20, NextRunAt: first.AddDate(0, 0, 4)}
+	assert.NoError(t, digestDB.SetDigest(ctx, weekly))
+	assert.NotZero(t, weekly.ID)
+	daily := &model.Digest{UserID: user.ID, Period: model.PeriodDaily, Hour: 20, NextRunAt: first.Add(time.Hour)}
+	assert.NoError(t, digestDB.SetDigest(ctx, daily))
+
+	// Setting the same period again replaces it.
+	again := &model.Digest{UserID: user.ID, Period: model.PeriodDaily, Hour: 21, NextRunAt: first}
+	assert.NoError(t, digestDB.SetDigest(ctx, again))
+	assert.Equal(t, daily.ID, again.ID)
+
+	digests, err := digestDB.ListDigests(ctx, user.ID)
+	if assert.NoError(t, err) && assert.Len(t, digests, 2) {
+		assert.Equal(t, daily.ID, digests[0].ID)
+		assert.Equal(t, 21, digests[0].Hour)
+		assert.Equal(t, "Asia/Tokyo", digests[0].Timezone)
+		assert.Equal(t, time.Sunday, digests[1].Weekday)
+	}
+
+	due, err := digestDB.ListDueDigests(ctx, first, 10)
+	if assert.NoError(t, err) && assert.Len(t, due, 1) {
+		assert.Equal(t, daily.ID, due[0].ID)
+	}
+
+	// Only the first instance to mark a run sends it.
+	next := first.AddDate(0, 0, 1)
+	marked, err := digestDB.MarkSent(ctx, due[0], next)
+	assert.NoError(t, err)
+	assert.True(t, marked)
+	marked, err = digestDB.MarkSent(ctx, due[0], next)
+	assert.NoError(t, err)
+	assert.False(t, marked)
+
+	due, _ = digestDB.ListDueDigests(ctx, first, 10)
+	assert.Empty(t, due)
+
+	assert.NoError(t, digestDB.DeleteDigest(ctx, user.ID, model.PeriodWeekly))
+	assert.ErrorIs(t, digestDB.DeleteDigest(ctx, user.ID, model.PeriodWeekly), database.ErrNotFound)
+	digests, _ = digestDB.ListDigests(ctx, user.ID)
+	assert.Len(t, digests, 1)
+
+	assert.Error(t, digestDB.SetDigest(ctx, &model.Digest{UserID: user.ID, Period: model.PeriodDaily, Hour: 25, NextRunAt: first}))
+}
@@ -0,0 +1,121 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+type Period string
+
+const (
+	PeriodDaily  = "DAILY"
+	PeriodWeekly = "WEEKLY"
+)
+
+// DefaultHour is when digests are sent unless the user picks a time.
+const DefaultHour = 21
+
+// Digest is a user's opt-in summary of their spending, pushed once a day or
+// once a week. It covers the day or week up to when it is sent.
+type Digest struct {
+	ID     int64
+	UserID int64
+	Period Period
+	// Weekday is the day weekly digests are sent on.
+	Weekday time.Weekday
+	// Hour and Minute are the time of day, in the user's time zone, the
+	// digest is sent at.
+	Hour   int
+	Minute int
+	// NextRunAt is when the next digest is due.
+	NextRunAt time.Time
+	// Timezone is the user's. It is read with the digest and ignored when
+	// saving it.
+	Timezone string
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (d *Digest) Validate() error {
+	if d.UserID == 0 {
+		return errors.New("user id must not be empty")
+	}
+
+	switch d.Period {
+	case PeriodDaily, PeriodWeekly:
+	default:
+		return errors.New("period must be either DAILY or WEEKLY")
+	}
+
+	if d.Weekday < time.Sunday || d.Weekday > time.Saturday {
+		return errors.New("weekday must be between 0 and 6")
+	}
+
+	if d.Hour < 0 || d.Hour > 23 || d.Minute < 0 || d.Minute > 59 {
+		return errors.New("time of day must be between 00:00 and 23:59")
+	}
+
+	if d.NextRunAt.IsZero() {
+		return errors.New("next run at must not be empty")
+	}
+
+	return nil
+}
+
+// Location returns the user's time zone, or fallback when they have not set
+// one.
+func (d *Digest) Location(fallback *time.Location) *time.Location {
+	if d.Timezone == "" {
+		return fallback
+	}
+
+	loc, err := time.LoadLocation(d.Timezone)
+	if err != nil {
+		return fallback
+	}
+
+	return loc
+}
+
+// Next returns the first time the digest is due after after, in loc.
+func (d *Digest) Next(after time.Time, loc *time.Location) time.Time {
+	after = after.In(loc)
+	for i := 0; ; i++ {
+		t := time.Date(after.Year(), after.Month(), after.Day()+i, d.Hour, d.Minute, 0, 0, loc)
+		if d.Period == PeriodWeekly && t.Weekday() != d.Weekday {
+			continue
+		}
+		if t.After(after) {
+			return t
+		}
+	}
+}
+
+// Start returns when the period a digest sent at end covers began: a day or a
+// week earlier in loc.
+func (d *Digest) Start(end time.Time, loc *time.Location) time.Time {
+	if d.Period == PeriodWeekly {
+		return end.In(loc).AddDate(0, 0, -7)
+	}
+	return end.In(loc).AddDate(0, 0, -1)
+}
+
+// Unit is the period in messages, "day" or "week".
+func (d *Digest) Unit() string {
+	if d.Period == PeriodWeekly {
+		return "week"
+	}
+	return "day"
+}
+
+// Schedule describes when the digest is sent, e.g. "weekly on Sunday at
+// 21:00".
+func (d *Digest) Schedule() string {
+	at := fmt.Sprintf("at %02d:%02d", d.Hour, d.Minute)
+	if d.Period == PeriodWeekly {
+		return "weekly on " + d.Weekday.String() + " " + at
+	}
+	return "daily " + at
+}
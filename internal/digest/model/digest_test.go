@@ -0,0 +1,113 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDigest_Validate(t *testing.T) {
+	valid := func() *Digest {
+		return &Digest{
+			UserID:    1,
+			Period:    PeriodDaily,
+			Hour:      21,
+			NextRunAt: time.Date(2025, 10, 15, 21, 0, 0, 0, time.UTC),
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(d *Digest)
+		wantErr string
+	}{
+		{name: "valid", modify: func(*Digest) {}},
+		{name: "weekly", modify: func(d *Digest) { d.Period = PeriodWeekly; d.Weekday = time.Saturday }},
+		{name: "no user", modify: func(d *Digest) { d.UserID = 0 }, wantErr: "user id must not be empty"},
+		{name: "bad period", modify: func(d *Digest) { d.Period = "MONTHLY" }, wantErr: "period must be either DAILY or WEEKLY"},
+		{name: "bad weekday", modify: func(d *Digest) { d.Weekday = 7 }, wantErr: "weekday must be between 0 and 6"},
+		{name: "bad hour", modify: func(d *Digest) { d.Hour = 24 }, wantErr: "time of day must be between 00:00 and 23:59"},
+		{name: "bad minute", modify: func(d *Digest) { d.Minute = 60 }, wantErr: "time of day must be between 00:00 and 23:59"},
+		{name: "no next run", modify: func(d *Digest) { d.NextRunAt = time.Time{} }, wantErr: "next run at must not be empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := valid()
+			tt.modify(d)
+
+			err := d.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDigest_Next(t *testing.T) {
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2025, 10, day, hour, minute, 0, 0, tokyo)
+	}
+
+	tests := []struct {
+		name     string
+		digest   Digest
+		after    time.Time
+		want     time.Time
+		schedule string
+	}{
+		{
+			name:     "daily later today",
+			digest:   Digest{Period: PeriodDaily, Hour: 21},
+			after:    at(15, 19, 30),
+			want:     at(15, 21, 0),
+			schedule: "daily at 21:00",
+		},
+		{
+			name:     "daily once sent",
+			digest:   Digest{Period: PeriodDaily, Hour: 21},
+			after:    at(15, 21, 0),
+			want:     at(16, 21, 0),
+			schedule: "daily at 21:00",
+		},
+		{
+			name:     "daily in the morning",
+			digest:   Digest{Period: PeriodDaily, Hour: 7, Minute: 30},
+			after:    at(15, 19, 30),
+			want:     at(16, 7, 30),
+			schedule: "daily at 07:30",
+		},
+		{
+			name:     "weekly",
+			digest:   Digest{Period: PeriodWeekly, Weekday: time.Sunday, Hour: 20},
+			after:    at(15, 19, 30), // a Wednesday
+			want:     at(19, 20, 0),
+			schedule: "weekly on Sunday at 20:00",
+		},
+		{
+			name:     "weekly once sent",
+			digest:   Digest{Period: PeriodWeekly, Weekday: time.Sunday, Hour: 20},
+			after:    at(19, 20, 0),
+			want:     at(26, 20, 0),
+			schedule: "weekly on Sunday at 20:00",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.digest.Next(tt.after, tokyo))
+			assert.Equal(t, tt.schedule, tt.digest.Schedule())
+		})
+	}
+}
+
+func TestDigest_Start(t *testing.T) {
+	end := time.Date(2025, 10, 19, 20, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, time.Date(2025, 10, 18, 20, 0, 0, 0, time.UTC), (&Digest{Period: PeriodDaily}).Start(end, time.UTC))
+	assert.Equal(t, time.Date(2025, 10, 12, 20, 0, 0, 0, time.UTC), (&Digest{Period: PeriodWeekly}).Start(end, time.UTC))
+}
@@ -0,0 +1,14 @@
+package e2e
+
+import (
+	"github/shaolim/momon/pkg/database"
+	"testing"
+)
+
+var testDatabaseInstance *database.TestInstance
+
+func TestMain(m *testing.M) {
+	testDatabaseInstance = database.MustTestInstance()
+	defer testDatabaseInstance.MustClose()
+	m.Run()
+}
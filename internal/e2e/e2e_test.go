@@ -0,0 +1,313 @@
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"github/shaolim/momon/internal/messaging"
+	"github/shaolim/momon/internal/receipt"
+	receiptmodel "github/shaolim/momon/internal/receipt/model"
+	"github/shaolim/momon/internal/serverenv"
+	transactiondb "github/shaolim/momon/internal/transaction/database"
+	"github/shaolim/momon/internal/transaction/model"
+	userdb "github/shaolim/momon/internal/user/database"
+	usermodel "github/shaolim/momon/internal/user/model"
+	msg "github/shaolim/momon/pkg/messaging"
+	"github/shaolim/momon/pkg/messaging/linetest"
+	"github/shaolim/momon/pkg/messaging/telegramtest"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const channelSecret = "e2e-secret"
+
+// send posts the events to the bot and waits for its replies.
+func send(t *testing.T, line *linetest.Server, url string, wantReplies int, events ...linetest.Event) []linetest.Reply {
+	t.Helper()
+
+	resp, err := line.SendWebhook(url, events...)
+	if err != nil {
+		t.Fatalf("failed to send webhook: %v", err)
+	}
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	replies, err := line.WaitForReplies(wantReplies, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return replies
+}
+
+func TestLineWebhook(t *testing.T) {
+	ctx := context.Background()
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+
+	line := linetest.NewServer(channelSecret)
+	defer line.Close()
+	line.SetProfile("U1", "Alice")
+	line.SetContent("image-1", []byte("jpeg bytes"), "image/jpeg")
+
+	messagingConfig := &msg.Config{LineChannelSecret: channelSecret, LineChannelToken: "token"}
+	lineAPI, err := msg.NewLineMessaging(messagingConfig, msg.WithBaseURL(line.URL))
+	if err != nil {
+		t.Fatalf("failed to create LINE client: %v", err)
+	}
+
+	fixture := receipt.NewFixture(&receiptmodel.Receipt{
+		Shop:            "Grocery Market",
+		TransactionDate: "2024-02-20 09:15",
+		Items: []receiptmodel.Item{
+			{Name: "Bread", Quantity: 1, Price: 50, Tax: 5, TotalPrice: 55},
+			{Name: "Milk", Quantity: 2, Price: 80, Tax: 16, TotalPrice: 176},
+		},
+		Tax:     21,
+		Total:   231,
+		IsValid: true,
+	})
+
+	m := messaging.New(&serverenv.Config{
+		Messaging:          messagingConfig,
+		WebhookWorkers:     2,
+		WebhookMaxAttempts: 3,
+		ProcessedEventTTL:  time.Hour,
+		ReceiptDraftTTL:    time.Hour,
+		DefaultCurrency:    "JPY",
+		DefaultLocation:    time.UTC,
+	}, serverenv.New(
+		serverenv.WithDatabase(testDB),
+		serverenv.WithMessenger(lineAPI),
+		serverenv.WithReceiptExtractor(fixture),
+	))
+	m.Start()
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		assert.NoError(t, m.Drain(shutdownCtx))
+	}()
+
+	bot := httptest.NewServer(m.Routes())
+	defer bot.Close()
+	callbackURL := bot.URL + "/callback"
+
+	users := userdb.New(testDB)
+	transactions := transactiondb.New(testDB)
+
+	// Follow registers the user with their LINE display name.
+	follow := line.FollowEvent("U1")
+	replies := send(t, line, callbackURL, 1, follow)
+	assert.Equal(t, follow.ReplyToken(), replies[0].ReplyToken)
+	assert.Equal(t, "Hi thank you for following me!!", replies[0].Messages[0].Text)
+
+	user, err := users.GetByLineUserID(ctx, "U1")
+	if err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+	assert.Equal(t, "Alice", user.DisplayName)
+	assert.Equal(t, usermodel.UserStatus(usermodel.UserStatusActive), user.Status)
+
+	// A text message is recorded as an expense.
+	text := line.TextMessageEvent("U1", "lunch 1200")
+	replies = send(t, line, callbackURL, 2, text)
+	assert.Equal(t, text.ReplyToken(), replies[1].ReplyToken)
+	assert.Contains(t, replies[1].Messages[0].Text, "Recorded")
+
+	// A redelivery of the same message is not recorded again.
+	resp, err := line.SendWebhook(callbackURL, text.Redelivered())
+	if err != nil {
+		t.Fatalf("failed to send webhook: %v", err)
+	}
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// A receipt photo is read and kept as a draft until the user saves it.
+	image := line.ImageMessageEvent("U1", "image-1")
+	replies = send(t, line, callbackURL, 3, image)
+	assert.Equal(t, image.ReplyToken(), replies[2].ReplyToken)
+	// The receipt comes back as a card listing its items.
+	card := replies[2].Messages[0]
+	assert.Equal(t, "flex", card.Type)
+	assert.Contains(t, card.AltText, "Found 231 JPY at Grocery Market on 2024-02-20. Save it?")
+	assert.Contains(t, string(card.Raw), `"text":"Milk x2"`)
+	assert.Equal(t, []string{"U1"}, line.Loading())
+	assert.Equal(t, 1, fixture.Calls())
+
+	labels := make(map[string]string, len(card.QuickReplies))
+	for _, q := range card.QuickReplies {
+		labels[q.Label] = q.Data
+	}
+	assert.Len(t, labels, 4)
+
+	saved, err := transactions.ListTransactions(ctx, &transactiondb.ListFilter{UserID: user.ID})
+	if err != nil {
+		t.Fatalf("failed to list transactions: %v", err)
+	}
+	assert.Len(t, saved, 1)
+
+	// Tapping Save records the expense; tapping it again doesn't.
+	save := line.PostbackEvent("U1", labels["Save"])
+	replies = send(t, line, callbackURL, 4, save)
+	assert.Equal(t, save.ReplyToken(), replies[3].ReplyToken)
+	assert.Contains(t, replies[3].Messages[0].Text, "Saved 231 JPY at Grocery Market on 2024-02-20.")
+
+	replies = send(t, line, callbackURL, 5, line.PostbackEvent("U1", labels["Save"]))
+	assert.Contains(t, replies[4].Messages[0].Text, "was saved, discarded or has expired")
+
+	saved, err = transactions.ListTransactions(ctx, &transactiondb.ListFilter{UserID: user.ID})
+	if err != nil {
+		t.Fatalf("failed to list transactions: %v", err)
+	}
+	if assert.Len(t, saved, 2) {
+		bySource := map[model.TransactionSource]*model.Transaction{}
+		for _, tx := range saved {
+			bySource[tx.Source] = tx
+		}
+
+		text := bySource[model.TransactionSourceText]
+		if assert.NotNil(t, text) {
+			assert.Equal(t, int64(1200), text.Amount)
+			assert.Equal(t, "lunch", text.Note)
+		}
+
+		receipt := bySource[model.TransactionSourceReceipt]
+		if assert.NotNil(t, receipt) {
+			assert.Equal(t, int64(231), receipt.Amount)
+			assert.Equal(t, "Grocery Market", receipt.Merchant)
+			assert.Equal(t, time.Date(2024, 2, 20, 9, 15, 0, 0, time.UTC), receipt.OccurredAt.UTC())
+		}
+	}
+
+	// The bot never replied to the redelivery.
+	assert.Len(t, line.Replies(), 5)
+}
+
+func TestTelegramWebhook(t *testing.T) {
+	ctx := context.Background()
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+
+	line := linetest.NewServer(channelSecret)
+	defer line.Close()
+	line.SetProfile("U1", "Alice")
+
+	telegram := telegramtest.NewServer("123:token", "telegram-secret")
+	defer telegram.Close()
+	telegram.SetUser(7, "Alice")
+	telegram.SetFile("photo-1", []byte("jpeg bytes"), "image/jpeg")
+
+	messagingConfig := &msg.Config{
+		LineChannelSecret:     channelSecret,
+		LineChannelToken:      "token",
+		TelegramBotToken:      "123:token",
+		TelegramWebhookSecret: "telegram-secret",
+	}
+	lineAPI, err := msg.NewLineMessaging(messagingConfig, msg.WithBaseURL(line.URL))
+	if err != nil {
+		t.Fatalf("failed to create LINE client: %v", err)
+	}
+	telegramAPI, err := msg.NewTelegramMessaging(messagingConfig, msg.WithTelegramBaseURL(telegram.URL))
+	if err != nil {
+		t.Fatalf("failed to create Telegram client: %v", err)
+	}
+
+	m := messaging.New(&serverenv.Config{
+		Messaging:          messagingConfig,
+		WebhookWorkers:     2,
+		WebhookMaxAttempts: 3,
+		ProcessedEventTTL:  time.Hour,
+		ReceiptDraftTTL:    time.Hour,
+		DefaultCurrency:    "JPY",
+		DefaultLocation:    time.UTC,
+	}, serverenv.New(
+		serverenv.WithDatabase(testDB),
+		serverenv.WithMessenger(lineAPI),
+		serverenv.WithTelegramMessenger(telegramAPI),
+		serverenv.WithReceiptExtractor(receipt.NewFixture(&receiptmodel.Receipt{Shop: "Lawson", Total: 540, IsValid: true})),
+	))
+	m.Start()
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		assert.NoError(t, m.Drain(shutdownCtx))
+	}()
+
+	bot := httptest.NewServer(m.Routes())
+	defer bot.Close()
+
+	sendTelegram := func(wantMessages int, update telegramtest.Update) []telegramtest.Message {
+		t.Helper()
+
+		resp, err := telegram.SendUpdate(bot.URL+"/telegram/callback", update)
+		if err != nil {
+			t.Fatalf("failed to send update: %v", err)
+		}
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		messages, err := telegram.WaitForMessages(wantMessages, 5*time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return messages
+	}
+
+	users := userdb.New(testDB)
+	transactions := transactiondb.New(testDB)
+
+	// A Telegram user gets an account of their own on their first message.
+	messages := sendTelegram(1, telegram.TextUpdate(7, "taxi 800"))
+	assert.Equal(t, "7", messages[0].ChatID)
+	assert.Contains(t, messages[0].Text, "Recorded expense of 800 JPY for taxi")
+
+	telegramUser, err := users.GetByIdentity(ctx, usermodel.ChannelTelegram, "7")
+	if err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+	assert.Equal(t, "Alice", telegramUser.DisplayName)
+
+	// The same person links it to their LINE account.
+	send(t, line, bot.URL+"/callback", 1, line.FollowEvent("U1"))
+	replies := send(t, line, bot.URL+"/callback", 2, line.TextMessageEvent("U1", "/link"))
+	var code string
+	if _, err := fmt.Sscanf(replies[1].Messages[0].Text, "Send /link %s", &code); err != nil {
+		t.Fatalf("no link code in %q", replies[1].Messages[0].Text)
+	}
+
+	messages = sendTelegram(2, telegram.TextUpdate(7, "/link "+code))
+	assert.Contains(t, messages[1].Text, "Linked!")
+
+	lineUser, err := users.GetByLineUserID(ctx, "U1")
+	if err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+	linked, err := users.GetByIdentity(ctx, usermodel.ChannelTelegram, "7")
+	if err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+	assert.Equal(t, lineUser.ID, linked.ID)
+
+	// Both the earlier and new Telegram entries belong to the LINE account.
+	sendTelegram(3, telegram.TextUpdate(7, "coffee 450"))
+	saved, err := transactions.ListTransactions(ctx, &transactiondb.ListFilter{UserID: lineUser.ID})
+	if err != nil {
+		t.Fatalf("failed to list transactions: %v", err)
+	}
+	assert.Len(t, saved, 2)
+
+	// Receipt buttons come back as callback queries.
+	messages = sendTelegram(4, telegram.PhotoUpdate(7, "photo-1"))
+	draft := messages[3]
+	assert.Contains(t, draft.Text, "Found 540 JPY at Lawson")
+	if !assert.Len(t, draft.Buttons, 4) {
+		return
+	}
+
+	messages = sendTelegram(5, telegram.CallbackUpdate(7, draft, draft.Buttons[0].Data))
+	assert.Contains(t, messages[4].Text, "Saved 540 JPY at Lawson")
+	assert.Len(t, telegram.Answered(), 1)
+
+	saved, err = transactions.ListTransactions(ctx, &transactiondb.ListFilter{UserID: lineUser.ID})
+	if err != nil {
+		t.Fatalf("failed to list transactions: %v", err)
+	}
+	assert.Len(t, saved, 3)
+}
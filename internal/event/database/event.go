@@ -0,0 +1,118 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"github/shaolim/momon/internal/event/model"
+	"github/shaolim/momon/pkg/database"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type EventDB interface {
+	ClaimEvent(ctx context.Context, event *model.ProcessedEvent) (bool, error)
+	MarkProcessed(ctx context.Context, eventID string) error
+	UnmarkProcessed(ctx context.Context, eventID string) error
+	DeleteProcessedBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+type eventDB struct {
+	db *database.DB
+}
+
+func New(db *database.DB) EventDB {
+	return &eventDB{
+		db: db,
+	}
+}
+
+// ClaimEvent records the event as being processed until event.LockedUntil.
+// It returns false without an error when the event was processed before or
+// another delivery of it is still being handled, so concurrent deliveries of
+// the same event agree on which one handles it. An event whose claim ran out,
+// e.g. because the process died while handling it, is claimed again.
+func (db *eventDB) ClaimEvent(ctx context.Context, event *model.ProcessedEvent) (bool, error) {
+	if err := event.Validate(); err != nil {
+		return false, err
+	}
+
+	if event.ProcessedAt.IsZero() {
+		event.ProcessedAt = time.Now()
+	}
+
+	claimed := false
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+			INSERT INTO processed_events (event_id, processed_at, locked_until)
+			VALUES($1, $2, $3)
+			ON CONFLICT (event_id) DO UPDATE
+				SET processed_at = EXCLUDED.processed_at, locked_until = EXCLUDED.locked_until
+				WHERE processed_events.locked_until <= EXCLUDED.processed_at
+		`, event.EventID, event.ProcessedAt, event.LockedUntil)
+		if err != nil {
+			return fmt.Errorf("insert processed_events: %w", err)
+		}
+		claimed = result.RowsAffected() == 1
+
+		return nil
+	}); err != nil {
+		return false, err
+	}
+
+	return claimed, nil
+}
+
+// MarkProcessed releases the claimed event's lease, so it is never handled
+// again.
+func (db *eventDB) MarkProcessed(ctx context.Context, eventID string) error {
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `UPDATE processed_events SET locked_until = NULL WHERE event_id = $1`, eventID)
+		if err != nil {
+			return fmt.Errorf("update processed_events: %w", err)
+		}
+
+		if result.RowsAffected() == 0 {
+			return database.ErrNotFound
+		}
+
+		return nil
+	})
+}
+
+// UnmarkProcessed forgets the event so it is handled again, e.g. after it
+// failed before changing anything.
+func (db *eventDB) UnmarkProcessed(ctx context.Context, eventID string) error {
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `DELETE FROM processed_events WHERE event_id = $1`, eventID)
+		if err != nil {
+			return fmt.Errorf("delete processed_events: %w", err)
+		}
+
+		if result.RowsAffected() == 0 {
+			return database.ErrNotFound
+		}
+
+		return nil
+	})
+}
+
+// DeleteProcessedBefore forgets events processed before the given time and
+// returns how many were deleted. LINE stops redelivering an event long before
+// it is deleted.
+func (db *eventDB) DeleteProcessedBefore(ctx context.Context, before time.Time) (int64, error) {
+	var deleted int64
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `DELETE FROM processed_events WHERE processed_at < $1`, before)
+		if err != nil {
+			return fmt.Errorf("delete processed_events: %w", err)
+		}
+		deleted = result.RowsAffected()
+
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,125 @@
+package database
+
+import (
+	"context"
+	"github/shaolim/momon/internal/event/model"
+	"github/shaolim/momon/pkg/database"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClaimEvent(t *testing.T) {
+	t.Parallel()
+
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	eventDB := New(testDB)
+	ctx := context.Background()
+	now := time.Now()
+
+	claimed, err := eventDB.ClaimEvent(ctx, &model.ProcessedEvent{EventID: "event-1", ProcessedAt: now, LockedUntil: now.Add(time.Minute)})
+	if err != nil {
+		t.Fatalf("failed to claim event: %v", err)
+	}
+	assert.True(t, claimed)
+
+	// A redelivery while the first delivery is being handled is not claimed.
+	claimed, err = eventDB.ClaimEvent(ctx, &model.ProcessedEvent{EventID: "event-1", ProcessedAt: now.Add(30 * time.Second), LockedUntil: now.Add(90 * time.Second)})
+	if err != nil {
+		t.Fatalf("failed to claim event: %v", err)
+	}
+	assert.False(t, claimed)
+
+	// The handler was lost; once its claim runs out the event is claimed again.
+	claimed, err = eventDB.ClaimEvent(ctx, &model.ProcessedEvent{EventID: "event-1", ProcessedAt: now.Add(time.Minute), LockedUntil: now.Add(2 * time.Minute)})
+	if err != nil {
+		t.Fatalf("failed to claim event: %v", err)
+	}
+	assert.True(t, claimed)
+
+	_, err = eventDB.ClaimEvent(ctx, &model.ProcessedEvent{LockedUntil: now})
+	assert.EqualError(t, err, "event id must not be empty")
+
+	_, err = eventDB.ClaimEvent(ctx, &model.ProcessedEvent{EventID: "event-2"})
+	assert.EqualError(t, err, "locked until must not be empty")
+}
+
+func TestMarkProcessed(t *testing.T) {
+	t.Parallel()
+
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	eventDB := New(testDB)
+	ctx := context.Background()
+	now := time.Now()
+
+	if _, err := eventDB.ClaimEvent(ctx, &model.ProcessedEvent{EventID: "event-1", ProcessedAt: now, LockedUntil: now.Add(time.Minute)}); err != nil {
+		t.Fatalf("failed to claim event: %v", err)
+	}
+	assert.NoError(t, eventDB.MarkProcessed(ctx, "event-1"))
+
+	// A processed event is never claimed again.
+	claimed, err := eventDB.ClaimEvent(ctx, &model.ProcessedEvent{EventID: "event-1", ProcessedAt: now.Add(time.Hour), LockedUntil: now.Add(time.Hour + time.Minute)})
+	if err != nil {
+		t.Fatalf("failed to claim event: %v", err)
+	}
+	assert.False(t, claimed)
+
+	assert.ErrorIs(t, eventDB.MarkProcessed(ctx, "event-2"), database.ErrNotFound)
+}
+
+func TestUnmarkProcessed(t *testing.T) {
+	t.Parallel()
+
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	eventDB := New(testDB)
+	ctx := context.Background()
+	lockedUntil := time.Now().Add(time.Minute)
+
+	if _, err := eventDB.ClaimEvent(ctx, &model.ProcessedEvent{EventID: "event-1", LockedUntil: lockedUntil}); err != nil {
+		t.Fatalf("failed to claim event: %v", err)
+	}
+
+	assert.NoError(t, eventDB.UnmarkProcessed(ctx, "event-1"))
+	claimed, err := eventDB.ClaimEvent(ctx, &model.ProcessedEvent{EventID: "event-1", LockedUntil: lockedUntil})
+	if err != nil {
+		t.Fatalf("failed to claim event: %v", err)
+	}
+	assert.True(t, claimed)
+
+	assert.NoError(t, eventDB.UnmarkProcessed(ctx, "event-1"))
+	assert.ErrorIs(t, eventDB.UnmarkProcessed(ctx, "event-1"), database.ErrNotFound)
+}
+
+func TestDeleteProcessedBefore(t *testing.T) {
+	t.Parallel()
+
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	eventDB := New(testDB)
+	ctx := context.Background()
+	now := time.Now()
+
+	for _, event := range []*model.ProcessedEvent{
+		{EventID: "old", ProcessedAt: now.Add(-48 * time.Hour), LockedUntil: now.Add(-47 * time.Hour)},
+		{EventID: "new", ProcessedAt: now.Add(-time.Hour), LockedUntil: now},
+	} {
+		if _, err := eventDB.ClaimEvent(ctx, event); err != nil {
+			t.Fatalf("failed to claim event: %v", err)
+		}
+		if err := eventDB.MarkProcessed(ctx, event.EventID); err != nil {
+			t.Fatalf("failed to mark event: %v", err)
+		}
+	}
+
+	deleted, err := eventDB.DeleteProcessedBefore(ctx, now.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatalf("failed to delete events: %v", err)
+	}
+	assert.Equal(t, int64(1), deleted)
+
+	// Only the deleted event can be claimed again.
+	claimed, _ := eventDB.ClaimEvent(ctx, &model.ProcessedEvent{EventID: "old", LockedUntil: now.Add(time.Minute)})
+	assert.True(t, claimed)
+	claimed, _ = eventDB.ClaimEvent(ctx, &model.ProcessedEvent{EventID: "new", LockedUntil: now.Add(time.Minute)})
+	assert.False(t, claimed)
+}
@@ -0,0 +1,28 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+// ProcessedEvent records that a webhook event was handled, so a redelivery of
+// the same event is ignored.
+type ProcessedEvent struct {
+	EventID     string
+	ProcessedAt time.Time
+	// LockedUntil is when the handler of an event still being processed is
+	// assumed lost. It is zero once the event has been processed.
+	LockedUntil time.Time
+}
+
+func (e *ProcessedEvent) Validate() error {
+	if e.EventID == "" {
+		return errors.New("event id must not be empty")
+	}
+
+	if e.LockedUntil.IsZero() {
+		return errors.New("locked until must not be empty")
+	}
+
+	return nil
+}
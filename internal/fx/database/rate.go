@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github/shaolim/momon/internal/fx/model"
+	"github/shaolim/momon/pkg/database"
+	"github/shaolim/momon/pkg/money"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type FXDB interface {
+	SetRates(ctx context.Context, rates []*model.Rate) error
+	GetRate(ctx context.Context, from, to money.Currency, date time.Time) (float64, error)
+}
+
+type fxDB struct {
+	db *database.DB
+}
+
+func New(db *database.DB) FXDB {
+	return &fxDB{
+		db: db,
+	}
+}
+
+// SetRates saves the rates in one go, replacing any already stored for the
+// same pair and date.
+func (db *fxDB) SetRates(ctx context.Context, rates []*model.Rate) error {
+	for _, r := range rates {
+		if err := r.Validate(); err != nil {
+			return fmt.Errorf("%s %s/%s: %w", r.Date.Format(time.DateOnly), r.Base, r.Quote, err)
+		}
+	}
+
+	now := time.Now()
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		for _, r := range rates {
+			if _, err := tx.Exec(ctx, `
+				INSERT INTO fx_rates (date, base, quote, rate, imported_at)
+				VALUES($1, $2, $3, $4::float8, $5)
+				ON CONFLICT (base, quote, date) DO UPDATE
+				SET rate = EXCLUDED.rate, imported_at = EXCLUDED.imported_at
+			`, r.Date.Format(time.DateOnly), r.Base, r.Quote, r.Rate, now); err != nil {
+				return fmt.Errorf("insert fx_rates: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetRate returns what one unit of from was worth in to on date, using the
+// latest rate on or before it in either direction. It returns
+// database.ErrNotFound when there is none.
+func (db *fxDB) GetRate(ctx context.Context, from, to money.Currency, date time.Time) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+
+	var (
+		base money.Currency
+		rate float64
+	)
+	row := db.db.Pool.QueryRow(ctx, `
+		SELECT base, rate::float8 FROM fx_rates
+		WHERE ((base = $1 AND quote = $2) OR (base = $2 AND quote = $1)) AND date <= $3
+		ORDER BY date DESC, base = $1 DESC
+		LIMIT 1
+	`, from, to, date.Format(time.DateOnly))
+	if err := row.Scan(&base, &rate); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, database.ErrNotFound
+		}
+		return 0, fmt.Errorf("select fx_rates: %w", err)
+	}
+
+	if base != from {
+		return 1 / rate, nil
+	}
+	return rate, nil
+}
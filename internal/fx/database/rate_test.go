@@ -0,0 +1,61 @@
+package database
+
+import (
+	"context"
+	"github/shaolim/momon/internal/fx/model"
+	"github/shaolim/momon/pkg/database"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRates(t *testing.T) {
+	t.Parallel()
+
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	fxDB := New(testDB)
+	ctx := context.Background()
+	day := func(d int) time.Time {
+		return time.Date(2025, 10, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	assert.NoError(t, fxDB.SetRates(ctx, []*model.Rate{
+		{Date: day(10), Base: "USD", Quote: "JPY", Rate: 150},
+		{Date: day(13), Base: "USD", Quote: "JPY", Rate: 152},
+		{Date: day(13), Base: "THB", Quote: "JPY", Rate: 4.5},
+	}))
+	// Importing a date again replaces its rate.
+	assert.NoError(t, fxDB.SetRates(ctx, []*model.Rate{{Date: day(13), Base: "USD", Quote: "JPY", Rate: 151.5}}))
+
+	rate, err := fxDB.GetRate(ctx, "USD", "JPY", day(15))
+	assert.NoError(t, err)
+	assert.Equal(t, 151.5, rate)
+
+	// Older dates use the latest rate before them.
+	rate, err = fxDB.GetRate(ctx, "USD", "JPY", day(12))
+	assert.NoError(t, err)
+	assert.Equal(t, 150.0, rate)
+
+	// Pairs are used in either direction.
+	rate, err = fxDB.GetRate(ctx, "JPY", "THB", day(13))
+	assert.NoError(t, err)
+	assert.InDelta(t, 1/4.5, rate, 1e-9)
+
+	rate, err = fxDB.GetRate(ctx, "JPY", "JPY", day(1))
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, rate)
+
+	_, err = fxDB.GetRate(ctx, "USD", "JPY", day(9))
+	assert.ErrorIs(t, err, database.ErrNotFound)
+	_, err = fxDB.GetRate(ctx, "EUR", "JPY", day(15))
+	assert.ErrorIs(t, err, database.ErrNotFound)
+
+	// A bad rate rejects the whole import.
+	assert.Error(t, fxDB.SetRates(ctx, []*model.Rate{
+		{Date: day(14), Base: "USD", Quote: "JPY", Rate: 153},
+		{Date: day(14), Base: "USD", Quote: "USD", Rate: 1},
+	}))
+	rate, _ = fxDB.GetRate(ctx, "USD", "JPY", day(14))
+	assert.Equal(t, 151.5, rate)
+}
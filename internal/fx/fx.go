@@ -0,0 +1,65 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	fxdb "github/shaolim/momon/internal/fx/database"
+	"github/shaolim/momon/pkg/database"
+	"github/shaolim/momon/pkg/money"
+	"time"
+)
+
+// Converter converts amounts to one currency at the rate on the day they were
+// spent. It remembers the rates it looked up, so use one per report.
+type Converter struct {
+	db    fxdb.FXDB
+	to    money.Currency
+	rates map[rateKey]float64
+}
+
+type rateKey struct {
+	from money.Currency
+	date string
+}
+
+func NewConverter(db fxdb.FXDB, to money.Currency) *Converter {
+	return &Converter{
+		db:    db,
+		to:    to,
+		rates: map[rateKey]float64{},
+	}
+}
+
+// Currency is what the converter converts to.
+func (c *Converter) Currency() money.Currency {
+	return c.to
+}
+
+// Convert returns amount in the converter's currency at the rate on date's
+// day. It returns false, and amount as it is, when there is no rate for it.
+func (c *Converter) Convert(ctx context.Context, amount money.Money, date time.Time) (money.Money, bool, error) {
+	if amount.Currency == c.to {
+		return amount, true, nil
+	}
+
+	key := rateKey{from: amount.Currency, date: date.Format(time.DateOnly)}
+	rate, ok := c.rates[key]
+	if !ok {
+		var err error
+		rate, err = c.db.GetRate(ctx, amount.Currency, c.to, date)
+		if err != nil && !errors.Is(err, database.ErrNotFound) {
+			return money.Money{}, false, err
+		}
+		// A zero rate remembers that there is none.
+		c.rates[key] = rate
+	}
+	if rate == 0 {
+		return amount, false, nil
+	}
+
+	converted, err := amount.Convert(c.to, rate)
+	if err != nil {
+		return money.Money{}, false, err
+	}
+	return converted, true, nil
+}
@@ -0,0 +1,50 @@
+package fx
+
+import (
+	"context"
+	"github/shaolim/momon/internal/fx/fxtest"
+	"github/shaolim/momon/internal/fx/model"
+	"github/shaolim/momon/pkg/money"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConverter_Convert(t *testing.T) {
+	ctx := context.Background()
+	date := time.Date(2025, 10, 15, 12, 0, 0, 0, time.UTC)
+	db := fxtest.NewDB(&model.Rate{Date: time.Date(2025, 10, 14, 0, 0, 0, 0, time.UTC), Base: "USD", Quote: "JPY", Rate: 150})
+	c := NewConverter(db, "JPY")
+	assert.Equal(t, money.Currency("JPY"), c.Currency())
+
+	converted, ok, err := c.Convert(ctx, money.New(1250, "USD"), date)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, money.New(1875, "JPY"), converted)
+
+	// The rate is looked up once per currency and day.
+	_, _, err = c.Convert(ctx, money.New(100, "USD"), date.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, db.Lookups())
+
+	// Amounts already in the currency are kept.
+	converted, ok, err = c.Convert(ctx, money.New(500, "JPY"), date)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, money.New(500, "JPY"), converted)
+
+	// Without a rate the amount is returned as it is.
+	for range 2 {
+		converted, ok, err = c.Convert(ctx, money.New(100, "EUR"), date)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		assert.Equal(t, money.New(100, "EUR"), converted)
+	}
+	assert.Equal(t, 2, db.Lookups())
+
+	// A date before the first rate has none either.
+	_, ok, err = c.Convert(ctx, money.New(100, "USD"), date.AddDate(0, 0, -2))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
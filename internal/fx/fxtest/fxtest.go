@@ -0,0 +1,61 @@
+// Package fxtest provides an in-memory FXDB for tests.
+package fxtest
+
+import (
+	"context"
+	fxdb "github/shaolim/momon/internal/fx/database"
+	"github/shaolim/momon/internal/fx/model"
+	"github/shaolim/momon/pkg/database"
+	"github/shaolim/momon/pkg/money"
+	"time"
+)
+
+// DB is an in-memory fxdb.FXDB that counts lookups. A rate without a date
+// applies to every day.
+type DB struct {
+	rates   []*model.Rate
+	lookups int
+}
+
+var _ fxdb.FXDB = (*DB)(nil)
+
+func NewDB(rates ...*model.Rate) *DB {
+	return &DB{rates: rates}
+}
+
+func (f *DB) SetRates(_ context.Context, rates []*model.Rate) error {
+	f.rates = append(f.rates, rates...)
+	return nil
+}
+
+// GetRate returns the latest rate on or before date, either way round.
+func (f *DB) GetRate(_ context.Context, from, to money.Currency, date time.Time) (float64, error) {
+	f.lookups++
+	var found *model.Rate
+	var rate float64
+	for _, r := range f.rates {
+		if r.Date.After(date) || (found != nil && r.Date.Before(found.Date)) {
+			continue
+		}
+		switch {
+		case r.Base == from && r.Quote == to:
+			found, rate = r, r.Rate
+		case r.Base == to && r.Quote == from:
+			found, rate = r, 1/r.Rate
+		}
+	}
+	if found == nil {
+		return 0, database.ErrNotFound
+	}
+	return rate, nil
+}
+
+// Rates returns the rates that were set.
+func (f *DB) Rates() []*model.Rate {
+	return f.rates
+}
+
+// Lookups returns how often a rate was looked up.
+func (f *DB) Lookups() int {
+	return f.lookups
+}
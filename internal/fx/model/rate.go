@@ -0,0 +1,39 @@
+package model
+
+import (
+	"errors"
+	"github/shaolim/momon/pkg/money"
+	"math"
+	"time"
+)
+
+// Rate is what one unit of Base was worth in Quote on Date, e.g. 151.23 for
+// USD to JPY. Amounts are in major units.
+type Rate struct {
+	Date  time.Time
+	Base  money.Currency
+	Quote money.Currency
+	Rate  float64
+}
+
+func (r *Rate) Validate() error {
+	if r.Date.IsZero() {
+		return errors.New("date must not be empty")
+	}
+
+	for _, c := range []money.Currency{r.Base, r.Quote} {
+		if parsed, err := money.ParseCurrency(string(c)); err != nil || parsed != c {
+			return errors.New("currencies must be 3-letter ISO 4217 codes")
+		}
+	}
+
+	if r.Base == r.Quote {
+		return errors.New("base and quote must differ")
+	}
+
+	if r.Rate <= 0 || math.IsInf(r.Rate, 0) || math.IsNaN(r.Rate) {
+		return errors.New("rate must be greater than zero")
+	}
+
+	return nil
+}
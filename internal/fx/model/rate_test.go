@@ -0,0 +1,43 @@
+package model
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRate_Validate(t *testing.T) {
+	valid := func() *Rate {
+		return &Rate{Date: time.Date(2025, 10, 15, 0, 0, 0, 0, time.UTC), Base: "USD", Quote: "JPY", Rate: 151.23}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(r *Rate)
+		wantErr string
+	}{
+		{name: "valid", modify: func(*Rate) {}},
+		{name: "no date", modify: func(r *Rate) { r.Date = time.Time{} }, wantErr: "date must not be empty"},
+		{name: "lower case", modify: func(r *Rate) { r.Base = "usd" }, wantErr: "currencies must be 3-letter ISO 4217 codes"},
+		{name: "bad quote", modify: func(r *Rate) { r.Quote = "YEN!" }, wantErr: "currencies must be 3-letter ISO 4217 codes"},
+		{name: "same currency", modify: func(r *Rate) { r.Quote = "USD" }, wantErr: "base and quote must differ"},
+		{name: "zero rate", modify: func(r *Rate) { r.Rate = 0 }, wantErr: "rate must be greater than zero"},
+		{name: "NaN rate", modify: func(r *Rate) { r.Rate = math.NaN() }, wantErr: "rate must be greater than zero"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := valid()
+			tt.modify(r)
+
+			err := r.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
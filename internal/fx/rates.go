@@ -0,0 +1,152 @@
+package fx
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	fxdb "github/shaolim/momon/internal/fx/database"
+	"github/shaolim/momon/internal/fx/model"
+	"github/shaolim/momon/pkg/money"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rateColumns are the fields of every imported rate.
+var rateColumns = []string{"date", "base", "quote", "rate"}
+
+// ReadCSV reads rates from CSV whose header names the columns date, base,
+// quote and rate in any order, e.g.
+//
+//	date,base,quote,rate
+//	2025-10-15,USD,JPY,151.23
+func ReadCSV(r io.Reader) ([]*model.Rate, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	index := map[string]int{}
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range rateColumns {
+		if _, ok := index[name]; !ok {
+			return nil, fmt.Errorf("header has no %s column", name)
+		}
+	}
+
+	var rates []*model.Rate
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		value, err := strconv.ParseFloat(strings.TrimSpace(record[index["rate"]]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid rate %q", line, record[index["rate"]])
+		}
+		rate, err := newRate(record[index["date"]], record[index["base"]], record[index["quote"]], value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rates = append(rates, rate)
+	}
+
+	return rates, nil
+}
+
+// ReadJSON reads rates from a JSON array such as
+//
+//	[{"date": "2025-10-15", "base": "USD", "quote": "JPY", "rate": 151.23}]
+func ReadJSON(r io.Reader) ([]*model.Rate, error) {
+	var records []struct {
+		Date  string  `json:"date"`
+		Base  string  `json:"base"`
+		Quote string  `json:"quote"`
+		Rate  float64 `json:"rate"`
+	}
+	if err := json.NewDecoder(r).Decode(&records); err != nil {
+		return nil, fmt.Errorf("failed to decode rates: %w", err)
+	}
+
+	rates := make([]*model.Rate, 0, len(records))
+	for i, record := range records {
+		rate, err := newRate(record.Date, record.Base, record.Quote, record.Rate)
+		if err != nil {
+			return nil, fmt.Errorf("rate %d: %w", i+1, err)
+		}
+		rates = append(rates, rate)
+	}
+
+	return rates, nil
+}
+
+func newRate(date, base, quote string, value float64) (*model.Rate, error) {
+	d, err := time.Parse(time.DateOnly, strings.TrimSpace(date))
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q", date)
+	}
+
+	rate := &model.Rate{Date: d, Rate: value}
+	if rate.Base, err = money.ParseCurrency(base); err != nil {
+		return nil, err
+	}
+	if rate.Quote, err = money.ParseCurrency(quote); err != nil {
+		return nil, err
+	}
+	if err := rate.Validate(); err != nil {
+		return nil, err
+	}
+
+	return rate, nil
+}
+
+// ImportFiles reads rates from CSV and JSON files, told apart by their
+// extension, and saves each file's rates in one go. It returns how many rates
+// were imported.
+func ImportFiles(ctx context.Context, db fxdb.FXDB, paths ...string) (int, error) {
+	imported := 0
+	for _, path := range paths {
+		rates, err := readFile(path)
+		if err != nil {
+			return imported, fmt.Errorf("%s: %w", path, err)
+		}
+
+		if err := db.SetRates(ctx, rates); err != nil {
+			return imported, fmt.Errorf("%s: %w", path, err)
+		}
+		imported += len(rates)
+	}
+
+	return imported, nil
+}
+
+func readFile(path string) ([]*model.Rate, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return ReadCSV(f)
+	case ".json":
+		return ReadJSON(f)
+	default:
+		return nil, errors.New("rates must be a .csv or .json file")
+	}
+}
@@ -0,0 +1,77 @@
+package fx
+
+import (
+	"context"
+	"github/shaolim/momon/internal/fx/fxtest"
+	"github/shaolim/momon/internal/fx/model"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadCSV(t *testing.T) {
+	date := time.Date(2025, 10, 15, 0, 0, 0, 0, time.UTC)
+
+	rates, err := ReadCSV(strings.NewReader("quote, base, date, rate\njpy,USD,2025-10-15,151.23\nJPY,EUR,2025-10-15,175.5\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []*model.Rate{
+		{Date: date, Base: "USD", Quote: "JPY", Rate: 151.23},
+		{Date: date, Base: "EUR", Quote: "JPY", Rate: 175.5},
+	}, rates)
+
+	tests := []struct {
+		name    string
+		csv     string
+		wantErr string
+	}{
+		{name: "no rate column", csv: "date,base,quote\n", wantErr: "header has no rate column"},
+		{name: "bad date", csv: "date,base,quote,rate\n2025-10-15,USD,JPY,151\n15/10/2025,USD,JPY,151\n", wantErr: `line 3: invalid date "15/10/2025"`},
+		{name: "bad rate", csv: "date,base,quote,rate\n2025-10-15,USD,JPY,abc\n", wantErr: `line 2: invalid rate "abc"`},
+		{name: "bad currency", csv: "date,base,quote,rate\n2025-10-15,US,JPY,151\n", wantErr: `line 2: invalid currency "US"`},
+		{name: "same currency", csv: "date,base,quote,rate\n2025-10-15,JPY,JPY,1\n", wantErr: "line 2: base and quote must differ"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadCSV(strings.NewReader(tt.csv))
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestReadJSON(t *testing.T) {
+	rates, err := ReadJSON(strings.NewReader(`[{"date": "2025-10-15", "base": "usd", "quote": "JPY", "rate": 151.23}]`))
+	assert.NoError(t, err)
+	assert.Equal(t, []*model.Rate{
+		{Date: time.Date(2025, 10, 15, 0, 0, 0, 0, time.UTC), Base: "USD", Quote: "JPY", Rate: 151.23},
+	}, rates)
+
+	_, err = ReadJSON(strings.NewReader(`[{"date": "2025-10-15", "base": "USD", "quote": "JPY", "rate": -1}]`))
+	assert.EqualError(t, err, "rate 1: rate must be greater than zero")
+}
+
+func TestImportFiles(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+	csvPath := write("rates.csv", "date,base,quote,rate\n2025-10-15,USD,JPY,151.23\n2025-10-16,USD,JPY,150.8\n")
+	jsonPath := write("rates.json", `[{"date": "2025-10-15", "base": "EUR", "quote": "JPY", "rate": 175.5}]`)
+	txtPath := write("rates.txt", "")
+
+	db := fxtest.NewDB()
+	imported, err := ImportFiles(ctx, db, csvPath, jsonPath)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, imported)
+	assert.Len(t, db.Rates(), 3)
+
+	_, err = ImportFiles(ctx, db, txtPath)
+	assert.EqualError(t, err, txtPath+": rates must be a .csv or .json file")
+}
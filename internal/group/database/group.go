@@ -0,0 +1,166 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github/shaolim/momon/internal/group/model"
+	"github/shaolim/momon/pkg/database"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type GroupDB interface {
+	Upsert(ctx context.Context, group *model.Group) error
+	GetByLineGroupID(ctx context.Context, lineGroupID string) (*model.Group, error)
+	UpdateStatus(ctx context.Context, lineGroupID string, status model.GroupStatus) error
+
+	AddMember(ctx context.Context, groupID, userID int64, now time.Time) error
+	RemoveMember(ctx context.Context, groupID, userID int64) error
+	ListMembers(ctx context.Context, groupID int64) ([]*model.Member, error)
+}
+
+type groupDB struct {
+	db *database.DB
+}
+
+func New(db *database.DB) GroupDB {
+	return &groupDB{
+		db: db,
+	}
+}
+
+const groupColumns = `id, line_group_id, status, created_at, updated_at`
+
+func scanGroup(row pgx.Row) (*model.Group, error) {
+	var g model.Group
+	if err := row.Scan(&g.ID, &g.LineGroupID, &g.Status, &g.CreatedAt, &g.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// Upsert inserts the group or, when the LINE group ID is already known,
+// updates the status of the existing row, e.g. when the bot is invited back.
+// The group's ID and CreatedAt are set from the stored row.
+func (db *groupDB) Upsert(ctx context.Context, group *model.Group) error {
+	if err := group.Validate(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	group.CreatedAt = now
+	group.UpdatedAt = now
+
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, `
+			INSERT INTO groups (line_group_id, status, created_at, updated_at)
+			VALUES($1, $2, $3, $4)
+			ON CONFLICT (line_group_id) DO UPDATE
+			SET status = EXCLUDED.status, updated_at = EXCLUDED.updated_at
+			RETURNING id, created_at
+		`, group.LineGroupID, group.Status, group.CreatedAt, group.UpdatedAt)
+
+		if err := row.Scan(&group.ID, &group.CreatedAt); err != nil {
+			return fmt.Errorf("insert groups: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (db *groupDB) GetByLineGroupID(ctx context.Context, lineGroupID string) (*model.Group, error) {
+	row := db.db.Pool.QueryRow(ctx, `SELECT `+groupColumns+` FROM groups WHERE line_group_id = $1`, lineGroupID)
+
+	group, err := scanGroup(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, database.ErrNotFound
+		}
+		return nil, fmt.Errorf("select groups: %w", err)
+	}
+
+	return group, nil
+}
+
+func (db *groupDB) UpdateStatus(ctx context.Context, lineGroupID string, status model.GroupStatus) error {
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+			UPDATE groups
+			SET status = $2, updated_at = $3
+			WHERE line_group_id = $1
+		`, lineGroupID, status, time.Now())
+		if err != nil {
+			return fmt.Errorf("update groups: %w", err)
+		}
+
+		if result.RowsAffected() == 0 {
+			return database.ErrNotFound
+		}
+
+		return nil
+	})
+}
+
+// AddMember adds the user to the group. Adding a member again keeps when they
+// first joined.
+func (db *groupDB) AddMember(ctx context.Context, groupID, userID int64, now time.Time) error {
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO group_members (group_id, user_id, joined_at)
+			VALUES($1, $2, $3)
+			ON CONFLICT (group_id, user_id) DO NOTHING
+		`, groupID, userID, now); err != nil {
+			return fmt.Errorf("insert group_members: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// RemoveMember takes the user out of the group. Their entries stay in the
+// group's ledger.
+func (db *groupDB) RemoveMember(ctx context.Context, groupID, userID int64) error {
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `DELETE FROM group_members WHERE group_id = $1 AND user_id = $2`, groupID, userID)
+		if err != nil {
+			return fmt.Errorf("delete group_members: %w", err)
+		}
+
+		if result.RowsAffected() == 0 {
+			return database.ErrNotFound
+		}
+
+		return nil
+	})
+}
+
+// ListMembers returns the group's members in the order they joined.
+func (db *groupDB) ListMembers(ctx context.Context, groupID int64) ([]*model.Member, error) {
+	rows, err := db.db.Pool.Query(ctx, `
+		SELECT m.group_id, m.user_id, u.display_name, m.joined_at
+		FROM group_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.group_id = $1
+		ORDER BY m.joined_at, m.user_id
+	`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("select group_members: %w", err)
+	}
+	defer rows.Close()
+
+	var members []*model.Member
+	for rows.Next() {
+		var m model.Member
+		if err := rows.Scan(&m.GroupID, &m.UserID, &m.DisplayName, &m.JoinedAt); err != nil {
+			return nil, fmt.Errorf("scan group_members: %w", err)
+		}
+		members = append(members, &m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate group_members: %w", err)
+	}
+
+	return members, nil
+}
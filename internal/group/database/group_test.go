@@ -0,0 +1,81 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"github/shaolim/momon/internal/group/model"
+	usermodel "github/shaolim/momon/internal/user/model"
+	"github/shaolim/momon/internal/user/usertest"
+	"github/shaolim/momon/pkg/database"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpsert(t *testing.T) {
+	t.Parallel()
+
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	groupDB := New(testDB)
+	ctx := context.Background()
+
+	_, err := groupDB.GetByLineGroupID(ctx, "C123")
+	assert.True(t, errors.Is(err, database.ErrNotFound), "got %v", err)
+
+	group := &model.Group{LineGroupID: "C123", Status: model.GroupStatusActive}
+	assert.NoError(t, groupDB.Upsert(ctx, group))
+	assert.NotZero(t, group.ID)
+
+	assert.NoError(t, groupDB.UpdateStatus(ctx, "C123", model.GroupStatusInActive))
+	got, err := groupDB.GetByLineGroupID(ctx, "C123")
+	if assert.NoError(t, err) {
+		assert.Equal(t, model.GroupStatus(model.GroupStatusInActive), got.Status)
+	}
+
+	// Joining again reactivates the same group.
+	again := &model.Group{LineGroupID: "C123", Status: model.GroupStatusActive}
+	assert.NoError(t, groupDB.Upsert(ctx, again))
+	assert.Equal(t, group.ID, again.ID)
+	got, _ = groupDB.GetByLineGroupID(ctx, "C123")
+	assert.Equal(t, model.GroupStatus(model.GroupStatusActive), got.Status)
+
+	err = groupDB.UpdateStatus(ctx, "C999", model.GroupStatusInActive)
+	assert.True(t, errors.Is(err, database.ErrNotFound), "got %v", err)
+	assert.Error(t, groupDB.Upsert(ctx, &model.Group{Status: model.GroupStatusActive}))
+}
+
+func TestMembers(t *testing.T) {
+	t.Parallel()
+
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	groupDB := New(testDB)
+	ctx := context.Background()
+	alice := usertest.MustAddUser(t, testDB, &usermodel.User{LineUserID: "line1", DisplayName: "Alice"})
+	bob := usertest.MustAddUser(t, testDB, &usermodel.User{LineUserID: "line2", DisplayName: "Bob"})
+	now := time.Now()
+
+	group := &model.Group{LineGroupID: "C123", Status: model.GroupStatusActive}
+	if err := groupDB.Upsert(ctx, group); err != nil {
+		t.Fatalf("failed to add group: %v", err)
+	}
+
+	assert.NoError(t, groupDB.AddMember(ctx, group.ID, bob.ID, now.Add(time.Minute)))
+	assert.NoError(t, groupDB.AddMember(ctx, group.ID, alice.ID, now))
+	// Adding a member again is a no-op.
+	assert.NoError(t, groupDB.AddMember(ctx, group.ID, alice.ID, now.Add(time.Hour)))
+
+	members, err := groupDB.ListMembers(ctx, group.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, members, 2) {
+		assert.Equal(t, "Alice", members[0].DisplayName)
+		assert.Equal(t, bob.ID, members[1].UserID)
+	}
+
+	assert.NoError(t, groupDB.RemoveMember(ctx, group.ID, bob.ID))
+	err = groupDB.RemoveMember(ctx, group.ID, bob.ID)
+	assert.True(t, errors.Is(err, database.ErrNotFound), "got %v", err)
+
+	members, _ = groupDB.ListMembers(ctx, group.ID)
+	assert.Len(t, members, 1)
+}
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+// Group is a LINE group chat the bot was added to. Its members share one
+// ledger: every entry sent in the group is recorded in it and attributed to
+// the member who sent it.
+type Group struct {
+	ID          int64
+	LineGroupID string
+	Status      GroupStatus
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (g *Group) Validate() error {
+	if g.LineGroupID == "" {
+		return errors.New("line group id must not be empty")
+	}
+
+	switch g.Status {
+	case GroupStatusActive, GroupStatusInActive:
+	default:
+		return errors.New("status must be either ACTIVE or INACTIVE")
+	}
+
+	return nil
+}
+
+type GroupStatus string
+
+const (
+	GroupStatusActive = "ACTIVE"
+	// GroupStatusInActive groups removed the bot. Their ledger is kept in case
+	// it is invited back.
+	GroupStatusInActive = "INACTIVE"
+)
+
+// Member is a user in a group, with their display name for summaries.
+type Member struct {
+	GroupID     int64
+	UserID      int64
+	DisplayName string
+	JoinedAt    time.Time
+}
@@ -0,0 +1,31 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroup_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		group   Group
+		wantErr string
+	}{
+		{name: "valid", group: Group{LineGroupID: "C123", Status: GroupStatusActive}},
+		{name: "inactive", group: Group{LineGroupID: "C123", Status: GroupStatusInActive}},
+		{name: "no line group id", group: Group{Status: GroupStatusActive}, wantErr: "line group id must not be empty"},
+		{name: "bad status", group: Group{LineGroupID: "C123", Status: "LEFT"}, wantErr: "status must be either ACTIVE or INACTIVE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.group.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github/shaolim/momon/internal/job/model"
+	"github/shaolim/momon/pkg/database"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type JobDB interface {
+	EnqueueJob(ctx context.Context, job *model.Job) (bool, error)
+	GetJob(ctx context.Context, id int64) (*model.Job, error)
+	ClaimJob(ctx context.Context, now time.Time, lease time.Duration) (*model.Job, error)
+	CompleteJob(ctx context.Context, job *model.Job) error
+	RetryJob(ctx context.Context, job *model.Job, runAt time.Time, lastError string) error
+	DeadLetterJob(ctx context.Context, job *model.Job, lastError string) error
+}
+
+type jobDB struct {
+	db *database.DB
+}
+
+func New(db *database.DB) JobDB {
+	return &jobDB{
+		db: db,
+	}
+}
+
+const jobColumns = `id, event_id, payload, status, attempts, last_error, run_at, locked_until, created_at, updated_at`
+
+func scanJob(row pgx.Row) (*model.Job, error) {
+	var (
+		j           model.Job
+		lockedUntil *time.Time
+	)
+	if err := row.Scan(&j.ID, &j.EventID, &j.Payload, &j.Status, &j.Attempts, &j.LastError,
+		&j.RunAt, &lockedUntil, &j.CreatedAt, &j.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if lockedUntil != nil {
+		j.LockedUntil = *lockedUntil
+	}
+	return &j, nil
+}
+
+// EnqueueJob stores a pending job. It returns false without an error when a
+// job for the same event ID exists already, which happens when LINE
+// redelivers a webhook.
+func (db *jobDB) EnqueueJob(ctx context.Context, job *model.Job) (bool, error) {
+	if err := job.Validate(); err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	if job.RunAt.IsZero() {
+		job.RunAt = now
+	}
+	job.Status = model.JobStatusPending
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	inserted := false
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, `
+			INSERT INTO webhook_jobs (event_id, payload, status, run_at, created_at, updated_at)
+			VALUES($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (event_id) DO NOTHING
+			RETURNING id
+		`, job.EventID, job.Payload, job.Status, job.RunAt, job.CreatedAt, job.UpdatedAt)
+
+		if err := row.Scan(&job.ID); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil
+			}
+			return fmt.Errorf("insert webhook_jobs: %w", err)
+		}
+		inserted = true
+
+		return nil
+	}); err != nil {
+		return false, err
+	}
+
+	return inserted, nil
+}
+
+func (db *jobDB) GetJob(ctx context.Context, id int64) (*model.Job, error) {
+	row := db.db.Pool.QueryRow(ctx, `SELECT `+jobColumns+` FROM webhook_jobs WHERE id = $1`, id)
+
+	job, err := scanJob(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, database.ErrNotFound
+		}
+		return nil, fmt.Errorf("select webhook_jobs: %w", err)
+	}
+
+	return job, nil
+}
+
+// ClaimJob locks the next job that is due, either pending or running with an
+// expired lease, and marks it running until now+lease. It returns
+// database.ErrNotFound when there is nothing to do. Concurrent workers never
+// claim the same job.
+func (db *jobDB) ClaimJob(ctx context.Context, now time.Time, lease time.Duration) (*model.Job, error) {
+	var job *model.Job
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, `
+			UPDATE webhook_jobs
+			SET status = $2, attempts = attempts + 1, locked_until = $3, updated_at = $5
+			WHERE id = (
+				SELECT id FROM webhook_jobs
+				WHERE (status = $4 AND run_at <= $1) OR (status = $2 AND locked_until <= $1)
+				ORDER BY run_at, id
+				LIMIT 1
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING `+jobColumns,
+			now, model.JobStatusRunning, now.Add(lease), model.JobStatusPending, time.Now())
+
+		var err error
+		if job, err = scanJob(row); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return database.ErrNotFound
+			}
+			return fmt.Errorf("claim webhook_jobs: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+func (db *jobDB) CompleteJob(ctx context.Context, job *model.Job) error {
+	return db.updateJob(ctx, job, model.JobStatusDone, nil, "")
+}
+
+// RetryJob puts a failed job back in the queue to be claimed again at runAt.
+func (db *jobDB) RetryJob(ctx context.Context, job *model.Job, runAt time.Time, lastError string) error {
+	return db.updateJob(ctx, job, model.JobStatusPending, &runAt, lastError)
+}
+
+// DeadLetterJob gives up on a job. It is kept with its last error for
+// inspection but never claimed again.
+func (db *jobDB) DeadLetterJob(ctx context.Context, job *model.Job, lastError string) error {
+	return db.updateJob(ctx, job, model.JobStatusDead, nil, lastError)
+}
+
+// updateJob releases the job's lease and moves it to status. runAt is only
+// changed when it is not nil. It returns database.ErrNotFound when the run
+// no longer owns the job because its lease ran out and another worker
+// claimed it, so a stale worker never overwrites the new run's state.
+func (db *jobDB) updateJob(ctx context.Context, job *model.Job, status model.JobStatus, runAt *time.Time, lastError string) error {
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+			UPDATE webhook_jobs
+			SET status = $2, run_at = COALESCE($3, run_at), last_error = $4, locked_until = NULL, updated_at = $5
+			WHERE id = $1 AND attempts = $6 AND status = $7
+		`, job.ID, status, runAt, lastError, time.Now(), job.Attempts, model.JobStatusRunning)
+		if err != nil {
+			return fmt.Errorf("update webhook_jobs: %w", err)
+		}
+
+		if result.RowsAffected() == 0 {
+			return database.ErrNotFound
+		}
+
+		return nil
+	})
+}
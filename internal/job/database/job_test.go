@@ -0,0 +1,138 @@
+package database
+
+import (
+	"context"
+	"github/shaolim/momon/internal/job/model"
+	"github/shaolim/momon/pkg/database"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnqueueJob(t *testing.T) {
+	t.Parallel()
+
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	jobDB := New(testDB)
+	ctx := context.Background()
+
+	job := &model.Job{EventID: "event-1", Payload: []byte(`{"type":"message"}`)}
+	inserted, err := jobDB.EnqueueJob(ctx, job)
+	if err != nil {
+		t.Fatalf("failed to enqueue job: %v", err)
+	}
+	assert.True(t, inserted)
+	assert.NotZero(t, job.ID)
+
+	got, err := jobDB.GetJob(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("failed to get job: %v", err)
+	}
+	assert.Equal(t, "event-1", got.EventID)
+	assert.JSONEq(t, `{"type":"message"}`, string(got.Payload))
+	assert.Equal(t, model.JobStatus(model.JobStatusPending), got.Status)
+	assert.Zero(t, got.Attempts)
+
+	// A redelivered webhook carries the same event ID.
+	inserted, err = jobDB.EnqueueJob(ctx, &model.Job{EventID: "event-1", Payload: []byte(`{"type":"message"}`)})
+	if err != nil {
+		t.Fatalf("failed to enqueue duplicate job: %v", err)
+	}
+	assert.False(t, inserted)
+
+	_, err = jobDB.EnqueueJob(ctx, &model.Job{Payload: []byte(`{}`)})
+	assert.EqualError(t, err, "event id must not be empty")
+}
+
+func TestClaimJob(t *testing.T) {
+	t.Parallel()
+
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	jobDB := New(testDB)
+	ctx := context.Background()
+	now := time.Now()
+
+	first := &model.Job{EventID: "event-1", Payload: []byte(`{}`), RunAt: now.Add(-time.Minute)}
+	later := &model.Job{EventID: "event-2", Payload: []byte(`{}`), RunAt: now.Add(time.Hour)}
+	for _, job := range []*model.Job{first, later} {
+		if _, err := jobDB.EnqueueJob(ctx, job); err != nil {
+			t.Fatalf("failed to enqueue job: %v", err)
+		}
+	}
+
+	claimed, err := jobDB.ClaimJob(ctx, now, time.Minute)
+	if err != nil {
+		t.Fatalf("failed to claim job: %v", err)
+	}
+	assert.Equal(t, first.ID, claimed.ID)
+	assert.Equal(t, model.JobStatus(model.JobStatusRunning), claimed.Status)
+	assert.Equal(t, 1, claimed.Attempts)
+
+	// The running job is leased and the other one is not due yet.
+	_, err = jobDB.ClaimJob(ctx, now, time.Minute)
+	assert.ErrorIs(t, err, database.ErrNotFound)
+
+	// Once the lease expires the job is assumed lost and claimed again.
+	reclaimed, err := jobDB.ClaimJob(ctx, now.Add(2*time.Minute), time.Minute)
+	if err != nil {
+		t.Fatalf("failed to reclaim job: %v", err)
+	}
+	assert.Equal(t, first.ID, reclaimed.ID)
+	assert.Equal(t, 2, reclaimed.Attempts)
+
+	// The lost run finishing late leaves the new run's state alone.
+	assert.ErrorIs(t, jobDB.CompleteJob(ctx, claimed), database.ErrNotFound)
+	assert.NoError(t, jobDB.CompleteJob(ctx, reclaimed))
+	got, err := jobDB.GetJob(ctx, first.ID)
+	if err != nil {
+		t.Fatalf("failed to get job: %v", err)
+	}
+	assert.Equal(t, model.JobStatus(model.JobStatusDone), got.Status)
+}
+
+func TestRetryAndDeadLetterJob(t *testing.T) {
+	t.Parallel()
+
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	jobDB := New(testDB)
+	ctx := context.Background()
+	now := time.Now()
+
+	job := &model.Job{EventID: "event-1", Payload: []byte(`{}`), RunAt: now}
+	if _, err := jobDB.EnqueueJob(ctx, job); err != nil {
+		t.Fatalf("failed to enqueue job: %v", err)
+	}
+	job, err := jobDB.ClaimJob(ctx, now, time.Minute)
+	if err != nil {
+		t.Fatalf("failed to claim job: %v", err)
+	}
+
+	if err := jobDB.RetryJob(ctx, job, now.Add(10*time.Second), "timeout"); err != nil {
+		t.Fatalf("failed to retry job: %v", err)
+	}
+	_, err = jobDB.ClaimJob(ctx, now, time.Minute)
+	assert.ErrorIs(t, err, database.ErrNotFound)
+
+	claimed, err := jobDB.ClaimJob(ctx, now.Add(10*time.Second), time.Minute)
+	if err != nil {
+		t.Fatalf("failed to claim retried job: %v", err)
+	}
+	assert.Equal(t, "timeout", claimed.LastError)
+	assert.Equal(t, 2, claimed.Attempts)
+
+	if err := jobDB.DeadLetterJob(ctx, claimed, "still timing out"); err != nil {
+		t.Fatalf("failed to dead letter job: %v", err)
+	}
+	got, err := jobDB.GetJob(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("failed to get job: %v", err)
+	}
+	assert.Equal(t, model.JobStatus(model.JobStatusDead), got.Status)
+	assert.Equal(t, "still timing out", got.LastError)
+
+	_, err = jobDB.ClaimJob(ctx, now.Add(time.Hour), time.Minute)
+	assert.ErrorIs(t, err, database.ErrNotFound)
+
+	assert.ErrorIs(t, jobDB.CompleteJob(ctx, &model.Job{}), database.ErrNotFound)
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+// Job is a webhook event waiting to be processed. Payload is the raw event
+// JSON exactly as LINE sent it.
+type Job struct {
+	ID      int64
+	EventID string
+	Payload []byte
+	Status  JobStatus
+	// Attempts counts how many times the job was claimed, including the
+	// current run.
+	Attempts  int
+	LastError string
+	// RunAt is the earliest time the job may be claimed.
+	RunAt time.Time
+	// LockedUntil is when a running job's lease ends. A job still running
+	// after that is assumed lost with its worker and is claimed again.
+	LockedUntil time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (j *Job) Validate() error {
+	if j.EventID == "" {
+		return errors.New("event id must not be empty")
+	}
+
+	if len(j.Payload) == 0 {
+		return errors.New("payload must not be empty")
+	}
+
+	return nil
+}
+
+type JobStatus string
+
+const (
+	JobStatusPending = "PENDING"
+	JobStatusRunning = "RUNNING"
+	JobStatusDone    = "DONE"
+	// JobStatusDead marks a job that failed too many times and is no longer
+	// retried.
+	JobStatusDead = "DEAD"
+)
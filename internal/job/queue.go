@@ -0,0 +1,265 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	jobdb "github/shaolim/momon/internal/job/database"
+	"github/shaolim/momon/internal/job/model"
+	"github/shaolim/momon/pkg/database"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Handler processes a job. Returning an error schedules a retry.
+type Handler func(ctx context.Context, job *model.Job) error
+
+// DeadLetterHandler is told about a job that failed for the last time, e.g. to
+// let the user know their message was dropped.
+type DeadLetterHandler func(ctx context.Context, job *model.Job, err error)
+
+// Queue runs jobs stored in Postgres on a bounded pool of workers. Failed jobs
+// are retried with exponential backoff and dead-lettered after too many
+// attempts.
+type Queue struct {
+	db           jobdb.JobDB
+	handler      Handler
+	onDeadLetter DeadLetterHandler
+
+	workers      int
+	maxAttempts  int
+	baseBackoff  time.Duration
+	maxBackoff   time.Duration
+	pollInterval time.Duration
+	lease        time.Duration
+
+	// ctx is passed to handlers and cancelled when Shutdown gives up waiting.
+	ctx    context.Context
+	cancel context.CancelFunc
+	stop   chan struct{}
+	wake   chan struct{}
+	wg     sync.WaitGroup
+	once   sync.Once
+}
+
+type QueueOption func(*Queue) *Queue
+
+// WithWorkers sets how many jobs run at the same time.
+func WithWorkers(n int) QueueOption {
+	return func(q *Queue) *Queue {
+		if n > 0 {
+			q.workers = n
+		}
+		return q
+	}
+}
+
+// WithMaxAttempts sets how many times a job runs before it is dead-lettered.
+func WithMaxAttempts(n int) QueueOption {
+	return func(q *Queue) *Queue {
+		if n > 0 {
+			q.maxAttempts = n
+		}
+		return q
+	}
+}
+
+// WithBackoff sets the delay before the first retry, doubled on every
+// following retry up to max.
+func WithBackoff(base, max time.Duration) QueueOption {
+	return func(q *Queue) *Queue {
+		q.baseBackoff = base
+		q.maxBackoff = max
+		return q
+	}
+}
+
+// WithPollInterval sets how often idle workers look for due retries.
+func WithPollInterval(d time.Duration) QueueOption {
+	return func(q *Queue) *Queue {
+		q.pollInterval = d
+		return q
+	}
+}
+
+// WithLease sets how long a job may run before it is assumed lost and claimed
+// by another worker.
+func WithLease(d time.Duration) QueueOption {
+	return func(q *Queue) *Queue {
+		q.lease = d
+		return q
+	}
+}
+
+// WithDeadLetterHandler sets a function called after a job is dead-lettered.
+func WithDeadLetterHandler(h DeadLetterHandler) QueueOption {
+	return func(q *Queue) *Queue {
+		q.onDeadLetter = h
+		return q
+	}
+}
+
+func NewQueue(db jobdb.JobDB, handler Handler, opts ...QueueOption) *Queue {
+	q := &Queue{
+		db:           db,
+		handler:      handler,
+		workers:      4,
+		maxAttempts:  5,
+		baseBackoff:  5 * time.Second,
+		maxBackoff:   10 * time.Minute,
+		pollInterval: time.Second,
+		lease:        5 * time.Minute,
+		stop:         make(chan struct{}),
+		wake:         make(chan struct{}, 1),
+	}
+	q.ctx, q.cancel = context.WithCancel(context.Background())
+
+	for _, f := range opts {
+		q = f(q)
+	}
+
+	return q
+}
+
+// Enqueue stores the event for processing. It returns false when the event was
+// queued before.
+func (q *Queue) Enqueue(ctx context.Context, eventID string, payload []byte) (bool, error) {
+	inserted, err := q.db.EnqueueJob(ctx, &model.Job{EventID: eventID, Payload: payload})
+	if err != nil {
+		return false, fmt.Errorf("failed to enqueue job: %w", err)
+	}
+
+	if inserted {
+		select {
+		case q.wake <- struct{}{}:
+		default:
+		}
+	}
+
+	return inserted, nil
+}
+
+// Start launches the workers.
+func (q *Queue) Start() {
+	for range q.workers {
+		q.wg.Go(q.work)
+	}
+}
+
+// Shutdown stops claiming jobs and waits for running ones to finish. When ctx
+// expires first the running jobs are cancelled and ctx's error is returned;
+// they are claimed again once their lease runs out.
+func (q *Queue) Shutdown(ctx context.Context) error {
+	q.once.Do(func() {
+		close(q.stop)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		q.cancel()
+		return nil
+	case <-ctx.Done():
+		q.cancel()
+		return fmt.Errorf("failed to wait for running jobs: %w", ctx.Err())
+	}
+}
+
+// Backoff returns the delay before retrying a job that failed attempts times.
+func (q *Queue) Backoff(attempts int) time.Duration {
+	d := q.baseBackoff
+	for i := 1; i < attempts && d < q.maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, q.maxBackoff)
+}
+
+func (q *Queue) work() {
+	for {
+		select {
+		case <-q.stop:
+			return
+		default:
+		}
+
+		job, err := q.db.ClaimJob(q.ctx, time.Now(), q.lease)
+		if err == nil {
+			q.run(job)
+			continue
+		}
+		if !errors.Is(err, database.ErrNotFound) {
+			slog.Error("failed to claim job", slog.Any("error", err))
+		}
+
+		select {
+		case <-q.stop:
+			return
+		case <-q.wake:
+		case <-time.After(q.pollInterval):
+		}
+	}
+}
+
+func (q *Queue) run(job *model.Job) {
+	err := q.handle(job)
+
+	// Record the outcome even when the handler was cancelled by Shutdown.
+	ctx := context.WithoutCancel(q.ctx)
+	logger := slog.With(slog.Int64("job_id", job.ID), slog.String("event_id", job.EventID), slog.Int("attempts", job.Attempts))
+
+	switch {
+	case err == nil:
+		if err := q.db.CompleteJob(ctx, job); err != nil {
+			logUpdateError(logger, "failed to complete job", err)
+		}
+	case job.Attempts >= q.maxAttempts:
+		logger.Error("job failed, giving up", slog.Any("error", err))
+		if err := q.db.DeadLetterJob(ctx, job, err.Error()); err != nil {
+			logUpdateError(logger, "failed to dead letter job", err)
+			return
+		}
+		if q.onDeadLetter != nil {
+			q.onDeadLetter(ctx, job, err)
+		}
+	default:
+		delay := q.Backoff(job.Attempts)
+		logger.Warn("job failed, retrying", slog.Duration("delay", delay), slog.Any("error", err))
+		if err := q.db.RetryJob(ctx, job, time.Now().Add(delay), err.Error()); err != nil {
+			logUpdateError(logger, "failed to retry job", err)
+		}
+	}
+}
+
+// logUpdateError logs a failure to record a run's outcome. A run whose lease
+// ran out before it finished no longer owns the job and leaves it to the
+// worker that claimed it next.
+func logUpdateError(logger *slog.Logger, msg string, err error) {
+	if errors.Is(err, database.ErrNotFound) {
+		logger.Warn("job was claimed again before it finished")
+		return
+	}
+	logger.Error(msg, slog.Any("error", err))
+}
+
+// handle runs the handler, turning a panic into an error so one bad event
+// does not take the worker down. The handler is cancelled when its lease runs
+// out, before another worker may claim the job, so one job never runs twice
+// at the same time.
+func (q *Queue) handle(job *model.Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(q.ctx, q.lease)
+	defer cancel()
+
+	return q.handler(ctx, job)
+}
@@ -0,0 +1,247 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"github/shaolim/momon/internal/job/model"
+	"github/shaolim/momon/pkg/database"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeJobDB is an in-memory JobDB that ignores leases.
+type fakeJobDB struct {
+	mu   sync.Mutex
+	jobs []*model.Job
+}
+
+func (f *fakeJobDB) EnqueueJob(_ context.Context, job *model.Job) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, j := range f.jobs {
+		if j.EventID == job.EventID {
+			return false, nil
+		}
+	}
+	job.ID = int64(len(f.jobs) + 1)
+	job.Status = model.JobStatusPending
+	f.jobs = append(f.jobs, job)
+	return true, nil
+}
+
+func (f *fakeJobDB) GetJob(_ context.Context, id int64) (*model.Job, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, j := range f.jobs {
+		if j.ID == id {
+			copied := *j
+			return &copied, nil
+		}
+	}
+	return nil, database.ErrNotFound
+}
+
+func (f *fakeJobDB) ClaimJob(_ context.Context, now time.Time, _ time.Duration) (*model.Job, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, j := range f.jobs {
+		if j.Status == model.JobStatusPending && !j.RunAt.After(now) {
+			j.Status = model.JobStatusRunning
+			j.Attempts++
+			copied := *j
+			return &copied, nil
+		}
+	}
+	return nil, database.ErrNotFound
+}
+
+func (f *fakeJobDB) CompleteJob(ctx context.Context, job *model.Job) error {
+	return f.update(job, model.JobStatusDone, time.Time{}, "")
+}
+
+func (f *fakeJobDB) RetryJob(ctx context.Context, job *model.Job, runAt time.Time, lastError string) error {
+	return f.update(job, model.JobStatusPending, runAt, lastError)
+}
+
+func (f *fakeJobDB) DeadLetterJob(ctx context.Context, job *model.Job, lastError string) error {
+	return f.update(job, model.JobStatusDead, time.Time{}, lastError)
+}
+
+func (f *fakeJobDB) update(job *model.Job, status model.JobStatus, runAt time.Time, lastError string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, j := range f.jobs {
+		if j.ID == job.ID && j.Attempts == job.Attempts && j.Status == model.JobStatusRunning {
+			j.Status = status
+			j.LastError = lastError
+			if !runAt.IsZero() {
+				j.RunAt = runAt
+			}
+			return nil
+		}
+	}
+	return database.ErrNotFound
+}
+
+// waitForStatus polls until the job reaches status.
+func waitForStatus(t *testing.T, db *fakeJobDB, id int64, status model.JobStatus) *model.Job {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := db.GetJob(context.Background(), id)
+		if err != nil {
+			t.Fatalf("failed to get job: %v", err)
+		}
+		if job.Status == status {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("job %d did not reach status %s", id, status)
+	return nil
+}
+
+func TestQueue(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("processes jobs once", func(t *testing.T) {
+		db := &fakeJobDB{}
+		var (
+			mu      sync.Mutex
+			handled []string
+		)
+		q := NewQueue(db, func(_ context.Context, job *model.Job) error {
+			mu.Lock()
+			defer mu.Unlock()
+			handled = append(handled, job.EventID)
+			return nil
+		}, WithWorkers(2), WithPollInterval(5*time.Millisecond))
+		q.Start()
+
+		inserted, err := q.Enqueue(ctx, "event-1", []byte(`{}`))
+		assert.NoError(t, err)
+		assert.True(t, inserted)
+
+		// Redeliveries are dropped.
+		inserted, err = q.Enqueue(ctx, "event-1", []byte(`{}`))
+		assert.NoError(t, err)
+		assert.False(t, inserted)
+
+		waitForStatus(t, db, 1, model.JobStatusDone)
+		assert.NoError(t, q.Shutdown(ctx))
+
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, []string{"event-1"}, handled)
+	})
+
+	t.Run("retries and dead letters", func(t *testing.T) {
+		db := &fakeJobDB{}
+		attempts := 0
+		var deadLetterErr error
+		q := NewQueue(db, func(_ context.Context, job *model.Job) error {
+			attempts = job.Attempts
+			return errors.New("receipt OCR timed out")
+		},
+			WithWorkers(1), WithMaxAttempts(3), WithBackoff(time.Millisecond, time.Millisecond), WithPollInterval(time.Millisecond),
+			WithDeadLetterHandler(func(_ context.Context, _ *model.Job, err error) {
+				deadLetterErr = err
+			}))
+		q.Start()
+
+		if _, err := q.Enqueue(ctx, "event-1", []byte(`{}`)); err != nil {
+			t.Fatalf("failed to enqueue: %v", err)
+		}
+
+		job := waitForStatus(t, db, 1, model.JobStatusDead)
+		assert.NoError(t, q.Shutdown(ctx))
+
+		assert.Equal(t, 3, attempts)
+		assert.Equal(t, 3, job.Attempts)
+		assert.Equal(t, "receipt OCR timed out", job.LastError)
+		assert.EqualError(t, deadLetterErr, "receipt OCR timed out")
+	})
+
+	t.Run("panics are retried", func(t *testing.T) {
+		db := &fakeJobDB{}
+		q := NewQueue(db, func(_ context.Context, job *model.Job) error {
+			if job.Attempts == 1 {
+				panic("boom")
+			}
+			return nil
+		}, WithWorkers(1), WithBackoff(time.Millisecond, time.Millisecond), WithPollInterval(time.Millisecond))
+		q.Start()
+
+		if _, err := q.Enqueue(ctx, "event-1", []byte(`{}`)); err != nil {
+			t.Fatalf("failed to enqueue: %v", err)
+		}
+
+		job := waitForStatus(t, db, 1, model.JobStatusDone)
+		assert.NoError(t, q.Shutdown(ctx))
+		assert.Equal(t, 2, job.Attempts)
+	})
+
+	t.Run("shutdown cancels jobs after the deadline", func(t *testing.T) {
+		db := &fakeJobDB{}
+		started := make(chan struct{})
+		q := NewQueue(db, func(ctx context.Context, _ *model.Job) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		}, WithWorkers(1), WithPollInterval(time.Millisecond))
+		q.Start()
+
+		if _, err := q.Enqueue(ctx, "event-1", []byte(`{}`)); err != nil {
+			t.Fatalf("failed to enqueue: %v", err)
+		}
+		<-started
+
+		shutdownCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, q.Shutdown(shutdownCtx), context.DeadlineExceeded)
+
+		// The cancelled run is recorded as a failure to retry later.
+		job := waitForStatus(t, db, 1, model.JobStatusPending)
+		assert.Equal(t, context.Canceled.Error(), job.LastError)
+	})
+
+	t.Run("handlers stop when their lease runs out", func(t *testing.T) {
+		db := &fakeJobDB{}
+		q := NewQueue(db, func(ctx context.Context, job *model.Job) error {
+			if job.Attempts == 1 {
+				// A hung HTTP call.
+				<-ctx.Done()
+				return ctx.Err()
+			}
+			return nil
+		}, WithWorkers(1), WithLease(10*time.Millisecond), WithBackoff(time.Millisecond, time.Millisecond), WithPollInterval(time.Millisecond))
+		q.Start()
+
+		if _, err := q.Enqueue(ctx, "event-1", []byte(`{}`)); err != nil {
+			t.Fatalf("failed to enqueue: %v", err)
+		}
+
+		job := waitForStatus(t, db, 1, model.JobStatusDone)
+		assert.NoError(t, q.Shutdown(ctx))
+		assert.Equal(t, 2, job.Attempts)
+		assert.Equal(t, "", job.LastError)
+	})
+}
+
+func TestQueue_Backoff(t *testing.T) {
+	q := NewQueue(&fakeJobDB{}, nil, WithBackoff(5*time.Second, time.Minute))
+
+	assert.Equal(t, 5*time.Second, q.Backoff(1))
+	assert.Equal(t, 10*time.Second, q.Backoff(2))
+	assert.Equal(t, 40*time.Second, q.Backoff(4))
+	assert.Equal(t, time.Minute, q.Backoff(5))
+	assert.Equal(t, time.Minute, q.Backoff(30))
+}
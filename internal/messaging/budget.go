@@ -0,0 +1,224 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github/shaolim/momon/internal/budget"
+	budgetmodel "github/shaolim/momon/internal/budget/model"
+	transactiondb "github/shaolim/momon/internal/transaction/database"
+	"github/shaolim/momon/internal/transaction/model"
+	usermodel "github/shaolim/momon/internal/user/model"
+	"github/shaolim/momon/pkg/database"
+	msg "github/shaolim/momon/pkg/messaging"
+	"github/shaolim/momon/pkg/money"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const budgetUsage = `Usage:
+/budget AMOUNT - limit all spending per month, e.g. /budget 100000
+/budget CATEGORY AMOUNT - limit one category, e.g. /budget Food 30000
+/budget [CATEGORY] off - remove a budget`
+
+// budgetCommand sets or removes the user's monthly budget, overall or for a
+// category.
+func (m *messaging) budgetCommand(ctx context.Context, req *Request) error {
+	if m.budgetDB == nil {
+		return req.Reply.ReplyText("Sorry, budgets aren't available right now.")
+	}
+	if len(req.Args) == 0 {
+		return req.Reply.ReplyText(budgetUsage)
+	}
+
+	last := req.Args[len(req.Args)-1]
+	category := m.budgetCategory(ctx, req.User.ID, strings.Join(req.Args[:len(req.Args)-1], " "))
+	if utf8.RuneCountInString(category) > budgetmodel.MaxCategoryLength {
+		return req.Reply.ReplyText(fmt.Sprintf("Please use a category of up to %d characters.", budgetmodel.MaxCategoryLength))
+	}
+	b := &budgetmodel.Budget{UserID: req.User.ID, Category: category, Currency: m.currency(req.User)}
+
+	if strings.EqualFold(last, "off") {
+		if err := m.budgetDB.DeleteBudget(ctx, req.User.ID, category); err != nil {
+			if errors.Is(err, database.ErrNotFound) {
+				return req.Reply.ReplyText(fmt.Sprintf("You have no %s budget.", b.Name()))
+			}
+			return fmt.Errorf("failed to delete budget: %w", err)
+		}
+		return replied(req.Reply.ReplyText(fmt.Sprintf("Removed your %s budget.", b.Name())))
+	}
+
+	amount, err := money.Parse(last, m.currency(req.User))
+	if err != nil || amount.Amount <= 0 {
+		return req.Reply.ReplyText(budgetUsage)
+	}
+	b.Amount = amount.Amount
+
+	if err := m.budgetDB.SetBudget(ctx, b); err != nil {
+		return fmt.Errorf("failed to set budget: %w", err)
+	}
+
+	reply := fmt.Sprintf("Your %s budget is now %s a month.", b.Name(), b.Money())
+	statuses, err := m.budgetStatuses(ctx, req.User, []*budgetmodel.Budget{b}, req.Now)
+	if err != nil {
+		slog.Warn("failed to evaluate budget", slog.Int64("budget_id", b.ID), slog.Any("error", err))
+	} else {
+		reply += "\n" + budgetProgress(statuses[0])
+	}
+	return replied(req.Reply.ReplyText(reply))
+}
+
+// budgetCategory returns the user's spelling of category when they have it, so
+// "/budget food 30000" shows up as Food.
+func (m *messaging) budgetCategory(ctx context.Context, userID int64, category string) string {
+	category = strings.TrimSpace(category)
+	if category == "" || m.categorizer == nil {
+		return category
+	}
+
+	categories, err := m.categorizer.Categories(ctx, userID)
+	if err != nil {
+		slog.Warn("failed to list categories", slog.Any("error", err))
+		return category
+	}
+	for _, c := range categories {
+		if strings.EqualFold(c.Name, category) {
+			return c.Name
+		}
+	}
+	return category
+}
+
+func (m *messaging) budgetsCommand(ctx context.Context, req *Request) error {
+	if m.budgetDB == nil {
+		return req.Reply.ReplyText("Sorry, budgets aren't available right now.")
+	}
+
+	budgets, err := m.budgetDB.ListBudgets(ctx, req.User.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list budgets: %w", err)
+	}
+	if len(budgets) == 0 {
+		return req.Reply.ReplyText("You have no budgets yet.\n\n" + budgetUsage)
+	}
+
+	statuses, err := m.budgetStatuses(ctx, req.User, budgets, req.Now)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	b.WriteString(startOfMonth(req.Now).Format("January 2006"))
+	for _, s := range statuses {
+		fmt.Fprintf(&b, "\n%s: %s of %s (%d%%)", s.Budget.Name(), s.Spent.Major(), s.Budget.Money(), s.Percent())
+	}
+
+	return req.Reply.ReplyText(b.String())
+}
+
+// budgetStatuses evaluates budgets against the expenses in the month now falls
+// in, converted to each budget's currency where there is a rate.
+func (m *messaging) budgetStatuses(ctx context.Context, user *usermodel.User, budgets []*budgetmodel.Budget, now time.Time) ([]*budget.Status, error) {
+	from := startOfMonth(now)
+	transactions, err := m.transactionDB.ListTransactions(ctx, &transactiondb.ListFilter{
+		UserID: user.ID,
+		Type:   model.TransactionTypeExpense,
+		From:   from,
+		To:     from.AddDate(0, 1, 0),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transactions: %w", err)
+	}
+
+	converted := map[money.Currency][]*model.Transaction{}
+	statuses := make([]*budget.Status, 0, len(budgets))
+	for _, b := range budgets {
+		inCurrency, ok := converted[b.Currency]
+		if !ok {
+			if inCurrency, err = m.convertTransactions(ctx, user, transactions, b.Currency); err != nil {
+				return nil, err
+			}
+			converted[b.Currency] = inCurrency
+		}
+		statuses = append(statuses, budget.Evaluate([]*budgetmodel.Budget{b}, inCurrency)...)
+	}
+	return statuses, nil
+}
+
+// budgetProgress describes how much of a budget is spent or left.
+func budgetProgress(s *budget.Status) string {
+	remaining := s.Remaining()
+	if remaining.IsNegative() {
+		return fmt.Sprintf("You've spent %s this month, %s over.", s.Spent, remaining.Abs())
+	}
+	return fmt.Sprintf("You've spent %s this month, %s left.", s.Spent, remaining)
+}
+
+// checkBudgets pushes an alert for each budget the expense just took past one
+// of the configured thresholds. Entries for other months are ignored. Each
+// threshold is marked before the push, so a failed push is logged rather than
+// repeated. Failures never undo the entry. Entries in a group's shared ledger
+// don't count towards personal budgets.
+func (m *messaging) checkBudgets(ctx context.Context, user *usermodel.User, t *model.Transaction, now time.Time) {
+	if m.budgetDB == nil || t.Type != model.TransactionTypeExpense || t.GroupID != 0 {
+		return
+	}
+
+	month := startOfMonth(now)
+	if t.OccurredAt.Before(month) || !t.OccurredAt.Before(month.AddDate(0, 1, 0)) {
+		return
+	}
+
+	budgets, err := m.budgetDB.ListBudgets(ctx, user.ID)
+	if err != nil {
+		slog.Error("failed to list budgets", slog.Int64("user_id", user.ID), slog.Any("error", err))
+		return
+	}
+	var affected []*budgetmodel.Budget
+	for _, b := range budgets {
+		// Budgets in other currencies count the expense where there is a rate.
+		if (b.Currency == t.Currency || m.fxDB != nil) && b.Covers(t.Category) {
+			affected = append(affected, b)
+		}
+	}
+	if len(affected) == 0 {
+		return
+	}
+
+	statuses, err := m.budgetStatuses(ctx, user, affected, now)
+	if err != nil {
+		slog.Error("failed to evaluate budgets", slog.Int64("user_id", user.ID), slog.Any("error", err))
+		return
+	}
+
+	for _, s := range statuses {
+		threshold, ok := budget.Crossed(s.Percent(), m.config.BudgetAlertThresholds)
+		if !ok {
+			continue
+		}
+
+		first, err := m.budgetDB.MarkAlerted(ctx, s.Budget.ID, month, threshold, now)
+		if err != nil {
+			slog.Error("failed to mark budget alert", slog.Int64("budget_id", s.Budget.ID), slog.Any("error", err))
+			continue
+		}
+		if !first {
+			continue
+		}
+
+		if err := m.push(ctx, user, "", msg.TextMessage(budgetAlert(s, threshold))); err != nil {
+			slog.Error("failed to push budget alert", slog.Int64("budget_id", s.Budget.ID), slog.Any("error", err))
+			continue
+		}
+		slog.Info("pushed budget alert", slog.Int64("budget_id", s.Budget.ID), slog.Int("threshold", threshold))
+	}
+}
+
+func budgetAlert(s *budget.Status, threshold int) string {
+	if threshold >= 100 {
+		return fmt.Sprintf("You've used up your %s budget of %s.\n%s", s.Budget.Name(), s.Budget.Money(), budgetProgress(s))
+	}
+	return fmt.Sprintf("Heads up: you've used %d%% of your %s budget of %s.\n%s", s.Percent(), s.Budget.Name(), s.Budget.Money(), budgetProgress(s))
+}
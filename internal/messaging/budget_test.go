@@ -0,0 +1,227 @@
+package messaging
+
+import (
+	"context"
+	"github/shaolim/momon/internal/budget/model"
+	"github/shaolim/momon/internal/category"
+	"github/shaolim/momon/internal/category/categorytest"
+	"github/shaolim/momon/internal/fx/fxtest"
+	fxmodel "github/shaolim/momon/internal/fx/model"
+	transactionmodel "github/shaolim/momon/internal/transaction/model"
+	usermodel "github/shaolim/momon/internal/user/model"
+	"github/shaolim/momon/pkg/database"
+	msg "github/shaolim/momon/pkg/messaging"
+	"github/shaolim/momon/pkg/messaging/messagingtest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeBudgetDB is an in-memory BudgetDB for handler tests.
+type fakeBudgetDB struct {
+	budgets []*model.Budget
+	alerts  map[budgetAlertKey]bool
+}
+
+type budgetAlertKey struct {
+	budgetID  int64
+	month     time.Time
+	threshold int
+}
+
+func (f *fakeBudgetDB) SetBudget(_ context.Context, budget *model.Budget) error {
+	if err := budget.Validate(); err != nil {
+		return err
+	}
+	for _, b := range f.budgets {
+		if b.UserID == budget.UserID && strings.EqualFold(b.Category, budget.Category) {
+			b.Amount = budget.Amount
+			*budget = *b
+			for key := range f.alerts {
+				if key.budgetID == b.ID {
+					delete(f.alerts, key)
+				}
+			}
+			return nil
+		}
+	}
+	budget.ID = int64(len(f.budgets) + 1)
+	f.budgets = append(f.budgets, budget)
+	return nil
+}
+
+func (f *fakeBudgetDB) DeleteBudget(_ context.Context, userID int64, category string) error {
+	for i, b := range f.budgets {
+		if b.UserID == userID && strings.EqualFold(b.Category, category) {
+			f.budgets = append(f.budgets[:i], f.budgets[i+1:]...)
+			return nil
+		}
+	}
+	return database.ErrNotFound
+}
+
+func (f *fakeBudgetDB) ListBudgets(_ context.Context, userID int64) ([]*model.Budget, error) {
+	var result []*model.Budget
+	for _, b := range f.budgets {
+		if b.UserID == userID {
+			result = append(result, b)
+		}
+	}
+	// The overall budget comes first, the rest by category.
+	slices.SortStableFunc(result, func(a, b *model.Budget) int {
+		if a.IsOverall() != b.IsOverall() {
+			if a.IsOverall() {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(strings.ToLower(a.Category), strings.ToLower(b.Category))
+	})
+	return result, nil
+}
+
+func (f *fakeBudgetDB) MarkAlerted(_ context.Context, budgetID int64, month time.Time, threshold int, _ time.Time) (bool, error) {
+	if f.alerts == nil {
+		f.alerts = map[budgetAlertKey]bool{}
+	}
+	key := budgetAlertKey{budgetID: budgetID, month: month, threshold: threshold}
+	if f.alerts[key] {
+		return false, nil
+	}
+	f.alerts[key] = true
+	return true, nil
+}
+
+func TestCommands_Budget(t *testing.T) {
+	now := time.Date(2025, 10, 15, 19, 30, 0, 0, time.UTC)
+	m := newTestMessaging(&fakeTransactionDB{})
+
+	assert.Equal(t, "Sorry, budgets aren't available right now.", dispatch(t, m, "/budgets", now))
+
+	m.budgetDB = &fakeBudgetDB{}
+	m.categorizer = category.NewCategorizer(categorytest.NewDB(), nil)
+
+	assert.Equal(t, "You have no budgets yet.\n\n"+budgetUsage, dispatch(t, m, "/budgets", now))
+	assert.Equal(t, budgetUsage, dispatch(t, m, "/budget", now))
+	assert.Equal(t, budgetUsage, dispatch(t, m, "/budget food lots", now))
+
+	dispatch(t, m, "lunch 1200", now)
+	dispatch(t, m, "taxi 3400", now)
+	dispatch(t, m, "lunch 900 2025-09-30", now)
+
+	assert.Equal(t,
+		"Your Food budget is now 30,000 JPY a month.\nYou've spent 1,200 JPY this month, 28,800 JPY left.",
+		dispatch(t, m, "/budget food 30,000", now))
+	assert.Equal(t,
+		"Your overall budget is now 4,000 JPY a month.\nYou've spent 4,600 JPY this month, 600 JPY over.",
+		dispatch(t, m, "/budget 4000", now))
+
+	assert.Equal(t,
+		"October 2025\noverall: 4,600 of 4,000 JPY (115%)\nFood: 1,200 of 30,000 JPY (4%)",
+		dispatch(t, m, "/budgets", now))
+
+	assert.Equal(t, "Removed your overall budget.", dispatch(t, m, "/budget off", now))
+	assert.Equal(t, "You have no Transport budget.", dispatch(t, m, "/budget transport off", now))
+}
+
+func TestCheckBudgets(t *testing.T) {
+	now := time.Date(2025, 10, 15, 19, 30, 0, 0, time.UTC)
+	messenger := messagingtest.NewMessenger()
+	m := newTestMessaging(&fakeTransactionDB{})
+	m.line = messenger
+	m.config.BudgetAlertThresholds = []int{80, 100}
+	m.categorizer = category.NewCategorizer(categorytest.NewDB(), nil)
+	budgetDB := &fakeBudgetDB{}
+	m.budgetDB = budgetDB
+	user := m.userDB.(*fakeUserDB).users[1]
+
+	record := func(text string) {
+		t.Helper()
+
+		if err := m.router.Dispatch(context.Background(), &Request{
+			User:  user,
+			Text:  text,
+			Now:   now,
+			Reply: &recordingReplier{},
+		}); err != nil {
+			t.Fatalf("Dispatch(%q) unexpected error: %v", text, err)
+		}
+	}
+	pushed := func() []string {
+		var texts []string
+		for _, p := range messenger.Pushes() {
+			assert.Equal(t, "U1", p.To)
+			texts = append(texts, p.Messages[0].Text)
+		}
+		return texts
+	}
+
+	record("/budget food 10000")
+
+	record("lunch 7000")
+	assert.Empty(t, pushed())
+
+	// Other categories and last month's entries don't count.
+	record("taxi 3000")
+	record("dinner 5000 2025-09-30")
+	assert.Empty(t, pushed())
+
+	record("lunch 1500")
+	assert.Equal(t, []string{
+		"Heads up: you've used 85% of your Food budget of 10,000 JPY.\nYou've spent 8,500 JPY this month, 1,500 JPY left.",
+	}, pushed())
+
+	// Each threshold is pushed once a month.
+	record("coffee 500")
+	assert.Len(t, pushed(), 1)
+
+	record("dinner 2000")
+	assert.Equal(t,
+		"You've used up your Food budget of 10,000 JPY.\nYou've spent 11,000 JPY this month, 1,000 JPY over.",
+		pushed()[1])
+
+	record("lunch 1000")
+	assert.Len(t, pushed(), 2)
+
+	// A new amount warns again.
+	record("/budget food 12000")
+	record("snack 100")
+	assert.Len(t, pushed(), 3)
+
+	// Expenses in other currencies count at the day's rate.
+	record("/budget food 20000")
+	m.fxDB = fxtest.NewDB(&fxmodel.Rate{Base: "USD", Quote: "JPY", Rate: 150})
+	souvenir := &transactionmodel.Transaction{UserID: 1, Amount: 4000, Currency: "USD", Type: transactionmodel.TransactionTypeExpense, Category: "Food", OccurredAt: now}
+	transactionDB := m.transactionDB.(*fakeTransactionDB)
+	transactionDB.transactions = append(transactionDB.transactions, souvenir)
+	m.checkBudgets(context.Background(), user, souvenir, now)
+	assert.Equal(t,
+		"Heads up: you've used 90% of your Food budget of 20,000 JPY.\nYou've spent 18,100 JPY this month, 1,900 JPY left.",
+		pushed()[3])
+}
+
+func TestPush(t *testing.T) {
+	ctx := context.Background()
+	line := messagingtest.NewMessenger()
+	telegram := messagingtest.NewMessenger()
+	m := newTestMessaging(&fakeTransactionDB{})
+	m.line = line
+	m.telegram = telegram
+	userDB := m.userDB.(*fakeUserDB)
+	userDB.identities = []*usermodel.Identity{{UserID: 2, Channel: usermodel.ChannelTelegram, ExternalID: "7"}}
+
+	assert.NoError(t, m.push(ctx, &usermodel.User{ID: 1, LineUserID: "U1", Status: usermodel.UserStatusActive}, "", msg.TextMessage("hi")))
+	// Users who blocked the bot on LINE are reached on Telegram.
+	assert.NoError(t, m.push(ctx, &usermodel.User{ID: 2, LineUserID: "U2", Status: usermodel.UserStatusInActive}, "", msg.TextMessage("hi")))
+	assert.ErrorIs(t, m.push(ctx, &usermodel.User{ID: 3}, "", msg.TextMessage("hi")), errUnreachable)
+
+	if assert.Len(t, line.Pushes(), 1) {
+		assert.Equal(t, "U1", line.Pushes()[0].To)
+	}
+	if assert.Len(t, telegram.Pushes(), 1) {
+		assert.Equal(t, "7", telegram.Pushes()[0].To)
+	}
+}
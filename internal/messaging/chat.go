@@ -0,0 +1,74 @@
+package messaging
+
+import (
+	"context"
+	groupmodel "github/shaolim/momon/internal/group/model"
+	msg "github/shaolim/momon/pkg/messaging"
+	"log/slog"
+)
+
+const deadLetterText = "Sorry, something went wrong with your last message. Please send it again."
+
+// chat is where a message came from and how to answer it, whatever the
+// platform.
+type chat struct {
+	messenger msg.Messenger
+	// id is the chat loading animations are shown in.
+	id         string
+	replyToken string
+	// pushTo is where replies are pushed when the reply token can't be used,
+	// e.g. the group rather than the member who sent the message.
+	pushTo string
+	// group is the LINE group the message was sent in, nil outside of
+	// groups.
+	group *groupmodel.Group
+}
+
+func (c *chat) replyText(ctx context.Context, text string) error {
+	return c.reply(ctx, msg.TextMessage(text))
+}
+
+// reply answers with the reply token, or pushes the messages to the chat when
+// that fails. A retried job's token has most likely expired, e.g. after a
+// receipt failed to read the first time, so its replies are pushed right away.
+func (c *chat) reply(ctx context.Context, messages ...msg.Message) error {
+	if c.pushTo == "" {
+		return c.messenger.Reply(ctx, c.replyToken, messages...)
+	}
+
+	if !retrying(ctx) {
+		err := c.messenger.Reply(ctx, c.replyToken, messages...)
+		if err == nil {
+			return nil
+		}
+		slog.Warn("failed to reply, pushing instead", slog.Any("error", err))
+	}
+
+	return c.messenger.Push(ctx, c.pushTo, "", messages...)
+}
+
+// retryingKey marks the context of a queued event's second or later attempt.
+type retryingKey struct{}
+
+func withRetrying(ctx context.Context) context.Context {
+	return context.WithValue(ctx, retryingKey{}, true)
+}
+
+func retrying(ctx context.Context) bool {
+	v, _ := ctx.Value(retryingKey{}).(bool)
+	return v
+}
+
+// chatReplier lets commands reply to the chat the message came from.
+type chatReplier struct {
+	ctx  context.Context
+	chat *chat
+}
+
+func (r *chatReplier) ReplyText(text string) error {
+	return r.chat.replyText(r.ctx, text)
+}
+
+func (r *chatReplier) Reply(messages ...msg.Message) error {
+	return r.chat.reply(r.ctx, messages...)
+}
@@ -0,0 +1,623 @@
+package messaging
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"github/shaolim/momon/internal/category"
+	categorymodel "github/shaolim/momon/internal/category/model"
+	"github/shaolim/momon/internal/fx"
+	"github/shaolim/momon/internal/messaging/flex"
+	"github/shaolim/momon/internal/transaction/model"
+	"github/shaolim/momon/internal/transaction/parser"
+	userdb "github/shaolim/momon/internal/user/database"
+	usermodel "github/shaolim/momon/internal/user/model"
+	"github/shaolim/momon/pkg/database"
+	"github/shaolim/momon/pkg/money"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	defaultLastCount = 5
+	maxLastCount     = 20
+
+	// linkCodeTTL is how long a /link code can be used.
+	linkCodeTTL = 10 * time.Minute
+	// linkCodeAlphabet leaves out characters that are easy to mistype.
+	linkCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+)
+
+const helpText = `Send an amount with a description to record it:
+lunch 1200
+taxi 3,400 yesterday
++50000 salary
+
+Commands:
+/today - today's spending
+/month - this month's summary
+/last [N] - your last N entries
+/undo - remove your last entry
+/category [name] - change your last entry's category
+/categories - list your categories
+/budget [category] amount - set a monthly budget
+/budgets - this month's budgets
+/recurring - rent, subscriptions and other repeating entries
+/digest [daily|weekly] [time] - get a spending summary pushed to you
+/timezone [name] - show or change your time zone
+/currency [code] - show or change your home currency
+/link [code] - use the same account on LINE and Telegram
+/help - show this message`
+
+func (m *messaging) newRouter() *Router {
+	r := NewRouter()
+	r.Handle("/help", m.helpCommand)
+	r.Handle("/today", m.todayCommand)
+	r.Handle("/month", m.monthCommand)
+	r.Handle("/last", m.lastCommand)
+	r.Handle("/undo", m.undoCommand)
+	r.Handle("/category", m.categoryCommand)
+	r.Handle("/categories", m.categoriesCommand)
+	r.Handle("/budget", m.personal(m.budgetCommand))
+	r.Handle("/budgets", m.personal(m.budgetsCommand))
+	r.Handle("/recurring", m.personal(m.recurringCommand))
+	r.Handle("/digest", m.personal(m.digestCommand))
+	r.Handle("/timezone", m.personal(m.timezoneCommand))
+	r.Handle("/currency", m.personal(m.currencyCommand))
+	r.Handle("/link", m.personal(m.linkCommand))
+	r.HandlePattern(regexp.MustCompile(`(?i)^undo$`), m.undoCommand)
+	r.Fallback(m.recordCommand)
+	return r
+}
+
+func (m *messaging) helpCommand(_ context.Context, req *Request) error {
+	return req.Reply.ReplyText(helpText)
+}
+
+// recordCommand saves messages such as "lunch 1200" as transactions.
+func (m *messaging) recordCommand(ctx context.Context, req *Request) error {
+	text := strings.TrimSpace(req.Text)
+	var entry *parser.Entry
+	var err error
+	if req.Group != nil {
+		// Most of a group's messages are chatter, so only entries the parser
+		// understands without the language model are recorded and the rest
+		// are ignored.
+		entry, err = parser.Parse(text, m.currency(req.User), req.Now)
+		if errors.Is(err, parser.ErrNoMatch) {
+			return nil
+		}
+	} else {
+		entry, err = m.parseEntry(ctx, text, m.currency(req.User), req.Now)
+	}
+	if err != nil {
+		if errors.Is(err, parser.ErrNoMatch) {
+			return req.Reply.ReplyText("Sorry, I didn't get that.\n\n" + helpText)
+		}
+		return fmt.Errorf("failed to parse message: %w", err)
+	}
+
+	transaction := &model.Transaction{
+		UserID:     req.User.ID,
+		GroupID:    req.GroupID(),
+		Amount:     entry.Amount.Amount,
+		Currency:   entry.Amount.Currency,
+		Type:       entry.Type,
+		OccurredAt: entry.OccurredAt,
+		Note:       entry.Description,
+		Source:     model.TransactionSourceText,
+	}
+	if transaction.Type == model.TransactionTypeExpense {
+		transaction.Category = m.categorize(ctx, req.User.ID, &category.Expense{Description: entry.Description})
+	}
+	if err := m.transactionDB.AddTransaction(ctx, transaction); err != nil {
+		return fmt.Errorf("failed to save transaction: %w", err)
+	}
+
+	reply := fmt.Sprintf("Recorded %s.\nSend /undo to remove it.", describeTransaction(transaction))
+	if transaction.Category != "" {
+		reply = fmt.Sprintf("Recorded %s under %s.\nSend /category NAME to change the category or /undo to remove it.",
+			describeTransaction(transaction), transaction.Category)
+	}
+	err = req.Reply.ReplyText(reply)
+	m.checkBudgets(ctx, req.User, transaction, req.Now)
+	return replied(err)
+}
+
+// parseEntry tries the deterministic parser first and only falls back to the
+// language model for messages it does not understand.
+func (m *messaging) parseEntry(ctx context.Context, text string, currency money.Currency, now time.Time) (*parser.Entry, error) {
+	entry, err := parser.Parse(text, currency, now)
+	if errors.Is(err, parser.ErrNoMatch) && m.textParser != nil {
+		return m.textParser.Parse(ctx, text, currency, now)
+	}
+	return entry, err
+}
+
+// categorize returns the user's category for the expense, or "" when there
+// is none or categorizing failed. Entries are recorded either way.
+func (m *messaging) categorize(ctx context.Context, userID int64, expense *category.Expense) string {
+	if m.categorizer == nil {
+		return ""
+	}
+
+	name, err := m.categorizer.Categorize(ctx, userID, expense)
+	if err != nil {
+		slog.Warn("failed to categorize expense", slog.Int64("user_id", userID), slog.Any("error", err))
+		return ""
+	}
+	return name
+}
+
+// categoryCommand moves the last entry to another category, adding it when the
+// user doesn't have it, and remembers the choice for similar entries.
+func (m *messaging) categoryCommand(ctx context.Context, req *Request) error {
+	if m.categorizer == nil {
+		return req.Reply.ReplyText("Sorry, categories aren't available right now.")
+	}
+
+	transaction, err := m.transactionDB.GetLatestTransaction(ctx, req.User.ID, req.GroupID())
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return req.Reply.ReplyText("There is nothing to categorize yet.")
+		}
+		return fmt.Errorf("failed to get latest transaction: %w", err)
+	}
+
+	if len(req.Args) == 0 {
+		current := transaction.Category
+		if current == "" {
+			current = "no category"
+		}
+		return req.Reply.ReplyText(fmt.Sprintf("Your last entry, %s, has %s.\nSend /category followed by a name to change it, e.g. /category Food", describeTransaction(transaction), current))
+	}
+
+	name := strings.Join(req.Args, " ")
+	if utf8.RuneCountInString(name) > categorymodel.MaxNameLength {
+		return req.Reply.ReplyText(fmt.Sprintf("Please use a category of up to %d characters.", categorymodel.MaxNameLength))
+	}
+
+	expense := &category.Expense{Merchant: transaction.Merchant, Description: transaction.Note}
+	if transaction.Category, err = m.categorizer.Learn(ctx, req.User.ID, expense, name); err != nil {
+		return fmt.Errorf("failed to learn category: %w", err)
+	}
+	if err := m.transactionDB.UpdateTransaction(ctx, transaction); err != nil {
+		return fmt.Errorf("failed to update transaction: %w", err)
+	}
+
+	reply := fmt.Sprintf("Moved %s to %s.", describeTransaction(transaction), transaction.Category)
+	if keyword := expense.Keyword(); keyword != "" {
+		reply += fmt.Sprintf("\nI'll file %q there from now on.", keyword)
+	}
+	err = req.Reply.ReplyText(reply)
+	m.checkBudgets(ctx, req.User, transaction, req.Now)
+	return replied(err)
+}
+
+func (m *messaging) categoriesCommand(ctx context.Context, req *Request) error {
+	if m.categorizer == nil {
+		return req.Reply.ReplyText("Sorry, categories aren't available right now.")
+	}
+
+	categories, err := m.categorizer.Categories(ctx, req.User.ID)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	b.WriteString("Your categories:")
+	for _, c := range categories {
+		b.WriteString("\n" + c.Name)
+	}
+	b.WriteString("\n\nSend /category followed by a name to move your last entry. A new name adds a category.")
+
+	return req.Reply.ReplyText(b.String())
+}
+
+func (m *messaging) undoCommand(ctx context.Context, req *Request) error {
+	transaction, err := m.transactionDB.GetLatestTransaction(ctx, req.User.ID, req.GroupID())
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return req.Reply.ReplyText("There is nothing to undo.")
+		}
+		return fmt.Errorf("failed to get latest transaction: %w", err)
+	}
+
+	if err := m.transactionDB.DeleteTransaction(ctx, transaction.ID); err != nil {
+		return fmt.Errorf("failed to delete transaction: %w", err)
+	}
+
+	return replied(req.Reply.ReplyText(fmt.Sprintf("Removed %s.", describeTransaction(transaction))))
+}
+
+// timezoneCommand shows the user's time zone or changes it to an IANA name
+// such as "America/New_York".
+func (m *messaging) timezoneCommand(ctx context.Context, req *Request) error {
+	if len(req.Args) == 0 {
+		return req.Reply.ReplyText(fmt.Sprintf("Your time zone is %s.\nSend /timezone followed by a name to change it, e.g. /timezone America/New_York", m.location(req.User)))
+	}
+
+	name := req.Args[0]
+	loc, err := time.LoadLocation(name)
+	if err != nil || name == "" || name == "Local" {
+		return req.Reply.ReplyText(fmt.Sprintf("I don't know the time zone %q. Use a name such as Asia/Tokyo or Europe/London.", name))
+	}
+
+	if err := m.userDB.UpdateTimezone(ctx, req.User.ID, loc.String()); err != nil {
+		return fmt.Errorf("failed to update timezone: %w", err)
+	}
+	req.User.Timezone = loc.String()
+	m.rescheduleDigests(ctx, req.User, req.Now)
+
+	return replied(req.Reply.ReplyText(fmt.Sprintf("Your time zone is now %s, where it is %s.", loc, req.Now.In(loc).Format("15:04 on Jan 2"))))
+}
+
+// currencyCommand shows the user's home currency or changes it. Amounts sent
+// without a currency are recorded in it and reports are totalled in it.
+func (m *messaging) currencyCommand(ctx context.Context, req *Request) error {
+	if len(req.Args) == 0 {
+		return req.Reply.ReplyText(fmt.Sprintf("Your home currency is %s.\nSend /currency followed by a code to change it, e.g. /currency USD", m.currency(req.User)))
+	}
+
+	currency, err := money.ParseCurrency(req.Args[0])
+	if err != nil {
+		return req.Reply.ReplyText(fmt.Sprintf("I don't know the currency %q. Use a 3-letter code such as JPY, USD or EUR.", req.Args[0]))
+	}
+
+	if err := m.userDB.UpdateCurrency(ctx, req.User.ID, currency); err != nil {
+		return fmt.Errorf("failed to update currency: %w", err)
+	}
+	req.User.Currency = currency
+
+	return replied(req.Reply.ReplyText(fmt.Sprintf("Your home currency is now %s. Amounts without a currency are recorded in it and your reports are totalled in it.", currency)))
+}
+
+// linkCommand lets a user keep one account across chat apps. Without
+// arguments it hands out a code; sending "/link CODE" from the other app moves
+// that app's identity to the account the code came from, along with its
+// entries unless its old account is still used from another app.
+func (m *messaging) linkCommand(ctx context.Context, req *Request) error {
+	if req.Identity == nil {
+		return req.Reply.ReplyText("Linking isn't available here.")
+	}
+
+	if len(req.Args) == 0 {
+		code, err := newLinkCode()
+		if err != nil {
+			return err
+		}
+		if err := m.userDB.AddLinkCode(ctx, &usermodel.LinkCode{
+			Code:      code,
+			UserID:    req.User.ID,
+			ExpiresAt: req.Now.Add(linkCodeTTL),
+		}); err != nil {
+			return fmt.Errorf("failed to add link code: %w", err)
+		}
+
+		return req.Reply.ReplyText(fmt.Sprintf("Send /link %s from your other chat app within %d minutes to use this account there too.", code, int(linkCodeTTL.Minutes())))
+	}
+
+	_, err := m.userDB.LinkIdentity(ctx, strings.ToUpper(req.Args[0]), req.Identity, req.Now)
+	switch {
+	case errors.Is(err, userdb.ErrInvalidLinkCode):
+		return req.Reply.ReplyText("That code is wrong or has expired. Send /link in your other chat app to get a new one.")
+	case errors.Is(err, userdb.ErrAlreadyLinked):
+		return req.Reply.ReplyText("This chat is already linked to that account.")
+	case errors.Is(err, userdb.ErrChannelLinked):
+		return req.Reply.ReplyText("That account is already linked to another chat on this app.")
+	case err != nil:
+		return fmt.Errorf("failed to link identity: %w", err)
+	}
+
+	return replied(req.Reply.ReplyText("Linked! Everything you send here now goes to the same account as your other chat app, including the entries you made here."))
+}
+
+func newLinkCode() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate link code: %w", err)
+	}
+	for i := range b {
+		b[i] = linkCodeAlphabet[int(b[i])%len(linkCodeAlphabet)]
+	}
+	return string(b), nil
+}
+
+func (m *messaging) todayCommand(ctx context.Context, req *Request) error {
+	from := startOfDay(req.Now)
+	filter := ledgerFilter(req)
+	filter.From, filter.To = from, from.AddDate(0, 0, 1)
+	transactions, err := m.transactionDB.ListTransactions(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("failed to list transactions: %w", err)
+	}
+
+	if len(transactions) == 0 {
+		return req.Reply.ReplyText("Nothing recorded today.")
+	}
+
+	converted, err := m.convertTransactions(ctx, req.User, transactions, m.currency(req.User))
+	if err != nil {
+		return err
+	}
+	expense, _, err := sumByType(converted)
+	if err != nil {
+		return fmt.Errorf("failed to sum transactions: %w", err)
+	}
+
+	if req.Group != nil {
+		return m.groupToday(ctx, req, transactions, converted, expense)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Today you spent %s.\n", formatTotals(expense))
+	for _, t := range transactions {
+		b.WriteString("\n" + todayLine(t, req.Now.Location()))
+	}
+
+	return req.Reply.ReplyText(b.String())
+}
+
+func (m *messaging) monthCommand(ctx context.Context, req *Request) error {
+	from := startOfMonth(req.Now)
+	filter := ledgerFilter(req)
+	filter.From, filter.To = from, from.AddDate(0, 1, 0)
+	transactions, err := m.transactionDB.ListTransactions(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("failed to list transactions: %w", err)
+	}
+
+	if len(transactions) == 0 {
+		return req.Reply.ReplyText(fmt.Sprintf("Nothing recorded in %s yet.", from.Format("January 2006")))
+	}
+
+	converted, err := m.convertTransactions(ctx, req.User, transactions, m.currency(req.User))
+	if err != nil {
+		return err
+	}
+	expense, income, err := sumByType(converted)
+	if err != nil {
+		return fmt.Errorf("failed to sum transactions: %w", err)
+	}
+
+	breakdown, err := categoryBreakdown(converted)
+	if err != nil {
+		return fmt.Errorf("failed to sum transactions: %w", err)
+	}
+
+	summary := &flex.Summary{
+		Title:     from.Format("January 2006"),
+		Spent:     formatTotals(expense),
+		Received:  formatTotals(income),
+		Entries:   len(transactions),
+		Breakdown: breakdown,
+	}
+	if req.Group != nil {
+		names, err := m.memberNames(ctx, req.Group, transactions)
+		if err != nil {
+			return err
+		}
+		if summary.Members, err = memberBreakdown(converted, names); err != nil {
+			return fmt.Errorf("failed to sum transactions: %w", err)
+		}
+	}
+
+	return req.Reply.Reply(flex.MonthlySummary(summary))
+}
+
+// categoryBreakdown totals expenses per category and currency, largest first.
+// It returns nothing until at least one expense has a category.
+func categoryBreakdown(transactions []*model.Transaction) ([]flex.Bar, error) {
+	categorized := slices.ContainsFunc(transactions, func(t *model.Transaction) bool {
+		return t.Type == model.TransactionTypeExpense && t.Category != ""
+	})
+	if !categorized {
+		return nil, nil
+	}
+
+	return expenseBars(transactions, func(t *model.Transaction) string {
+		return cmp.Or(t.Category, "Uncategorized")
+	})
+}
+
+// expenseBars totals expenses per label and currency, largest first.
+func expenseBars(transactions []*model.Transaction, label func(t *model.Transaction) string) ([]flex.Bar, error) {
+	type key struct {
+		label    string
+		currency money.Currency
+	}
+
+	totals := map[key]money.Money{}
+	for _, t := range transactions {
+		if t.Type != model.TransactionTypeExpense {
+			continue
+		}
+		k := key{label: label(t), currency: t.Currency}
+
+		total, ok := totals[k]
+		if !ok {
+			total = money.New(0, t.Currency)
+		}
+		var err error
+		if totals[k], err = total.Add(t.Money()); err != nil {
+			return nil, err
+		}
+	}
+
+	bars := make([]flex.Bar, 0, len(totals))
+	for k, total := range totals {
+		bars = append(bars, flex.Bar{Label: k.label, Amount: total})
+	}
+	slices.SortFunc(bars, func(a, b flex.Bar) int {
+		if c := strings.Compare(string(a.Amount.Currency), string(b.Amount.Currency)); c != 0 {
+			return c
+		}
+		if a.Amount.Amount != b.Amount.Amount {
+			return cmp.Compare(b.Amount.Amount, a.Amount.Amount)
+		}
+		return strings.Compare(a.Label, b.Label)
+	})
+	return bars, nil
+}
+
+func (m *messaging) lastCommand(ctx context.Context, req *Request) error {
+	count := defaultLastCount
+	if len(req.Args) > 0 {
+		n, err := strconv.Atoi(req.Args[0])
+		if err != nil || n <= 0 {
+			return req.Reply.ReplyText("Usage: /last [N], e.g. /last 10")
+		}
+		count = min(n, maxLastCount)
+	}
+
+	filter := ledgerFilter(req)
+	filter.Limit = count
+	transactions, err := m.transactionDB.ListTransactions(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("failed to list transactions: %w", err)
+	}
+
+	if len(transactions) == 0 {
+		return req.Reply.ReplyText("Nothing recorded yet.")
+	}
+
+	var names map[int64]string
+	if req.Group != nil {
+		if names, err = m.memberNames(ctx, req.Group, transactions); err != nil {
+			return err
+		}
+	}
+
+	lines := make([]string, 0, len(transactions))
+	for _, t := range transactions {
+		lines = append(lines, memberLine(transactionLine(t), t, names))
+	}
+
+	return req.Reply.ReplyText(strings.Join(lines, "\n"))
+}
+
+// describeTransaction renders a one-line summary such as
+// "expense of 1,200 JPY for lunch on 2025-10-01".
+func describeTransaction(t *model.Transaction) string {
+	kind := "expense"
+	if t.Type == model.TransactionTypeIncome {
+		kind = "income"
+	}
+
+	description := t.Note
+	if description == "" {
+		description = t.Merchant
+	}
+
+	s := fmt.Sprintf("%s of %s", kind, t.Money())
+	if description != "" {
+		s += " for " + description
+	}
+	return s + " on " + t.OccurredAt.Format("2006-01-02")
+}
+
+// transactionLine renders a transaction as a single list row, e.g.
+// "10/01 -1,200 JPY lunch".
+func transactionLine(t *model.Transaction) string {
+	return entryLine(t.OccurredAt.Format("01/02"), t)
+}
+
+// todayLine renders one of today's transactions with the time of day in loc,
+// e.g. "12:30 -1,200 JPY lunch", or "--:--" when the receipt showed no time.
+func todayLine(t *model.Transaction, loc *time.Location) string {
+	if t.TimeUnknown {
+		return entryLine("--:--", t)
+	}
+	return entryLine(t.OccurredAt.In(loc).Format("15:04"), t)
+}
+
+func entryLine(when string, t *model.Transaction) string {
+	line := when + " " + money.New(t.SignedAmount(), t.Currency).String()
+	if t.Note != "" {
+		line += " " + t.Note
+	} else if t.Merchant != "" {
+		line += " " + t.Merchant
+	}
+	return line
+}
+
+// convertTransactions returns the transactions with their amounts in to, at
+// the rate of the day, in the user's time zone, each was made. Transactions
+// without a rate are kept as they are, so their totals stay apart.
+func (m *messaging) convertTransactions(ctx context.Context, user *usermodel.User, transactions []*model.Transaction, to money.Currency) ([]*model.Transaction, error) {
+	if m.fxDB == nil {
+		return transactions, nil
+	}
+
+	converter := fx.NewConverter(m.fxDB, to)
+	loc := m.location(user)
+	converted := make([]*model.Transaction, 0, len(transactions))
+	for _, t := range transactions {
+		amount, ok, err := converter.Convert(ctx, t.Money(), t.OccurredAt.In(loc))
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert transaction %d: %w", t.ID, err)
+		}
+		if ok && amount.Currency != t.Currency {
+			copied := *t
+			copied.Amount, copied.Currency = amount.Amount, amount.Currency
+			t = &copied
+		}
+		converted = append(converted, t)
+	}
+	return converted, nil
+}
+
+// sumByType totals expenses and incomes per currency.
+func sumByType(transactions []*model.Transaction) (expense, income map[money.Currency]money.Money, err error) {
+	expense = map[money.Currency]money.Money{}
+	income = map[money.Currency]money.Money{}
+	for _, t := range transactions {
+		totals := expense
+		if t.Type == model.TransactionTypeIncome {
+			totals = income
+		}
+
+		total, ok := totals[t.Currency]
+		if !ok {
+			total = money.New(0, t.Currency)
+		}
+		if totals[t.Currency], err = total.Add(t.Money()); err != nil {
+			return nil, nil, err
+		}
+	}
+	return expense, income, nil
+}
+
+// formatTotals renders per-currency totals, e.g. "1,200 JPY + 15.00 USD".
+func formatTotals(totals map[money.Currency]money.Money) string {
+	if len(totals) == 0 {
+		return "nothing"
+	}
+
+	currencies := make([]money.Currency, 0, len(totals))
+	for currency := range totals {
+		currencies = append(currencies, currency)
+	}
+	slices.Sort(currencies)
+
+	parts := make([]string, 0, len(currencies))
+	for _, currency := range currencies {
+		parts = append(parts, totals[currency].String())
+	}
+	return strings.Join(parts, " + ")
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func startOfMonth(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
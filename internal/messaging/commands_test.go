@@ -0,0 +1,693 @@
+package messaging
+
+import (
+	"context"
+	"github/shaolim/momon/internal/category"
+	"github/shaolim/momon/internal/category/categorytest"
+	"github/shaolim/momon/internal/fx/fxtest"
+	fxmodel "github/shaolim/momon/internal/fx/model"
+	"github/shaolim/momon/internal/messaging/flex"
+	"github/shaolim/momon/internal/serverenv"
+	transactiondb "github/shaolim/momon/internal/transaction/database"
+	"github/shaolim/momon/internal/transaction/model"
+	userdb "github/shaolim/momon/internal/user/database"
+	usermodel "github/shaolim/momon/internal/user/model"
+	"github/shaolim/momon/pkg/database"
+	"github/shaolim/momon/pkg/money"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeTransactionDB is an in-memory TransactionDB for handler tests.
+type fakeTransactionDB struct {
+	transactions []*model.Transaction
+	drafts       []*model.Draft
+	recurring    []*model.Recurring
+	nextID       int64
+}
+
+func (f *fakeTransactionDB) AddTransaction(_ context.Context, transaction *model.Transaction) error {
+	if err := transaction.Validate(); err != nil {
+		return err
+	}
+	f.nextID++
+	transaction.ID = f.nextID
+	f.transactions = append(f.transactions, transaction)
+	return nil
+}
+
+func (f *fakeTransactionDB) GetTransaction(_ context.Context, id int64) (*model.Transaction, error) {
+	for _, t := range f.transactions {
+		if t.ID == id {
+			return t, nil
+		}
+	}
+	return nil, database.ErrNotFound
+}
+
+func (f *fakeTransactionDB) UpdateTransaction(_ context.Context, transaction *model.Transaction) error {
+	for i, t := range f.transactions {
+		if t.ID == transaction.ID {
+			f.transactions[i] = transaction
+			return nil
+		}
+	}
+	return database.ErrNotFound
+}
+
+func (f *fakeTransactionDB) DeleteTransaction(_ context.Context, id int64) error {
+	for i, t := range f.transactions {
+		if t.ID == id {
+			f.transactions = append(f.transactions[:i], f.transactions[i+1:]...)
+			return nil
+		}
+	}
+	return database.ErrNotFound
+}
+
+func (f *fakeTransactionDB) ListTransactions(_ context.Context, filter *transactiondb.ListFilter) ([]*model.Transaction, error) {
+	var result []*model.Transaction
+	for _, t := range f.transactions {
+		if filter.UserID != 0 && t.UserID != filter.UserID {
+			continue
+		}
+		if t.GroupID != filter.GroupID {
+			continue
+		}
+		if filter.Type != "" && t.Type != filter.Type {
+			continue
+		}
+		if filter.Category != "" && t.Category != filter.Category {
+			continue
+		}
+		if !filter.From.IsZero() && t.OccurredAt.Before(filter.From) {
+			continue
+		}
+		if !filter.To.IsZero() && !t.OccurredAt.Before(filter.To) {
+			continue
+		}
+		result = append(result, t)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].OccurredAt.Equal(result[j].OccurredAt) {
+			return result[i].OccurredAt.After(result[j].OccurredAt)
+		}
+		return result[i].ID > result[j].ID
+	})
+	if filter.Limit > 0 && len(result) > filter.Limit {
+		result = result[:filter.Limit]
+	}
+	return result, nil
+}
+
+func (f *fakeTransactionDB) GetLatestTransaction(_ context.Context, userID, groupID int64) (*model.Transaction, error) {
+	for i := len(f.transactions) - 1; i >= 0; i-- {
+		if f.transactions[i].UserID == userID && f.transactions[i].GroupID == groupID {
+			return f.transactions[i], nil
+		}
+	}
+	return nil, database.ErrNotFound
+}
+
+func (f *fakeTransactionDB) AddDraft(_ context.Context, draft *model.Draft) error {
+	if err := draft.Validate(); err != nil {
+		return err
+	}
+	f.nextID++
+	draft.ID = f.nextID
+	draft.UpdatedAt = time.Now()
+	f.drafts = append(f.drafts, draft)
+	return nil
+}
+
+func (f *fakeTransactionDB) GetDraft(_ context.Context, id int64) (*model.Draft, error) {
+	for _, d := range f.drafts {
+		if d.ID == id {
+			copied := *d
+			return &copied, nil
+		}
+	}
+	return nil, database.ErrNotFound
+}
+
+func (f *fakeTransactionDB) GetPendingDraft(_ context.Context, userID int64, since time.Time) (*model.Draft, error) {
+	for i := len(f.drafts) - 1; i >= 0; i-- {
+		d := f.drafts[i]
+		if d.UserID == userID && d.Step != model.DraftStepReview && d.UpdatedAt.After(since) {
+			copied := *d
+			return &copied, nil
+		}
+	}
+	return nil, database.ErrNotFound
+}
+
+func (f *fakeTransactionDB) UpdateDraft(_ context.Context, draft *model.Draft) error {
+	if err := draft.Validate(); err != nil {
+		return err
+	}
+	for i, d := range f.drafts {
+		if d.ID == draft.ID {
+			copied := *draft
+			copied.UpdatedAt = time.Now()
+			f.drafts[i] = &copied
+			return nil
+		}
+	}
+	return database.ErrNotFound
+}
+
+func (f *fakeTransactionDB) SaveDraft(ctx context.Context, id int64, now time.Time) (*model.Transaction, error) {
+	for i, d := range f.drafts {
+		if d.ID == id && d.ExpiresAt.After(now) {
+			f.drafts = append(f.drafts[:i], f.drafts[i+1:]...)
+			transaction := d.Transaction()
+			if err := f.AddTransaction(ctx, transaction); err != nil {
+				return nil, err
+			}
+			return transaction, nil
+		}
+	}
+	return nil, database.ErrNotFound
+}
+
+func (f *fakeTransactionDB) DeleteDraft(_ context.Context, id int64) error {
+	for i, d := range f.drafts {
+		if d.ID == id {
+			f.drafts = append(f.drafts[:i], f.drafts[i+1:]...)
+			return nil
+		}
+	}
+	return database.ErrNotFound
+}
+
+func (f *fakeTransactionDB) DeleteExpiredDrafts(_ context.Context, now time.Time) (int64, error) {
+	var deleted int64
+	kept := f.drafts[:0]
+	for _, d := range f.drafts {
+		if d.ExpiresAt.After(now) {
+			kept = append(kept, d)
+		} else {
+			deleted++
+		}
+	}
+	f.drafts = kept
+	return deleted, nil
+}
+
+func (f *fakeTransactionDB) AddRecurring(_ context.Context, recurring *model.Recurring) error {
+	if err := recurring.Validate(); err != nil {
+		return err
+	}
+	f.nextID++
+	recurring.ID = f.nextID
+	copied := *recurring
+	f.recurring = append(f.recurring, &copied)
+	return nil
+}
+
+func (f *fakeTransactionDB) ListRecurring(_ context.Context, userID int64) ([]*model.Recurring, error) {
+	var result []*model.Recurring
+	for _, r := range f.recurring {
+		if r.UserID == userID {
+			copied := *r
+			result = append(result, &copied)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].NextRunAt.Before(result[j].NextRunAt) })
+	return result, nil
+}
+
+func (f *fakeTransactionDB) ListDueRecurring(_ context.Context, now time.Time, limit int) ([]*model.Recurring, error) {
+	var result []*model.Recurring
+	for _, r := range f.recurring {
+		if !r.NextRunAt.After(now) && len(result) < limit {
+			copied := *r
+			result = append(result, &copied)
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeTransactionDB) DeleteRecurring(_ context.Context, userID, id int64) error {
+	for i, r := range f.recurring {
+		if r.ID == id && r.UserID == userID {
+			f.recurring = append(f.recurring[:i], f.recurring[i+1:]...)
+			return nil
+		}
+	}
+	return database.ErrNotFound
+}
+
+func (f *fakeTransactionDB) PostRecurring(ctx context.Context, recurring *model.Recurring, next time.Time) (*model.Transaction, error) {
+	for _, r := range f.recurring {
+		if r.ID == recurring.ID && r.NextRunAt.Equal(recurring.NextRunAt) {
+			transaction := r.Transaction(r.NextRunAt)
+			if err := f.AddTransaction(ctx, transaction); err != nil {
+				return nil, err
+			}
+			r.NextRunAt = next
+			recurring.NextRunAt = next
+			return transaction, nil
+		}
+	}
+	return nil, database.ErrNotFound
+}
+
+// fakeUserDB is an in-memory UserDB for handler tests.
+type fakeUserDB struct {
+	users      map[int64]*usermodel.User
+	identities []*usermodel.Identity
+	linkCodes  map[string]*usermodel.LinkCode
+}
+
+func (f *fakeUserDB) AddUser(_ context.Context, user *usermodel.User) error {
+	if err := user.Validate(); err != nil {
+		return err
+	}
+	if f.users == nil {
+		f.users = map[int64]*usermodel.User{}
+	}
+	user.ID = int64(len(f.users) + 1)
+	f.users[user.ID] = user
+	return nil
+}
+
+func (f *fakeUserDB) GetUser(_ context.Context, id int64) (*usermodel.User, error) {
+	if user, ok := f.users[id]; ok {
+		return user, nil
+	}
+	return nil, database.ErrNotFound
+}
+
+func (f *fakeUserDB) GetByLineUserID(_ context.Context, lineUserID string) (*usermodel.User, error) {
+	for _, u := range f.users {
+		if u.LineUserID == lineUserID {
+			return u, nil
+		}
+	}
+	return nil, database.ErrNotFound
+}
+
+func (f *fakeUserDB) Upsert(ctx context.Context, user *usermodel.User) error {
+	if existing, err := f.GetByLineUserID(ctx, user.LineUserID); err == nil {
+		existing.DisplayName, existing.Status = user.DisplayName, user.Status
+		user.ID, user.Timezone = existing.ID, existing.Timezone
+		return nil
+	}
+	return f.AddUser(ctx, user)
+}
+
+func (f *fakeUserDB) UpdateStatus(ctx context.Context, lineUserID string, status usermodel.UserStatus) error {
+	user, err := f.GetByLineUserID(ctx, lineUserID)
+	if err != nil {
+		return err
+	}
+	user.Status = status
+	return nil
+}
+
+func (f *fakeUserDB) UpdateTimezone(_ context.Context, userID int64, timezone string) error {
+	user, ok := f.users[userID]
+	if !ok {
+		return database.ErrNotFound
+	}
+	user.Timezone = timezone
+	return nil
+}
+
+func (f *fakeUserDB) UpdateCurrency(_ context.Context, userID int64, currency money.Currency) error {
+	user, ok := f.users[userID]
+	if !ok {
+		return database.ErrNotFound
+	}
+	user.Currency = currency
+	return nil
+}
+
+func (f *fakeUserDB) GetByIdentity(ctx context.Context, channel usermodel.Channel, externalID string) (*usermodel.User, error) {
+	for _, i := range f.identities {
+		if i.Channel == channel && i.ExternalID == externalID {
+			return f.users[i.UserID], nil
+		}
+	}
+	if channel == usermodel.ChannelLine {
+		return f.GetByLineUserID(ctx, externalID)
+	}
+	return nil, database.ErrNotFound
+}
+
+func (f *fakeUserDB) AddUserWithIdentity(ctx context.Context, user *usermodel.User, identity *usermodel.Identity) error {
+	if err := f.AddUser(ctx, user); err != nil {
+		return err
+	}
+	identity.UserID = user.ID
+	f.identities = append(f.identities, identity)
+	return nil
+}
+
+func (f *fakeUserDB) ListIdentities(_ context.Context, userID int64) ([]*usermodel.Identity, error) {
+	var identities []*usermodel.Identity
+	for _, i := range f.identities {
+		if i.UserID == userID {
+			identities = append(identities, i)
+		}
+	}
+	return identities, nil
+}
+
+func (f *fakeUserDB) AddLinkCode(_ context.Context, code *usermodel.LinkCode) error {
+	if f.linkCodes == nil {
+		f.linkCodes = map[string]*usermodel.LinkCode{}
+	}
+	f.linkCodes[code.Code] = code
+	return nil
+}
+
+// LinkIdentity moves the identity but, unlike the real one, leaves
+// transactions and the old user alone.
+func (f *fakeUserDB) LinkIdentity(_ context.Context, code string, identity *usermodel.Identity, now time.Time) (*usermodel.User, error) {
+	linkCode, ok := f.linkCodes[code]
+	if !ok || !linkCode.ExpiresAt.After(now) {
+		return nil, userdb.ErrInvalidLinkCode
+	}
+	delete(f.linkCodes, code)
+
+	for _, i := range f.identities {
+		if i.UserID == linkCode.UserID && i.Channel == identity.Channel {
+			if i.ExternalID == identity.ExternalID {
+				return nil, userdb.ErrAlreadyLinked
+			}
+			return nil, userdb.ErrChannelLinked
+		}
+	}
+	for _, i := range f.identities {
+		if i.Channel == identity.Channel && i.ExternalID == identity.ExternalID {
+			i.UserID = linkCode.UserID
+		}
+	}
+	return f.users[linkCode.UserID], nil
+}
+
+func newTestMessaging(transactionDB *fakeTransactionDB) *messaging {
+	m := &messaging{
+		config:        &serverenv.Config{DefaultCurrency: "JPY", DefaultLocation: time.UTC, ReceiptDraftTTL: 24 * time.Hour},
+		userDB:        &fakeUserDB{users: map[int64]*usermodel.User{1: {ID: 1, LineUserID: "U1"}}},
+		transactionDB: transactionDB,
+	}
+	m.router = m.newRouter()
+	return m
+}
+
+func dispatch(t *testing.T, m *messaging, text string, now time.Time) string {
+	t.Helper()
+
+	replier := &recordingReplier{}
+	err := m.router.Dispatch(context.Background(), &Request{
+		User:  &usermodel.User{ID: 1},
+		Text:  text,
+		Now:   now,
+		Reply: replier,
+	})
+	if err != nil {
+		t.Fatalf("Dispatch(%q) unexpected error: %v", text, err)
+	}
+	if len(replier.replies) != 1 {
+		t.Fatalf("Dispatch(%q) replied %d times, want 1", text, len(replier.replies))
+	}
+	return replier.replies[0]
+}
+
+func TestCommands(t *testing.T) {
+	now := time.Date(2025, 10, 15, 19, 30, 0, 0, time.UTC)
+
+	transactionDB := &fakeTransactionDB{}
+	m := newTestMessaging(transactionDB)
+
+	assert.Equal(t, helpText, dispatch(t, m, "/help", now))
+	assert.Equal(t, "Nothing recorded today.", dispatch(t, m, "/today", now))
+
+	assert.Equal(t,
+		"Recorded expense of 1,200 JPY for lunch on 2025-10-15.\nSend /undo to remove it.",
+		dispatch(t, m, "lunch 1200", now))
+	dispatch(t, m, "taxi 3,400 yesterday", now)
+	dispatch(t, m, "+50000 salary", now)
+	dispatch(t, m, "rent 85000 2025-09-30", now)
+
+	assert.Equal(t,
+		"Today you spent 1,200 JPY.\n\n19:30 50,000 JPY salary\n19:30 -1,200 JPY lunch",
+		dispatch(t, m, "/today", now))
+
+	assert.Equal(t,
+		"October 2025\nSpent: 4,600 JPY\nReceived: 50,000 JPY\nEntries: 3",
+		dispatch(t, m, "/month", now))
+
+	assert.Equal(t,
+		"10/15 50,000 JPY salary\n10/15 -1,200 JPY lunch",
+		dispatch(t, m, "/last 2", now))
+	assert.Equal(t, "Usage: /last [N], e.g. /last 10", dispatch(t, m, "/last zero", now))
+
+	assert.Equal(t, "Removed expense of 85,000 JPY for rent on 2025-09-30.", dispatch(t, m, "/undo", now))
+	assert.Equal(t, "Removed income of 50,000 JPY for salary on 2025-10-15.", dispatch(t, m, "undo", now))
+	assert.Len(t, transactionDB.transactions, 2)
+
+	assert.Contains(t, dispatch(t, m, "how are you", now), "Sorry, I didn't get that.")
+}
+
+func TestCommands_UndoNothing(t *testing.T) {
+	m := newTestMessaging(&fakeTransactionDB{})
+
+	assert.Equal(t, "There is nothing to undo.", dispatch(t, m, "/undo", time.Now()))
+}
+
+func TestCommands_Timezone(t *testing.T) {
+	now := time.Date(2025, 10, 15, 19, 30, 0, 0, time.UTC)
+	m := newTestMessaging(&fakeTransactionDB{})
+
+	assert.Contains(t, dispatch(t, m, "/timezone", now), "Your time zone is UTC.")
+	assert.Equal(t,
+		"Your time zone is now Asia/Tokyo, where it is 04:30 on Oct 16.",
+		dispatch(t, m, "/timezone Asia/Tokyo", now))
+	assert.Equal(t, "Asia/Tokyo", m.userDB.(*fakeUserDB).users[1].Timezone)
+
+	assert.Equal(t,
+		`I don't know the time zone "Mars/Olympus". Use a name such as Asia/Tokyo or Europe/London.`,
+		dispatch(t, m, "/timezone Mars/Olympus", now))
+	assert.Equal(t, "Asia/Tokyo", m.userDB.(*fakeUserDB).users[1].Timezone)
+}
+
+func TestCommands_Currency(t *testing.T) {
+	now := time.Date(2025, 10, 15, 19, 30, 0, 0, time.UTC)
+	m := newTestMessaging(&fakeTransactionDB{})
+
+	assert.Contains(t, dispatch(t, m, "/currency", now), "Your home currency is JPY.")
+	assert.Equal(t,
+		"Your home currency is now USD. Amounts without a currency are recorded in it and your reports are totalled in it.",
+		dispatch(t, m, "/currency usd", now))
+	assert.Equal(t, money.Currency("USD"), m.userDB.(*fakeUserDB).users[1].Currency)
+
+	assert.Equal(t,
+		`I don't know the currency "dollars". Use a 3-letter code such as JPY, USD or EUR.`,
+		dispatch(t, m, "/currency dollars", now))
+	assert.Equal(t, money.Currency("USD"), m.userDB.(*fakeUserDB).users[1].Currency)
+}
+
+func TestCommands_ConvertedTotals(t *testing.T) {
+	now := time.Date(2025, 10, 15, 19, 30, 0, 0, time.UTC)
+	transactionDB := &fakeTransactionDB{}
+	m := newTestMessaging(transactionDB)
+
+	for _, transaction := range []*model.Transaction{
+		{Amount: 1200, Currency: "JPY", Note: "lunch", OccurredAt: now.Add(-time.Hour)},
+		{Amount: 1000, Currency: "USD", Note: "souvenir", OccurredAt: now.Add(-2 * time.Hour)},
+		// From a receipt that printed no time.
+		{Amount: 500, Currency: "EUR", Note: "coffee", OccurredAt: time.Date(2025, 10, 15, 12, 0, 0, 0, time.UTC), TimeUnknown: true},
+	} {
+		transaction.UserID = 1
+		transaction.Type = model.TransactionTypeExpense
+		transactionDB.transactions = append(transactionDB.transactions, transaction)
+	}
+
+	assert.Equal(t,
+		"Today you spent 5.00 EUR + 1,200 JPY + 10.00 USD.\n\n18:30 -1,200 JPY lunch\n17:30 -10.00 USD souvenir\n--:-- -5.00 EUR coffee",
+		dispatch(t, m, "/today", now))
+
+	// With rates, totals are in the home currency. Entries without a rate
+	// stay apart and the entries themselves keep their own currency.
+	m.fxDB = fxtest.NewDB(&fxmodel.Rate{Base: "USD", Quote: "JPY", Rate: 150})
+	assert.Equal(t,
+		"Today you spent 5.00 EUR + 2,700 JPY.\n\n18:30 -1,200 JPY lunch\n17:30 -10.00 USD souvenir\n--:-- -5.00 EUR coffee",
+		dispatch(t, m, "/today", now))
+	assert.Equal(t,
+		"October 2025\nSpent: 5.00 EUR + 2,700 JPY\nReceived: nothing\nEntries: 3",
+		dispatch(t, m, "/month", now))
+}
+
+func TestCommands_Link(t *testing.T) {
+	now := time.Date(2025, 10, 15, 19, 30, 0, 0, time.UTC)
+	m := newTestMessaging(&fakeTransactionDB{})
+	userDB := m.userDB.(*fakeUserDB)
+	userDB.users[2] = &usermodel.User{ID: 2}
+	userDB.identities = []*usermodel.Identity{
+		{UserID: 1, Channel: usermodel.ChannelTelegram, ExternalID: "7"},
+		{UserID: 2, Channel: usermodel.ChannelTelegram, ExternalID: "8"},
+	}
+
+	link := func(identity *usermodel.Identity, code string) string {
+		t.Helper()
+
+		replier := &recordingReplier{}
+		if err := m.router.Dispatch(context.Background(), &Request{
+			User:     userDB.users[identity.UserID],
+			Identity: identity,
+			Text:     "/link " + code,
+			Now:      now,
+			Reply:    replier,
+		}); err != nil {
+			t.Fatalf("Dispatch(/link %s) unexpected error: %v", code, err)
+		}
+		return replier.replies[0]
+	}
+	addCode := func(code string, expiresAt time.Time) {
+		userDB.linkCodes[code] = &usermodel.LinkCode{Code: code, UserID: 1, ExpiresAt: expiresAt}
+	}
+	userDB.linkCodes = map[string]*usermodel.LinkCode{}
+
+	addCode("EXPIRE", now)
+	assert.Contains(t, link(userDB.identities[1], "EXPIRE"), "That code is wrong or has expired.")
+
+	addCode("SAMEAC", now.Add(time.Minute))
+	assert.Equal(t, "This chat is already linked to that account.", link(userDB.identities[0], "SAMEAC"))
+
+	addCode("TAKEN1", now.Add(time.Minute))
+	assert.Equal(t, "That account is already linked to another chat on this app.", link(userDB.identities[1], "TAKEN1"))
+
+	assert.Equal(t, "Linking isn't available here.", dispatch(t, m, "/link", now))
+}
+
+func TestCommands_Category(t *testing.T) {
+	now := time.Date(2025, 10, 15, 19, 30, 0, 0, time.UTC)
+	transactionDB := &fakeTransactionDB{}
+	m := newTestMessaging(transactionDB)
+
+	assert.Equal(t, "Sorry, categories aren't available right now.", dispatch(t, m, "/categories", now))
+
+	m.categorizer = category.NewCategorizer(categorytest.NewDB(), nil)
+
+	assert.Equal(t, "There is nothing to categorize yet.", dispatch(t, m, "/category Food", now))
+
+	assert.Equal(t,
+		"Recorded expense of 1,200 JPY for lunch on 2025-10-15 under Food.\nSend /category NAME to change the category or /undo to remove it.",
+		dispatch(t, m, "lunch 1200", now))
+	assert.Equal(t,
+		"Recorded expense of 3,000 JPY for haircut on 2025-10-15.\nSend /undo to remove it.",
+		dispatch(t, m, "haircut 3000", now))
+
+	assert.Equal(t,
+		"Your last entry, expense of 3,000 JPY for haircut on 2025-10-15, has no category.\nSend /category followed by a name to change it, e.g. /category Food",
+		dispatch(t, m, "/category", now))
+	assert.Equal(t,
+		"Moved expense of 3,000 JPY for haircut on 2025-10-15 to Beauty.\nI'll file \"haircut\" there from now on.",
+		dispatch(t, m, "/category Beauty", now))
+	assert.Equal(t, "Beauty", transactionDB.transactions[1].Category)
+
+	// The correction is remembered.
+	assert.Contains(t, dispatch(t, m, "Haircut 3500", now), "under Beauty.")
+
+	reply := dispatch(t, m, "/categories", now)
+	assert.True(t, strings.HasPrefix(reply, "Your categories:\nFood\nGroceries\n"))
+	assert.Contains(t, reply, "\nOther\nBeauty\n")
+
+	assert.Equal(t, "Please use a category of up to 50 characters.", dispatch(t, m, "/category "+strings.Repeat("a", 51), now))
+}
+
+func TestCategoryBreakdown(t *testing.T) {
+	expense := func(category string, amount int64, currency money.Currency) *model.Transaction {
+		return &model.Transaction{Type: model.TransactionTypeExpense, Category: category, Amount: amount, Currency: currency}
+	}
+
+	bars, err := categoryBreakdown([]*model.Transaction{
+		expense("Food", 1200, "JPY"),
+		expense("", 800, "JPY"),
+		expense("Food", 3000, "JPY"),
+		expense("Travel", 4500, "USD"),
+		{Type: model.TransactionTypeIncome, Category: "Salary", Amount: 50000, Currency: "JPY"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []flex.Bar{
+		{Label: "Food", Amount: money.New(4200, "JPY")},
+		{Label: "Uncategorized", Amount: money.New(800, "JPY")},
+		{Label: "Travel", Amount: money.New(4500, "USD")},
+	}, bars)
+
+	// A single "Uncategorized" bar says nothing.
+	bars, err = categoryBreakdown([]*model.Transaction{expense("", 800, "JPY")})
+	assert.NoError(t, err)
+	assert.Empty(t, bars)
+}
+
+func TestDescribeTransaction(t *testing.T) {
+	occurredAt := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		transaction *model.Transaction
+		want        string
+	}{
+		{
+			name: "expense with note",
+			transaction: &model.Transaction{
+				Amount: 1200, Currency: "JPY", Type: model.TransactionTypeExpense, Note: "lunch", OccurredAt: occurredAt,
+			},
+			want: "expense of 1,200 JPY for lunch on 2025-10-01",
+		},
+		{
+			name: "income without note",
+			transaction: &model.Transaction{
+				Amount: 50000, Currency: "JPY", Type: model.TransactionTypeIncome, OccurredAt: occurredAt,
+			},
+			want: "income of 50,000 JPY on 2025-10-01",
+		},
+		{
+			name: "falls back to merchant",
+			transaction: &model.Transaction{
+				Amount: 2310, Currency: "JPY", Type: model.TransactionTypeExpense, Merchant: "Lawson", OccurredAt: occurredAt,
+			},
+			want: "expense of 2,310 JPY for Lawson on 2025-10-01",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, describeTransaction(tt.transaction))
+		})
+	}
+}
+
+func TestSumByType(t *testing.T) {
+	var transactions []*model.Transaction
+	for range 10 {
+		transactions = append(transactions, &model.Transaction{Amount: 10, Currency: "USD", Type: model.TransactionTypeExpense})
+	}
+	transactions = append(transactions,
+		&model.Transaction{Amount: 1200, Currency: "JPY", Type: model.TransactionTypeExpense},
+		&model.Transaction{Amount: 50000, Currency: "JPY", Type: model.TransactionTypeIncome},
+	)
+
+	expense, income, err := sumByType(transactions)
+	assert.NoError(t, err)
+	assert.Equal(t, map[money.Currency]money.Money{"USD": money.New(100, "USD"), "JPY": money.New(1200, "JPY")}, expense)
+	assert.Equal(t, map[money.Currency]money.Money{"JPY": money.New(50000, "JPY")}, income)
+}
+
+func TestFormatTotals(t *testing.T) {
+	assert.Equal(t, "nothing", formatTotals(map[money.Currency]money.Money{}))
+	assert.Equal(t, "1,200 JPY + 0.15 USD", formatTotals(map[money.Currency]money.Money{
+		"USD": money.New(15, "USD"),
+		"JPY": money.New(1200, "JPY"),
+	}))
+}
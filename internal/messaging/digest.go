@@ -0,0 +1,328 @@
+package messaging
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"github/shaolim/momon/internal/digest/model"
+	transactiondb "github/shaolim/momon/internal/transaction/database"
+	transactionmodel "github/shaolim/momon/internal/transaction/model"
+	usermodel "github/shaolim/momon/internal/user/model"
+	"github/shaolim/momon/pkg/database"
+	msg "github/shaolim/momon/pkg/messaging"
+	"github/shaolim/momon/pkg/money"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// digestInterval is how often due digests are sent.
+const digestInterval = time.Minute
+
+// digestBatch bounds how many digests are built per tick, as each one lists
+// two periods of the user's transactions. Digests due at the same minute
+// beyond it go out a minute late.
+const digestBatch = 100
+
+// digestTopCategories is how many categories a digest lists.
+const digestTopCategories = 3
+
+const digestUsage = `Usage:
+/digest - show your digests
+/digest daily [HH:MM] - e.g. /digest daily 21:00
+/digest weekly [WEEKDAY] [HH:MM] - e.g. /digest weekly sun 20:00
+/digest daily off or /digest weekly off - stop one
+/digest off - stop both`
+
+func (m *messaging) digestCommand(ctx context.Context, req *Request) error {
+	if m.digestDB == nil {
+		return req.Reply.ReplyText("Sorry, digests aren't available right now.")
+	}
+
+	if len(req.Args) == 0 {
+		return m.listDigests(ctx, req)
+	}
+
+	switch strings.ToLower(req.Args[0]) {
+	case "daily":
+		return m.setDigest(ctx, req, model.PeriodDaily, req.Args[1:])
+	case "weekly":
+		return m.setDigest(ctx, req, model.PeriodWeekly, req.Args[1:])
+	case "off":
+		if len(req.Args) != 1 {
+			return req.Reply.ReplyText(digestUsage)
+		}
+		return m.stopDigests(ctx, req)
+	default:
+		return req.Reply.ReplyText(digestUsage)
+	}
+}
+
+func (m *messaging) setDigest(ctx context.Context, req *Request, period model.Period, args []string) error {
+	name := strings.ToLower(string(period))
+	if len(args) == 1 && strings.EqualFold(args[0], "off") {
+		if err := m.digestDB.DeleteDigest(ctx, req.User.ID, period); err != nil {
+			if errors.Is(err, database.ErrNotFound) {
+				return req.Reply.ReplyText(fmt.Sprintf("You have no %s digest.", name))
+			}
+			return fmt.Errorf("failed to delete digest: %w", err)
+		}
+		return replied(req.Reply.ReplyText(fmt.Sprintf("Stopped your %s digest.", name)))
+	}
+
+	digest := &model.Digest{UserID: req.User.ID, Period: period, Hour: model.DefaultHour}
+	if period == model.PeriodWeekly {
+		digest.Weekday = time.Sunday
+	}
+	for _, arg := range args {
+		if weekday, ok := parseWeekday(strings.ToLower(arg)); ok && period == model.PeriodWeekly {
+			digest.Weekday = weekday
+			continue
+		}
+		hour, minute, ok := parseClock(arg)
+		if !ok {
+			return req.Reply.ReplyText(digestUsage)
+		}
+		digest.Hour, digest.Minute = hour, minute
+	}
+	loc := m.location(req.User)
+	digest.NextRunAt = digest.Next(req.Now, loc)
+
+	if err := m.digestDB.SetDigest(ctx, digest); err != nil {
+		return fmt.Errorf("failed to set digest: %w", err)
+	}
+
+	return replied(req.Reply.ReplyText(fmt.Sprintf("You'll get a digest %s, starting %s.", digest.Schedule(), digest.NextRunAt.In(loc).Format("Jan 2"))))
+}
+
+func (m *messaging) stopDigests(ctx context.Context, req *Request) error {
+	stopped := false
+	for _, period := range []model.Period{model.PeriodDaily, model.PeriodWeekly} {
+		err := m.digestDB.DeleteDigest(ctx, req.User.ID, period)
+		if errors.Is(err, database.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to delete digest: %w", err)
+		}
+		stopped = true
+	}
+
+	if !stopped {
+		return req.Reply.ReplyText("You have no digests.")
+	}
+	return replied(req.Reply.ReplyText("Stopped your digests."))
+}
+
+func (m *messaging) listDigests(ctx context.Context, req *Request) error {
+	digests, err := m.digestDB.ListDigests(ctx, req.User.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list digests: %w", err)
+	}
+	if len(digests) == 0 {
+		return req.Reply.ReplyText("You have no digests yet.\n\n" + digestUsage)
+	}
+
+	loc := m.location(req.User)
+	lines := []string{"Your digests:"}
+	for _, d := range digests {
+		lines = append(lines, fmt.Sprintf("%s, next %s", d.Schedule(), d.NextRunAt.In(loc).Format("Jan 2")))
+	}
+	lines = append(lines, "Send /digest off to stop them.")
+
+	return req.Reply.ReplyText(strings.Join(lines, "\n"))
+}
+
+// rescheduleDigests moves the user's digests to the same time of day in
+// their new time zone.
+func (m *messaging) rescheduleDigests(ctx context.Context, user *usermodel.User, now time.Time) {
+	if m.digestDB == nil {
+		return
+	}
+
+	digests, err := m.digestDB.ListDigests(ctx, user.ID)
+	if err != nil {
+		slog.Error("failed to list digests", slog.Int64("user_id", user.ID), slog.Any("error", err))
+		return
+	}
+
+	for _, d := range digests {
+		d.NextRunAt = d.Next(now, m.location(user))
+		if err := m.digestDB.SetDigest(ctx, d); err != nil {
+			slog.Error("failed to reschedule digest", slog.Int64("digest_id", d.ID), slog.Any("error", err))
+		}
+	}
+}
+
+// parseClock understands "21", "21:00" and "7:30".
+func parseClock(s string) (hour, minute int, ok bool) {
+	hh, mm, found := strings.Cut(s, ":")
+	hour, err := strconv.Atoi(hh)
+	if err != nil || hour < 0 || hour > 23 {
+		return 0, 0, false
+	}
+	if found {
+		if len(mm) != 2 {
+			return 0, 0, false
+		}
+		if minute, err = strconv.Atoi(mm); err != nil || minute < 0 || minute > 59 {
+			return 0, 0, false
+		}
+	}
+	return hour, minute, true
+}
+
+// sendDigests pushes every digest due at now. Each is marked sent before it is
+// pushed, so a restart or a second instance never sends it twice; a digest
+// missed while the server was down is sent once, late.
+func (m *messaging) sendDigests(ctx context.Context, now time.Time) {
+	due, err := m.digestDB.ListDueDigests(ctx, now, digestBatch)
+	if err != nil {
+		slog.Error("failed to list due digests", slog.Any("error", err))
+		return
+	}
+
+	for _, d := range due {
+		loc := d.Location(m.config.DefaultLocation)
+		marked, err := m.digestDB.MarkSent(ctx, d, d.Next(now, loc))
+		if err != nil {
+			slog.Error("failed to mark digest sent", slog.Int64("digest_id", d.ID), slog.Any("error", err))
+			continue
+		}
+		if !marked {
+			continue
+		}
+
+		if err := m.sendDigest(ctx, d, loc); err != nil {
+			slog.Error("failed to send digest", slog.Int64("digest_id", d.ID), slog.Any("error", err))
+		}
+	}
+}
+
+// sendDigest pushes the digest due at d.NextRunAt. Nothing is sent when the
+// user recorded no expenses in its period.
+func (m *messaging) sendDigest(ctx context.Context, d *model.Digest, loc *time.Location) error {
+	end := d.NextRunAt.In(loc)
+	start := d.Start(end, loc)
+
+	transactions, err := m.transactionDB.ListTransactions(ctx, &transactiondb.ListFilter{
+		UserID: d.UserID,
+		Type:   transactionmodel.TransactionTypeExpense,
+		From:   start,
+		To:     end,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list transactions: %w", err)
+	}
+	if len(transactions) == 0 {
+		return nil
+	}
+
+	previous, err := m.transactionDB.ListTransactions(ctx, &transactiondb.ListFilter{
+		UserID: d.UserID,
+		Type:   transactionmodel.TransactionTypeExpense,
+		From:   d.Start(start, loc),
+		To:     start,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list transactions: %w", err)
+	}
+
+	user, err := m.userDB.GetUser(ctx, d.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if transactions, err = m.convertTransactions(ctx, user, transactions, m.currency(user)); err != nil {
+		return err
+	}
+	if previous, err = m.convertTransactions(ctx, user, previous, m.currency(user)); err != nil {
+		return err
+	}
+
+	text, err := digestText(d, transactions, previous, loc)
+	if err != nil {
+		return fmt.Errorf("failed to sum transactions: %w", err)
+	}
+
+	return m.push(ctx, user, "", msg.TextMessage(text))
+}
+
+// digestText renders a digest from the expenses in its period and the one
+// before, e.g.
+//
+//	Your daily digest
+//	You spent 5,600 JPY in the last day, 1,200 JPY more than the day before.
+//	Top categories: Food 3,200 JPY, Transport 2,400 JPY
+//	Biggest expense: 2,400 JPY for taxi on Oct 15
+func digestText(d *model.Digest, transactions, previous []*transactionmodel.Transaction, loc *time.Location) (string, error) {
+	spent, _, err := sumByType(transactions)
+	if err != nil {
+		return "", err
+	}
+	spentBefore, _, err := sumByType(previous)
+	if err != nil {
+		return "", err
+	}
+
+	lines := []string{
+		fmt.Sprintf("Your %s digest", strings.ToLower(string(d.Period))),
+		spentComparison(spent, spentBefore, d.Unit()),
+	}
+
+	breakdown, err := categoryBreakdown(transactions)
+	if err != nil {
+		return "", err
+	}
+	if len(breakdown) > 0 {
+		top := make([]string, 0, digestTopCategories)
+		for _, bar := range breakdown[:min(len(breakdown), digestTopCategories)] {
+			top = append(top, bar.Label+" "+bar.Amount.String())
+		}
+		lines = append(lines, "Top categories: "+strings.Join(top, ", "))
+	}
+
+	var biggest *transactionmodel.Transaction
+	for _, t := range transactions {
+		if biggest == nil || t.Amount > biggest.Amount {
+			biggest = t
+		}
+	}
+	line := "Biggest expense: " + biggest.Money().String()
+	if description := cmp.Or(biggest.Note, biggest.Merchant); description != "" {
+		line += " for " + description
+	}
+	lines = append(lines, line+" on "+biggest.OccurredAt.In(loc).Format("Jan 2"))
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// spentComparison says what was spent in the last unit against the one
+// before. Totals are compared only when both are in a single, same currency.
+func spentComparison(spent, before map[money.Currency]money.Money, unit string) string {
+	s := fmt.Sprintf("You spent %s in the last %s", formatTotals(spent), unit)
+	if len(before) == 0 {
+		return fmt.Sprintf("%s, nothing the %s before.", s, unit)
+	}
+
+	if len(spent) == 1 && len(before) == 1 {
+		for currency, total := range spent {
+			previous, ok := before[currency]
+			if !ok {
+				break
+			}
+			switch diff := total.Amount - previous.Amount; {
+			case diff > 0:
+				return fmt.Sprintf("%s, %s more than the %s before.", s, money.New(diff, currency), unit)
+			case diff < 0:
+				return fmt.Sprintf("%s, %s less than the %s before.", s, money.New(-diff, currency), unit)
+			default:
+				return fmt.Sprintf("%s, the same as the %s before.", s, unit)
+			}
+		}
+	}
+
+	return fmt.Sprintf("%s, against %s the %s before.", s, formatTotals(before), unit)
+}
@@ -0,0 +1,207 @@
+package messaging
+
+import (
+	"cmp"
+	"context"
+	"github/shaolim/momon/internal/digest/model"
+	transactionmodel "github/shaolim/momon/internal/transaction/model"
+	"github/shaolim/momon/pkg/database"
+	"github/shaolim/momon/pkg/messaging/messagingtest"
+	"github/shaolim/momon/pkg/money"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDigestDB is an in-memory DigestDB for handler tests.
+type fakeDigestDB struct {
+	digests []*model.Digest
+	nextID  int64
+}
+
+func (f *fakeDigestDB) SetDigest(_ context.Context, digest *model.Digest) error {
+	if err := digest.Validate(); err != nil {
+		return err
+	}
+	for _, d := range f.digests {
+		if d.UserID == digest.UserID && d.Period == digest.Period {
+			digest.ID = d.ID
+			*d = *digest
+			return nil
+		}
+	}
+	f.nextID++
+	digest.ID = f.nextID
+	copied := *digest
+	f.digests = append(f.digests, &copied)
+	return nil
+}
+
+func (f *fakeDigestDB) DeleteDigest(_ context.Context, userID int64, period model.Period) error {
+	for i, d := range f.digests {
+		if d.UserID == userID && d.Period == period {
+			f.digests = append(f.digests[:i], f.digests[i+1:]...)
+			return nil
+		}
+	}
+	return database.ErrNotFound
+}
+
+func (f *fakeDigestDB) ListDigests(_ context.Context, userID int64) ([]*model.Digest, error) {
+	var result []*model.Digest
+	for _, d := range f.digests {
+		if d.UserID == userID {
+			copied := *d
+			result = append(result, &copied)
+		}
+	}
+	// The daily digest comes first.
+	slices.SortFunc(result, func(a, b *model.Digest) int {
+		if a.Period == b.Period {
+			return 0
+		}
+		if a.Period == model.PeriodDaily {
+			return -1
+		}
+		return 1
+	})
+	return result, nil
+}
+
+func (f *fakeDigestDB) ListDueDigests(_ context.Context, now time.Time, limit int) ([]*model.Digest, error) {
+	var result []*model.Digest
+	for _, d := range f.digests {
+		if !d.NextRunAt.After(now) && len(result) < limit {
+			copied := *d
+			result = append(result, &copied)
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeDigestDB) MarkSent(_ context.Context, digest *model.Digest, next time.Time) (bool, error) {
+	for _, d := range f.digests {
+		if d.ID == digest.ID && d.NextRunAt.Equal(digest.NextRunAt) {
+			d.NextRunAt = next
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestCommands_Digest(t *testing.T) {
+	now := time.Date(2025, 10, 15, 19, 30, 0, 0, time.UTC) // a Wednesday
+	m := newTestMessaging(&fakeTransactionDB{})
+
+	assert.Equal(t, "Sorry, digests aren't available right now.", dispatch(t, m, "/digest", now))
+
+	digestDB := &fakeDigestDB{}
+	m.digestDB = digestDB
+
+	assert.Equal(t, "You have no digests yet.\n\n"+digestUsage, dispatch(t, m, "/digest", now))
+	assert.Equal(t, digestUsage, dispatch(t, m, "/digest monthly", now))
+	assert.Equal(t, digestUsage, dispatch(t, m, "/digest daily 25", now))
+	assert.Equal(t, digestUsage, dispatch(t, m, "/digest daily 7:5", now))
+	assert.Equal(t, digestUsage, dispatch(t, m, "/digest daily sun", now))
+
+	assert.Equal(t, "You'll get a digest daily at 21:00, starting Oct 15.", dispatch(t, m, "/digest daily", now))
+	assert.Equal(t, "You'll get a digest weekly on Saturday at 08:30, starting Oct 18.", dispatch(t, m, "/digest weekly sat 8:30", now))
+	// Setting a period again replaces it.
+	assert.Equal(t, "You'll get a digest daily at 07:30, starting Oct 16.", dispatch(t, m, "/digest daily 7:30", now))
+	assert.Len(t, digestDB.digests, 2)
+
+	assert.Equal(t, `Your digests:
+daily at 07:30, next Oct 16
+weekly on Saturday at 08:30, next Oct 18
+Send /digest off to stop them.`, dispatch(t, m, "/digest", now))
+
+	// Digests keep their time of day when the time zone changes.
+	dispatch(t, m, "/timezone Asia/Tokyo", now)
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	assert.True(t, time.Date(2025, 10, 16, 7, 30, 0, 0, tokyo).Equal(digestDB.digests[0].NextRunAt))
+
+	assert.Equal(t, "Stopped your weekly digest.", dispatch(t, m, "/digest weekly off", now))
+	assert.Equal(t, "You have no weekly digest.", dispatch(t, m, "/digest weekly off", now))
+	assert.Equal(t, "Stopped your digests.", dispatch(t, m, "/digest off", now))
+	assert.Equal(t, "You have no digests.", dispatch(t, m, "/digest off", now))
+}
+
+func TestSendDigests(t *testing.T) {
+	ctx := context.Background()
+	messenger := messagingtest.NewMessenger()
+	transactionDB := &fakeTransactionDB{}
+	m := newTestMessaging(transactionDB)
+	m.line = messenger
+	digestDB := &fakeDigestDB{}
+	m.digestDB = digestDB
+
+	at := func(day, hour int) time.Time {
+		return time.Date(2025, 10, day, hour, 0, 0, 0, time.UTC)
+	}
+	for _, transaction := range []*transactionmodel.Transaction{
+		{Amount: 2000, Note: "lunch", Category: "Food", OccurredAt: at(14, 12)},
+		{Amount: 1200, Note: "lunch", Category: "Food", OccurredAt: at(15, 12)},
+		{Amount: 2400, Note: "taxi", Category: "Transport", OccurredAt: at(15, 18)},
+		{Amount: 500, Note: "coffee", Category: "Food", OccurredAt: at(15, 20)},
+		{Amount: 3000, Note: "dinner", OccurredAt: at(15, 22)},
+		{Amount: 300000, Note: "salary", Type: transactionmodel.TransactionTypeIncome, OccurredAt: at(15, 12)},
+	} {
+		transaction.UserID = 1
+		transaction.Currency = "JPY"
+		transaction.Type = cmp.Or(transaction.Type, transactionmodel.TransactionTypeExpense)
+		transactionDB.transactions = append(transactionDB.transactions, transaction)
+	}
+	assert.NoError(t, digestDB.SetDigest(ctx, &model.Digest{UserID: 1, Period: model.PeriodDaily, Hour: 21, NextRunAt: at(15, 21)}))
+
+	pushed := func() []string {
+		var texts []string
+		for _, p := range messenger.Pushes() {
+			assert.Equal(t, "U1", p.To)
+			texts = append(texts, p.Messages[0].Text)
+		}
+		return texts
+	}
+
+	m.sendDigests(ctx, at(15, 20))
+	assert.Empty(t, pushed())
+
+	m.sendDigests(ctx, at(15, 21))
+	assert.Equal(t, []string{`Your daily digest
+You spent 4,100 JPY in the last day, 2,100 JPY more than the day before.
+Top categories: Transport 2,400 JPY, Food 1,700 JPY
+Biggest expense: 2,400 JPY for taxi on Oct 15`}, pushed())
+	assert.Equal(t, at(16, 21), digestDB.digests[0].NextRunAt)
+
+	// Running again, e.g. after a restart, doesn't send it twice.
+	m.sendDigests(ctx, at(15, 21).Add(time.Minute))
+	assert.Len(t, pushed(), 1)
+
+	// A digest missed while the server was down is sent once, late.
+	m.sendDigests(ctx, at(17, 21))
+	if texts := pushed(); assert.Len(t, texts, 2) {
+		assert.Equal(t, `Your daily digest
+You spent 3,000 JPY in the last day, 1,100 JPY less than the day before.
+Biggest expense: 3,000 JPY for dinner on Oct 15`, texts[1])
+	}
+	assert.Equal(t, at(18, 21), digestDB.digests[0].NextRunAt)
+
+	// Nothing is sent for a period without expenses.
+	m.sendDigests(ctx, at(18, 21))
+	assert.Len(t, pushed(), 2)
+	assert.Equal(t, at(19, 21), digestDB.digests[0].NextRunAt)
+}
+
+func TestSpentComparison(t *testing.T) {
+	jpy := func(amount int64) map[money.Currency]money.Money {
+		return map[money.Currency]money.Money{"JPY": money.New(amount, "JPY")}
+	}
+
+	assert.Equal(t, "You spent 1,000 JPY in the last week, nothing the week before.",
+		spentComparison(jpy(1000), nil, "week"))
+	assert.Equal(t, "You spent 1,000 JPY in the last day, the same as the day before.",
+		spentComparison(jpy(1000), jpy(1000), "day"))
+	assert.Equal(t, "You spent 1,000 JPY in the last day, against 15.00 USD the day before.",
+		spentComparison(jpy(1000), map[money.Currency]money.Money{"USD": money.New(1500, "USD")}, "day"))
+}
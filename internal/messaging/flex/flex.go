@@ -0,0 +1,83 @@
+// Package flex renders bot replies as LINE Flex Message cards. Every card comes
+// with a plain text version for platforms that can't show Flex, which LINE
+// also uses for notifications.
+package flex
+
+import (
+	messagingapi "github.com/line/line-bot-sdk-go/v8/linebot/messaging_api"
+)
+
+const (
+	colorAccent = "#1DB446"
+	colorText   = "#111111"
+	colorMuted  = "#888888"
+	colorTrack  = "#EEEEEE"
+)
+
+func text(s, size, color string) *messagingapi.FlexText {
+	return &messagingapi.FlexText{
+		Text:  s,
+		Size:  size,
+		Color: color,
+		Wrap:  true,
+	}
+}
+
+func bold(t *messagingapi.FlexText) *messagingapi.FlexText {
+	t.Weight = messagingapi.FlexTextWEIGHT_BOLD
+	return t
+}
+
+func separator() *messagingapi.FlexSeparator {
+	return &messagingapi.FlexSeparator{Margin: "lg"}
+}
+
+// row lays out a label on the left and a value on the right.
+func row(label, value *messagingapi.FlexText) *messagingapi.FlexBox {
+	label.Flex = 3
+	value.Flex = 2
+	value.Align = messagingapi.FlexTextALIGN_END
+	return &messagingapi.FlexBox{
+		Layout:   messagingapi.FlexBoxLAYOUT_HORIZONTAL,
+		Contents: []messagingapi.FlexComponentInterface{label, value},
+	}
+}
+
+func vertical(margin string, contents ...messagingapi.FlexComponentInterface) *messagingapi.FlexBox {
+	return &messagingapi.FlexBox{
+		Layout:   messagingapi.FlexBoxLAYOUT_VERTICAL,
+		Margin:   margin,
+		Spacing:  "sm",
+		Contents: contents,
+	}
+}
+
+// bar is a horizontal bar filled to percent.
+func bar(percent int) *messagingapi.FlexBox {
+	percent = min(max(percent, 1), 100)
+	return &messagingapi.FlexBox{
+		Layout:          messagingapi.FlexBoxLAYOUT_VERTICAL,
+		BackgroundColor: colorTrack,
+		Height:          "6px",
+		CornerRadius:    "3px",
+		Contents: []messagingapi.FlexComponentInterface{
+			&messagingapi.FlexBox{
+				Layout:          messagingapi.FlexBoxLAYOUT_VERTICAL,
+				BackgroundColor: colorAccent,
+				Width:           percentString(percent),
+				Height:          "6px",
+				CornerRadius:    "3px",
+				Contents:        []messagingapi.FlexComponentInterface{},
+			},
+		},
+	}
+}
+
+func bubble(body ...messagingapi.FlexComponentInterface) *messagingapi.FlexBubble {
+	return &messagingapi.FlexBubble{
+		Body: &messagingapi.FlexBox{
+			Layout:   messagingapi.FlexBoxLAYOUT_VERTICAL,
+			Contents: body,
+		},
+	}
+}
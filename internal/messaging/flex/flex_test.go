@@ -0,0 +1,155 @@
+package flex
+
+import (
+	"encoding/json"
+	"github/shaolim/momon/internal/receipt/model"
+	"github/shaolim/momon/pkg/money"
+	"testing"
+	"time"
+
+	messagingapi "github.com/line/line-bot-sdk-go/v8/linebot/messaging_api"
+	"github.com/stretchr/testify/assert"
+)
+
+// texts returns the text of every text component in the card, in order.
+func texts(t *testing.T, container messagingapi.FlexContainerInterface) []string {
+	t.Helper()
+
+	data, err := json.Marshal(&messagingapi.FlexMessage{AltText: "alt", Contents: container})
+	if err != nil {
+		t.Fatalf("failed to marshal flex message: %v", err)
+	}
+
+	var (
+		node  any
+		found []string
+		walk  func(any)
+	)
+	if err := json.Unmarshal(data, &node); err != nil {
+		t.Fatalf("failed to unmarshal flex message: %v", err)
+	}
+	walk = func(n any) {
+		switch v := n.(type) {
+		case map[string]any:
+			if v["type"] == "text" {
+				found = append(found, v["text"].(string))
+			}
+			for _, key := range []string{"contents", "body"} {
+				walk(v[key])
+			}
+		case []any:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(node)
+	return found
+}
+
+func TestReceipt(t *testing.T) {
+	r := &model.Receipt{
+		Shop: "Lawson",
+		Items: []model.Item{
+			{Name: "Bento", Quantity: 2, TotalPrice: 1100},
+			{Name: "Tea", Quantity: 1, TotalPrice: 1210},
+		},
+		Tax:   210,
+		Total: 2310,
+	}
+
+	message := Receipt(&ReceiptCard{
+		Title:    "Saved 2,310 JPY at Lawson on 2024-01-15.",
+		Receipt:  r,
+		Currency: "JPY",
+		Date:     time.Date(2024, 1, 15, 14, 30, 0, 0, time.UTC),
+		Category: "groceries",
+	})
+
+	assert.Equal(t, "Saved 2,310 JPY at Lawson on 2024-01-15.\nCategory: groceries\n\nBento x2  1,100 JPY\nTea x1  1,210 JPY\nTax  210 JPY", message.Text)
+	assert.Equal(t, []string{
+		"Saved 2,310 JPY at Lawson on 2024-01-15.",
+		"Lawson", "2024-01-15 14:30", "groceries",
+		"Bento x2", "1,100 JPY",
+		"Tea", "1,210 JPY",
+		"Tax", "210 JPY",
+		"Total", "2,310 JPY",
+	}, texts(t, message.Flex))
+}
+
+func TestReceipt_WithoutItems(t *testing.T) {
+	message := Receipt(&ReceiptCard{
+		Title:       "Saved 500 JPY on 2024-01-15.",
+		Receipt:     &model.Receipt{Total: 500},
+		Currency:    "JPY",
+		Date:        time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC),
+		TimeUnknown: true,
+	})
+
+	assert.Equal(t, "Saved 500 JPY on 2024-01-15.", message.Text)
+	// LINE rejects empty text components.
+	assert.NotContains(t, texts(t, message.Flex), "")
+	assert.Contains(t, texts(t, message.Flex), "Receipt")
+	assert.Contains(t, texts(t, message.Flex), "2024-01-15 (time not shown)")
+}
+
+func TestMonthlySummary(t *testing.T) {
+	message := MonthlySummary(&Summary{
+		Title:    "October 2025",
+		Spent:    "4,000 JPY + 12.00 USD",
+		Received: "50,000 JPY",
+		Entries:  4,
+		Breakdown: []Bar{
+			{Label: "Food", Amount: money.New(3000, "JPY")},
+			{Label: "Transport", Amount: money.New(1000, "JPY")},
+			{Label: "Food", Amount: money.New(1200, "USD")},
+		},
+	})
+
+	assert.Equal(t, "October 2025\nSpent: 4,000 JPY + 12.00 USD\nReceived: 50,000 JPY\nEntries: 4"+
+		"\n\nFood  3,000 JPY  75%\nTransport  1,000 JPY  25%\nFood  12.00 USD  100%", message.Text)
+
+	data, err := json.Marshal(message.Flex)
+	if err != nil {
+		t.Fatalf("failed to marshal flex container: %v", err)
+	}
+	for _, width := range []string{`"width":"75%"`, `"width":"25%"`, `"width":"100%"`} {
+		assert.Contains(t, string(data), width)
+	}
+}
+
+func TestMonthlySummary_Members(t *testing.T) {
+	message := MonthlySummary(&Summary{
+		Title:     "October 2025",
+		Spent:     "4,000 JPY",
+		Received:  "nothing",
+		Entries:   3,
+		Breakdown: []Bar{{Label: "Food", Amount: money.New(4000, "JPY")}},
+		Members: []Bar{
+			{Label: "Alice", Amount: money.New(3000, "JPY")},
+			{Label: "Bob", Amount: money.New(1000, "JPY")},
+		},
+	})
+
+	assert.Equal(t, "October 2025\nSpent: 4,000 JPY\nReceived: nothing\nEntries: 3"+
+		"\n\nFood  4,000 JPY  100%\n\nBy member\nAlice  3,000 JPY  75%\nBob  1,000 JPY  25%", message.Text)
+	assert.Contains(t, texts(t, message.Flex), "By member")
+}
+
+func TestMonthlySummary_WithoutBreakdown(t *testing.T) {
+	message := MonthlySummary(&Summary{Title: "October 2025", Spent: "1,200 JPY", Received: "nothing", Entries: 1})
+
+	assert.Equal(t, "October 2025\nSpent: 1,200 JPY\nReceived: nothing\nEntries: 1", message.Text)
+	assert.Equal(t, []string{
+		"October 2025",
+		"Spent", "1,200 JPY",
+		"Received", "nothing",
+		"Entries", "1",
+	}, texts(t, message.Flex))
+}
+
+func TestShare(t *testing.T) {
+	assert.Equal(t, 33, share(1, 3))
+	assert.Equal(t, 67, share(2, 3))
+	assert.Equal(t, 0, share(1, 0))
+}
@@ -0,0 +1,96 @@
+package flex
+
+import (
+	"fmt"
+	"github/shaolim/momon/internal/receipt/model"
+	msg "github/shaolim/momon/pkg/messaging"
+	"github/shaolim/momon/pkg/money"
+	"strings"
+	"time"
+
+	messagingapi "github.com/line/line-bot-sdk-go/v8/linebot/messaging_api"
+)
+
+// ReceiptCard is a receipt and what to say about it.
+type ReceiptCard struct {
+	// Title heads the card and its text version, e.g. "Saved 1,200 JPY at
+	// Lawson on 2024-01-15."
+	Title    string
+	Receipt  *model.Receipt
+	Currency money.Currency
+	Date     time.Time
+	// TimeUnknown shows only the date, for receipts that print no time.
+	TimeUnknown bool
+	// Category is shown under the date when set.
+	Category string
+}
+
+// Receipt renders a receipt as a card with the shop, date, one row per item,
+// the tax and the total.
+func Receipt(c *ReceiptCard) msg.Message {
+	amount := func(v int64) string {
+		return money.New(v, c.Currency).String()
+	}
+
+	r := c.Receipt
+	shop := r.Shop
+	if shop == "" {
+		shop = "Receipt"
+	}
+
+	var (
+		b     strings.Builder
+		items = make([]messagingapi.FlexComponentInterface, 0, len(r.Items))
+	)
+	b.WriteString(c.Title)
+	if c.Category != "" {
+		fmt.Fprintf(&b, "\nCategory: %s", c.Category)
+	}
+	if len(r.Items) > 0 {
+		b.WriteString("\n")
+	}
+	for _, item := range r.Items {
+		fmt.Fprintf(&b, "\n%s x%g  %s", item.Name, item.Quantity, amount(item.TotalPrice))
+
+		name := item.Name
+		if item.Quantity != 1 {
+			name = fmt.Sprintf("%s x%g", item.Name, item.Quantity)
+		}
+		items = append(items, row(text(name, "sm", colorText), text(amount(item.TotalPrice), "sm", colorText)))
+	}
+	if len(r.Items) > 0 && r.Tax != 0 {
+		fmt.Fprintf(&b, "\nTax  %s", amount(r.Tax))
+	}
+
+	heading := []messagingapi.FlexComponentInterface{
+		bold(text(shop, "xl", colorText)),
+		text(c.date(), "xs", colorMuted),
+	}
+	if c.Category != "" {
+		heading = append(heading, text(c.Category, "xs", colorAccent))
+	}
+
+	body := []messagingapi.FlexComponentInterface{
+		bold(text(c.Title, "xs", colorAccent)),
+		vertical("md", heading...),
+	}
+	if len(items) > 0 {
+		body = append(body, separator(), vertical("lg", items...))
+	}
+	body = append(body, separator(), vertical("lg",
+		row(text("Tax", "sm", colorMuted), text(amount(r.Tax), "sm", colorMuted)),
+		row(bold(text("Total", "md", colorText)), bold(text(amount(r.Total), "md", colorText))),
+	))
+
+	return msg.Message{
+		Text: b.String(),
+		Flex: bubble(body...),
+	}
+}
+
+func (c *ReceiptCard) date() string {
+	if c.TimeUnknown {
+		return c.Date.Format("2006-01-02") + " (time not shown)"
+	}
+	return c.Date.Format("2006-01-02 15:04")
+}
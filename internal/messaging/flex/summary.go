@@ -0,0 +1,98 @@
+package flex
+
+import (
+	"fmt"
+	msg "github/shaolim/momon/pkg/messaging"
+	"github/shaolim/momon/pkg/money"
+	"strconv"
+	"strings"
+
+	messagingapi "github.com/line/line-bot-sdk-go/v8/linebot/messaging_api"
+)
+
+// Summary is a period's totals, e.g. a month's.
+type Summary struct {
+	Title    string
+	Spent    string
+	Received string
+	Entries  int
+	// Breakdown is where the money went, largest first. Bars are sized
+	// against the other bars in the same currency.
+	Breakdown []Bar
+	// Members is what each member of a group spent, largest first. It is
+	// empty outside of groups.
+	Members []Bar
+}
+
+type Bar struct {
+	Label  string
+	Amount money.Money
+}
+
+// MonthlySummary renders the totals with a bar per breakdown entry, then a
+// bar per member.
+func MonthlySummary(s *Summary) msg.Message {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", s.Title)
+	fmt.Fprintf(&b, "Spent: %s\n", s.Spent)
+	fmt.Fprintf(&b, "Received: %s\n", s.Received)
+	fmt.Fprintf(&b, "Entries: %d", s.Entries)
+
+	body := []messagingapi.FlexComponentInterface{
+		bold(text(s.Title, "xl", colorText)),
+		vertical("lg",
+			row(text("Spent", "sm", colorMuted), bold(text(s.Spent, "sm", colorText))),
+			row(text("Received", "sm", colorMuted), text(s.Received, "sm", colorText)),
+			row(text("Entries", "sm", colorMuted), text(strconv.Itoa(s.Entries), "sm", colorText)),
+		),
+	}
+
+	if len(s.Breakdown) > 0 {
+		b.WriteString("\n")
+		body = append(body, separator(), vertical("lg", bars(&b, s.Breakdown)...))
+	}
+
+	if len(s.Members) > 0 {
+		b.WriteString("\n\nBy member")
+		heading := bold(text("By member", "sm", colorText))
+		body = append(body, separator(), vertical("lg", append([]messagingapi.FlexComponentInterface{heading}, bars(&b, s.Members)...)...))
+	}
+
+	return msg.Message{
+		Text: b.String(),
+		Flex: bubble(body...),
+	}
+}
+
+// bars renders a bar per entry, sized against the entries in the same
+// currency, and writes them as text lines to b.
+func bars(b *strings.Builder, entries []Bar) []messagingapi.FlexComponentInterface {
+	totals := map[money.Currency]int64{}
+	for _, entry := range entries {
+		totals[entry.Amount.Currency] += entry.Amount.Amount
+	}
+
+	components := make([]messagingapi.FlexComponentInterface, 0, len(entries))
+	for _, entry := range entries {
+		percent := share(entry.Amount.Amount, totals[entry.Amount.Currency])
+		fmt.Fprintf(b, "\n%s  %s  %d%%", entry.Label, entry.Amount, percent)
+
+		components = append(components, vertical("md",
+			row(text(entry.Label, "sm", colorText), text(fmt.Sprintf("%s · %d%%", entry.Amount, percent), "sm", colorMuted)),
+			bar(percent),
+		))
+	}
+	return components
+}
+
+// share returns part as a whole percentage of total.
+func share(part, total int64) int {
+	if total <= 0 {
+		return 0
+	}
+	return int((part*100 + total/2) / total)
+}
+
+func percentString(percent int) string {
+	return strconv.Itoa(percent) + "%"
+}
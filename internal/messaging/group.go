@@ -0,0 +1,259 @@
+package messaging
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"github/shaolim/momon/internal/group/model"
+	"github/shaolim/momon/internal/messaging/flex"
+	transactiondb "github/shaolim/momon/internal/transaction/database"
+	transactionmodel "github/shaolim/momon/internal/transaction/model"
+	usermodel "github/shaolim/momon/internal/user/model"
+	"github/shaolim/momon/pkg/database"
+	"github/shaolim/momon/pkg/money"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/line/line-bot-sdk-go/v8/linebot/webhook"
+)
+
+const groupGreeting = `Hi everyone! I'll keep a shared ledger for this group.
+Send entries such as "lunch 1200" or a receipt photo and I'll record them under your name.
+Send /today or /month to see what the group spent, per member.`
+
+// handleJoin starts a shared ledger for the group the bot was added to, or
+// picks up the old one when it is invited back.
+func (m *messaging) handleJoin(ctx context.Context, e webhook.JoinEvent) error {
+	c := m.lineChat(e.Source, e.ReplyToken)
+	source, ok := e.Source.(webhook.GroupSource)
+	if !ok {
+		return c.replyText(ctx, "I can only keep a shared ledger in a group chat.")
+	}
+	if m.groupDB == nil {
+		return c.replyText(ctx, "Sorry, I can't keep a shared ledger right now.")
+	}
+
+	group, err := m.group(ctx, source.GroupId)
+	if err != nil {
+		return err
+	}
+	slog.Info("joined group", slog.Int64("group_id", group.ID))
+
+	return c.replyText(ctx, groupGreeting)
+}
+
+// handleLeave deactivates the group the bot was removed from. Its ledger is
+// kept in case the bot is invited back.
+func (m *messaging) handleLeave(ctx context.Context, e webhook.LeaveEvent) error {
+	source, ok := e.Source.(webhook.GroupSource)
+	if !ok || m.groupDB == nil {
+		return nil
+	}
+
+	if err := m.groupDB.UpdateStatus(ctx, source.GroupId, model.GroupStatusInActive); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			slog.Info("left unknown group", slog.String("line_group_id", source.GroupId))
+			return nil
+		}
+		return fmt.Errorf("failed to deactivate group: %w", err)
+	}
+
+	return nil
+}
+
+// handleMemberJoined adds new members of the group, registering them from
+// their group profile when they are not known yet.
+func (m *messaging) handleMemberJoined(ctx context.Context, e webhook.MemberJoinedEvent) error {
+	source, ok := e.Source.(webhook.GroupSource)
+	if !ok || m.groupDB == nil || e.Joined == nil {
+		return nil
+	}
+
+	group, err := m.group(ctx, source.GroupId)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for _, member := range e.Joined.Members {
+		user, err := m.resolveUser(ctx, webhook.GroupSource{GroupId: source.GroupId, UserId: member.UserId})
+		if err != nil {
+			return fmt.Errorf("failed to resolve user: %w", err)
+		}
+		if err := m.groupDB.AddMember(ctx, group.ID, user.ID, time.Now()); err != nil {
+			return fmt.Errorf("failed to add member: %w", err)
+		}
+		names = append(names, user.DisplayName)
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	return replied(m.lineChat(e.Source, e.ReplyToken).replyText(ctx,
+		fmt.Sprintf("Welcome, %s! Entries you send here go into the group's shared ledger.", strings.Join(names, ", "))))
+}
+
+// handleMemberLeft removes members who left the group. Their entries stay in
+// its ledger.
+func (m *messaging) handleMemberLeft(ctx context.Context, e webhook.MemberLeftEvent) error {
+	source, ok := e.Source.(webhook.GroupSource)
+	if !ok || m.groupDB == nil || e.Left == nil {
+		return nil
+	}
+
+	group, err := m.groupDB.GetByLineGroupID(ctx, source.GroupId)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to get group: %w", err)
+	}
+
+	for _, member := range e.Left.Members {
+		user, err := m.userDB.GetByLineUserID(ctx, member.UserId)
+		if errors.Is(err, database.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+
+		err = m.groupDB.RemoveMember(ctx, group.ID, user.ID)
+		if err != nil && !errors.Is(err, database.ErrNotFound) {
+			return fmt.Errorf("failed to remove member: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// group returns the active group with the LINE group ID, starting a ledger
+// for it when the join event was missed and reactivating it when it was
+// left.
+func (m *messaging) group(ctx context.Context, lineGroupID string) (*model.Group, error) {
+	group, err := m.groupDB.GetByLineGroupID(ctx, lineGroupID)
+	if err == nil && group.Status == model.GroupStatusActive {
+		return group, nil
+	}
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		return nil, fmt.Errorf("failed to get group: %w", err)
+	}
+
+	group = &model.Group{LineGroupID: lineGroupID, Status: model.GroupStatusActive}
+	if err := m.groupDB.Upsert(ctx, group); err != nil {
+		return nil, fmt.Errorf("failed to save group: %w", err)
+	}
+	return group, nil
+}
+
+// enterGroup points the chat at the group's ledger when the message was sent
+// in a group, adding the sender as a member. Members who were in the group
+// before the bot are added the first time they send something.
+func (m *messaging) enterGroup(ctx context.Context, c *chat, source webhook.SourceInterface, user *usermodel.User) error {
+	s, ok := source.(webhook.GroupSource)
+	if !ok || m.groupDB == nil {
+		return nil
+	}
+
+	group, err := m.group(ctx, s.GroupId)
+	if err != nil {
+		return err
+	}
+	if err := m.groupDB.AddMember(ctx, group.ID, user.ID, time.Now()); err != nil {
+		return fmt.Errorf("failed to add member: %w", err)
+	}
+
+	c.group = group
+	return nil
+}
+
+// personal wraps commands about the user's own account, which make no sense
+// in a group's shared ledger.
+func (m *messaging) personal(h HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, req *Request) error {
+		if req.Group != nil {
+			return req.Reply.ReplyText("That only works in a one-on-one chat with me.")
+		}
+		return h(ctx, req)
+	}
+}
+
+// ledgerFilter lists the group's shared ledger in groups and the user's own
+// entries elsewhere.
+func ledgerFilter(req *Request) *transactiondb.ListFilter {
+	if req.Group != nil {
+		return &transactiondb.ListFilter{GroupID: req.Group.ID}
+	}
+	return &transactiondb.ListFilter{UserID: req.User.ID}
+}
+
+// memberNames maps the users who made the transactions to their display
+// names, including members who have left the group since.
+func (m *messaging) memberNames(ctx context.Context, group *model.Group, transactions []*transactionmodel.Transaction) (map[int64]string, error) {
+	members, err := m.groupDB.ListMembers(ctx, group.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list members: %w", err)
+	}
+
+	names := make(map[int64]string, len(members))
+	for _, member := range members {
+		names[member.UserID] = cmp.Or(member.DisplayName, "Unknown")
+	}
+	for _, t := range transactions {
+		if _, ok := names[t.UserID]; ok {
+			continue
+		}
+		user, err := m.userDB.GetUser(ctx, t.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user: %w", err)
+		}
+		names[t.UserID] = cmp.Or(user.DisplayName, "Unknown")
+	}
+	return names, nil
+}
+
+// memberBreakdown totals expenses per member and currency, largest first.
+func memberBreakdown(transactions []*transactionmodel.Transaction, names map[int64]string) ([]flex.Bar, error) {
+	return expenseBars(transactions, func(t *transactionmodel.Transaction) string {
+		return names[t.UserID]
+	})
+}
+
+// memberLine follows the transaction's list row with who made it when names
+// is set, e.g. "10/01 -1,200 JPY lunch (Alice)".
+func memberLine(line string, t *transactionmodel.Transaction, names map[int64]string) string {
+	if names == nil {
+		return line
+	}
+	return fmt.Sprintf("%s (%s)", line, names[t.UserID])
+}
+
+// groupToday answers /today in a group with the shared total, what each member
+// spent and who made each entry.
+func (m *messaging) groupToday(ctx context.Context, req *Request, transactions, converted []*transactionmodel.Transaction, expense map[money.Currency]money.Money) error {
+	names, err := m.memberNames(ctx, req.Group, transactions)
+	if err != nil {
+		return err
+	}
+	members, err := memberBreakdown(converted, names)
+	if err != nil {
+		return fmt.Errorf("failed to sum transactions: %w", err)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Today the group spent %s.\n", formatTotals(expense))
+	if len(members) > 0 {
+		spent := make([]string, 0, len(members))
+		for _, bar := range members {
+			spent = append(spent, bar.Label+" "+bar.Amount.String())
+		}
+		fmt.Fprintf(&b, "By member: %s\n", strings.Join(spent, ", "))
+	}
+	for _, t := range transactions {
+		b.WriteString("\n" + memberLine(todayLine(t, req.Now.Location()), t, names))
+	}
+
+	return req.Reply.ReplyText(b.String())
+}
@@ -0,0 +1,260 @@
+package messaging
+
+import (
+	"context"
+	"github/shaolim/momon/internal/group/model"
+	"github/shaolim/momon/internal/transaction/parser"
+	usermodel "github/shaolim/momon/internal/user/model"
+	"github/shaolim/momon/pkg/database"
+	"github/shaolim/momon/pkg/messaging/messagingtest"
+	"github/shaolim/momon/pkg/money"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/line/line-bot-sdk-go/v8/linebot/webhook"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeGroupDB is an in-memory GroupDB for handler tests.
+type fakeGroupDB struct {
+	groups  []*model.Group
+	members []*model.Member
+	userDB  *fakeUserDB
+}
+
+func (f *fakeGroupDB) Upsert(ctx context.Context, group *model.Group) error {
+	if err := group.Validate(); err != nil {
+		return err
+	}
+	if existing, err := f.GetByLineGroupID(ctx, group.LineGroupID); err == nil {
+		existing.Status = group.Status
+		group.ID = existing.ID
+		return nil
+	}
+	group.ID = int64(len(f.groups) + 1)
+	copied := *group
+	f.groups = append(f.groups, &copied)
+	return nil
+}
+
+func (f *fakeGroupDB) GetByLineGroupID(_ context.Context, lineGroupID string) (*model.Group, error) {
+	for _, g := range f.groups {
+		if g.LineGroupID == lineGroupID {
+			return g, nil
+		}
+	}
+	return nil, database.ErrNotFound
+}
+
+func (f *fakeGroupDB) UpdateStatus(ctx context.Context, lineGroupID string, status model.GroupStatus) error {
+	group, err := f.GetByLineGroupID(ctx, lineGroupID)
+	if err != nil {
+		return err
+	}
+	group.Status = status
+	return nil
+}
+
+func (f *fakeGroupDB) AddMember(_ context.Context, groupID, userID int64, now time.Time) error {
+	if f.member(groupID, userID) < 0 {
+		f.members = append(f.members, &model.Member{GroupID: groupID, UserID: userID, JoinedAt: now})
+	}
+	return nil
+}
+
+func (f *fakeGroupDB) RemoveMember(_ context.Context, groupID, userID int64) error {
+	i := f.member(groupID, userID)
+	if i < 0 {
+		return database.ErrNotFound
+	}
+	f.members = slices.Delete(f.members, i, i+1)
+	return nil
+}
+
+func (f *fakeGroupDB) ListMembers(_ context.Context, groupID int64) ([]*model.Member, error) {
+	var result []*model.Member
+	for _, member := range f.members {
+		if member.GroupID == groupID {
+			copied := *member
+			copied.DisplayName = f.userDB.users[member.UserID].DisplayName
+			result = append(result, &copied)
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeGroupDB) member(groupID, userID int64) int {
+	return slices.IndexFunc(f.members, func(m *model.Member) bool {
+		return m.GroupID == groupID && m.UserID == userID
+	})
+}
+
+func newTestGroupMessaging(transactionDB *fakeTransactionDB) (*messaging, *fakeGroupDB, *messagingtest.Messenger) {
+	messenger := messagingtest.NewMessenger()
+	messenger.SetProfile("U1", "Alice")
+	messenger.SetProfile("U2", "Bob")
+
+	m := newTestMessaging(transactionDB)
+	m.line = messenger
+	userDB := m.userDB.(*fakeUserDB)
+	userDB.users[1].DisplayName = "Alice"
+	groupDB := &fakeGroupDB{userDB: userDB}
+	m.groupDB = groupDB
+	return m, groupDB, messenger
+}
+
+func TestGroupEvents(t *testing.T) {
+	ctx := context.Background()
+	transactionDB := &fakeTransactionDB{}
+	m, groupDB, messenger := newTestGroupMessaging(transactionDB)
+	group := webhook.GroupSource{GroupId: "G1"}
+
+	assert.NoError(t, m.handleEvent(ctx, webhook.JoinEvent{Source: group, ReplyToken: "join"}))
+	assert.Equal(t, []string{groupGreeting}, messenger.ReplyTexts())
+	if assert.Len(t, groupDB.groups, 1) {
+		assert.Equal(t, "G1", groupDB.groups[0].LineGroupID)
+		assert.Equal(t, model.GroupStatus(model.GroupStatusActive), groupDB.groups[0].Status)
+	}
+
+	// Bob isn't a friend of the bot and is registered from his group profile.
+	assert.NoError(t, m.handleEvent(ctx, webhook.MemberJoinedEvent{
+		Source:     group,
+		ReplyToken: "member-joined",
+		Joined:     &webhook.JoinedMembers{Members: []webhook.UserSource{{UserId: "U2"}}},
+	}))
+	assert.Equal(t, "Welcome, Bob! Entries you send here go into the group's shared ledger.", messenger.ReplyTexts()[1])
+	bob, err := m.userDB.GetByLineUserID(ctx, "U2")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Bob", bob.DisplayName)
+	}
+
+	// Entries sent in the group go into its ledger under the sender.
+	for _, e := range []struct{ user, text string }{{"U1", "lunch 1200"}, {"U2", "taxi 800"}} {
+		assert.NoError(t, m.handleEvent(ctx, webhook.MessageEvent{
+			Source:     webhook.GroupSource{GroupId: "G1", UserId: e.user},
+			ReplyToken: "text",
+			Message:    webhook.TextMessageContent{Text: e.text},
+		}))
+	}
+	if assert.Len(t, transactionDB.transactions, 2) {
+		assert.Equal(t, int64(1), transactionDB.transactions[0].UserID)
+		assert.Equal(t, int64(1), transactionDB.transactions[0].GroupID)
+		assert.Equal(t, bob.ID, transactionDB.transactions[1].UserID)
+		assert.Equal(t, int64(1), transactionDB.transactions[1].GroupID)
+	}
+	// Alice was in the group before the bot and became a member by sending
+	// something.
+	assert.Len(t, groupDB.members, 2)
+
+	assert.NoError(t, m.handleEvent(ctx, webhook.MemberLeftEvent{
+		Source: group,
+		Left:   &webhook.LeftMembers{Members: []webhook.UserSource{{UserId: "U2"}, {UserId: "U9"}}},
+	}))
+	if assert.Len(t, groupDB.members, 1) {
+		assert.Equal(t, int64(1), groupDB.members[0].UserID)
+	}
+
+	assert.NoError(t, m.handleEvent(ctx, webhook.LeaveEvent{Source: group}))
+	assert.Equal(t, model.GroupStatus(model.GroupStatusInActive), groupDB.groups[0].Status)
+
+	// A message after the bot is invited back reactivates the ledger.
+	assert.NoError(t, m.handleEvent(ctx, webhook.JoinEvent{Source: group, ReplyToken: "join"}))
+	assert.Equal(t, model.GroupStatus(model.GroupStatusActive), groupDB.groups[0].Status)
+	assert.Len(t, groupDB.groups, 1)
+
+	assert.NoError(t, m.handleEvent(ctx, webhook.JoinEvent{Source: webhook.RoomSource{RoomId: "R1"}, ReplyToken: "room"}))
+	assert.Equal(t, "I can only keep a shared ledger in a group chat.", messenger.ReplyTexts()[len(messenger.ReplyTexts())-1])
+}
+
+func TestCommands_Group(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 10, 15, 19, 30, 0, 0, time.UTC)
+	transactionDB := &fakeTransactionDB{}
+	m, groupDB, _ := newTestGroupMessaging(transactionDB)
+
+	bob := &usermodel.User{LineUserID: "U2", DisplayName: "Bob", Status: usermodel.UserStatusActive}
+	assert.NoError(t, m.userDB.AddUser(ctx, bob))
+	group := &model.Group{LineGroupID: "G1", Status: model.GroupStatusActive}
+	assert.NoError(t, groupDB.Upsert(ctx, group))
+	assert.NoError(t, groupDB.AddMember(ctx, group.ID, 1, now))
+	assert.NoError(t, groupDB.AddMember(ctx, group.ID, bob.ID, now))
+
+	in := func(user *usermodel.User, text string) string {
+		t.Helper()
+
+		replier := &recordingReplier{}
+		err := m.router.Dispatch(ctx, &Request{User: user, Group: group, Text: text, Now: now, Reply: replier})
+		if err != nil {
+			t.Fatalf("Dispatch(%q) unexpected error: %v", text, err)
+		}
+		return replier.replies[0]
+	}
+	alice := &usermodel.User{ID: 1}
+
+	assert.Equal(t, "Recorded expense of 1,200 JPY for lunch on 2025-10-15.\nSend /undo to remove it.", in(alice, "lunch 1200"))
+	in(bob, "taxi 800")
+	in(bob, "dinner 3000")
+	in(alice, "rent 85000 2025-09-30")
+	// Personal entries stay out of the group's ledger, and the other way round.
+	dispatch(t, m, "coffee 300", now)
+
+	assert.Equal(t, `Today the group spent 5,000 JPY.
+By member: Bob 3,800 JPY, Alice 1,200 JPY
+
+19:30 -3,000 JPY dinner (Bob)
+19:30 -800 JPY taxi (Bob)
+19:30 -1,200 JPY lunch (Alice)`, in(alice, "/today"))
+	assert.Equal(t, "Today you spent 300 JPY.\n\n19:30 -300 JPY coffee", dispatch(t, m, "/today", now))
+
+	assert.Equal(t, `October 2025
+Spent: 5,000 JPY
+Received: nothing
+Entries: 3
+
+By member
+Bob  3,800 JPY  76%
+Alice  1,200 JPY  24%`, in(bob, "/month"))
+
+	// Members who left are still named on their entries.
+	assert.NoError(t, groupDB.RemoveMember(ctx, group.ID, bob.ID))
+	assert.Equal(t, "10/15 -3,000 JPY dinner (Bob)\n10/15 -800 JPY taxi (Bob)", in(alice, "/last 2"))
+
+	// Undo only takes back the sender's own entry in the group.
+	assert.Equal(t, "Removed expense of 85,000 JPY for rent on 2025-09-30.", in(alice, "/undo"))
+	assert.Equal(t, "Removed expense of 1,200 JPY for lunch on 2025-10-15.", in(alice, "/undo"))
+	assert.Equal(t, "There is nothing to undo.", in(alice, "/undo"))
+	assert.Equal(t, "Removed expense of 300 JPY for coffee on 2025-10-15.", dispatch(t, m, "/undo", now))
+
+	assert.Equal(t, "That only works in a one-on-one chat with me.", in(alice, "/budgets"))
+	assert.Equal(t, "That only works in a one-on-one chat with me.", in(alice, "/currency USD"))
+}
+
+func TestCommands_GroupChatter(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 10, 15, 19, 30, 0, 0, time.UTC)
+	transactionDB := &fakeTransactionDB{}
+	m, groupDB, _ := newTestGroupMessaging(transactionDB)
+	m.textParser = parserFunc(func(context.Context, string, money.Currency, time.Time) (*parser.Entry, error) {
+		t.Fatal("group chatter was sent to the language model")
+		return nil, nil
+	})
+
+	group := &model.Group{LineGroupID: "G1", Status: model.GroupStatusActive}
+	assert.NoError(t, groupDB.Upsert(ctx, group))
+	assert.NoError(t, groupDB.AddMember(ctx, group.ID, 1, now))
+
+	replier := &recordingReplier{}
+	for _, text := range []string{"see you tonight", "who's bringing the cake?"} {
+		err := m.router.Dispatch(ctx, &Request{User: &usermodel.User{ID: 1}, Group: group, Text: text, Now: now, Reply: replier})
+		assert.NoError(t, err)
+	}
+	assert.Empty(t, replier.replies)
+	assert.Empty(t, transactionDB.transactions)
+}
+
+type parserFunc func(ctx context.Context, text string, currency money.Currency, now time.Time) (*parser.Entry, error)
+
+func (f parserFunc) Parse(ctx context.Context, text string, currency money.Currency, now time.Time) (*parser.Entry, error) {
+	return f(ctx, text, currency, now)
+}
@@ -0,0 +1,55 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github/shaolim/momon/internal/user/model"
+	"github/shaolim/momon/pkg/database"
+	"log/slog"
+
+	"github.com/line/line-bot-sdk-go/v8/linebot/webhook"
+)
+
+// handleFollow registers the user, or reactivates them with their latest
+// display name when they follow the bot again, and greets them.
+func (m *messaging) handleFollow(ctx context.Context, e webhook.FollowEvent) error {
+	if source, ok := e.Source.(webhook.UserSource); ok && m.userDB != nil {
+		profile, err := m.line.GetProfile(ctx, source.UserId)
+		if err != nil {
+			return fmt.Errorf("failed to get profile: %w", err)
+		}
+
+		user := &model.User{
+			LineUserID:  source.UserId,
+			DisplayName: profile.DisplayName,
+			Status:      model.UserStatusActive,
+		}
+		if err := m.userDB.Upsert(ctx, user); err != nil {
+			return fmt.Errorf("failed to save user: %w", err)
+		}
+
+		slog.Info("user followed", slog.Int64("user_id", user.ID))
+	}
+
+	return m.lineChat(e.Source, e.ReplyToken).replyText(ctx, "Hi thank you for following me!!")
+}
+
+// handleUnfollow deactivates the user. Their transactions are kept so that
+// following again restores their history.
+func (m *messaging) handleUnfollow(ctx context.Context, e webhook.UnfollowEvent) error {
+	source, ok := e.Source.(webhook.UserSource)
+	if !ok || m.userDB == nil {
+		return nil
+	}
+
+	if err := m.userDB.UpdateStatus(ctx, source.UserId, model.UserStatusInActive); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			slog.Info("unfollowed by unknown user", slog.String("line_user_id", source.UserId))
+			return nil
+		}
+		return fmt.Errorf("failed to deactivate user: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,186 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github/shaolim/momon/internal/receipt/model"
+	transactionmodel "github/shaolim/momon/internal/transaction/model"
+	usermodel "github/shaolim/momon/internal/user/model"
+	msg "github/shaolim/momon/pkg/messaging"
+	"github/shaolim/momon/pkg/money"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/line/line-bot-sdk-go/v8/linebot/webhook"
+)
+
+func (m *messaging) handleImageMessage(ctx context.Context, e webhook.MessageEvent, message webhook.ImageMessageContent) error {
+	c := m.lineChat(e.Source, e.ReplyToken)
+	if m.receipt == nil || m.transactionDB == nil {
+		return c.replyText(ctx, "Sorry, I can't read receipts right now.")
+	}
+
+	user, err := m.resolveUser(ctx, e.Source)
+	if err != nil {
+		return fmt.Errorf("failed to resolve user: %w", err)
+	}
+	if err := m.enterGroup(ctx, c, e.Source, user); err != nil {
+		return err
+	}
+
+	return m.readReceipt(ctx, c, user, message.Id)
+}
+
+// readReceipt reads the receipt photo with the given content ID and keeps it
+// as a draft until the user saves, edits or discards it.
+func (m *messaging) readReceipt(ctx context.Context, c *chat, user *usermodel.User, contentID string) error {
+	// Reading a receipt takes a while, so let the user know we are on it.
+	if err := c.messenger.ShowLoading(ctx, c.id, 60); err != nil {
+		slog.Warn("failed to show loading animation", slog.Any("error", err))
+	}
+
+	content, contentType, err := c.messenger.GetContent(ctx, contentID)
+	if err != nil {
+		return err
+	}
+
+	// Don't reply on failure: the reply token is single use and the event is
+	// retried. The user is told if all attempts fail.
+	r, err := m.receipt.Extract(ctx, content, contentType)
+	if err != nil {
+		return fmt.Errorf("failed to read receipt: %w", err)
+	}
+
+	if !r.IsValid {
+		return c.replyText(ctx, fmt.Sprintf("That doesn't look like a receipt I can read: %s", r.Message))
+	}
+
+	now := time.Now()
+	occurredAt, dateNote := receiptTime(r, m.location(user), now)
+	draft := draftFromReceipt(user.ID, r, receiptCurrency(r, m.currency(user)), occurredAt, now.Add(m.config.ReceiptDraftTTL))
+	if c.group != nil {
+		draft.GroupID = c.group.ID
+	}
+	draft.Category = m.categorize(ctx, user.ID, draftExpense(draft))
+	if err := m.transactionDB.AddDraft(ctx, draft); err != nil {
+		return fmt.Errorf("failed to save receipt draft: %w", err)
+	}
+
+	slog.Info("saved receipt draft", slog.Int64("draft_id", draft.ID), slog.Int64("user_id", user.ID))
+
+	var notes []string
+	if dateNote != "" {
+		notes = append(notes, dateNote)
+	}
+	discrepancies, err := r.Validate(model.DefaultTolerance)
+	if err != nil {
+		// The draft is saved already; the user can still check the total.
+		slog.Warn("failed to check receipt", slog.Int64("draft_id", draft.ID), slog.Any("error", err))
+	}
+	if len(discrepancies) > 0 {
+		slog.Warn("receipt does not add up", slog.Int64("draft_id", draft.ID), slog.Any("discrepancies", discrepancies))
+		notes = append(notes, discrepancySummary(r, discrepancies, draft.Currency))
+	}
+
+	title := fmt.Sprintf("Found %s. Save it?", describeReceipt(draft.Money(), draft.Merchant, draft.OccurredAt))
+	messages := []msg.Message{draftCard(draft, title, m.location(user))}
+	if len(notes) > 0 {
+		messages = append(messages, msg.TextMessage(strings.Join(notes, "\n\n")))
+	}
+	// LINE only shows the quick replies of the last message.
+	messages[len(messages)-1].QuickReplies = draftQuickReplies(draft.ID)
+
+	return replied(c.reply(ctx, messages...))
+}
+
+// receiptTime returns when the receipt was issued in the user's time zone. When
+// the date is missing or unusable it falls back to now and returns a note
+// telling the user so.
+func receiptTime(r *model.Receipt, loc *time.Location, now time.Time) (model.TransactionTime, string) {
+	now = now.In(loc)
+
+	date, err := r.TransactionTime(loc, now)
+	switch {
+	case err == nil:
+		return date, ""
+	case errors.Is(err, model.ErrFutureTransactionDate):
+		return model.TransactionTime{Time: now}, fmt.Sprintf("The receipt is dated %s, which is in the future, so I used today's date.", r.TransactionDate)
+	default:
+		slog.Warn("failed to parse receipt date", slog.String("date", r.TransactionDate), slog.Any("error", err))
+		return model.TransactionTime{Time: now}, "I couldn't read the date on the receipt, so I used today's date."
+	}
+}
+
+// draftFromReceipt converts an extracted receipt into a draft expense that
+// occurred at occurredAt.
+func draftFromReceipt(userID int64, r *model.Receipt, currency money.Currency, occurredAt model.TransactionTime, expiresAt time.Time) *transactionmodel.Draft {
+	items := make([]transactionmodel.Item, 0, len(r.Items))
+	for _, item := range r.Items {
+		items = append(items, transactionmodel.Item{
+			Name:       item.Name,
+			Quantity:   item.Quantity,
+			Price:      item.Price,
+			Tax:        item.Tax,
+			TotalPrice: item.TotalPrice,
+		})
+	}
+
+	return &transactionmodel.Draft{
+		UserID:      userID,
+		Amount:      r.Total,
+		Currency:    currency,
+		Merchant:    r.Shop,
+		OccurredAt:  occurredAt.Time,
+		TimeUnknown: occurredAt.TimeUnknown,
+		Tax:         r.Tax,
+		Items:       items,
+		Step:        transactionmodel.DraftStepReview,
+		ExpiresAt:   expiresAt,
+	}
+}
+
+// receiptCurrency is the currency printed on the receipt, or fallback when it
+// could not be told.
+func receiptCurrency(r *model.Receipt, fallback money.Currency) money.Currency {
+	if currency, err := money.ParseCurrency(r.Currency); err == nil {
+		return currency
+	}
+	return fallback
+}
+
+// describeReceipt reads like "1,200 JPY at Lawson on 2024-01-15", leaving out
+// the shop when the receipt has none.
+func describeReceipt(amount money.Money, merchant string, date time.Time) string {
+	if merchant == "" {
+		return fmt.Sprintf("%s on %s", amount, date.Format("2006-01-02"))
+	}
+	return fmt.Sprintf("%s at %s on %s", amount, merchant, date.Format("2006-01-02"))
+}
+
+// discrepancySummary explains to the user which numbers on the receipt do not
+// add up, e.g. "items add up to 2,310 JPY but total says 2,130 JPY".
+func discrepancySummary(r *model.Receipt, discrepancies []model.Discrepancy, currency money.Currency) string {
+	amount := func(v int64) money.Money {
+		return money.New(v, currency)
+	}
+
+	var b strings.Builder
+	b.WriteString("Some numbers don't add up:")
+	for _, d := range discrepancies {
+		switch d.Kind {
+		case model.DiscrepancyTotal:
+			fmt.Fprintf(&b, "\n- items add up to %s but total says %s", amount(d.Expected), amount(d.Actual))
+		case model.DiscrepancyTax:
+			fmt.Fprintf(&b, "\n- item taxes add up to %s but tax says %s", amount(d.Expected), amount(d.Actual))
+		case model.DiscrepancyItemTotal:
+			item := r.Items[d.Item]
+			fmt.Fprintf(&b, "\n- %s: %g x %s should be %s but the line says %s",
+				item.Name, item.Quantity, amount(item.Price), amount(d.Expected), amount(d.Actual))
+		}
+	}
+	b.WriteString("\n\nI used the total printed on the receipt. If it's wrong, tap Edit total before saving.")
+
+	return b.String()
+}
@@ -0,0 +1,265 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	jobmodel "github/shaolim/momon/internal/job/model"
+	"github/shaolim/momon/internal/receipt"
+	"github/shaolim/momon/internal/receipt/model"
+	transactionmodel "github/shaolim/momon/internal/transaction/model"
+	"github/shaolim/momon/pkg/messaging/messagingtest"
+	"github/shaolim/momon/pkg/money"
+	"testing"
+	"time"
+
+	"github.com/line/line-bot-sdk-go/v8/linebot/webhook"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDraftFromReceipt(t *testing.T) {
+	t.Run("valid receipt", func(t *testing.T) {
+		r := &model.Receipt{
+			Shop:            "Grocery Market",
+			TransactionDate: "2024-02-20 09:15",
+			Items: []model.Item{
+				{Name: "Bread", Quantity: 1, Price: 50, Tax: 5, TotalPrice: 55},
+				{Name: "Milk", Quantity: 2, Price: 80, Tax: 16, TotalPrice: 176},
+			},
+			Tax:     21,
+			Total:   231,
+			IsValid: true,
+		}
+
+		occurredAt := time.Date(2024, 2, 20, 9, 15, 0, 0, time.UTC)
+		expiresAt := occurredAt.Add(24 * time.Hour)
+		got := draftFromReceipt(7, r, "JPY", model.TransactionTime{Time: occurredAt, TimeUnknown: true}, expiresAt)
+
+		assert.Equal(t, int64(7), got.UserID)
+		assert.Equal(t, int64(231), got.Amount)
+		assert.Equal(t, money.Currency("JPY"), got.Currency)
+		assert.Equal(t, transactionmodel.DraftStep(transactionmodel.DraftStepReview), got.Step)
+		assert.Equal(t, "Grocery Market", got.Merchant)
+		assert.Equal(t, occurredAt, got.OccurredAt)
+		assert.True(t, got.TimeUnknown)
+		assert.Equal(t, expiresAt, got.ExpiresAt)
+		assert.Equal(t, int64(21), got.Tax)
+		assert.Len(t, got.Items, 2)
+		assert.Equal(t, int64(176), got.Items[1].TotalPrice)
+		assert.NoError(t, got.Validate())
+	})
+
+	t.Run("no items", func(t *testing.T) {
+		r := &model.Receipt{Shop: "Lawson", Total: 1200, IsValid: true}
+
+		got := draftFromReceipt(7, r, "JPY", model.TransactionTime{Time: time.Now()}, time.Now().Add(time.Hour))
+
+		assert.Empty(t, got.Items)
+	})
+}
+
+func TestReceiptCurrency(t *testing.T) {
+	assert.Equal(t, money.Currency("USD"), receiptCurrency(&model.Receipt{Currency: "usd"}, "JPY"))
+	assert.Equal(t, money.Currency("JPY"), receiptCurrency(&model.Receipt{}, "JPY"))
+	assert.Equal(t, money.Currency("JPY"), receiptCurrency(&model.Receipt{Currency: "$"}, "JPY"))
+}
+
+func TestReceiptTime(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+	// 00:30 on Oct 16 in Tokyo, still Oct 15 on a UTC server.
+	now := time.Date(2025, 10, 15, 15, 30, 0, 0, time.UTC)
+
+	t.Run("receipt date in the user's time zone", func(t *testing.T) {
+		got, note := receiptTime(&model.Receipt{TransactionDate: "2025-10-16 00:10"}, tokyo, now)
+
+		assert.Equal(t, time.Date(2025, 10, 16, 0, 10, 0, 0, tokyo), got.Time)
+		assert.False(t, got.TimeUnknown)
+		assert.Empty(t, note)
+	})
+
+	t.Run("date without a time", func(t *testing.T) {
+		got, note := receiptTime(&model.Receipt{TransactionDate: "2025-10-15"}, tokyo, now)
+
+		assert.Equal(t, time.Date(2025, 10, 15, 12, 0, 0, 0, tokyo), got.Time)
+		assert.True(t, got.TimeUnknown)
+		assert.Empty(t, note)
+	})
+
+	t.Run("unparsable date falls back to now", func(t *testing.T) {
+		got, note := receiptTime(&model.Receipt{TransactionDate: "yesterday"}, tokyo, now)
+
+		assert.True(t, now.Equal(got.Time))
+		assert.False(t, got.TimeUnknown)
+		assert.Equal(t, tokyo, got.Time.Location())
+		assert.Equal(t, "I couldn't read the date on the receipt, so I used today's date.", note)
+	})
+
+	t.Run("future date falls back to now", func(t *testing.T) {
+		got, note := receiptTime(&model.Receipt{TransactionDate: "2052-10-16 00:10"}, tokyo, now)
+
+		assert.True(t, now.Equal(got.Time))
+		assert.Equal(t, "The receipt is dated 2052-10-16 00:10, which is in the future, so I used today's date.", note)
+	})
+}
+
+func TestDescribeReceipt(t *testing.T) {
+	date := time.Date(2024, 1, 15, 14, 30, 0, 0, time.UTC)
+
+	assert.Equal(t, "2,310 JPY at Lawson on 2024-01-15", describeReceipt(money.New(2310, "JPY"), "Lawson", date))
+	assert.Equal(t, "2,310 JPY on 2024-01-15", describeReceipt(money.New(2310, "JPY"), "", date))
+}
+
+func TestDiscrepancySummary(t *testing.T) {
+	r := &model.Receipt{
+		Items: []model.Item{
+			{Name: "Bento", Quantity: 2, Price: 1155, TotalPrice: 2310},
+			{Name: "Tea", Quantity: 1, Price: 150, TotalPrice: 160},
+		},
+		Tax:     0,
+		Total:   2130,
+		IsValid: true,
+	}
+
+	discrepancies, err := r.Validate(model.DefaultTolerance)
+	assert.NoError(t, err)
+	got := discrepancySummary(r, discrepancies, "JPY")
+
+	want := "Some numbers don't add up:" +
+		"\n- Tea: 1 x 150 JPY should be 150 JPY but the line says 160 JPY" +
+		"\n- items add up to 2,470 JPY but total says 2,130 JPY" +
+		"\n\nI used the total printed on the receipt. If it's wrong, tap Edit total before saving."
+	assert.Equal(t, want, got)
+}
+
+func TestHandleImageMessage(t *testing.T) {
+	ctx := context.Background()
+	event := webhook.MessageEvent{
+		Source:     webhook.UserSource{UserId: "U1"},
+		ReplyToken: "reply-token",
+	}
+	image := webhook.ImageMessageContent{Id: "image-1"}
+	fixture := &model.Receipt{
+		Shop:            "Lawson",
+		TransactionDate: "2024-01-15 14:30",
+		Total:           1200,
+		IsValid:         true,
+	}
+
+	setup := func(extractor receipt.ReceiptExtractor) (*messaging, *messagingtest.Messenger, *fakeTransactionDB) {
+		transactionDB := &fakeTransactionDB{}
+		messenger := messagingtest.NewMessenger()
+		messenger.SetContent("image-1", []byte("jpeg bytes"), "image/jpeg")
+
+		m := newTestMessaging(transactionDB)
+		m.line = messenger
+		m.receipt = extractor
+		return m, messenger, transactionDB
+	}
+
+	t.Run("keeps the receipt as a draft", func(t *testing.T) {
+		m, messenger, transactionDB := setup(receipt.NewFixture(fixture))
+
+		assert.NoError(t, m.handleImageMessage(ctx, event, image))
+
+		assert.Equal(t, []string{"Found 1,200 JPY at Lawson on 2024-01-15. Save it?"}, messenger.ReplyTexts())
+		reply := messenger.Replies()[0]
+		assert.NotNil(t, reply.Messages[0].Flex)
+		assert.Equal(t, "reply-token", reply.ReplyToken)
+		assert.Equal(t, []string{"U1"}, messenger.Loading())
+
+		assert.Empty(t, transactionDB.transactions)
+		if assert.Len(t, transactionDB.drafts, 1) {
+			draft := transactionDB.drafts[0]
+			assert.Equal(t, int64(1200), draft.Amount)
+			assert.Equal(t, draftQuickReplies(draft.ID), reply.Messages[0].QuickReplies)
+		}
+	})
+
+	t.Run("quick replies go on the last message", func(t *testing.T) {
+		undated := *fixture
+		undated.TransactionDate = ""
+		m, messenger, transactionDB := setup(receipt.NewFixture(&undated))
+
+		assert.NoError(t, m.handleImageMessage(ctx, event, image))
+
+		messages := messenger.Replies()[0].Messages
+		if assert.Len(t, messages, 2) {
+			assert.Empty(t, messages[0].QuickReplies)
+			assert.Equal(t, draftQuickReplies(transactionDB.drafts[0].ID), messages[1].QuickReplies)
+		}
+	})
+
+	t.Run("failed extraction is retried without replying", func(t *testing.T) {
+		m, messenger, transactionDB := setup(receipt.NewFailingFixture(errors.New("model overloaded")))
+
+		assert.Error(t, m.handleImageMessage(ctx, event, image))
+
+		assert.Empty(t, messenger.Replies())
+		assert.Empty(t, transactionDB.drafts)
+	})
+
+	t.Run("expired reply token falls back to a push", func(t *testing.T) {
+		m, messenger, transactionDB := setup(receipt.NewFixture(fixture))
+		messenger.FailRepliesWith(errors.New("invalid reply token"))
+
+		assert.NoError(t, m.handleImageMessage(ctx, event, image))
+
+		pushes := messenger.Pushes()
+		if assert.Len(t, pushes, 1) && assert.Len(t, transactionDB.drafts, 1) {
+			assert.Equal(t, "U1", pushes[0].To)
+			assert.Equal(t, draftQuickReplies(transactionDB.drafts[0].ID), pushes[0].Messages[0].QuickReplies)
+		}
+	})
+
+	t.Run("a retried job pushes the draft", func(t *testing.T) {
+		failed := false
+		m, messenger, transactionDB := setup(nil)
+		m.eventDB = &fakeEventDB{}
+		m.receipt = extractorFunc(func(ctx context.Context, image []byte, contentType string) (*model.Receipt, error) {
+			if !failed {
+				failed = true
+				return nil, errors.New("model overloaded")
+			}
+			return receipt.NewFixture(fixture).Extract(ctx, image, contentType)
+		})
+		job := &jobmodel.Job{
+			EventID:     "01IMAGE",
+			Payload:     []byte(`{"type":"message","webhookEventId":"01IMAGE","replyToken":"reply-token","source":{"type":"user","userId":"U1"},"timestamp":0,"mode":"active","deliveryContext":{"isRedelivery":false},"message":{"type":"image","id":"image-1","contentProvider":{"type":"line"}}}`),
+			LockedUntil: time.Now().Add(time.Minute),
+		}
+
+		job.Attempts = 1
+		assert.Error(t, m.handleJob(ctx, job))
+		job.Attempts = 2
+		assert.NoError(t, m.handleJob(ctx, job))
+
+		// The reply token expired while the job waited for its retry.
+		assert.Empty(t, messenger.Replies())
+		pushes := messenger.Pushes()
+		if assert.Len(t, pushes, 1) && assert.Len(t, transactionDB.drafts, 1) {
+			assert.Equal(t, "U1", pushes[0].To)
+			assert.NotNil(t, pushes[0].Messages[0].Flex)
+			assert.Equal(t, draftQuickReplies(transactionDB.drafts[0].ID), pushes[0].Messages[0].QuickReplies)
+		}
+	})
+
+	t.Run("failed reply after saving is not retried", func(t *testing.T) {
+		m, messenger, transactionDB := setup(nil)
+		// Replies fail from the moment the receipt has been read.
+		m.receipt = extractorFunc(func(ctx context.Context, image []byte, contentType string) (*model.Receipt, error) {
+			messenger.FailWith(errors.New("invalid reply token"))
+			return receipt.NewFixture(fixture).Extract(ctx, image, contentType)
+		})
+
+		assert.NoError(t, m.handleImageMessage(ctx, event, image))
+		assert.Len(t, transactionDB.drafts, 1)
+	})
+}
+
+type extractorFunc func(ctx context.Context, image []byte, contentType string) (*model.Receipt, error)
+
+func (f extractorFunc) Extract(ctx context.Context, image []byte, contentType string) (*model.Receipt, error) {
+	return f(ctx, image, contentType)
+}
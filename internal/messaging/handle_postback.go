@@ -0,0 +1,244 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github/shaolim/momon/internal/category"
+	categorymodel "github/shaolim/momon/internal/category/model"
+	"github/shaolim/momon/internal/messaging/flex"
+	receiptmodel "github/shaolim/momon/internal/receipt/model"
+	"github/shaolim/momon/internal/transaction/model"
+	usermodel "github/shaolim/momon/internal/user/model"
+	"github/shaolim/momon/pkg/database"
+	msg "github/shaolim/momon/pkg/messaging"
+	"github/shaolim/momon/pkg/money"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/line/line-bot-sdk-go/v8/linebot/webhook"
+)
+
+// Actions offered as quick replies on a receipt draft. They come back as
+// postback data like "action=save&draft=42".
+const (
+	draftActionSave           = "save"
+	draftActionEditTotal      = "edit_total"
+	draftActionChangeCategory = "change_category"
+	draftActionDiscard        = "discard"
+)
+
+// draftInputTimeout is how long after tapping Edit total or Change category
+// the next text message is taken as the answer. Later messages are recorded
+// as usual, so a forgotten draft doesn't swallow them.
+const draftInputTimeout = 10 * time.Minute
+
+const draftGoneText = "That receipt was saved, discarded or has expired. Send the photo again to start over."
+
+func draftPostback(action string, id int64) string {
+	return url.Values{
+		"action": {action},
+		"draft":  {strconv.FormatInt(id, 10)},
+	}.Encode()
+}
+
+func draftQuickReplies(id int64) []msg.QuickReply {
+	return []msg.QuickReply{
+		{Label: "Save", Data: draftPostback(draftActionSave, id)},
+		{Label: "Edit total", Data: draftPostback(draftActionEditTotal, id)},
+		{Label: "Change category", Data: draftPostback(draftActionChangeCategory, id)},
+		{Label: "Discard", Data: draftPostback(draftActionDiscard, id)},
+	}
+}
+
+// draftExpense is what the draft is categorized by.
+func draftExpense(d *model.Draft) *category.Expense {
+	items := make([]string, 0, len(d.Items))
+	for _, item := range d.Items {
+		items = append(items, item.Name)
+	}
+	return &category.Expense{Merchant: d.Merchant, Items: items}
+}
+
+// draftCard shows the draft as a receipt card headed by title.
+func draftCard(d *model.Draft, title string, loc *time.Location) msg.Message {
+	items := make([]receiptmodel.Item, 0, len(d.Items))
+	for _, item := range d.Items {
+		items = append(items, receiptmodel.Item{
+			Name:       item.Name,
+			Quantity:   item.Quantity,
+			Price:      item.Price,
+			Tax:        item.Tax,
+			TotalPrice: item.TotalPrice,
+		})
+	}
+
+	return flex.Receipt(&flex.ReceiptCard{
+		Title:       title,
+		Receipt:     &receiptmodel.Receipt{Shop: d.Merchant, Items: items, Tax: d.Tax, Total: d.Amount},
+		Currency:    d.Currency,
+		Date:        d.OccurredAt.In(loc),
+		TimeUnknown: d.TimeUnknown,
+		Category:    d.Category,
+	})
+}
+
+func (m *messaging) handlePostbackEvent(ctx context.Context, e webhook.PostbackEvent) error {
+	c := m.lineChat(e.Source, e.ReplyToken)
+	if m.transactionDB == nil {
+		return c.replyText(ctx, "Sorry, I can't record transactions right now.")
+	}
+	if e.Postback == nil {
+		return nil
+	}
+
+	user, err := m.resolveUser(ctx, e.Source)
+	if err != nil {
+		return fmt.Errorf("failed to resolve user: %w", err)
+	}
+
+	return m.handlePostback(ctx, c, user, e.Postback.Data)
+}
+
+// handlePostback runs the action the user tapped on a receipt draft from any
+// channel.
+func (m *messaging) handlePostback(ctx context.Context, c *chat, user *usermodel.User, data string) error {
+	values, err := url.ParseQuery(data)
+	if err != nil {
+		slog.Warn("invalid postback", slog.String("data", data), slog.Any("error", err))
+		return nil
+	}
+	id, err := strconv.ParseInt(values.Get("draft"), 10, 64)
+	if err != nil {
+		slog.Warn("invalid postback", slog.String("data", data), slog.Any("error", err))
+		return nil
+	}
+
+	now := time.Now()
+	draft, err := m.transactionDB.GetDraft(ctx, id)
+	if errors.Is(err, database.ErrNotFound) {
+		return c.replyText(ctx, draftGoneText)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get draft: %w", err)
+	}
+	if draft.UserID != user.ID || !draft.ExpiresAt.After(now) {
+		return c.replyText(ctx, draftGoneText)
+	}
+
+	switch action := values.Get("action"); action {
+	case draftActionSave:
+		// Saving deletes the draft, so tapping Save twice saves it once.
+		transaction, err := m.transactionDB.SaveDraft(ctx, id, now)
+		if errors.Is(err, database.ErrNotFound) {
+			return c.replyText(ctx, draftGoneText)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to save draft: %w", err)
+		}
+
+		slog.Info("saved receipt", slog.Int64("transaction_id", transaction.ID), slog.Int64("user_id", user.ID))
+
+		loc := m.location(user)
+		receipt := describeReceipt(transaction.Money(), transaction.Merchant, transaction.OccurredAt.In(loc))
+		err = c.replyText(ctx, fmt.Sprintf("Saved %s.\nSend /undo to remove it.", receipt))
+		m.checkBudgets(ctx, user, transaction, now.In(loc))
+		return replied(err)
+	case draftActionEditTotal:
+		draft.Step = model.DraftStepTotal
+		if err := m.transactionDB.UpdateDraft(ctx, draft); err != nil {
+			return fmt.Errorf("failed to update draft: %w", err)
+		}
+		return replied(c.replyText(ctx, fmt.Sprintf("What's the correct total? It's %s now.", draft.Money())))
+	case draftActionChangeCategory:
+		draft.Step = model.DraftStepCategory
+		if err := m.transactionDB.UpdateDraft(ctx, draft); err != nil {
+			return fmt.Errorf("failed to update draft: %w", err)
+		}
+		return replied(c.replyText(ctx, m.categoryQuestion(ctx, user)))
+	case draftActionDiscard:
+		err := m.transactionDB.DeleteDraft(ctx, id)
+		if errors.Is(err, database.ErrNotFound) {
+			return c.replyText(ctx, draftGoneText)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to delete draft: %w", err)
+		}
+		return replied(c.replyText(ctx, "Discarded the receipt. Nothing was saved."))
+	default:
+		slog.Warn("unknown postback action", slog.String("action", action))
+		return nil
+	}
+}
+
+// answerDraft takes text as the total or category the user's draft waits for
+// and shows the draft again. It returns false when no draft is waiting.
+func (m *messaging) answerDraft(ctx context.Context, c *chat, user *usermodel.User, text string, now time.Time) (bool, error) {
+	draft, err := m.transactionDB.GetPendingDraft(ctx, user.ID, now.Add(-draftInputTimeout))
+	if errors.Is(err, database.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get pending draft: %w", err)
+	}
+	if !draft.ExpiresAt.After(now) {
+		return false, nil
+	}
+
+	text = strings.TrimSpace(text)
+	switch draft.Step {
+	case model.DraftStepTotal:
+		amount, err := money.Parse(text, draft.Currency)
+		if err != nil || amount.Amount <= 0 {
+			return true, c.replyText(ctx, fmt.Sprintf("Please send the total as a number, e.g. %s.", draft.Money().Major()))
+		}
+		draft.Amount = amount.Amount
+	case model.DraftStepCategory:
+		if text == "" || utf8.RuneCountInString(text) > categorymodel.MaxNameLength {
+			return true, c.replyText(ctx, fmt.Sprintf("Please send a category of up to %d characters.", categorymodel.MaxNameLength))
+		}
+		draft.Category = text
+		if m.categorizer != nil {
+			// Receipts from the same shop go to the same category next time.
+			if draft.Category, err = m.categorizer.Learn(ctx, user.ID, draftExpense(draft), text); err != nil {
+				return true, fmt.Errorf("failed to learn category: %w", err)
+			}
+		}
+	}
+
+	draft.Step = model.DraftStepReview
+	if err := m.transactionDB.UpdateDraft(ctx, draft); err != nil {
+		return true, fmt.Errorf("failed to update draft: %w", err)
+	}
+
+	title := fmt.Sprintf("Updated: %s. Save it?", describeReceipt(draft.Money(), draft.Merchant, draft.OccurredAt.In(m.location(user))))
+	card := draftCard(draft, title, m.location(user))
+	card.QuickReplies = draftQuickReplies(draft.ID)
+
+	return true, replied(c.reply(ctx, card))
+}
+
+// categoryQuestion asks which category a draft belongs to, listing the user's
+// categories when they can be read.
+func (m *messaging) categoryQuestion(ctx context.Context, user *usermodel.User) string {
+	const question = "Which category is it? Send a name, e.g. Groceries."
+	if m.categorizer == nil {
+		return question
+	}
+
+	categories, err := m.categorizer.Categories(ctx, user.ID)
+	if err != nil {
+		slog.Warn("failed to list categories", slog.Any("error", err))
+		return question
+	}
+
+	names := make([]string, 0, len(categories))
+	for _, c := range categories {
+		names = append(names, c.Name)
+	}
+	return fmt.Sprintf("Which category is it? Send one of %s, or a new name.", strings.Join(names, ", "))
+}
@@ -0,0 +1,256 @@
+package messaging
+
+import (
+	"context"
+	"github/shaolim/momon/internal/category"
+	"github/shaolim/momon/internal/category/categorytest"
+	receiptmodel "github/shaolim/momon/internal/receipt/model"
+	"github/shaolim/momon/internal/transaction/model"
+	usermodel "github/shaolim/momon/internal/user/model"
+	msg "github/shaolim/momon/pkg/messaging"
+	"github/shaolim/momon/pkg/messaging/messagingtest"
+	"testing"
+	"time"
+
+	"github.com/line/line-bot-sdk-go/v8/linebot/webhook"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandlePostback(t *testing.T) {
+	ctx := context.Background()
+	user := &usermodel.User{ID: 1}
+
+	setup := func(t *testing.T) (*messaging, *chat, *messagingtest.Messenger, *fakeTransactionDB, *model.Draft) {
+		t.Helper()
+
+		transactionDB := &fakeTransactionDB{}
+		messenger := messagingtest.NewMessenger()
+		m := newTestMessaging(transactionDB)
+		m.line = messenger
+
+		draft := &model.Draft{
+			UserID:     1,
+			Amount:     1200,
+			Currency:   "JPY",
+			Merchant:   "Lawson",
+			OccurredAt: time.Date(2024, 1, 15, 14, 30, 0, 0, time.UTC),
+			Items:      []model.Item{{Name: "Bento", Quantity: 1, Price: 1200, TotalPrice: 1200}},
+			Step:       model.DraftStepReview,
+			ExpiresAt:  time.Now().Add(time.Hour),
+		}
+		if err := transactionDB.AddDraft(ctx, draft); err != nil {
+			t.Fatalf("failed to add draft: %v", err)
+		}
+
+		c := &chat{messenger: messenger, id: "U1", replyToken: "reply-token"}
+		return m, c, messenger, transactionDB, draft
+	}
+
+	t.Run("save", func(t *testing.T) {
+		m, c, messenger, transactionDB, draft := setup(t)
+
+		assert.NoError(t, m.handlePostback(ctx, c, user, draftPostback(draftActionSave, draft.ID)))
+
+		assert.Equal(t, []string{"Saved 1,200 JPY at Lawson on 2024-01-15.\nSend /undo to remove it."}, messenger.ReplyTexts())
+		assert.Empty(t, transactionDB.drafts)
+		if assert.Len(t, transactionDB.transactions, 1) {
+			saved := transactionDB.transactions[0]
+			assert.Equal(t, int64(1200), saved.Amount)
+			assert.Equal(t, model.TransactionSource(model.TransactionSourceReceipt), saved.Source)
+			assert.Len(t, saved.Items, 1)
+		}
+	})
+
+	t.Run("saving twice saves once", func(t *testing.T) {
+		m, c, messenger, transactionDB, draft := setup(t)
+
+		assert.NoError(t, m.handlePostback(ctx, c, user, draftPostback(draftActionSave, draft.ID)))
+		assert.NoError(t, m.handlePostback(ctx, c, user, draftPostback(draftActionSave, draft.ID)))
+
+		assert.Len(t, transactionDB.transactions, 1)
+		assert.Equal(t, draftGoneText, messenger.ReplyTexts()[1])
+	})
+
+	t.Run("expired draft", func(t *testing.T) {
+		m, c, messenger, transactionDB, draft := setup(t)
+		transactionDB.drafts[0].ExpiresAt = time.Now().Add(-time.Minute)
+
+		assert.NoError(t, m.handlePostback(ctx, c, user, draftPostback(draftActionSave, draft.ID)))
+
+		assert.Equal(t, []string{draftGoneText}, messenger.ReplyTexts())
+		assert.Empty(t, transactionDB.transactions)
+	})
+
+	t.Run("someone else's draft", func(t *testing.T) {
+		m, c, messenger, transactionDB, draft := setup(t)
+
+		assert.NoError(t, m.handlePostback(ctx, c, &usermodel.User{ID: 2}, draftPostback(draftActionDiscard, draft.ID)))
+
+		assert.Equal(t, []string{draftGoneText}, messenger.ReplyTexts())
+		assert.Len(t, transactionDB.drafts, 1)
+	})
+
+	t.Run("discard", func(t *testing.T) {
+		m, c, messenger, transactionDB, draft := setup(t)
+
+		assert.NoError(t, m.handlePostback(ctx, c, user, draftPostback(draftActionDiscard, draft.ID)))
+
+		assert.Equal(t, []string{"Discarded the receipt. Nothing was saved."}, messenger.ReplyTexts())
+		assert.Empty(t, transactionDB.drafts)
+		assert.Empty(t, transactionDB.transactions)
+	})
+
+	t.Run("edit total", func(t *testing.T) {
+		m, c, messenger, transactionDB, draft := setup(t)
+
+		assert.NoError(t, m.handlePostback(ctx, c, user, draftPostback(draftActionEditTotal, draft.ID)))
+		assert.Equal(t, []string{"What's the correct total? It's 1,200 JPY now."}, messenger.ReplyTexts())
+
+		// Invalid answers are asked again.
+		assert.NoError(t, m.dispatchText(ctx, c, user, nil, "twelve"))
+		assert.Equal(t, "Please send the total as a number, e.g. 1,200.", messenger.ReplyTexts()[1])
+
+		assert.NoError(t, m.dispatchText(ctx, c, user, nil, "1,350"))
+
+		assert.Equal(t, "Updated: 1,350 JPY at Lawson on 2024-01-15. Save it?\n\nBento x1  1,200 JPY", messenger.ReplyTexts()[2])
+		reply := messenger.Replies()[2].Messages[0]
+		assert.NotNil(t, reply.Flex)
+		assert.Equal(t, draftQuickReplies(draft.ID), reply.QuickReplies)
+
+		// The answer isn't recorded as an entry.
+		assert.Empty(t, transactionDB.transactions)
+		assert.Equal(t, int64(1350), transactionDB.drafts[0].Amount)
+		assert.Equal(t, model.DraftStep(model.DraftStepReview), transactionDB.drafts[0].Step)
+	})
+
+	t.Run("change category", func(t *testing.T) {
+		m, c, messenger, transactionDB, draft := setup(t)
+
+		assert.NoError(t, m.handlePostback(ctx, c, user, draftPostback(draftActionChangeCategory, draft.ID)))
+		assert.NoError(t, m.dispatchText(ctx, c, user, nil, " groceries "))
+
+		assert.Equal(t, "Updated: 1,200 JPY at Lawson on 2024-01-15. Save it?\nCategory: groceries\n\nBento x1  1,200 JPY", messenger.ReplyTexts()[1])
+
+		assert.NoError(t, m.handlePostback(ctx, c, user, draftPostback(draftActionSave, draft.ID)))
+		if assert.Len(t, transactionDB.transactions, 1) {
+			assert.Equal(t, "groceries", transactionDB.transactions[0].Category)
+		}
+	})
+
+	t.Run("change category learns the merchant", func(t *testing.T) {
+		m, c, messenger, transactionDB, draft := setup(t)
+		categoryDB := categorytest.NewDB()
+		m.categorizer = category.NewCategorizer(categoryDB, nil)
+
+		assert.NoError(t, m.handlePostback(ctx, c, user, draftPostback(draftActionChangeCategory, draft.ID)))
+		assert.Contains(t, messenger.ReplyTexts()[0], "Send one of Food, Groceries, Transport,")
+
+		assert.NoError(t, m.dispatchText(ctx, c, user, nil, "food"))
+		assert.Equal(t, "Food", transactionDB.drafts[0].Category)
+
+		// The next receipt from Lawson is filed the same way.
+		got, err := m.categorizer.Categorize(ctx, user.ID, &category.Expense{Merchant: "LAWSON"})
+		assert.NoError(t, err)
+		assert.Equal(t, "Food", got)
+	})
+
+	t.Run("commands are not taken as answers", func(t *testing.T) {
+		m, c, messenger, transactionDB, draft := setup(t)
+
+		assert.NoError(t, m.handlePostback(ctx, c, user, draftPostback(draftActionEditTotal, draft.ID)))
+		assert.NoError(t, m.dispatchText(ctx, c, user, nil, "/help"))
+
+		assert.Contains(t, messenger.ReplyTexts()[1], "/undo")
+		assert.Equal(t, model.DraftStep(model.DraftStepTotal), transactionDB.drafts[0].Step)
+	})
+
+	t.Run("stale questions are not answered", func(t *testing.T) {
+		m, c, _, transactionDB, draft := setup(t)
+
+		assert.NoError(t, m.handlePostback(ctx, c, user, draftPostback(draftActionEditTotal, draft.ID)))
+		transactionDB.drafts[0].UpdatedAt = time.Now().Add(-draftInputTimeout - time.Minute)
+
+		assert.NoError(t, m.dispatchText(ctx, c, user, nil, "lunch 800"))
+
+		assert.Equal(t, int64(1200), transactionDB.drafts[0].Amount)
+		if assert.Len(t, transactionDB.transactions, 1) {
+			assert.Equal(t, int64(800), transactionDB.transactions[0].Amount)
+		}
+	})
+
+	t.Run("unknown postback is ignored", func(t *testing.T) {
+		m, c, messenger, _, _ := setup(t)
+
+		assert.NoError(t, m.handlePostback(ctx, c, user, "richmenu=1"))
+
+		assert.Empty(t, messenger.Replies())
+	})
+}
+
+func TestHandlePostbackEvent(t *testing.T) {
+	transactionDB := &fakeTransactionDB{}
+	messenger := messagingtest.NewMessenger()
+	m := newTestMessaging(transactionDB)
+	m.line = messenger
+
+	draft := draftFromReceipt(1, &receiptmodel.Receipt{Shop: "Lawson", Total: 1200, IsValid: true}, "JPY", receiptmodel.TransactionTime{Time: time.Now()}, time.Now().Add(time.Hour))
+	if err := transactionDB.AddDraft(context.Background(), draft); err != nil {
+		t.Fatalf("failed to add draft: %v", err)
+	}
+
+	event := webhook.PostbackEvent{
+		Source:         webhook.UserSource{UserId: "U1"},
+		ReplyToken:     "reply-token",
+		WebhookEventId: "event-1",
+		Postback:       &webhook.PostbackContent{Data: draftPostback(draftActionSave, draft.ID)},
+	}
+
+	eventID, _ := eventDelivery(event)
+	assert.Equal(t, "event-1", eventID)
+
+	assert.NoError(t, m.handleEvent(context.Background(), event))
+
+	assert.Len(t, transactionDB.transactions, 1)
+	if assert.Len(t, messenger.Replies(), 1) {
+		assert.Equal(t, "reply-token", messenger.Replies()[0].ReplyToken)
+	}
+}
+
+func TestHandleTelegramCallbackQuery(t *testing.T) {
+	ctx := context.Background()
+	transactionDB := &fakeTransactionDB{}
+	telegram := messagingtest.NewMessenger()
+	m := newTestMessaging(transactionDB)
+	m.telegram = telegram
+
+	// A receipt photo registers the Telegram user and leaves a draft.
+	userDB := m.userDB.(*fakeUserDB)
+	user := &usermodel.User{DisplayName: "Aiko", Status: usermodel.UserStatusActive}
+	identity := &usermodel.Identity{Channel: usermodel.ChannelTelegram, ExternalID: "7"}
+	if err := userDB.AddUserWithIdentity(ctx, user, identity); err != nil {
+		t.Fatalf("failed to add user: %v", err)
+	}
+	draft := draftFromReceipt(user.ID, &receiptmodel.Receipt{Shop: "Lawson", Total: 1200, IsValid: true}, "JPY", receiptmodel.TransactionTime{Time: time.Now()}, time.Now().Add(time.Hour))
+	if err := transactionDB.AddDraft(ctx, draft); err != nil {
+		t.Fatalf("failed to add draft: %v", err)
+	}
+
+	update := &msg.TelegramUpdate{
+		UpdateID: 2,
+		CallbackQuery: &msg.TelegramCallbackQuery{
+			ID:      "query-1",
+			From:    &msg.TelegramUser{ID: 7, FirstName: "Aiko"},
+			Message: &msg.TelegramMessage{MessageID: 5, Chat: msg.TelegramChat{ID: 7, Type: "private"}},
+			Data:    draftPostback(draftActionSave, draft.ID),
+		},
+	}
+
+	assert.NoError(t, m.handleTelegramUpdate(ctx, update))
+
+	if assert.Len(t, transactionDB.transactions, 1) {
+		assert.Equal(t, user.ID, transactionDB.transactions[0].UserID)
+	}
+	if assert.Len(t, telegram.Replies(), 1) {
+		assert.Equal(t, "7:5", telegram.Replies()[0].ReplyToken)
+	}
+}
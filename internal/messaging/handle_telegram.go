@@ -0,0 +1,218 @@
+package messaging
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	jobmodel "github/shaolim/momon/internal/job/model"
+	usermodel "github/shaolim/momon/internal/user/model"
+	"github/shaolim/momon/pkg/database"
+	msg "github/shaolim/momon/pkg/messaging"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// telegramEventPrefix sets Telegram updates apart from LINE events in the
+// job queue and the processed-events store.
+const telegramEventPrefix = "telegram:"
+
+func telegramEventID(update *msg.TelegramUpdate) string {
+	return telegramEventPrefix + strconv.FormatInt(update.UpdateID, 10)
+}
+
+// TelegramCallback receives updates from the Telegram Bot API and feeds them
+// into the same pipeline as LINE events.
+func (m *messaging) TelegramCallback(w http.ResponseWriter, r *http.Request) {
+	secret := m.config.Messaging.MessagingConfig().TelegramWebhookSecret
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Telegram-Bot-Api-Secret-Token")), []byte(secret)) != 1 {
+		slog.Error("invalid telegram webhook secret")
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Error reading request body", http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	var update msg.TelegramUpdate
+	if err := json.Unmarshal(body, &update); err != nil {
+		slog.Error("failed to unmarshal telegram update", slog.Any("error", err))
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if m.queue != nil {
+		// Telegram resends updates we fail; the update ID deduplicates them.
+		inserted, err := m.queue.Enqueue(r.Context(), telegramEventID(&update), body)
+		if err != nil {
+			slog.Error("failed to enqueue telegram update", slog.Any("error", err))
+			http.Error(w, "Error enqueuing update", http.StatusInternalServerError)
+			return
+		}
+		if !inserted {
+			slog.Info("skipped duplicate telegram update", slog.Int64("update_id", update.UpdateID))
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	m.process(func(ctx context.Context) {
+		if err := m.processTelegramUpdate(ctx, &update, time.Now().Add(eventLease)); err != nil {
+			slog.Error("failed to process telegram update", slog.Any("error", err))
+		}
+	})
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (m *messaging) handleTelegramJob(ctx context.Context, j *jobmodel.Job) error {
+	var update msg.TelegramUpdate
+	if err := json.Unmarshal(j.Payload, &update); err != nil {
+		return fmt.Errorf("failed to unmarshal telegram update: %w", err)
+	}
+
+	return m.processTelegramUpdate(ctx, &update, j.LockedUntil)
+}
+
+func (m *messaging) handleTelegramDeadLetter(ctx context.Context, j *jobmodel.Job) {
+	var update msg.TelegramUpdate
+	if err := json.Unmarshal(j.Payload, &update); err != nil || m.telegram == nil {
+		return
+	}
+
+	message := update.Message
+	if update.CallbackQuery != nil {
+		message = update.CallbackQuery.Message
+	}
+	if message == nil {
+		return
+	}
+
+	if err := m.telegram.Push(ctx, strconv.FormatInt(message.Chat.ID, 10), "", msg.TextMessage(deadLetterText)); err != nil {
+		slog.Error("failed to push message", slog.Any("error", err))
+	}
+}
+
+func (m *messaging) processTelegramUpdate(ctx context.Context, update *msg.TelegramUpdate, lockedUntil time.Time) error {
+	return m.processOnce(ctx, telegramEventID(update), false, lockedUntil, func() error {
+		return m.handleTelegramUpdate(ctx, update)
+	})
+}
+
+// handleTelegramUpdate handles text messages, receipt photos and taps on the
+// buttons under a receipt. Other updates, and messages from bots, are ignored.
+func (m *messaging) handleTelegramUpdate(ctx context.Context, update *msg.TelegramUpdate) error {
+	if update.CallbackQuery != nil {
+		return m.handleTelegramCallbackQuery(ctx, update.CallbackQuery)
+	}
+
+	message := update.Message
+	if message == nil || message.From == nil || message.From.IsBot {
+		slog.Info("unhandled telegram update", slog.Int64("update_id", update.UpdateID))
+		return nil
+	}
+
+	c := m.telegramChat(message)
+	if m.userDB == nil || m.transactionDB == nil {
+		return c.replyText(ctx, "Sorry, I can't record transactions right now.")
+	}
+
+	user, identity, err := m.resolveTelegramUser(ctx, message.From)
+	if err != nil {
+		return fmt.Errorf("failed to resolve user: %w", err)
+	}
+
+	switch {
+	case len(message.Photo) > 0:
+		if m.receipt == nil {
+			return c.replyText(ctx, "Sorry, I can't read receipts right now.")
+		}
+		// Sizes are sent smallest first; the largest reads best.
+		largest := slices.MaxFunc(message.Photo, func(a, b msg.TelegramPhotoSize) int {
+			return a.Width*a.Height - b.Width*b.Height
+		})
+		return m.readReceipt(ctx, c, user, largest.FileID)
+	case message.Text != "":
+		return m.dispatchText(ctx, c, user, identity, message.Text)
+	default:
+		slog.Info("unhandled telegram message", slog.Int64("update_id", update.UpdateID))
+		return nil
+	}
+}
+
+// handleTelegramCallbackQuery runs the action behind a button the user tapped
+// under one of the bot's messages.
+func (m *messaging) handleTelegramCallbackQuery(ctx context.Context, query *msg.TelegramCallbackQuery) error {
+	if query.From == nil || query.From.IsBot || query.Message == nil {
+		slog.Info("unhandled telegram callback query", slog.String("callback_query_id", query.ID))
+		return nil
+	}
+
+	// Telegram shows a spinner on the button until the query is answered.
+	if answerer, ok := m.telegram.(interface {
+		AnswerCallback(ctx context.Context, callbackQueryID string) error
+	}); ok {
+		if err := answerer.AnswerCallback(ctx, query.ID); err != nil {
+			slog.Warn("failed to answer callback query", slog.Any("error", err))
+		}
+	}
+
+	c := m.telegramChat(query.Message)
+	if m.userDB == nil || m.transactionDB == nil {
+		return c.replyText(ctx, "Sorry, I can't record transactions right now.")
+	}
+
+	user, _, err := m.resolveTelegramUser(ctx, query.From)
+	if err != nil {
+		return fmt.Errorf("failed to resolve user: %w", err)
+	}
+
+	return m.handlePostback(ctx, c, user, query.Data)
+}
+
+// telegramChat answers a Telegram message.
+func (m *messaging) telegramChat(message *msg.TelegramMessage) *chat {
+	return &chat{
+		messenger:  m.telegram,
+		id:         strconv.FormatInt(message.Chat.ID, 10),
+		replyToken: msg.TelegramReplyToken(message.Chat.ID, message.MessageID),
+		pushTo:     strconv.FormatInt(message.Chat.ID, 10),
+	}
+}
+
+// resolveTelegramUser looks up the user by their Telegram user ID, registering
+// them with their Telegram name when they are not known yet.
+func (m *messaging) resolveTelegramUser(ctx context.Context, from *msg.TelegramUser) (*usermodel.User, *usermodel.Identity, error) {
+	identity := &usermodel.Identity{
+		Channel:    usermodel.ChannelTelegram,
+		ExternalID: strconv.FormatInt(from.ID, 10),
+	}
+
+	user, err := m.userDB.GetByIdentity(ctx, identity.Channel, identity.ExternalID)
+	if err == nil {
+		identity.UserID = user.ID
+		return user, identity, nil
+	}
+	if !errors.Is(err, database.ErrNotFound) {
+		return nil, nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	user = &usermodel.User{
+		DisplayName: from.DisplayName(),
+		Status:      usermodel.UserStatusActive,
+	}
+	if err := m.userDB.AddUserWithIdentity(ctx, user, identity); err != nil {
+		return nil, nil, fmt.Errorf("failed to save user: %w", err)
+	}
+
+	return user, identity, nil
+}
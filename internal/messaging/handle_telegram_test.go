@@ -0,0 +1,136 @@
+package messaging
+
+import (
+	"context"
+	"github/shaolim/momon/internal/job"
+	"github/shaolim/momon/internal/serverenv"
+	usermodel "github/shaolim/momon/internal/user/model"
+	msg "github/shaolim/momon/pkg/messaging"
+	"github/shaolim/momon/pkg/messaging/messagingtest"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTelegramCallback(t *testing.T) {
+	jobDB := &recordingJobDB{}
+	m := New(&serverenv.Config{
+		Messaging: &msg.Config{TelegramBotToken: "123:abc", TelegramWebhookSecret: "telegram-secret"},
+	}, serverenv.New(serverenv.WithTelegramMessenger(messagingtest.NewMessenger())))
+	m.queue = job.NewQueue(jobDB, m.handleJob)
+
+	post := func(secret string) int {
+		body := `{"update_id":42,"message":{"message_id":1,"from":{"id":7,"first_name":"Aiko"},"chat":{"id":7,"type":"private"},"text":"lunch 1200"}}`
+		req := httptest.NewRequest(http.MethodPost, "/telegram/callback", strings.NewReader(body))
+		req.Header.Set("X-Telegram-Bot-Api-Secret-Token", secret)
+		rec := httptest.NewRecorder()
+		m.Routes().ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, post("wrong"))
+	assert.Empty(t, jobDB.jobs)
+
+	assert.Equal(t, http.StatusOK, post("telegram-secret"))
+	assert.Equal(t, http.StatusOK, post("telegram-secret"))
+	if assert.Len(t, jobDB.jobs, 1) {
+		assert.Equal(t, "telegram:42", jobDB.jobs[0].EventID)
+	}
+}
+
+func TestHandleTelegramUpdate(t *testing.T) {
+	ctx := context.Background()
+	textUpdate := func(updateID int64, text string) *msg.TelegramUpdate {
+		return &msg.TelegramUpdate{
+			UpdateID: updateID,
+			Message: &msg.TelegramMessage{
+				MessageID: updateID,
+				From:      &msg.TelegramUser{ID: 7, FirstName: "Aiko"},
+				Chat:      msg.TelegramChat{ID: 7, Type: "private"},
+				Text:      text,
+			},
+		}
+	}
+
+	transactionDB := &fakeTransactionDB{}
+	telegram := messagingtest.NewMessenger()
+	m := newTestMessaging(transactionDB)
+	m.telegram = telegram
+	userDB := m.userDB.(*fakeUserDB)
+
+	assert.NoError(t, m.handleTelegramUpdate(ctx, textUpdate(1, "lunch 1200")))
+
+	// The sender is registered under their Telegram ID.
+	user, err := userDB.GetByIdentity(ctx, usermodel.ChannelTelegram, "7")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Aiko", user.DisplayName)
+		assert.Empty(t, user.LineUserID)
+	}
+	if assert.Len(t, transactionDB.transactions, 1) {
+		assert.Equal(t, user.ID, transactionDB.transactions[0].UserID)
+	}
+	if assert.Len(t, telegram.Replies(), 1) {
+		assert.Equal(t, "7:1", telegram.Replies()[0].ReplyToken)
+		assert.Contains(t, telegram.ReplyTexts()[0], "Recorded expense of 1,200 JPY for lunch")
+	}
+
+	// Messages from other bots are ignored.
+	update := textUpdate(2, "lunch 1200")
+	update.Message.From.IsBot = true
+	assert.NoError(t, m.handleTelegramUpdate(ctx, update))
+	assert.Len(t, transactionDB.transactions, 1)
+}
+
+func TestHandleTelegramUpdate_Link(t *testing.T) {
+	ctx := context.Background()
+	m := newTestMessaging(&fakeTransactionDB{})
+	telegram := messagingtest.NewMessenger()
+	m.telegram = telegram
+	userDB := m.userDB.(*fakeUserDB)
+
+	// The LINE user asks for a code.
+	line := &recordingReplier{}
+	assert.NoError(t, m.router.Dispatch(ctx, &Request{
+		User:     userDB.users[1],
+		Identity: &usermodel.Identity{UserID: 1, Channel: usermodel.ChannelLine, ExternalID: "U1"},
+		Text:     "/link",
+		Now:      time.Now(),
+		Reply:    line,
+	}))
+	if !assert.Len(t, userDB.linkCodes, 1) {
+		return
+	}
+	var code string
+	for code = range userDB.linkCodes {
+	}
+	assert.Contains(t, line.replies[0], "Send /link "+code)
+
+	// The code is entered in Telegram, in lower case.
+	send := func(updateID int64, text string) string {
+		t.Helper()
+		assert.NoError(t, m.handleTelegramUpdate(ctx, &msg.TelegramUpdate{
+			UpdateID: updateID,
+			Message: &msg.TelegramMessage{
+				MessageID: updateID,
+				From:      &msg.TelegramUser{ID: 7, FirstName: "Aiko"},
+				Chat:      msg.TelegramChat{ID: 7, Type: "private"},
+				Text:      text,
+			},
+		}))
+		texts := telegram.ReplyTexts()
+		return texts[len(texts)-1]
+	}
+
+	assert.Contains(t, send(1, "/link "+strings.ToLower(code)), "Linked!")
+	user, err := userDB.GetByIdentity(ctx, usermodel.ChannelTelegram, "7")
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(1), user.ID)
+	}
+
+	// Codes work once.
+	assert.Contains(t, send(2, "/link "+code), "That code is wrong or has expired.")
+}
@@ -0,0 +1,52 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	usermodel "github/shaolim/momon/internal/user/model"
+	"strings"
+	"time"
+
+	"github.com/line/line-bot-sdk-go/v8/linebot/webhook"
+)
+
+func (m *messaging) handleTextMessage(ctx context.Context, e webhook.MessageEvent, message webhook.TextMessageContent) error {
+	c := m.lineChat(e.Source, e.ReplyToken)
+	if m.transactionDB == nil {
+		return c.replyText(ctx, "Sorry, I can't record transactions right now.")
+	}
+
+	user, err := m.resolveUser(ctx, e.Source)
+	if err != nil {
+		return fmt.Errorf("failed to resolve user: %w", err)
+	}
+	if err := m.enterGroup(ctx, c, e.Source, user); err != nil {
+		return err
+	}
+
+	identity := &usermodel.Identity{UserID: user.ID, Channel: usermodel.ChannelLine, ExternalID: sourceUserID(e.Source)}
+	return m.dispatchText(ctx, c, user, identity, message.Text)
+}
+
+// dispatchText runs the command or records the entry in a text message from
+// any channel. Text that isn't a command answers a receipt draft waiting for
+// a total or category first.
+func (m *messaging) dispatchText(ctx context.Context, c *chat, user *usermodel.User, identity *usermodel.Identity, text string) error {
+	now := time.Now().In(m.location(user))
+
+	if !strings.HasPrefix(strings.TrimSpace(text), "/") {
+		answered, err := m.answerDraft(ctx, c, user, text, now)
+		if answered || err != nil {
+			return err
+		}
+	}
+
+	return m.router.Dispatch(ctx, &Request{
+		User:     user,
+		Identity: identity,
+		Group:    c.group,
+		Text:     text,
+		Now:      now,
+		Reply:    &chatReplier{ctx: ctx, chat: c},
+	})
+}
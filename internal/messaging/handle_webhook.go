@@ -1,13 +1,18 @@
 package messaging
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	eventmodel "github/shaolim/momon/internal/event/model"
+	jobmodel "github/shaolim/momon/internal/job/model"
+	msg "github/shaolim/momon/pkg/messaging"
 	"io"
 	"log/slog"
 	"net/http"
+	"strings"
+	"time"
 
-	messagingapi "github.com/line/line-bot-sdk-go/v8/linebot/messaging_api"
 	"github.com/line/line-bot-sdk-go/v8/linebot/webhook"
 )
 
@@ -34,77 +39,292 @@ func (m *messaging) Callback(w http.ResponseWriter, r *http.Request) {
 	}
 
 	slog.Info("callback", slog.Any("response", cb))
-	go func() {
-		err := m.processCallback(cb)
+
+	if m.queue != nil {
+		if err := m.enqueueEvents(r.Context(), body); err != nil {
+			// LINE redelivers webhooks we fail; events that were stored
+			// already are deduplicated by their webhook event ID.
+			slog.Error("failed to enqueue events", slog.Any("error", err))
+			http.Error(w, "Error enqueuing events", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	m.process(func(ctx context.Context) {
+		err := m.processCallback(ctx, cb)
 		if err != nil {
 			slog.Error("failed to process callback", slog.Any("error", err))
 		}
-	}()
+	})
 
 	w.WriteHeader(http.StatusOK)
 }
 
-func (m *messaging) processCallback(callback webhook.CallbackRequest) error {
+// rawCallback is a webhook body with the events kept as raw JSON, so each one
+// is queued exactly as LINE sent it.
+type rawCallback struct {
+	Events []json.RawMessage `json:"events"`
+}
+
+// rawEvent holds the fields every event has that are needed before the event
+// is fully decoded.
+type rawEvent struct {
+	WebhookEventID  string `json:"webhookEventId"`
+	DeliveryContext struct {
+		IsRedelivery bool `json:"isRedelivery"`
+	} `json:"deliveryContext"`
+	Source struct {
+		UserID string `json:"userId"`
+	} `json:"source"`
+}
+
+func (m *messaging) enqueueEvents(ctx context.Context, body []byte) error {
+	var cb rawCallback
+	if err := json.Unmarshal(body, &cb); err != nil {
+		return fmt.Errorf("failed to unmarshal events: %w", err)
+	}
+
+	for _, data := range cb.Events {
+		var e rawEvent
+		if err := json.Unmarshal(data, &e); err != nil {
+			return fmt.Errorf("failed to unmarshal event: %w", err)
+		}
+
+		inserted, err := m.queue.Enqueue(ctx, e.WebhookEventID, data)
+		if err != nil {
+			return err
+		}
+		if !inserted {
+			slog.Info("skipped duplicate event", slog.String("webhook_event_id", e.WebhookEventID), slog.Bool("redelivery", e.DeliveryContext.IsRedelivery))
+		}
+	}
+
+	return nil
+}
+
+// handleJob processes a queued LINE event or Telegram update. The event is
+// claimed until the job's lease ends, so a job claimed again after its worker
+// was lost finds the lost run's claim expired.
+func (m *messaging) handleJob(ctx context.Context, j *jobmodel.Job) error {
+	if j.Attempts > 1 {
+		ctx = withRetrying(ctx)
+	}
+	if strings.HasPrefix(j.EventID, telegramEventPrefix) {
+		return m.handleTelegramJob(ctx, j)
+	}
+
+	event, err := webhook.UnmarshalEvent(j.Payload)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal event: %w", err)
+	}
+
+	return m.processEvent(ctx, event, j.LockedUntil)
+}
+
+// handleDeadLetter tells the user we gave up on their message, since they
+// may never have got a reply.
+func (m *messaging) handleDeadLetter(ctx context.Context, j *jobmodel.Job, _ error) {
+	if strings.HasPrefix(j.EventID, telegramEventPrefix) {
+		m.handleTelegramDeadLetter(ctx, j)
+		return
+	}
+
+	var e rawEvent
+	if err := json.Unmarshal(j.Payload, &e); err != nil || e.Source.UserID == "" {
+		return
+	}
+
+	if err := m.line.Push(ctx, e.Source.UserID, "", msg.TextMessage(deadLetterText)); err != nil {
+		slog.Error("failed to push message", slog.Any("error", err))
+	}
+}
+
+func (m *messaging) processCallback(ctx context.Context, callback webhook.CallbackRequest) error {
 	for _, event := range callback.Events {
-		switch e := event.(type) {
-		case webhook.MessageEvent:
-			switch message := e.Message.(type) {
-			case webhook.TextMessageContent:
-				slog.Info("text", slog.String("text", message.Text))
-				resp, err := m.env.GetLineMessagingAPI().ReplyMessage(&messagingapi.ReplyMessageRequest{
-					ReplyToken: e.ReplyToken,
-					Messages: []messagingapi.MessageInterface{
-						&messagingapi.TextMessage{
-							Text: "Hi i got your message. thank you",
-						},
-					},
-				})
-				if err != nil {
-					slog.Error("failed to reply message", slog.Any("error", err))
-					return err
-				}
-
-				slog.Info("reply message", slog.Any("resp", resp))
-			default:
-				slog.Info("unknown event", slog.Any("event", message))
-			}
-		case webhook.FollowEvent:
-			slog.Info("FollowEvent", slog.Any("event", e))
-			if e.Source.GetType() == "user" {
-				// TODO: Save User or update the status
-				user := e.Source.(webhook.UserSource)
-				res, err := m.env.GetLineMessagingAPI().GetProfile(user.UserId)
-				if err != nil {
-					slog.Error("failed to get profile", slog.String("error", err.Error()))
-					return fmt.Errorf("failed to get profile: %w", err)
-				}
-
-				slog.Info("user profile", slog.Any("profile", res))
-			}
+		if err := m.processEvent(ctx, event, time.Now().Add(eventLease)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *messaging) processEvent(ctx context.Context, event webhook.EventInterface, lockedUntil time.Time) error {
+	eventID, redelivery := eventDelivery(event)
+	return m.processOnce(ctx, eventID, redelivery, lockedUntil, func() error {
+		return m.handleEvent(ctx, event)
+	})
+}
+
+// processOnce runs handle unless the event was processed before. The event is
+// claimed before any side effects, so a redelivery arriving while the first
+// delivery is still running, e.g. reading a receipt, is dropped too. Until
+// handle returns the claim only lasts until lockedUntil, so an event whose
+// handler died with the process is handled again when it is redelivered or
+// its job is retried. When handle fails the claim is removed so the event can
+// be retried; handlers must therefore only return an error when they have not
+// changed anything yet.
+func (m *messaging) processOnce(ctx context.Context, eventID string, redelivery bool, lockedUntil time.Time, handle func() error) error {
+	if eventID == "" || m.eventDB == nil {
+		return handle()
+	}
 
-			resp, err := m.env.GetLineMessagingAPI().ReplyMessage(&messagingapi.ReplyMessageRequest{
-				ReplyToken: e.ReplyToken,
-				Messages: []messagingapi.MessageInterface{
-					&messagingapi.TextMessage{
-						Text: "Hi thank you for following me!!",
-					},
-				},
-			})
-			if err != nil {
-				slog.Error("failed to reply message", slog.Any("error", err))
+	logger := slog.With(slog.String("webhook_event_id", eventID), slog.Bool("redelivery", redelivery))
+
+	claimed, err := m.eventDB.ClaimEvent(ctx, &eventmodel.ProcessedEvent{EventID: eventID, LockedUntil: lockedUntil})
+	if err != nil {
+		return fmt.Errorf("failed to claim event: %w", err)
+	}
+	if !claimed {
+		logger.Info("skipped processed event")
+		return nil
+	}
+
+	err = handle()
+	// The handler may have been cancelled by a shutdown.
+	ctx = context.WithoutCancel(ctx)
+	if err != nil {
+		if err := m.eventDB.UnmarkProcessed(ctx, eventID); err != nil {
+			logger.Error("failed to unmark event", slog.Any("error", err))
+		}
+		return err
+	}
+
+	if err := m.eventDB.MarkProcessed(ctx, eventID); err != nil {
+		logger.Error("failed to mark event processed", slog.Any("error", err))
+	}
+
+	return nil
+}
+
+// eventDelivery returns the webhook event ID of the events the bot acts on and
+// whether LINE is redelivering it. Other events have no side effects and
+// return an empty ID.
+func eventDelivery(event webhook.EventInterface) (string, bool) {
+	redelivered := func(dc *webhook.DeliveryContext) bool {
+		return dc != nil && dc.IsRedelivery
+	}
+
+	switch e := event.(type) {
+	case webhook.MessageEvent:
+		return e.WebhookEventId, redelivered(e.DeliveryContext)
+	case webhook.FollowEvent:
+		return e.WebhookEventId, redelivered(e.DeliveryContext)
+	case webhook.UnfollowEvent:
+		return e.WebhookEventId, redelivered(e.DeliveryContext)
+	case webhook.PostbackEvent:
+		return e.WebhookEventId, redelivered(e.DeliveryContext)
+	case webhook.JoinEvent:
+		return e.WebhookEventId, redelivered(e.DeliveryContext)
+	case webhook.LeaveEvent:
+		return e.WebhookEventId, redelivered(e.DeliveryContext)
+	case webhook.MemberJoinedEvent:
+		return e.WebhookEventId, redelivered(e.DeliveryContext)
+	case webhook.MemberLeftEvent:
+		return e.WebhookEventId, redelivered(e.DeliveryContext)
+	default:
+		return "", false
+	}
+}
+
+// replied reports a failed reply to a change that was saved already. It
+// returns nil, since failing would remove the event's processed mark and a
+// retry would save the change twice.
+func replied(err error) error {
+	if err != nil {
+		slog.Error("failed to reply after saving", slog.Any("error", err))
+	}
+	return nil
+}
+
+func (m *messaging) handleEvent(ctx context.Context, event webhook.EventInterface) error {
+	switch e := event.(type) {
+	case webhook.MessageEvent:
+		switch message := e.Message.(type) {
+		case webhook.TextMessageContent:
+			slog.Info("text", slog.String("text", message.Text))
+			if err := m.handleTextMessage(ctx, e, message); err != nil {
+				slog.Error("failed to handle text message", slog.Any("error", err))
 				return err
 			}
-
-			slog.Info("reply message follow user", slog.Any("resp", resp))
-		case webhook.UnfollowEvent:
-			slog.Info("UnfollowEvent", slog.Any("event", e))
-			if e.Source.GetType() == "user" {
-				// TODO: update user status
+		case webhook.ImageMessageContent:
+			if err := m.handleImageMessage(ctx, e, message); err != nil {
+				slog.Error("failed to handle image message", slog.Any("error", err))
+				return err
 			}
 		default:
-			slog.Info("unknown event", slog.Any("event", e))
+			slog.Info("unknown event", slog.Any("event", message))
+		}
+	case webhook.FollowEvent:
+		slog.Info("FollowEvent", slog.Any("event", e))
+		if err := m.handleFollow(ctx, e); err != nil {
+			slog.Error("failed to handle follow event", slog.Any("error", err))
+			return err
+		}
+	case webhook.UnfollowEvent:
+		slog.Info("UnfollowEvent", slog.Any("event", e))
+		if err := m.handleUnfollow(ctx, e); err != nil {
+			slog.Error("failed to handle unfollow event", slog.Any("error", err))
+			return err
 		}
+	case webhook.PostbackEvent:
+		slog.Info("PostbackEvent", slog.Any("event", e))
+		if err := m.handlePostbackEvent(ctx, e); err != nil {
+			slog.Error("failed to handle postback event", slog.Any("error", err))
+			return err
+		}
+	case webhook.JoinEvent:
+		slog.Info("JoinEvent", slog.Any("event", e))
+		if err := m.handleJoin(ctx, e); err != nil {
+			slog.Error("failed to handle join event", slog.Any("error", err))
+			return err
+		}
+	case webhook.LeaveEvent:
+		slog.Info("LeaveEvent", slog.Any("event", e))
+		if err := m.handleLeave(ctx, e); err != nil {
+			slog.Error("failed to handle leave event", slog.Any("error", err))
+			return err
+		}
+	case webhook.MemberJoinedEvent:
+		slog.Info("MemberJoinedEvent", slog.Any("event", e))
+		if err := m.handleMemberJoined(ctx, e); err != nil {
+			slog.Error("failed to handle member joined event", slog.Any("error", err))
+			return err
+		}
+	case webhook.MemberLeftEvent:
+		slog.Info("MemberLeftEvent", slog.Any("event", e))
+		if err := m.handleMemberLeft(ctx, e); err != nil {
+			slog.Error("failed to handle member left event", slog.Any("error", err))
+			return err
+		}
+	default:
+		slog.Info("unknown event", slog.Any("event", e))
 	}
 
 	return nil
 }
+
+// lineChat answers a LINE event.
+func (m *messaging) lineChat(source webhook.SourceInterface, replyToken string) *chat {
+	return &chat{
+		messenger:  m.line,
+		id:         sourceUserID(source),
+		replyToken: replyToken,
+		pushTo:     sourceChatID(source),
+	}
+}
+
+// sourceChatID returns the chat the event came from: the group or room, or
+// the user in a one-on-one chat.
+func sourceChatID(source webhook.SourceInterface) string {
+	switch s := source.(type) {
+	case webhook.GroupSource:
+		return s.GroupId
+	case webhook.RoomSource:
+		return s.RoomId
+	default:
+		return sourceUserID(source)
+	}
+}
@@ -0,0 +1,249 @@
+package messaging
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	eventmodel "github/shaolim/momon/internal/event/model"
+	"github/shaolim/momon/internal/job"
+	"github/shaolim/momon/internal/job/model"
+	"github/shaolim/momon/internal/serverenv"
+	usermodel "github/shaolim/momon/internal/user/model"
+	"github/shaolim/momon/pkg/database"
+	msg "github/shaolim/momon/pkg/messaging"
+	"github/shaolim/momon/pkg/messaging/messagingtest"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/line/line-bot-sdk-go/v8/linebot/webhook"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingJobDB stores enqueued jobs and never hands them out.
+type recordingJobDB struct {
+	jobs []*model.Job
+}
+
+func (f *recordingJobDB) EnqueueJob(_ context.Context, j *model.Job) (bool, error) {
+	for _, existing := range f.jobs {
+		if existing.EventID == j.EventID {
+			return false, nil
+		}
+	}
+	j.ID = int64(len(f.jobs) + 1)
+	f.jobs = append(f.jobs, j)
+	return true, nil
+}
+
+func (f *recordingJobDB) GetJob(context.Context, int64) (*model.Job, error) {
+	return nil, database.ErrNotFound
+}
+
+func (f *recordingJobDB) ClaimJob(context.Context, time.Time, time.Duration) (*model.Job, error) {
+	return nil, database.ErrNotFound
+}
+
+func (f *recordingJobDB) CompleteJob(context.Context, *model.Job) error { return nil }
+
+func (f *recordingJobDB) RetryJob(context.Context, *model.Job, time.Time, string) error { return nil }
+
+func (f *recordingJobDB) DeadLetterJob(context.Context, *model.Job, string) error { return nil }
+
+const testChannelSecret = "test-secret"
+
+func postCallback(t *testing.T, m *messaging, body string) int {
+	t.Helper()
+
+	mac := hmac.New(sha256.New, []byte(testChannelSecret))
+	mac.Write([]byte(body))
+
+	req := httptest.NewRequest(http.MethodPost, "/callback", strings.NewReader(body))
+	req.Header.Set("X-Line-Signature", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	rec := httptest.NewRecorder()
+	m.Routes().ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestCallback_EnqueuesEvents(t *testing.T) {
+	jobDB := &recordingJobDB{}
+	m := New(&serverenv.Config{
+		Messaging: &msg.Config{LineChannelSecret: testChannelSecret},
+	}, serverenv.New())
+	m.queue = job.NewQueue(jobDB, m.handleJob)
+
+	body := `{"destination":"U0","events":[
+		{"type":"message","webhookEventId":"01EVENT1","source":{"type":"user","userId":"U1"},"message":{"type":"text","id":"1","text":"lunch 1200"}},
+		{"type":"follow","webhookEventId":"01EVENT2","source":{"type":"user","userId":"U2"}}
+	]}`
+
+	assert.Equal(t, http.StatusOK, postCallback(t, m, body))
+	// A redelivery of the same webhook is acknowledged but not queued again.
+	assert.Equal(t, http.StatusOK, postCallback(t, m, body))
+
+	if assert.Len(t, jobDB.jobs, 2) {
+		assert.Equal(t, "01EVENT1", jobDB.jobs[0].EventID)
+		assert.Contains(t, string(jobDB.jobs[0].Payload), `"text":"lunch 1200"`)
+		assert.Equal(t, "01EVENT2", jobDB.jobs[1].EventID)
+	}
+}
+
+// fakeEventDB is an in-memory EventDB.
+type fakeEventDB struct {
+	processed map[string]*eventmodel.ProcessedEvent
+}
+
+func (f *fakeEventDB) ClaimEvent(_ context.Context, event *eventmodel.ProcessedEvent) (bool, error) {
+	if event.ProcessedAt.IsZero() {
+		event.ProcessedAt = time.Now()
+	}
+	if existing, ok := f.processed[event.EventID]; ok {
+		if existing.LockedUntil.IsZero() || existing.LockedUntil.After(event.ProcessedAt) {
+			return false, nil
+		}
+	}
+	if f.processed == nil {
+		f.processed = map[string]*eventmodel.ProcessedEvent{}
+	}
+	copied := *event
+	f.processed[event.EventID] = &copied
+	return true, nil
+}
+
+func (f *fakeEventDB) MarkProcessed(_ context.Context, eventID string) error {
+	event, ok := f.processed[eventID]
+	if !ok {
+		return database.ErrNotFound
+	}
+	event.LockedUntil = time.Time{}
+	return nil
+}
+
+func (f *fakeEventDB) UnmarkProcessed(_ context.Context, eventID string) error {
+	if _, ok := f.processed[eventID]; !ok {
+		return database.ErrNotFound
+	}
+	delete(f.processed, eventID)
+	return nil
+}
+
+func (f *fakeEventDB) DeleteProcessedBefore(_ context.Context, before time.Time) (int64, error) {
+	var deleted int64
+	for id, event := range f.processed {
+		if event.ProcessedAt.Before(before) {
+			delete(f.processed, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// failingUserDB fails every status update.
+type failingUserDB struct {
+	*fakeUserDB
+}
+
+func (f *failingUserDB) UpdateStatus(context.Context, string, usermodel.UserStatus) error {
+	return errors.New("connection reset")
+}
+
+func TestProcessEvent_Redelivery(t *testing.T) {
+	ctx := context.Background()
+	unfollow := func(redelivery bool) webhook.UnfollowEvent {
+		return webhook.UnfollowEvent{
+			Source:          webhook.UserSource{UserId: "U1"},
+			WebhookEventId:  "01EVENT1",
+			DeliveryContext: &webhook.DeliveryContext{IsRedelivery: redelivery},
+		}
+	}
+	lockedUntil := time.Now().Add(eventLease)
+
+	t.Run("processes an event once", func(t *testing.T) {
+		userDB := &fakeUserDB{users: map[int64]*usermodel.User{1: {ID: 1, LineUserID: "U1", Status: usermodel.UserStatusActive}}}
+		m := &messaging{userDB: userDB, eventDB: &fakeEventDB{}}
+
+		assert.NoError(t, m.processEvent(ctx, unfollow(false), lockedUntil))
+		assert.Equal(t, usermodel.UserStatus(usermodel.UserStatusInActive), userDB.users[1].Status)
+
+		// The user followed again before LINE redelivered the unfollow.
+		userDB.users[1].Status = usermodel.UserStatusActive
+		assert.NoError(t, m.processEvent(ctx, unfollow(true), lockedUntil))
+		assert.Equal(t, usermodel.UserStatus(usermodel.UserStatusActive), userDB.users[1].Status)
+	})
+
+	t.Run("failed events are processed again", func(t *testing.T) {
+		eventDB := &fakeEventDB{}
+		userDB := &fakeUserDB{users: map[int64]*usermodel.User{1: {ID: 1, LineUserID: "U1", Status: usermodel.UserStatusActive}}}
+		m := &messaging{userDB: &failingUserDB{userDB}, eventDB: eventDB}
+
+		assert.Error(t, m.processEvent(ctx, unfollow(false), lockedUntil))
+		assert.Empty(t, eventDB.processed)
+
+		m.userDB = userDB
+		assert.NoError(t, m.processEvent(ctx, unfollow(true), lockedUntil))
+		assert.Equal(t, usermodel.UserStatus(usermodel.UserStatusInActive), userDB.users[1].Status)
+		assert.Contains(t, eventDB.processed, "01EVENT1")
+	})
+
+	t.Run("lost events are processed again after their lease", func(t *testing.T) {
+		eventDB := &fakeEventDB{}
+		userDB := &fakeUserDB{users: map[int64]*usermodel.User{1: {ID: 1, LineUserID: "U1", Status: usermodel.UserStatusActive}}}
+		m := &messaging{userDB: userDB, eventDB: eventDB}
+
+		// The process died while handling the first delivery.
+		claimedAt := time.Now().Add(-eventLease)
+		_, err := eventDB.ClaimEvent(ctx, &eventmodel.ProcessedEvent{EventID: "01EVENT1", ProcessedAt: claimedAt, LockedUntil: claimedAt.Add(eventLease)})
+		assert.NoError(t, err)
+
+		assert.NoError(t, m.processEvent(ctx, unfollow(true), lockedUntil))
+		assert.Equal(t, usermodel.UserStatus(usermodel.UserStatusInActive), userDB.users[1].Status)
+		assert.True(t, eventDB.processed["01EVENT1"].LockedUntil.IsZero())
+	})
+
+	t.Run("queued events are claimed until their job's lease ends", func(t *testing.T) {
+		eventDB := &fakeEventDB{}
+		userDB := &fakeUserDB{users: map[int64]*usermodel.User{1: {ID: 1, LineUserID: "U1", Status: usermodel.UserStatusActive}}}
+		m := &messaging{userDB: userDB, eventDB: eventDB}
+		job := func(lockedUntil time.Time) *model.Job {
+			return &model.Job{
+				EventID:     "01EVENT1",
+				Payload:     []byte(`{"type":"unfollow","webhookEventId":"01EVENT1","source":{"type":"user","userId":"U1"},"timestamp":0,"mode":"active","deliveryContext":{"isRedelivery":false}}`),
+				LockedUntil: lockedUntil,
+			}
+		}
+
+		// A worker claimed the job and died; its claim ended with the job's lease.
+		claimedAt := time.Now().Add(-time.Minute)
+		_, err := eventDB.ClaimEvent(ctx, &eventmodel.ProcessedEvent{EventID: "01EVENT1", ProcessedAt: claimedAt, LockedUntil: claimedAt})
+		assert.NoError(t, err)
+
+		assert.NoError(t, m.handleJob(ctx, job(time.Now().Add(time.Minute))))
+		assert.Equal(t, usermodel.UserStatus(usermodel.UserStatusInActive), userDB.users[1].Status)
+		assert.True(t, eventDB.processed["01EVENT1"].LockedUntil.IsZero())
+
+		// Once processed, the event is not handled again.
+		userDB.users[1].Status = usermodel.UserStatusActive
+		assert.NoError(t, m.handleJob(ctx, job(time.Now().Add(time.Minute))))
+		assert.Equal(t, usermodel.UserStatus(usermodel.UserStatusActive), userDB.users[1].Status)
+	})
+}
+
+func TestHandleDeadLetter(t *testing.T) {
+	messenger := messagingtest.NewMessenger()
+	m := &messaging{line: messenger}
+
+	m.handleDeadLetter(context.Background(), &model.Job{
+		EventID: "01EVENT1",
+		Payload: []byte(`{"type":"message","webhookEventId":"01EVENT1","source":{"type":"user","userId":"U1"}}`),
+	}, errors.New("receipt OCR timed out"))
+
+	pushes := messenger.Pushes()
+	if assert.Len(t, pushes, 1) {
+		assert.Equal(t, "U1", pushes[0].To)
+		assert.Equal(t, "Sorry, something went wrong with your last message. Please send it again.", pushes[0].Messages[0].Text)
+	}
+}
@@ -1,24 +1,202 @@
 package messaging
 
 import (
+	"context"
+	"fmt"
+	budgetdb "github/shaolim/momon/internal/budget/database"
+	"github/shaolim/momon/internal/category"
+	categorydb "github/shaolim/momon/internal/category/database"
+	digestdb "github/shaolim/momon/internal/digest/database"
+	eventdb "github/shaolim/momon/internal/event/database"
+	fxdb "github/shaolim/momon/internal/fx/database"
+	groupdb "github/shaolim/momon/internal/group/database"
+	"github/shaolim/momon/internal/job"
+	jobdb "github/shaolim/momon/internal/job/database"
+	"github/shaolim/momon/internal/receipt"
 	"github/shaolim/momon/internal/serverenv"
+	transactiondb "github/shaolim/momon/internal/transaction/database"
+	"github/shaolim/momon/internal/transaction/parser"
+	userdb "github/shaolim/momon/internal/user/database"
+	msg "github/shaolim/momon/pkg/messaging"
+	"log/slog"
 	"net/http"
+	"sync"
+	"time"
 )
 
+// cleanupInterval is how often processed events past their TTL and expired
+// receipt drafts are deleted.
+const cleanupInterval = time.Hour
+
+// eventLease is how long an event handled without the queue may run before
+// its handler is assumed lost and a redelivery of it is handled again. Queued
+// events are claimed for their job's lease instead.
+const eventLease = 5 * time.Minute
+
 type messaging struct {
 	env    *serverenv.ServerEnv
 	config *serverenv.Config
+
+	line msg.Messenger
+	// telegram is nil when the Telegram bot is disabled.
+	telegram      msg.Messenger
+	userDB        userdb.UserDB
+	transactionDB transactiondb.TransactionDB
+	receipt       receipt.ReceiptExtractor
+	textParser    parser.Parser
+	categorizer   *category.Categorizer
+	budgetDB      budgetdb.BudgetDB
+	digestDB      digestdb.DigestDB
+	fxDB          fxdb.FXDB
+	groupDB       groupdb.GroupDB
+	router        *Router
+
+	// queue persists webhook events and processes them with retries. Without
+	// a database events are processed in memory instead.
+	queue *job.Queue
+	// eventDB remembers processed webhook events so redeliveries are ignored.
+	eventDB eventdb.EventDB
+
+	// inflight tracks in-memory callbacks still being processed after the
+	// webhook was acknowledged. Their context is cancelled when Drain gives up.
+	inflight sync.WaitGroup
+	ctx      context.Context
+	cancel   context.CancelFunc
+
+	// background runs housekeeping until stop is closed by Drain.
+	background sync.WaitGroup
+	stop       chan struct{}
 }
 
 func New(config *serverenv.Config, env *serverenv.ServerEnv) *messaging {
-	return &messaging{
+	m := &messaging{
 		env:    env,
 		config: config,
+		stop:   make(chan struct{}),
+	}
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+
+	var suggester category.Suggester
+	if client := env.GetOpenAIClient(); client != nil {
+		m.textParser = parser.NewOpenAI(client)
+		suggester = category.NewOpenAI(client)
+	}
+
+	if db := env.GetDatabase(); db != nil {
+		m.userDB = userdb.New(db)
+		m.transactionDB = transactiondb.New(db)
+		m.eventDB = eventdb.New(db)
+		m.categorizer = category.NewCategorizer(categorydb.New(db), suggester)
+		m.budgetDB = budgetdb.New(db)
+		m.digestDB = digestdb.New(db)
+		m.fxDB = fxdb.New(db)
+		m.groupDB = groupdb.New(db)
+		m.queue = job.NewQueue(jobdb.New(db), m.handleJob,
+			job.WithWorkers(config.WebhookWorkers),
+			job.WithMaxAttempts(config.WebhookMaxAttempts),
+			job.WithDeadLetterHandler(m.handleDeadLetter),
+		)
 	}
+
+	m.line = env.GetMessenger()
+	m.telegram = env.GetTelegramMessenger()
+	m.receipt = env.GetReceiptExtractor()
+
+	m.router = m.newRouter()
+
+	return m
 }
 
 func (m *messaging) Routes() http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("POST /callback", m.Callback)
+	if m.telegram != nil {
+		mux.HandleFunc("POST /telegram/callback", m.TelegramCallback)
+	}
 	return mux
 }
+
+// Start begins processing queued webhook events, deleting processed ones
+// past their TTL along with expired receipt drafts, posting recurring
+// transactions as they come due and sending digests.
+func (m *messaging) Start() {
+	if m.queue != nil {
+		m.queue.Start()
+	}
+	if m.eventDB != nil {
+		m.background.Go(func() { m.every(cleanupInterval, m.cleanup) })
+	}
+	if m.transactionDB != nil {
+		m.background.Go(func() { m.every(recurringInterval, m.postRecurring) })
+	}
+	if m.digestDB != nil {
+		m.background.Go(func() { m.every(digestInterval, m.sendDigests) })
+	}
+}
+
+// every runs fn right away and then every interval until Drain is called.
+func (m *messaging) every(interval time.Duration, fn func(ctx context.Context, now time.Time)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		fn(m.ctx, time.Now())
+
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *messaging) cleanup(ctx context.Context, now time.Time) {
+	deleted, err := m.eventDB.DeleteProcessedBefore(ctx, now.Add(-m.config.ProcessedEventTTL))
+	if err != nil {
+		slog.Error("failed to delete processed events", slog.Any("error", err))
+	} else if deleted > 0 {
+		slog.Info("deleted processed events", slog.Int64("count", deleted))
+	}
+
+	deleted, err = m.transactionDB.DeleteExpiredDrafts(ctx, now)
+	if err != nil {
+		slog.Error("failed to delete expired receipt drafts", slog.Any("error", err))
+	} else if deleted > 0 {
+		slog.Info("deleted expired receipt drafts", slog.Int64("count", deleted))
+	}
+}
+
+// process runs f in the background and tracks it so Drain can wait for it.
+func (m *messaging) process(f func(ctx context.Context)) {
+	m.inflight.Go(func() {
+		f(m.ctx)
+	})
+}
+
+// Drain waits for callbacks that are still being processed. It must be called
+// after the HTTP server stopped accepting requests. When ctx expires first the
+// remaining work is cancelled and ctx's error is returned; queued events are
+// picked up again on the next start.
+func (m *messaging) Drain(ctx context.Context) error {
+	close(m.stop)
+	m.background.Wait()
+
+	if m.queue != nil {
+		return m.queue.Shutdown(ctx)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		m.inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		m.cancel()
+		return nil
+	case <-ctx.Done():
+		m.cancel()
+		return fmt.Errorf("failed to drain callbacks: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,54 @@
+package messaging
+
+import (
+	"context"
+	"github/shaolim/momon/internal/serverenv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrain(t *testing.T) {
+	t.Run("waits for in-flight callbacks", func(t *testing.T) {
+		m := New(&serverenv.Config{}, serverenv.New())
+
+		release := make(chan struct{})
+		finished := false
+		m.process(func(ctx context.Context) {
+			<-release
+			finished = true
+		})
+
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			close(release)
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		assert.NoError(t, m.Drain(ctx))
+		assert.True(t, finished)
+	})
+
+	t.Run("cancels callbacks after the deadline", func(t *testing.T) {
+		m := New(&serverenv.Config{}, serverenv.New())
+
+		cancelled := make(chan struct{})
+		m.process(func(ctx context.Context) {
+			<-ctx.Done()
+			close(cancelled)
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		assert.ErrorIs(t, m.Drain(ctx), context.DeadlineExceeded)
+		select {
+		case <-cancelled:
+		case <-time.After(time.Second):
+			t.Fatal("callback context was not cancelled")
+		}
+	})
+}
@@ -0,0 +1,39 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	usermodel "github/shaolim/momon/internal/user/model"
+	msg "github/shaolim/momon/pkg/messaging"
+)
+
+// errUnreachable is returned when a user can't be pushed to, e.g. because
+// they blocked the bot on LINE and don't use Telegram.
+var errUnreachable = errors.New("user has no channel to push to")
+
+// push sends messages to the user outside of a reply: on LINE while they
+// follow the bot, otherwise in their Telegram chat. See msg.Messenger.Push for
+// retryKey.
+func (m *messaging) push(ctx context.Context, user *usermodel.User, retryKey string, messages ...msg.Message) error {
+	if user.LineUserID != "" && user.Status != usermodel.UserStatusInActive {
+		return m.line.Push(ctx, user.LineUserID, retryKey, messages...)
+	}
+
+	if m.telegram == nil {
+		return errUnreachable
+	}
+
+	identities, err := m.userDB.ListIdentities(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list identities: %w", err)
+	}
+	for _, identity := range identities {
+		// A private Telegram chat has the same ID as the user.
+		if identity.Channel == usermodel.ChannelTelegram {
+			return m.telegram.Push(ctx, identity.ExternalID, retryKey, messages...)
+		}
+	}
+
+	return errUnreachable
+}
@@ -0,0 +1,263 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github/shaolim/momon/internal/category"
+	"github/shaolim/momon/internal/transaction/model"
+	"github/shaolim/momon/internal/transaction/parser"
+	"github/shaolim/momon/pkg/database"
+	msg "github/shaolim/momon/pkg/messaging"
+	"github/shaolim/momon/pkg/money"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// recurringInterval is how often due recurring transactions are posted.
+const recurringInterval = time.Minute
+
+// recurringBatch bounds how many rules are listed per tick. A rule that fell
+// behind posts all its missed runs at once, so this does not bound the
+// transactions posted.
+const recurringBatch = 100
+
+const recurringUsage = `Usage:
+/recurring - list your recurring entries
+/recurring add ENTRY monthly [DAY|end] - e.g. /recurring add rent 85000 monthly 27
+/recurring add ENTRY weekly [WEEKDAY] - e.g. /recurring add gym 800 weekly mon
+/recurring add ENTRY yearly [MM-DD] - e.g. /recurring add domain 1500 yearly 03-14
+/recurring remove ID - stop one`
+
+func (m *messaging) recurringCommand(ctx context.Context, req *Request) error {
+	if len(req.Args) == 0 {
+		return m.listRecurring(ctx, req)
+	}
+
+	switch strings.ToLower(req.Args[0]) {
+	case "add":
+		return m.addRecurring(ctx, req, req.Args[1:])
+	case "remove", "stop":
+		return m.removeRecurring(ctx, req, req.Args[1:])
+	default:
+		return req.Reply.ReplyText(recurringUsage)
+	}
+}
+
+func (m *messaging) addRecurring(ctx context.Context, req *Request, args []string) error {
+	recurring, rest, ok := parseSchedule(args, req.Now)
+	if !ok {
+		return req.Reply.ReplyText(recurringUsage)
+	}
+
+	entry, err := m.parseEntry(ctx, strings.Join(rest, " "), m.currency(req.User), req.Now)
+	if err != nil {
+		if errors.Is(err, parser.ErrNoMatch) {
+			return req.Reply.ReplyText(recurringUsage)
+		}
+		return fmt.Errorf("failed to parse entry: %w", err)
+	}
+
+	recurring.UserID = req.User.ID
+	recurring.Amount = entry.Amount.Amount
+	recurring.Currency = entry.Amount.Currency
+	recurring.Type = entry.Type
+	recurring.Note = entry.Description
+	if recurring.Type == model.TransactionTypeExpense {
+		recurring.Category = m.categorize(ctx, req.User.ID, &category.Expense{Description: entry.Description})
+	}
+	recurring.NextRunAt = recurring.Next(req.Now, m.location(req.User))
+
+	if err := m.transactionDB.AddRecurring(ctx, recurring); err != nil {
+		return fmt.Errorf("failed to add recurring transaction: %w", err)
+	}
+
+	return replied(req.Reply.ReplyText(fmt.Sprintf("Added #%d: %s %s, %s. The first is posted on %s.\nSend /recurring to see all of them.",
+		recurring.ID, recurringKind(recurring), recurring.Money(), recurring.Schedule(), recurring.NextRunAt.Format(time.DateOnly))))
+}
+
+func (m *messaging) removeRecurring(ctx context.Context, req *Request, args []string) error {
+	if len(args) != 1 {
+		return req.Reply.ReplyText(recurringUsage)
+	}
+	id, err := strconv.ParseInt(strings.TrimPrefix(args[0], "#"), 10, 64)
+	if err != nil {
+		return req.Reply.ReplyText(recurringUsage)
+	}
+
+	if err := m.transactionDB.DeleteRecurring(ctx, req.User.ID, id); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return req.Reply.ReplyText(fmt.Sprintf("You have no recurring entry #%d.", id))
+		}
+		return fmt.Errorf("failed to delete recurring transaction: %w", err)
+	}
+
+	return replied(req.Reply.ReplyText(fmt.Sprintf("Stopped #%d. Entries it already posted are kept.", id)))
+}
+
+func (m *messaging) listRecurring(ctx context.Context, req *Request) error {
+	recurring, err := m.transactionDB.ListRecurring(ctx, req.User.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list recurring transactions: %w", err)
+	}
+	if len(recurring) == 0 {
+		return req.Reply.ReplyText("You have no recurring entries yet.\n\n" + recurringUsage)
+	}
+
+	loc := m.location(req.User)
+	monthly := map[money.Currency]money.Money{}
+	var b strings.Builder
+	b.WriteString("Your recurring entries:")
+	for _, r := range recurring {
+		fmt.Fprintf(&b, "\n#%d %s %s %s, next %s", r.ID, r.Note, money.New(r.Transaction(r.NextRunAt).SignedAmount(), r.Currency),
+			r.Schedule(), r.NextRunAt.In(loc).Format("01/02"))
+
+		if r.Type == model.TransactionTypeExpense {
+			total, ok := monthly[r.Currency]
+			if !ok {
+				total = money.New(0, r.Currency)
+			}
+			if monthly[r.Currency], err = total.Add(r.Monthly()); err != nil {
+				return fmt.Errorf("failed to sum recurring transactions: %w", err)
+			}
+		}
+	}
+	if len(monthly) > 0 {
+		fmt.Fprintf(&b, "\n\nRecurring expenses come to about %s a month.", formatTotals(monthly))
+	}
+	b.WriteString("\nSend /recurring remove ID to stop one.")
+
+	return req.Reply.ReplyText(b.String())
+}
+
+func recurringKind(r *model.Recurring) string {
+	kind := "expense"
+	if r.Type == model.TransactionTypeIncome {
+		kind = "income"
+	}
+	if r.Note != "" {
+		return r.Note + " " + kind
+	}
+	return kind
+}
+
+// parseSchedule splits words such as "rent 85000 monthly 27" into a
+// recurrence and the entry before it. A missing day defaults to now's.
+func parseSchedule(words []string, now time.Time) (*model.Recurring, []string, bool) {
+	i := len(words) - 1
+	for ; i > 0; i-- {
+		switch strings.ToLower(words[i]) {
+		case "weekly", "monthly", "yearly":
+		default:
+			continue
+		}
+		break
+	}
+	if i <= 0 || len(words)-i > 2 {
+		return nil, nil, false
+	}
+	spec := ""
+	if i+1 < len(words) {
+		spec = strings.ToLower(words[i+1])
+	}
+
+	r := &model.Recurring{}
+	switch strings.ToLower(words[i]) {
+	case "weekly":
+		r.Frequency = model.FrequencyWeekly
+		r.Day = int(now.Weekday())
+		if spec != "" {
+			day, ok := parseWeekday(spec)
+			if !ok {
+				return nil, nil, false
+			}
+			r.Day = int(day)
+		}
+	case "monthly":
+		r.Frequency = model.FrequencyMonthly
+		r.Day = now.Day()
+		switch spec {
+		case "":
+		case "end", "last":
+			r.Day = model.LastDay
+		default:
+			day, err := strconv.Atoi(strings.TrimRight(spec, "stndrh"))
+			if err != nil || day < 1 || day > 31 {
+				return nil, nil, false
+			}
+			r.Day = day
+		}
+	case "yearly":
+		r.Frequency = model.FrequencyYearly
+		r.Month, r.Day = now.Month(), now.Day()
+		if spec != "" {
+			date, err := time.Parse("1-2", spec)
+			if err != nil {
+				return nil, nil, false
+			}
+			r.Month, r.Day = date.Month(), date.Day()
+		}
+	}
+
+	return r, words[:i], true
+}
+
+func parseWeekday(s string) (time.Weekday, bool) {
+	if len(s) < 3 {
+		return 0, false
+	}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.HasPrefix(strings.ToLower(day.String()), s) {
+			return day, true
+		}
+	}
+	return 0, false
+}
+
+// postRecurring posts every recurring transaction due at now, catching up on
+// runs missed while the server was down, and tells the user about each. The
+// database posts every run once, however many instances try.
+func (m *messaging) postRecurring(ctx context.Context, now time.Time) {
+	due, err := m.transactionDB.ListDueRecurring(ctx, now, recurringBatch)
+	if err != nil {
+		slog.Error("failed to list due recurring transactions", slog.Any("error", err))
+		return
+	}
+
+	for _, r := range due {
+		loc := r.Location(m.config.DefaultLocation)
+		for !r.NextRunAt.After(now) {
+			transaction, err := m.transactionDB.PostRecurring(ctx, r, r.Next(r.NextRunAt, loc))
+			if errors.Is(err, database.ErrNotFound) {
+				break
+			}
+			if err != nil {
+				slog.Error("failed to post recurring transaction", slog.Int64("recurring_id", r.ID), slog.Any("error", err))
+				break
+			}
+
+			slog.Info("posted recurring transaction", slog.Int64("recurring_id", r.ID), slog.Int64("transaction_id", transaction.ID))
+			transaction.OccurredAt = transaction.OccurredAt.In(loc)
+			m.notifyRecurring(ctx, r, transaction, now.In(loc))
+		}
+	}
+}
+
+// notifyRecurring tells the user a recurring transaction was posted. The
+// transaction is kept when that fails.
+func (m *messaging) notifyRecurring(ctx context.Context, r *model.Recurring, t *model.Transaction, now time.Time) {
+	user, err := m.userDB.GetUser(ctx, r.UserID)
+	if err != nil {
+		slog.Error("failed to get user", slog.Int64("user_id", r.UserID), slog.Any("error", err))
+		return
+	}
+
+	text := fmt.Sprintf("Recorded your recurring %s (%s).\nSend /recurring remove %d to stop it.", describeTransaction(t), r.Schedule(), r.ID)
+	if err := m.push(ctx, user, "", msg.TextMessage(text)); err != nil {
+		slog.Error("failed to push recurring transaction", slog.Int64("transaction_id", t.ID), slog.Any("error", err))
+	}
+
+	m.checkBudgets(ctx, user, t, now)
+}
@@ -0,0 +1,99 @@
+package messaging
+
+import (
+	"context"
+	"github/shaolim/momon/internal/transaction/model"
+	"github/shaolim/momon/pkg/messaging/messagingtest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommands_Recurring(t *testing.T) {
+	now := time.Date(2025, 10, 15, 19, 30, 0, 0, time.UTC) // a Wednesday
+	transactionDB := &fakeTransactionDB{}
+	m := newTestMessaging(transactionDB)
+
+	assert.Equal(t, "You have no recurring entries yet.\n\n"+recurringUsage, dispatch(t, m, "/recurring", now))
+	assert.Equal(t, recurringUsage, dispatch(t, m, "/recurring add rent 85000", now))
+	assert.Equal(t, recurringUsage, dispatch(t, m, "/recurring add rent 85000 monthly 32", now))
+	assert.Equal(t, recurringUsage, dispatch(t, m, "/recurring add gym 800 weekly someday", now))
+	assert.Equal(t, recurringUsage, dispatch(t, m, "/recurring add monthly 27", now))
+
+	assert.Equal(t,
+		"Added #1: rent expense 85,000 JPY, monthly on the 27th. The first is posted on 2025-10-27.\nSend /recurring to see all of them.",
+		dispatch(t, m, "/recurring add rent 85,000 monthly 27th", now))
+	assert.Equal(t,
+		"Added #2: gym expense 800 JPY, weekly on Monday. The first is posted on 2025-10-20.\nSend /recurring to see all of them.",
+		dispatch(t, m, "/recurring add gym 800 weekly mon", now))
+	assert.Equal(t,
+		"Added #3: salary income 300,000 JPY, monthly on the last day. The first is posted on 2025-10-31.\nSend /recurring to see all of them.",
+		dispatch(t, m, "/recurring add +300000 salary monthly end", now))
+	assert.Equal(t,
+		"Added #4: domain expense 1,200 JPY, yearly on March 14. The first is posted on 2026-03-14.\nSend /recurring to see all of them.",
+		dispatch(t, m, "/recurring add domain 1200 yearly 3-14", now))
+	// Without a day it repeats on today's, from the next time it comes round.
+	assert.Equal(t,
+		"Added #5: netflix expense 1,490 JPY, monthly on the 15th. The first is posted on 2025-11-15.\nSend /recurring to see all of them.",
+		dispatch(t, m, "/recurring add netflix 1490 monthly", now))
+	assert.Empty(t, transactionDB.transactions)
+
+	assert.Equal(t, `Your recurring entries:
+#2 gym -800 JPY weekly on Monday, next 10/20
+#1 rent -85,000 JPY monthly on the 27th, next 10/27
+#3 salary 300,000 JPY monthly on the last day, next 10/31
+#5 netflix -1,490 JPY monthly on the 15th, next 11/15
+#4 domain -1,200 JPY yearly on March 14, next 03/14
+
+Recurring expenses come to about 90,056 JPY a month.
+Send /recurring remove ID to stop one.`, dispatch(t, m, "/recurring", now))
+
+	assert.Equal(t, "Stopped #4. Entries it already posted are kept.", dispatch(t, m, "/recurring remove #4", now))
+	assert.Equal(t, "You have no recurring entry #4.", dispatch(t, m, "/recurring remove 4", now))
+	assert.Equal(t, recurringUsage, dispatch(t, m, "/recurring remove domain", now))
+	assert.Len(t, transactionDB.recurring, 4)
+}
+
+func TestPostRecurring(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 10, 15, 19, 30, 0, 0, time.UTC)
+	messenger := messagingtest.NewMessenger()
+	transactionDB := &fakeTransactionDB{}
+	m := newTestMessaging(transactionDB)
+	m.line = messenger
+
+	dispatch(t, m, "/recurring add rent 85000 monthly 27", now)
+
+	m.postRecurring(ctx, time.Date(2025, 10, 27, 8, 59, 0, 0, time.UTC))
+	assert.Empty(t, transactionDB.transactions)
+
+	due := time.Date(2025, 10, 27, 9, 0, 0, 0, time.UTC)
+	m.postRecurring(ctx, due)
+	if assert.Len(t, transactionDB.transactions, 1) {
+		posted := transactionDB.transactions[0]
+		assert.Equal(t, model.TransactionSource(model.TransactionSourceRecurring), posted.Source)
+		assert.Equal(t, due, posted.OccurredAt)
+		assert.Equal(t, "rent", posted.Note)
+	}
+	if assert.Len(t, messenger.Pushes(), 1) {
+		assert.Equal(t, "U1", messenger.Pushes()[0].To)
+		assert.Equal(t,
+			"Recorded your recurring expense of 85,000 JPY for rent on 2025-10-27 (monthly on the 27th).\nSend /recurring remove 1 to stop it.",
+			messenger.Pushes()[0].Messages[0].Text)
+	}
+
+	// A second pass, e.g. after a restart, doesn't post it again.
+	m.postRecurring(ctx, due.Add(time.Minute))
+	assert.Len(t, transactionDB.transactions, 1)
+
+	// Runs missed while the server was down are caught up.
+	m.postRecurring(ctx, time.Date(2026, 1, 28, 0, 0, 0, 0, time.UTC))
+	var dates []string
+	for _, posted := range transactionDB.transactions {
+		dates = append(dates, posted.OccurredAt.Format(time.DateOnly))
+	}
+	assert.Equal(t, []string{"2025-10-27", "2025-11-27", "2025-12-27", "2026-01-27"}, dates)
+	assert.Len(t, messenger.Pushes(), 4)
+	assert.Equal(t, time.Date(2026, 2, 27, 9, 0, 0, 0, time.UTC), transactionDB.recurring[0].NextRunAt)
+}
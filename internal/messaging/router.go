@@ -0,0 +1,103 @@
+package messaging
+
+import (
+	"context"
+	groupmodel "github/shaolim/momon/internal/group/model"
+	"github/shaolim/momon/internal/user/model"
+	msg "github/shaolim/momon/pkg/messaging"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Replier sends a reply to the chat a command came from. LINE reply tokens
+// are single use, so handlers should reply at most once.
+type Replier interface {
+	ReplyText(text string) error
+	Reply(messages ...msg.Message) error
+}
+
+// Request is a text message addressed to the bot, resolved to a Momon user.
+type Request struct {
+	User *model.User
+	// Identity is who sent the message on which channel.
+	Identity *model.Identity
+	// Group is the LINE group the message was sent in, nil outside of
+	// groups. Entries sent in a group go into its shared ledger.
+	Group *groupmodel.Group
+	Text  string
+	// Args holds the words following a prefix command, or the submatches of a
+	// pattern command.
+	Args  []string
+	Now   time.Time
+	Reply Replier
+}
+
+// GroupID is the ID of the group the message was sent in, or 0.
+func (r *Request) GroupID() int64 {
+	if r.Group == nil {
+		return 0
+	}
+	return r.Group.ID
+}
+
+type HandlerFunc func(ctx context.Context, req *Request) error
+
+type route struct {
+	prefix  string
+	pattern *regexp.Regexp
+	handler HandlerFunc
+}
+
+// Router dispatches text messages to the first handler whose prefix or
+// pattern matches, in registration order.
+type Router struct {
+	routes   []route
+	fallback HandlerFunc
+}
+
+func NewRouter() *Router {
+	return &Router{}
+}
+
+// Handle registers h for messages whose first word is prefix, ignoring case.
+func (r *Router) Handle(prefix string, h HandlerFunc) {
+	r.routes = append(r.routes, route{prefix: strings.ToLower(prefix), handler: h})
+}
+
+// HandlePattern registers h for messages matching pattern.
+func (r *Router) HandlePattern(pattern *regexp.Regexp, h HandlerFunc) {
+	r.routes = append(r.routes, route{pattern: pattern, handler: h})
+}
+
+// Fallback registers h for messages no other route matches.
+func (r *Router) Fallback(h HandlerFunc) {
+	r.fallback = h
+}
+
+func (r *Router) Dispatch(ctx context.Context, req *Request) error {
+	text := strings.TrimSpace(req.Text)
+	fields := strings.Fields(text)
+
+	for _, rt := range r.routes {
+		if rt.pattern != nil {
+			if m := rt.pattern.FindStringSubmatch(text); m != nil {
+				req.Args = m[1:]
+				return rt.handler(ctx, req)
+			}
+			continue
+		}
+
+		if len(fields) > 0 && strings.ToLower(fields[0]) == rt.prefix {
+			req.Args = fields[1:]
+			return rt.handler(ctx, req)
+		}
+	}
+
+	if r.fallback != nil {
+		req.Args = fields
+		return r.fallback(ctx, req)
+	}
+
+	return nil
+}
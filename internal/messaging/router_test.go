@@ -0,0 +1,85 @@
+package messaging
+
+import (
+	"context"
+	msg "github/shaolim/momon/pkg/messaging"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingReplier collects replies instead of sending them to LINE. replies
+// holds the text of each reply's first message.
+type recordingReplier struct {
+	replies  []string
+	messages [][]msg.Message
+}
+
+func (r *recordingReplier) ReplyText(text string) error {
+	return r.Reply(msg.TextMessage(text))
+}
+
+func (r *recordingReplier) Reply(messages ...msg.Message) error {
+	r.replies = append(r.replies, messages[0].Text)
+	r.messages = append(r.messages, messages)
+	return nil
+}
+
+func TestRouter_Dispatch(t *testing.T) {
+	var (
+		called string
+		args   []string
+	)
+	handler := func(name string) HandlerFunc {
+		return func(_ context.Context, req *Request) error {
+			called = name
+			args = req.Args
+			return nil
+		}
+	}
+
+	r := NewRouter()
+	r.Handle("/last", handler("last"))
+	r.Handle("/LAST-ALL", handler("last-all"))
+	r.HandlePattern(regexp.MustCompile(`(?i)^undo$`), handler("undo"))
+	r.HandlePattern(regexp.MustCompile(`^budget (\w+) (\d+)$`), handler("budget"))
+	r.Fallback(handler("fallback"))
+
+	tests := []struct {
+		text     string
+		wantCall string
+		wantArgs []string
+	}{
+		{text: "/last", wantCall: "last", wantArgs: []string{}},
+		{text: "  /last 10 ", wantCall: "last", wantArgs: []string{"10"}},
+		{text: "/Last 3", wantCall: "last", wantArgs: []string{"3"}},
+		{text: "/last-all", wantCall: "last-all", wantArgs: []string{}},
+		{text: "/lastly", wantCall: "fallback", wantArgs: []string{"/lastly"}},
+		{text: "UNDO", wantCall: "undo", wantArgs: []string{}},
+		{text: "budget food 30000", wantCall: "budget", wantArgs: []string{"food", "30000"}},
+		{text: "lunch 1200", wantCall: "fallback", wantArgs: []string{"lunch", "1200"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			called, args = "", nil
+
+			err := r.Dispatch(context.Background(), &Request{Text: tt.text, Reply: &recordingReplier{}})
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCall, called)
+			assert.Equal(t, tt.wantArgs, args)
+		})
+	}
+}
+
+func TestRouter_DispatchWithoutFallback(t *testing.T) {
+	r := NewRouter()
+	r.Handle("/help", func(context.Context, *Request) error {
+		t.Fatal("unexpected call")
+		return nil
+	})
+
+	assert.NoError(t, r.Dispatch(context.Background(), &Request{Text: "hello"}))
+}
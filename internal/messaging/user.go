@@ -0,0 +1,80 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github/shaolim/momon/internal/user/model"
+	"github/shaolim/momon/pkg/database"
+	msg "github/shaolim/momon/pkg/messaging"
+	"github/shaolim/momon/pkg/money"
+	"time"
+
+	"github.com/line/line-bot-sdk-go/v8/linebot/webhook"
+)
+
+// sourceUserID returns the LINE user ID of whoever triggered the event, or an
+// empty string when LINE did not include it.
+func sourceUserID(source webhook.SourceInterface) string {
+	switch s := source.(type) {
+	case webhook.UserSource:
+		return s.UserId
+	case webhook.GroupSource:
+		return s.UserId
+	case webhook.RoomSource:
+		return s.UserId
+	default:
+		return ""
+	}
+}
+
+// resolveUser looks up who triggered the event by their LINE user ID,
+// registering them from their LINE profile when they are not known yet.
+// Members of a group are registered even if they haven't added the bot as a
+// friend.
+func (m *messaging) resolveUser(ctx context.Context, source webhook.SourceInterface) (*model.User, error) {
+	lineUserID := sourceUserID(source)
+	if lineUserID == "" {
+		return nil, errors.New("line user id must not be empty")
+	}
+
+	user, err := m.userDB.GetByLineUserID(ctx, lineUserID)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, database.ErrNotFound) {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	var profile *msg.Profile
+	if s, ok := source.(webhook.GroupSource); ok {
+		profile, err = m.line.GetGroupMemberProfile(ctx, s.GroupId, lineUserID)
+	} else {
+		profile, err = m.line.GetProfile(ctx, lineUserID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get profile: %w", err)
+	}
+
+	user = &model.User{
+		LineUserID:  lineUserID,
+		DisplayName: profile.DisplayName,
+		Status:      model.UserStatusActive,
+	}
+	if err := m.userDB.Upsert(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to save user: %w", err)
+	}
+
+	return user, nil
+}
+
+// currency is what the user's amounts are in unless they say otherwise, and
+// what their reports are totalled in.
+func (m *messaging) currency(user *model.User) money.Currency {
+	return user.HomeCurrency(m.config.DefaultCurrency)
+}
+
+// location is the time zone the user's days start in.
+func (m *messaging) location(user *model.User) *time.Location {
+	return user.Location(m.config.DefaultLocation)
+}
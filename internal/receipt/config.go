@@ -0,0 +1,42 @@
+package receipt
+
+import (
+	"fmt"
+
+	"github.com/openai/openai-go/v3"
+)
+
+const (
+	BackendOpenAI  = "openai"
+	BackendFixture = "fixture"
+)
+
+type Config struct {
+	// Backend selects the extractor, either "openai" (default) or "fixture".
+	Backend string
+	// Model and BaseURL configure the OpenAI backend. Both are optional.
+	Model   string
+	BaseURL string
+	// FixturePath is the JSON receipt returned by the fixture backend.
+	FixturePath string
+}
+
+func (c *Config) ReceiptConfig() *Config {
+	return c
+}
+
+// NewExtractor builds the extractor selected by the config. client is only
+// used by the OpenAI backend.
+func NewExtractor(cfg *Config, client *openai.Client) (ReceiptExtractor, error) {
+	switch cfg.Backend {
+	case "", BackendOpenAI:
+		if client == nil {
+			return nil, fmt.Errorf("openai receipt backend requires an OpenAI client")
+		}
+		return NewOpenAI(client, WithModel(cfg.Model), WithBaseURL(cfg.BaseURL)), nil
+	case BackendFixture:
+		return LoadFixture(cfg.FixturePath)
+	default:
+		return nil, fmt.Errorf("unknown receipt backend %q", cfg.Backend)
+	}
+}
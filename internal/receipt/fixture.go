@@ -0,0 +1,72 @@
+package receipt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github/shaolim/momon/internal/receipt/model"
+	"os"
+	"sync"
+)
+
+// Fixture is an offline ReceiptExtractor that returns a canned receipt for
+// every image. It is meant for tests and for running the bot without a model.
+type Fixture struct {
+	receipt *model.Receipt
+	err     error
+
+	mu    sync.Mutex
+	calls int
+}
+
+func NewFixture(receipt *model.Receipt) *Fixture {
+	return &Fixture{
+		receipt: receipt,
+	}
+}
+
+// NewFailingFixture returns a Fixture whose Extract always fails with err.
+func NewFailingFixture(err error) *Fixture {
+	return &Fixture{
+		err: err,
+	}
+}
+
+// LoadFixture reads the canned receipt from a JSON file in the same format
+// model.Receipt marshals to.
+func LoadFixture(path string) (*Fixture, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture: %w", err)
+	}
+
+	var r model.Receipt
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal fixture: %w", err)
+	}
+
+	return NewFixture(&r), nil
+}
+
+func (f *Fixture) Extract(_ context.Context, _ []byte, _ string) (*model.Receipt, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	// Hand out a copy so callers can't modify the fixture.
+	r := *f.receipt
+	r.Items = append([]model.Item(nil), f.receipt.Items...)
+	return &r, nil
+}
+
+// Calls returns how many times Extract has been called.
+func (f *Fixture) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.calls
+}
@@ -1,15 +1,25 @@
 package model
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"fmt"
+	"github/shaolim/momon/pkg/money"
+	"math"
+)
 
+// Receipt is the data extracted from a receipt image. Monetary values are in
+// the currency's minor unit, e.g. yen for JPY and cents for USD.
 type Receipt struct {
-	Shop            string  `json:"shop"`
-	TransactionDate string  `json:"transactionDate"`
-	Items           []Item  `json:"items"`
-	Tax             float64 `json:"tax"`
-	Total           float64 `json:"total"`
-	IsValid         bool    `json:"isValid"`
-	Message         string  `json:"message"`
+	Shop            string `json:"shop"`
+	TransactionDate string `json:"transactionDate"`
+	Items           []Item `json:"items"`
+	Tax             int64  `json:"tax"`
+	Total           int64  `json:"total"`
+	IsValid         bool   `json:"isValid"`
+	Message         string `json:"message"`
+	// Currency is the ISO 4217 code the amounts are in, empty when the
+	// receipt does not tell.
+	Currency string `json:"currency"`
 }
 
 func (r *Receipt) String() string {
@@ -17,12 +27,139 @@ func (r *Receipt) String() string {
 	return string(jsonBytes)
 }
 
-// TODO: validate the calculation
+// Tolerance is how far apart two amounts may be and still be considered
+// equal. They match when the difference is within Absolute or within Relative
+// of the larger amount.
+type Tolerance struct {
+	Absolute int64
+	Relative float64
+}
+
+// DefaultTolerance absorbs per-line rounding and the 1% slack the extraction
+// prompt allows.
+var DefaultTolerance = Tolerance{Absolute: 1, Relative: 0.01}
+
+func (t Tolerance) equal(a, b money.Money) bool {
+	diff := math.Abs(float64(a.Amount) - float64(b.Amount))
+	return diff <= float64(t.Absolute) || diff <= t.Relative*math.Max(math.Abs(float64(a.Amount)), math.Abs(float64(b.Amount)))
+}
+
+type DiscrepancyKind string
+
+const (
+	// DiscrepancyItemTotal means an item's quantity × price + tax does not
+	// match its totalPrice.
+	DiscrepancyItemTotal = "ITEM_TOTAL"
+	// DiscrepancyTotal means the items do not add up to the receipt total.
+	DiscrepancyTotal = "TOTAL"
+	// DiscrepancyTax means the item taxes do not add up to the receipt tax.
+	DiscrepancyTax = "TAX"
+)
+
+// Discrepancy is a calculation on the receipt that does not add up. Expected
+// is what the parts add up to and Actual is what the receipt states.
+type Discrepancy struct {
+	Kind DiscrepancyKind
+	// Item is the index into Items for DiscrepancyItemTotal, -1 otherwise.
+	Item     int
+	Expected int64
+	Actual   int64
+}
+
+// Validate checks the receipt arithmetic and returns every discrepancy found.
+// Receipts that are not valid are not checked. It fails when the amounts are
+// too large to add up.
+func (r *Receipt) Validate(tolerance Tolerance) ([]Discrepancy, error) {
+	if !r.IsValid {
+		return nil, nil
+	}
+
+	var (
+		currency      = money.Currency(r.Currency)
+		total         = money.New(r.Total, currency)
+		tax           = money.New(r.Tax, currency)
+		discrepancies []Discrepancy
+		itemsTotal    = money.New(0, currency)
+		itemsTax      = money.New(0, currency)
+	)
+
+	for i, item := range r.Items {
+		expected, err := item.expected(currency)
+		if err != nil {
+			return nil, fmt.Errorf("item %d: %w", i, err)
+		}
+		if !tolerance.equal(expected, money.New(item.TotalPrice, currency)) {
+			discrepancies = append(discrepancies, Discrepancy{
+				Kind:     DiscrepancyItemTotal,
+				Item:     i,
+				Expected: expected.Amount,
+				Actual:   item.TotalPrice,
+			})
+		}
+		if itemsTotal, err = itemsTotal.Add(money.New(item.TotalPrice, currency)); err != nil {
+			return nil, fmt.Errorf("items total: %w", err)
+		}
+		if itemsTax, err = itemsTax.Add(money.New(item.Tax, currency)); err != nil {
+			return nil, fmt.Errorf("items tax: %w", err)
+		}
+	}
+
+	if len(r.Items) == 0 {
+		return discrepancies, nil
+	}
+
+	if itemsTax.IsZero() {
+		// Tax is not itemized. Prices either already include it, as on most
+		// Japanese receipts, or it is added on top of the item sum.
+		withTax, err := itemsTotal.Add(tax)
+		if err != nil {
+			return nil, fmt.Errorf("items total: %w", err)
+		}
+		if !tolerance.equal(itemsTotal, total) && !tolerance.equal(withTax, total) {
+			discrepancies = append(discrepancies, Discrepancy{
+				Kind:     DiscrepancyTotal,
+				Item:     -1,
+				Expected: withTax.Amount,
+				Actual:   r.Total,
+			})
+		}
+		return discrepancies, nil
+	}
+
+	if !tolerance.equal(itemsTotal, total) {
+		discrepancies = append(discrepancies, Discrepancy{
+			Kind:     DiscrepancyTotal,
+			Item:     -1,
+			Expected: itemsTotal.Amount,
+			Actual:   r.Total,
+		})
+	}
+
+	if !tolerance.equal(itemsTax, tax) {
+		discrepancies = append(discrepancies, Discrepancy{
+			Kind:     DiscrepancyTax,
+			Item:     -1,
+			Expected: itemsTax.Amount,
+			Actual:   r.Tax,
+		})
+	}
+
+	return discrepancies, nil
+}
 
 type Item struct {
 	Name       string  `json:"name"`
 	Quantity   float64 `json:"quantity"`
-	Price      float64 `json:"price"`
-	Tax        float64 `json:"tax"`
-	TotalPrice float64 `json:"totalPrice"`
+	Price      int64   `json:"price"`
+	Tax        int64   `json:"tax"`
+	TotalPrice int64   `json:"totalPrice"`
+}
+
+// expected is what the item should cost: quantity × price + tax.
+func (i Item) expected(currency money.Currency) (money.Money, error) {
+	subtotal, err := money.New(i.Price, currency).Mul(i.Quantity)
+	if err != nil {
+		return money.Money{}, err
+	}
+	return subtotal.Add(money.New(i.Tax, currency))
 }
@@ -2,7 +2,11 @@ package model_test
 
 import (
 	"encoding/json"
+	"errors"
 	"github/shaolim/momon/internal/receipt/model"
+	"github/shaolim/momon/pkg/money"
+	"math"
+	"reflect"
 	"testing"
 )
 
@@ -31,7 +35,7 @@ func TestReceipt_String(t *testing.T) {
 				IsValid: true,
 				Message: "",
 			},
-			want: `{"shop":"Test Store","transactionDate":"2024-01-15 14:30","items":[{"name":"Apple","quantity":2,"price":100,"tax":10,"totalPrice":210}],"tax":10,"total":210,"isValid":true,"message":""}`,
+			want: `{"shop":"Test Store","transactionDate":"2024-01-15 14:30","items":[{"name":"Apple","quantity":2,"price":100,"tax":10,"totalPrice":210}],"tax":10,"total":210,"isValid":true,"message":"","currency":""}`,
 		},
 		{
 			name: "valid receipt with multiple items",
@@ -59,7 +63,7 @@ func TestReceipt_String(t *testing.T) {
 				IsValid: true,
 				Message: "",
 			},
-			want: `{"shop":"Grocery Market","transactionDate":"2024-02-20 09:15","items":[{"name":"Bread","quantity":1,"price":50,"tax":5,"totalPrice":55},{"name":"Milk","quantity":2,"price":80,"tax":16,"totalPrice":176}],"tax":21,"total":231,"isValid":true,"message":""}`,
+			want: `{"shop":"Grocery Market","transactionDate":"2024-02-20 09:15","items":[{"name":"Bread","quantity":1,"price":50,"tax":5,"totalPrice":55},{"name":"Milk","quantity":2,"price":80,"tax":16,"totalPrice":176}],"tax":21,"total":231,"isValid":true,"message":"","currency":""}`,
 		},
 		{
 			name: "empty receipt",
@@ -72,7 +76,7 @@ func TestReceipt_String(t *testing.T) {
 				IsValid:         false,
 				Message:         "",
 			},
-			want: `{"shop":"","transactionDate":"","items":[],"tax":0,"total":0,"isValid":false,"message":""}`,
+			want: `{"shop":"","transactionDate":"","items":[],"tax":0,"total":0,"isValid":false,"message":"","currency":""}`,
 		},
 		{
 			name: "receipt with nil items",
@@ -85,7 +89,7 @@ func TestReceipt_String(t *testing.T) {
 				IsValid:         true,
 				Message:         "",
 			},
-			want: `{"shop":"Test Shop","transactionDate":"2024-03-10 12:00","items":null,"tax":0,"total":0,"isValid":true,"message":""}`,
+			want: `{"shop":"Test Shop","transactionDate":"2024-03-10 12:00","items":null,"tax":0,"total":0,"isValid":true,"message":"","currency":""}`,
 		},
 		{
 			name: "receipt with zero values",
@@ -106,7 +110,7 @@ func TestReceipt_String(t *testing.T) {
 				IsValid: true,
 				Message: "",
 			},
-			want: `{"shop":"Zero Store","transactionDate":"2024-04-05 00:00","items":[{"name":"Free Item","quantity":0,"price":0,"tax":0,"totalPrice":0}],"tax":0,"total":0,"isValid":true,"message":""}`,
+			want: `{"shop":"Zero Store","transactionDate":"2024-04-05 00:00","items":[{"name":"Free Item","quantity":0,"price":0,"tax":0,"totalPrice":0}],"tax":0,"total":0,"isValid":true,"message":"","currency":""}`,
 		},
 	}
 
@@ -231,13 +235,13 @@ func TestItem_JSONMarshaling(t *testing.T) {
 			wantErr: false,
 		},
 		{
-			name: "marshal item with decimal values",
+			name: "marshal item with fractional quantity",
 			item: model.Item{
 				Name:       "Banana",
 				Quantity:   1.5,
-				Price:      99.99,
-				Tax:        15.99,
-				TotalPrice: 165.98,
+				Price:      9999,
+				Tax:        1599,
+				TotalPrice: 16598,
 			},
 			wantErr: false,
 		},
@@ -420,13 +424,13 @@ func TestReceipt_String_EdgeCases(t *testing.T) {
 				{
 					Name:       "Expensive Item",
 					Quantity:   1000000,
-					Price:      999999.99,
-					Tax:        99999.99,
-					TotalPrice: 1099999989.99,
+					Price:      99999999,
+					Tax:        9999999,
+					TotalPrice: 100000008999999,
 				},
 			},
-			Tax:     99999.99,
-			Total:   1099999989.99,
+			Tax:     9999999,
+			Total:   100000008999999,
 			IsValid: true,
 			Message: "",
 		}
@@ -480,3 +484,143 @@ func TestReceipt_String_EdgeCases(t *testing.T) {
 		}
 	})
 }
+
+func TestReceipt_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		receipt model.Receipt
+		want    []model.Discrepancy
+	}{
+		{
+			name: "consistent receipt with itemized tax",
+			receipt: model.Receipt{
+				Items: []model.Item{
+					{Name: "Bread", Quantity: 1, Price: 50, Tax: 5, TotalPrice: 55},
+					{Name: "Milk", Quantity: 2, Price: 80, Tax: 16, TotalPrice: 176},
+				},
+				Tax:     21,
+				Total:   231,
+				IsValid: true,
+			},
+		},
+		{
+			name: "tax included in prices",
+			receipt: model.Receipt{
+				Items: []model.Item{
+					{Name: "Bento", Quantity: 1, Price: 540, TotalPrice: 540},
+					{Name: "Tea", Quantity: 1, Price: 160, TotalPrice: 160},
+				},
+				Tax:     51,
+				Total:   700,
+				IsValid: true,
+			},
+		},
+		{
+			name: "tax added on top of items",
+			receipt: model.Receipt{
+				Items: []model.Item{
+					{Name: "Burger", Quantity: 1, Price: 1000, TotalPrice: 1000},
+				},
+				Tax:     100,
+				Total:   1100,
+				IsValid: true,
+			},
+		},
+		{
+			name: "rounding within tolerance",
+			receipt: model.Receipt{
+				Items: []model.Item{
+					{Name: "Banana", Quantity: 1.5, Price: 99, Tax: 16, TotalPrice: 164},
+				},
+				Tax:     16,
+				Total:   165,
+				IsValid: true,
+			},
+		},
+		{
+			name: "items do not add up to total",
+			receipt: model.Receipt{
+				Items: []model.Item{
+					{Name: "Bento", Quantity: 2, Price: 1155, TotalPrice: 2310},
+				},
+				Total:   2130,
+				IsValid: true,
+			},
+			want: []model.Discrepancy{
+				{Kind: model.DiscrepancyTotal, Item: -1, Expected: 2310, Actual: 2130},
+			},
+		},
+		{
+			name: "item line and tax mismatch",
+			receipt: model.Receipt{
+				Items: []model.Item{
+					{Name: "Bread", Quantity: 1, Price: 50, Tax: 5, TotalPrice: 55},
+					{Name: "Milk", Quantity: 2, Price: 80, Tax: 16, TotalPrice: 186},
+				},
+				Tax:     30,
+				Total:   241,
+				IsValid: true,
+			},
+			want: []model.Discrepancy{
+				{Kind: model.DiscrepancyItemTotal, Item: 1, Expected: 176, Actual: 186},
+				{Kind: model.DiscrepancyTax, Item: -1, Expected: 21, Actual: 30},
+			},
+		},
+		{
+			name: "invalid receipt is not checked",
+			receipt: model.Receipt{
+				Items:   []model.Item{{Name: "Bento", Quantity: 1, Price: 100, TotalPrice: 999}},
+				Total:   1,
+				IsValid: false,
+			},
+		},
+		{
+			name:    "no items",
+			receipt: model.Receipt{Total: 500, IsValid: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.receipt.Validate(model.DefaultTolerance)
+			if err != nil {
+				t.Fatalf("Validate() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReceipt_ValidateTolerance(t *testing.T) {
+	receipt := model.Receipt{
+		Items:   []model.Item{{Name: "Bento", Quantity: 1, Price: 1000, TotalPrice: 1000}},
+		Total:   1005,
+		IsValid: true,
+	}
+
+	if got, err := receipt.Validate(model.DefaultTolerance); err != nil || len(got) != 0 {
+		t.Errorf("Validate(DefaultTolerance) = %+v, want none", got)
+	}
+
+	strict := model.Tolerance{}
+	if got, err := receipt.Validate(strict); err != nil || len(got) != 1 || got[0].Kind != model.DiscrepancyTotal {
+		t.Errorf("Validate(strict) = %+v, want one TOTAL discrepancy", got)
+	}
+}
+
+func TestReceipt_ValidateOverflow(t *testing.T) {
+	receipt := model.Receipt{
+		Items: []model.Item{
+			{Name: "Bento", Quantity: 1, Price: math.MaxInt64, TotalPrice: math.MaxInt64},
+			{Name: "Tea", Quantity: 1, Price: 150, TotalPrice: 150},
+		},
+		Total:   150,
+		IsValid: true,
+	}
+
+	if _, err := receipt.Validate(model.DefaultTolerance); !errors.Is(err, money.ErrOverflow) {
+		t.Errorf("Validate() error = %v, want %v", err, money.ErrOverflow)
+	}
+}
@@ -0,0 +1,92 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoTransactionDate      = errors.New("receipt has no transaction date")
+	ErrInvalidTransactionDate = errors.New("receipt transaction date is not a date")
+	ErrFutureTransactionDate  = errors.New("receipt transaction date is in the future")
+)
+
+// MaxFutureSkew is how far past now a receipt date may be before it is
+// rejected. It covers a shop clock that runs a little fast.
+const MaxFutureSkew = 24 * time.Hour
+
+// dateTimeLayouts are the layouts the model has been seen to return for
+// receipts that show a time, the first being the one the prompt asks for.
+var dateTimeLayouts = []string{
+	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02T15:04:05",
+	"2006/1/2 15:04",
+	"2006/1/2 15:04:05",
+	"2006.1.2 15:04",
+	"2006年1月2日 15:04",
+	"2006年1月2日 15時04分",
+}
+
+// dateLayouts are used for receipts that only show a date.
+var dateLayouts = []string{
+	"2006-01-02",
+	"2006/1/2",
+	"2006.1.2",
+	"2006年1月2日",
+}
+
+// TransactionTime is a parsed Receipt.TransactionDate.
+type TransactionTime struct {
+	Time time.Time
+	// TimeUnknown is set when the receipt only shows a date. Time is then noon
+	// of that day, so it stays on the same day if the zone is slightly off.
+	TimeUnknown bool
+}
+
+// TransactionTime parses TransactionDate in loc. Dates carrying their own
+// offset, such as RFC 3339, are converted to loc. Dates more than
+// MaxFutureSkew after now are rejected.
+func (r *Receipt) TransactionTime(loc *time.Location, now time.Time) (TransactionTime, error) {
+	value := strings.Join(strings.Fields(r.TransactionDate), " ")
+	if value == "" {
+		return TransactionTime{}, ErrNoTransactionDate
+	}
+
+	result, ok := parseTransactionTime(value, loc)
+	if !ok {
+		return TransactionTime{}, fmt.Errorf("%w: %q", ErrInvalidTransactionDate, r.TransactionDate)
+	}
+
+	if result.Time.After(now.Add(MaxFutureSkew)) {
+		return TransactionTime{}, fmt.Errorf("%w: %q", ErrFutureTransactionDate, r.TransactionDate)
+	}
+
+	return result, nil
+}
+
+func parseTransactionTime(value string, loc *time.Location) (TransactionTime, bool) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return TransactionTime{Time: t.In(loc)}, true
+	}
+
+	for _, layout := range dateTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return TransactionTime{Time: t}, true
+		}
+	}
+
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return TransactionTime{
+				Time:        time.Date(t.Year(), t.Month(), t.Day(), 12, 0, 0, 0, loc),
+				TimeUnknown: true,
+			}, true
+		}
+	}
+
+	return TransactionTime{}, false
+}
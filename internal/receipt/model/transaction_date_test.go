@@ -0,0 +1,111 @@
+package model_test
+
+import (
+	"errors"
+	"github/shaolim/momon/internal/receipt/model"
+	"testing"
+	"time"
+)
+
+func TestReceipt_TransactionTime(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+	now := time.Date(2025, 10, 15, 19, 30, 0, 0, tokyo)
+
+	tests := []struct {
+		name            string
+		date            string
+		want            time.Time
+		wantTimeUnknown bool
+		wantErr         error
+	}{
+		{
+			name: "prompt format",
+			date: "2025-10-15 00:15",
+			want: time.Date(2025, 10, 15, 0, 15, 0, 0, tokyo),
+		},
+		{
+			name: "midnight is a real time",
+			date: "2025-10-15 00:00",
+			want: time.Date(2025, 10, 15, 0, 0, 0, 0, tokyo),
+		},
+		{
+			name: "seconds",
+			date: "2025-10-14 23:59:30",
+			want: time.Date(2025, 10, 14, 23, 59, 30, 0, tokyo),
+		},
+		{
+			name: "slashes without padding",
+			date: "2025/10/3 8:05",
+			want: time.Date(2025, 10, 3, 8, 5, 0, 0, tokyo),
+		},
+		{
+			name: "japanese",
+			date: "2025年10月3日 18:20",
+			want: time.Date(2025, 10, 3, 18, 20, 0, 0, tokyo),
+		},
+		{
+			name: "rfc3339 is converted to the location",
+			date: "2025-10-14T15:30:00Z",
+			want: time.Date(2025, 10, 15, 0, 30, 0, 0, tokyo),
+		},
+		{
+			name:            "date only",
+			date:            "2025-10-03",
+			want:            time.Date(2025, 10, 3, 12, 0, 0, 0, tokyo),
+			wantTimeUnknown: true,
+		},
+		{
+			name:            "japanese date only",
+			date:            "2025年10月3日",
+			want:            time.Date(2025, 10, 3, 12, 0, 0, 0, tokyo),
+			wantTimeUnknown: true,
+		},
+		{
+			name: "slightly in the future",
+			date: "2025-10-16 09:00",
+			want: time.Date(2025, 10, 16, 9, 0, 0, 0, tokyo),
+		},
+		{
+			name:    "far in the future",
+			date:    "2052-10-15 12:00",
+			wantErr: model.ErrFutureTransactionDate,
+		},
+		{
+			name:    "empty",
+			date:    " ",
+			wantErr: model.ErrNoTransactionDate,
+		},
+		{
+			name:    "not a date",
+			date:    "yesterday",
+			wantErr: model.ErrInvalidTransactionDate,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &model.Receipt{TransactionDate: tt.date}
+
+			got, err := r.TransactionTime(tokyo, now)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("TransactionTime() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("TransactionTime() unexpected error: %v", err)
+			}
+
+			if !got.Time.Equal(tt.want) || got.Time.Location() != tokyo {
+				t.Errorf("Time = %v, want %v", got.Time, tt.want)
+			}
+			if got.TimeUnknown != tt.wantTimeUnknown {
+				t.Errorf("TimeUnknown = %v, want %v", got.TimeUnknown, tt.wantTimeUnknown)
+			}
+		})
+	}
+}
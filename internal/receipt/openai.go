@@ -0,0 +1,194 @@
+package receipt
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github/shaolim/momon/internal/receipt/model"
+	"strings"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+)
+
+// OpenAI extracts receipts with an OpenAI chat model. It works with any
+// OpenAI-compatible server, see WithBaseURL.
+type OpenAI struct {
+	client         *openai.Client
+	model          openai.ChatModel
+	prompt         string
+	requestOptions []option.RequestOption
+}
+
+type OpenAIOption func(*OpenAI) *OpenAI
+
+// WithModel overrides the chat model, which defaults to gpt-4o.
+func WithModel(model string) OpenAIOption {
+	return func(o *OpenAI) *OpenAI {
+		if model != "" {
+			o.model = model
+		}
+		return o
+	}
+}
+
+// WithBaseURL sends requests to another OpenAI-compatible server, e.g. a local
+// model server, instead of the client's default endpoint.
+func WithBaseURL(baseURL string) OpenAIOption {
+	return func(o *OpenAI) *OpenAI {
+		if baseURL != "" {
+			o.requestOptions = append(o.requestOptions, option.WithBaseURL(baseURL))
+		}
+		return o
+	}
+}
+
+func NewOpenAI(client *openai.Client, opts ...OpenAIOption) *OpenAI {
+	prompt := `You are a receipt information extraction assistant. Your task is to analyze the uploaded image and extract structured receipt data.
+
+VALIDATION RULES:
+1. Verify the image is a valid receipt (must contain: merchant name, date, items with prices, and total)
+2. If the image is NOT a receipt (e.g., random photo, document, etc.), return an invalid receipt
+3. If the receipt is too blurry or text is unreadable, return an invalid receipt
+
+FIELD DESCRIPTIONS:
+- shop: Merchant/store name as shown on receipt
+- transactionDate: Date and time in YYYY-MM-DD HH:MM format, or only YYYY-MM-DD if the time is not printed (do not guess 00:00)
+- items: Array of all purchased items
+  - name: Product name/description
+  - quantity: Number of units purchased (default: 1 if not specified)
+  - price: Unit price per item (not total)
+  - tax: Tax amount for this specific item (0 if not itemized)
+  - totalPrice: Calculated as (quantity × price) + tax
+- tax: Total tax amount for entire receipt (sum all item taxes, or use receipt total tax)
+- total: Final total amount paid (must match receipt total)
+- currency: ISO 4217 code of the amounts (e.g. JPY, USD, THB, EUR), judged from currency symbols, the address, the language and the tax format; empty if it cannot be told
+- isValid: Must be true for valid receipts
+- message: Empty for valid receipts
+
+INVALID RECEIPT:
+- Set isValid to false and explain why in message
+- Leave the other fields empty (empty strings, empty items and zero amounts)
+
+CALCULATION REQUIREMENTS:
+- Verify that sum of all item totalPrices matches the receipt subtotal
+- Verify that subtotal + tax = total on receipt
+- If calculations don't match receipt within 1% tolerance, still extract visible data but note any discrepancy
+- All monetary values are whole numbers in the smallest currency unit (e.g., 1234 cents for $12.34, 1234 yen for ¥1,234)
+
+CRITICAL REQUIREMENTS:
+- Return ONLY the JSON object described by the response schema, no additional text or explanation
+- Use an empty string or 0 for values that cannot be read`
+
+	o := &OpenAI{
+		client: client,
+		model:  openai.ChatModelGPT4o,
+		prompt: prompt,
+	}
+	for _, f := range opts {
+		o = f(o)
+	}
+
+	return o
+}
+
+// cleanJSONResponse removes markdown code blocks and other formatting from the API response
+func cleanJSONResponse(content string) string {
+	// Trim whitespace
+	content = strings.TrimSpace(content)
+
+	// Remove markdown code blocks (```json ... ``` or ``` ... ```)
+	if strings.HasPrefix(content, "```") {
+		// Find the first newline after ```
+		lines := strings.Split(content, "\n")
+		if len(lines) > 0 {
+			// Remove first line (```json or ```)
+			lines = lines[1:]
+		}
+		// Remove last line if it's ```
+		if len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "```" {
+			lines = lines[:len(lines)-1]
+		}
+		content = strings.Join(lines, "\n")
+	}
+
+	// Trim again after removing code blocks
+	content = strings.TrimSpace(content)
+
+	return content
+}
+
+func (r *OpenAI) Extract(ctx context.Context, imgBytes []byte, mimeType string) (*model.Receipt, error) {
+	if mimeType == "" {
+		mimeType = "image/jpeg"
+	}
+	dataURL := fmt.Sprintf("data:%s;base64,%s", mimeType, base64.StdEncoding.EncodeToString(imgBytes))
+
+	messages := []openai.ChatCompletionMessageParamUnion{
+		{
+			OfUser: &openai.ChatCompletionUserMessageParam{
+				Content: openai.ChatCompletionUserMessageParamContentUnion{
+					OfArrayOfContentParts: []openai.ChatCompletionContentPartUnionParam{
+						{
+							OfText: &openai.ChatCompletionContentPartTextParam{
+								Text: r.prompt,
+							},
+						},
+						{
+							OfImageURL: &openai.ChatCompletionContentPartImageParam{
+								ImageURL: openai.ChatCompletionContentPartImageImageURLParam{
+									URL: dataURL,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	req := openai.ChatCompletionNewParams{
+		Messages: messages,
+		Model:    r.model,
+		ResponseFormat: openai.ChatCompletionNewParamsResponseFormatUnion{
+			OfJSONSchema: &openai.ResponseFormatJSONSchemaParam{
+				JSONSchema: openai.ResponseFormatJSONSchemaJSONSchemaParam{
+					Name:   "receipt",
+					Schema: receiptSchema,
+					Strict: openai.Bool(true),
+				},
+			},
+		},
+	}
+
+	resp, err := r.client.Chat.Completions.New(ctx, req, r.requestOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get response from OpenAI: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("no choices returned from OpenAI")
+	}
+	message := resp.Choices[0].Message
+	if message.Refusal != "" {
+		return nil, fmt.Errorf("model refused to read receipt: %s", message.Refusal)
+	}
+
+	return unmarshalReceipt(message.Content)
+}
+
+// unmarshalReceipt decodes the model output. With structured outputs the
+// content is plain JSON, but servers that ignore response_format may still
+// wrap it in markdown, so fall back to stripping code fences.
+func unmarshalReceipt(content string) (*model.Receipt, error) {
+	var result model.Receipt
+	if err := json.Unmarshal([]byte(content), &result); err == nil {
+		return &result, nil
+	}
+
+	if err := json.Unmarshal([]byte(cleanJSONResponse(content)), &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal receipt: %w, content: %s", err, content)
+	}
+
+	return &result, nil
+}
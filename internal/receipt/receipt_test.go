@@ -1,114 +1,142 @@
 package receipt
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github/shaolim/momon/internal/receipt/model"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
 	"testing"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
 )
 
-func TestCleanJSONResponse(t *testing.T) {
+func TestFixture(t *testing.T) {
+	fixture, err := LoadFixture(filepath.Join("testdata", "receipt.json"))
+	if err != nil {
+		t.Fatalf("LoadFixture() unexpected error: %v", err)
+	}
+
+	var extractor ReceiptExtractor = fixture
+	got, err := ReadReceipt(context.Background(), extractor, filepath.Join("..", "..", "sample", "receipt.jpeg"))
+	if err != nil {
+		t.Fatalf("ReadReceipt() unexpected error: %v", err)
+	}
+
+	if got.Shop != "Grocery Market" || got.Total != 231 || len(got.Items) != 2 {
+		t.Errorf("ReadReceipt() = %s", got)
+	}
+
+	// Changing the returned receipt must not leak into the next call.
+	got.Items[0].Name = "changed"
+	again, _ := fixture.Extract(context.Background(), nil, "")
+	if again.Items[0].Name != "Bread" {
+		t.Errorf("fixture was modified by a caller: %s", again)
+	}
+
+	if fixture.Calls() != 2 {
+		t.Errorf("Calls() = %d, want 2", fixture.Calls())
+	}
+}
+
+func TestFailingFixture(t *testing.T) {
+	wantErr := errors.New("model unavailable")
+	fixture := NewFailingFixture(wantErr)
+
+	if _, err := fixture.Extract(context.Background(), nil, ""); !errors.Is(err, wantErr) {
+		t.Errorf("Extract() error = %v, want %v", err, wantErr)
+	}
+}
+
+func TestNewExtractor(t *testing.T) {
+	client := openai.NewClient(option.WithAPIKey("test"))
+
 	tests := []struct {
-		name     string
-		input    string
-		expected string
+		name    string
+		cfg     *Config
+		client  *openai.Client
+		want    string
+		wantErr bool
 	}{
-		{
-			name:     "plain JSON without markdown",
-			input:    `{"shop":"Test Store","isValid":true}`,
-			expected: `{"shop":"Test Store","isValid":true}`,
-		},
-		{
-			name: "JSON wrapped in markdown code blocks with json tag",
-			input: "```json\n{\"shop\":\"Test Store\",\"isValid\":true}\n```",
-			expected: `{"shop":"Test Store","isValid":true}`,
-		},
-		{
-			name: "JSON wrapped in markdown code blocks without tag",
-			input: "```\n{\"shop\":\"Test Store\",\"isValid\":true}\n```",
-			expected: `{"shop":"Test Store","isValid":true}`,
-		},
-		{
-			name: "JSON with leading and trailing whitespace",
-			input: "  \n  {\"shop\":\"Test Store\",\"isValid\":true}  \n  ",
-			expected: `{"shop":"Test Store","isValid":true}`,
-		},
-		{
-			name: "multiline JSON in code blocks",
-			input: "```json\n{\n  \"shop\": \"Test Store\",\n  \"isValid\": true\n}\n```",
-			expected: "{\n  \"shop\": \"Test Store\",\n  \"isValid\": true\n}",
-		},
-		{
-			name: "code block with extra whitespace",
-			input: "  ```json  \n{\"shop\":\"Test Store\"}\n```  ",
-			expected: `{"shop":"Test Store"}`,
-		},
-		{
-			name:     "empty string",
-			input:    "",
-			expected: "",
-		},
-		{
-			name:     "only whitespace",
-			input:    "   \n\t  ",
-			expected: "",
-		},
-		{
-			name: "code block with no closing marker",
-			input: "```json\n{\"shop\":\"Test Store\"}",
-			expected: `{"shop":"Test Store"}`,
-		},
+		{name: "default is openai", cfg: &Config{}, client: &client, want: "*receipt.OpenAI"},
+		{name: "openai without client", cfg: &Config{Backend: BackendOpenAI}, wantErr: true},
+		{name: "fixture", cfg: &Config{Backend: BackendFixture, FixturePath: filepath.Join("testdata", "receipt.json")}, want: "*receipt.Fixture"},
+		{name: "missing fixture", cfg: &Config{Backend: BackendFixture, FixturePath: "missing.json"}, wantErr: true},
+		{name: "unknown", cfg: &Config{Backend: "tesseract"}, wantErr: true},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := cleanJSONResponse(tt.input)
-			if result != tt.expected {
-				t.Errorf("cleanJSONResponse() = %q, want %q", result, tt.expected)
+			got, err := NewExtractor(tt.cfg, tt.client)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewExtractor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && fmt.Sprintf("%T", got) != tt.want {
+				t.Errorf("NewExtractor() = %T, want %s", got, tt.want)
 			}
 		})
 	}
 }
 
-func TestCleanJSONResponse_RealWorldExamples(t *testing.T) {
-	t.Run("typical OpenAI response with markdown", func(t *testing.T) {
-		input := "```json\n{\n    \"shop\": \"Walmart\",\n    \"transactionDate\": \"2024-01-15 14:30\",\n    \"items\": [\n        {\n            \"name\": \"Milk\",\n            \"quantity\": 2,\n            \"price\": 350,\n            \"tax\": 0,\n            \"totalPrice\": 700\n        }\n    ],\n    \"tax\": 0,\n    \"total\": 700,\n    \"isValid\": true\n}\n```"
-
-		result := cleanJSONResponse(input)
-
-		// Should not contain markdown markers
-		if containsBackticks(result) {
-			t.Errorf("cleanJSONResponse() still contains backticks: %q", result)
-		}
-
-		// Should start with {
-		if len(result) == 0 || result[0] != '{' {
-			t.Errorf("cleanJSONResponse() does not start with '{': %q", result)
+func TestOpenAI_BaseURLAndModel(t *testing.T) {
+	var (
+		gotModel      string
+		gotFormatType string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			http.NotFound(w, r)
+			return
 		}
 
-		// Should end with }
-		if len(result) == 0 || result[len(result)-1] != '}' {
-			t.Errorf("cleanJSONResponse() does not end with '}': %q", result)
+		var body struct {
+			Model          string `json:"model"`
+			ResponseFormat struct {
+				Type string `json:"type"`
+			} `json:"response_format"`
 		}
-	})
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		gotModel = body.Model
+		gotFormatType = body.ResponseFormat.Type
 
-	t.Run("OpenAI response without markdown", func(t *testing.T) {
-		input := `{
-    "shop": "Target",
-    "isValid": true
-}`
+		content, _ := json.Marshal(model.Receipt{Shop: "Local Model Mart", Total: 500, IsValid: true})
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"id":      "chatcmpl-test",
+			"object":  "chat.completion",
+			"created": 0,
+			"model":   body.Model,
+			"choices": []map[string]any{{
+				"index":         0,
+				"finish_reason": "stop",
+				"message": map[string]any{
+					"role":    "assistant",
+					"content": "```json\n" + string(content) + "\n```",
+				},
+			}},
+		})
+	}))
+	defer srv.Close()
 
-		result := cleanJSONResponse(input)
+	client := openai.NewClient(option.WithAPIKey("test"), option.WithMaxRetries(0))
+	extractor := NewOpenAI(&client, WithModel("llava"), WithBaseURL(srv.URL+"/v1/"))
 
-		// Should preserve the JSON structure
-		if result != input {
-			t.Errorf("cleanJSONResponse() modified plain JSON: got %q, want %q", result, input)
-		}
-	})
-}
+	got, err := extractor.Extract(context.Background(), []byte("image"), "image/png")
+	if err != nil {
+		t.Fatalf("Extract() unexpected error: %v", err)
+	}
 
-func containsBackticks(s string) bool {
-	for _, ch := range s {
-		if ch == '`' {
-			return true
-		}
+	if gotModel != "llava" {
+		t.Errorf("model = %q, want llava", gotModel)
+	}
+	if gotFormatType != "json_schema" {
+		t.Errorf("response_format.type = %q, want json_schema", gotFormatType)
+	}
+	if got.Shop != "Local Model Mart" || got.Total != 500 {
+		t.Errorf("Extract() = %s", got)
 	}
-	return false
 }
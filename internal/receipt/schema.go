@@ -0,0 +1,61 @@
+package receipt
+
+import (
+	"github/shaolim/momon/internal/receipt/model"
+	"reflect"
+	"strings"
+)
+
+// receiptSchema is the JSON schema sent as the structured output format. It
+// is derived from model.Receipt so the two cannot drift apart.
+var receiptSchema = jsonSchema(reflect.TypeFor[model.Receipt]())
+
+// jsonSchema builds a strict-mode JSON schema for t: every field listed in
+// the json tags is required and no additional properties are allowed. It only
+// supports the kinds used by the receipt model.
+func jsonSchema(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return jsonSchema(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": jsonSchema(t.Elem())}
+	case reflect.Struct:
+		properties := map[string]any{}
+		required := []string{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+
+			properties[name] = jsonSchema(field.Type)
+			required = append(required, name)
+		}
+
+		return map[string]any{
+			"type":                 "object",
+			"properties":           properties,
+			"required":             required,
+			"additionalProperties": false,
+		}
+	default:
+		panic("jsonSchema: unsupported kind " + t.Kind().String())
+	}
+}
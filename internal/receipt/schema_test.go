@@ -0,0 +1,61 @@
+package receipt
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReceiptSchema(t *testing.T) {
+	b, err := json.Marshal(receiptSchema)
+	if err != nil {
+		t.Fatalf("failed to marshal schema: %v", err)
+	}
+
+	want := `{
+		"type": "object",
+		"additionalProperties": false,
+		"required": ["shop", "transactionDate", "items", "tax", "total", "isValid", "message", "currency"],
+		"properties": {
+			"shop": {"type": "string"},
+			"transactionDate": {"type": "string"},
+			"items": {
+				"type": "array",
+				"items": {
+					"type": "object",
+					"additionalProperties": false,
+					"required": ["name", "quantity", "price", "tax", "totalPrice"],
+					"properties": {
+						"name": {"type": "string"},
+						"quantity": {"type": "number"},
+						"price": {"type": "integer"},
+						"tax": {"type": "integer"},
+						"totalPrice": {"type": "integer"}
+					}
+				}
+			},
+			"tax": {"type": "integer"},
+			"total": {"type": "integer"},
+			"isValid": {"type": "boolean"},
+			"message": {"type": "string"},
+			"currency": {"type": "string"}
+		}
+	}`
+	assert.JSONEq(t, want, string(b))
+}
+
+func TestJSONSchema_SkipsIgnoredFields(t *testing.T) {
+	type example struct {
+		Name     string `json:"name,omitempty"`
+		Count    int
+		Internal string `json:"-"`
+		hidden   string
+	}
+
+	schema := jsonSchema(reflect.TypeFor[example]())
+
+	assert.Equal(t, []string{"name", "Count"}, schema["required"])
+	assert.Equal(t, map[string]any{"type": "integer"}, schema["properties"].(map[string]any)["Count"])
+}
@@ -1,9 +1,17 @@
 package serverenv
 
 import (
+	"errors"
+	"fmt"
+	"github/shaolim/momon/internal/receipt"
 	"github/shaolim/momon/pkg/database"
 	"github/shaolim/momon/pkg/messaging"
+	"github/shaolim/momon/pkg/money"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type LineMessagingConfigProvider interface {
@@ -14,29 +22,249 @@ type DatabaseConfigProvider interface {
 	DatabaseConfig() *database.Config
 }
 
+type ReceiptConfigProvider interface {
+	ReceiptConfig() *receipt.Config
+}
+
 type Config struct {
 	Messaging LineMessagingConfigProvider
 	Database  DatabaseConfigProvider
+	Receipt   ReceiptConfigProvider
 	Host      string
+	// MigrationsDir holds the SQL migrations applied at startup.
+	MigrationsDir string
+	OpenAIAPIKey  string
+	// ShutdownTimeout bounds how long a shutdown waits for in-flight webhook
+	// work before cancelling it.
+	ShutdownTimeout time.Duration
+	// WebhookWorkers is how many webhook events are processed at once and
+	// WebhookMaxAttempts how often a failing one is tried before giving up.
+	WebhookWorkers     int
+	WebhookMaxAttempts int
+	// ProcessedEventTTL is how long handled webhook event IDs are remembered
+	// to ignore LINE's redeliveries.
+	ProcessedEventTTL time.Duration
+	// ReceiptDraftTTL is how long a receipt read from a photo waits for the
+	// user to save it before it is dropped.
+	ReceiptDraftTTL time.Duration
+	// DefaultCurrency is the ISO 4217 code used when a receipt or message
+	// does not state its currency.
+	DefaultCurrency money.Currency
+	// DefaultLocation is the time zone of users who have not chosen one. It
+	// decides where a day starts for receipts and summaries.
+	DefaultLocation *time.Location
+	// BudgetAlertThresholds are the percentages of a monthly budget, in
+	// ascending order, at which the user is warned. Each is pushed at most
+	// once per budget and month.
+	BudgetAlertThresholds []int
 }
 
-func LoadEnv() *Config {
+// LoadEnv reads the config from the environment. It reports every missing or
+// invalid variable at once so a misconfigured deploy fails on startup.
+func LoadEnv() (*Config, error) {
 	messagingConfig := &messaging.Config{
 		LineChannelSecret: os.Getenv("LINE_CHANNEL_SECRET"),
 		LineChannelToken:  os.Getenv("LINE_CHANNEL_TOKEN"),
+
+		TelegramBotToken:      os.Getenv("TELEGRAM_BOT_TOKEN"),
+		TelegramWebhookSecret: os.Getenv("TELEGRAM_WEBHOOK_SECRET"),
+	}
+
+	receiptConfig := &receipt.Config{
+		Backend:     os.Getenv("RECEIPT_BACKEND"),
+		Model:       os.Getenv("RECEIPT_MODEL"),
+		BaseURL:     os.Getenv("OPENAI_BASE_URL"),
+		FixturePath: os.Getenv("RECEIPT_FIXTURE"),
+	}
+
+	var errs []error
+
+	host := os.Getenv("HTTP_PORT")
+	if host == "" {
+		host = "8080"
+	}
+
+	shutdownTimeout := 25 * time.Second
+	if v := os.Getenv("SHUTDOWN_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			errs = append(errs, fmt.Errorf("SHUTDOWN_TIMEOUT: invalid duration %q", v))
+		}
+		shutdownTimeout = d
+	}
+
+	processedEventTTL := 72 * time.Hour
+	if v := os.Getenv("PROCESSED_EVENT_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			errs = append(errs, fmt.Errorf("PROCESSED_EVENT_TTL: invalid duration %q", v))
+		}
+		processedEventTTL = d
+	}
+
+	receiptDraftTTL := 24 * time.Hour
+	if v := os.Getenv("RECEIPT_DRAFT_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			errs = append(errs, fmt.Errorf("RECEIPT_DRAFT_TTL: invalid duration %q", v))
+		}
+		receiptDraftTTL = d
+	}
+
+	webhookWorkers, err := positiveIntEnv("WEBHOOK_WORKERS", 4)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	webhookMaxAttempts, err := positiveIntEnv("WEBHOOK_MAX_ATTEMPTS", 5)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	budgetAlertThresholds := []int{80, 100}
+	if v := os.Getenv("BUDGET_ALERT_THRESHOLDS"); v != "" {
+		thresholds, err := parsePercentages(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("BUDGET_ALERT_THRESHOLDS: %w", err))
+		}
+		budgetAlertThresholds = thresholds
+	}
+
+	defaultCurrency := os.Getenv("DEFAULT_CURRENCY")
+	if defaultCurrency == "" {
+		defaultCurrency = "JPY"
+	}
+	currency, err := money.ParseCurrency(defaultCurrency)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("DEFAULT_CURRENCY: %w", err))
+	}
+
+	defaultTimezone := os.Getenv("DEFAULT_TIMEZONE")
+	if defaultTimezone == "" {
+		defaultTimezone = "Asia/Tokyo"
+	}
+	location, err := time.LoadLocation(defaultTimezone)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("DEFAULT_TIMEZONE: invalid timezone %q", defaultTimezone))
+	}
+
+	config := &Config{
+		Messaging:          messagingConfig,
+		Database:           databaseEnv(),
+		Receipt:            receiptConfig,
+		Host:               host,
+		MigrationsDir:      migrationsDirEnv(),
+		OpenAIAPIKey:       os.Getenv("OPENAI_APIKEY"),
+		ShutdownTimeout:    shutdownTimeout,
+		WebhookWorkers:     webhookWorkers,
+		WebhookMaxAttempts: webhookMaxAttempts,
+		ProcessedEventTTL:  processedEventTTL,
+		ReceiptDraftTTL:    receiptDraftTTL,
+		DefaultCurrency:    currency,
+		DefaultLocation:    location,
+
+		BudgetAlertThresholds: budgetAlertThresholds,
+	}
+
+	if err := config.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return config, nil
+}
+
+// LoadDatabaseEnv reads only what is needed to connect to and migrate the
+// database, for commands such as import-rates that don't run the bot.
+func LoadDatabaseEnv() (*Config, error) {
+	config := &Config{
+		Database:      databaseEnv(),
+		MigrationsDir: migrationsDirEnv(),
+	}
+	if config.Database.DatabaseConfig().Name == "" {
+		return nil, errors.New("invalid config: DB_NAME is required")
 	}
 
-	databaseConfig := &database.Config{
+	return config, nil
+}
+
+func databaseEnv() *database.Config {
+	return &database.Config{
 		Name:     os.Getenv("DB_NAME"),
 		User:     os.Getenv("DB_USER"),
 		Host:     os.Getenv("DB_HOST"),
 		Port:     os.Getenv("DB_PORT"),
 		Password: os.Getenv("DB_PASSWORD"),
+		SSLMode:  os.Getenv("DB_SSLMODE"),
 	}
+}
 
-	return &Config{
-		Messaging: messagingConfig,
-		Database:  databaseConfig,
-		Host:      os.Getenv("HTTP_PORT"),
+func migrationsDirEnv() string {
+	if dir := os.Getenv("MIGRATIONS_DIR"); dir != "" {
+		return dir
 	}
+	return "migrations"
+}
+
+// positiveIntEnv reads a positive integer variable, returning def when unset.
+func positiveIntEnv(name string, def int) (int, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%s: must be a positive integer, got %q", name, v)
+	}
+	return n, nil
+}
+
+// parsePercentages reads a comma-separated list of positive percentages such
+// as "80,100" and returns them sorted without duplicates.
+func parsePercentages(v string) ([]int, error) {
+	var percentages []int
+	for field := range strings.SplitSeq(v, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(field), "%")))
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid percentage %q", strings.TrimSpace(field))
+		}
+		percentages = append(percentages, n)
+	}
+
+	slices.Sort(percentages)
+	return slices.Compact(percentages), nil
+}
+
+// Validate checks that the variables the server cannot run without are set.
+func (c *Config) Validate() error {
+	var errs []error
+	required := func(name, value string) {
+		if value == "" {
+			errs = append(errs, fmt.Errorf("%s is required", name))
+		}
+	}
+
+	messagingConfig := c.Messaging.MessagingConfig()
+	required("LINE_CHANNEL_SECRET", messagingConfig.LineChannelSecret)
+	required("LINE_CHANNEL_TOKEN", messagingConfig.LineChannelToken)
+	if messagingConfig.TelegramBotToken != "" {
+		// Without the secret anyone could post updates as any Telegram user.
+		required("TELEGRAM_WEBHOOK_SECRET", messagingConfig.TelegramWebhookSecret)
+	}
+
+	required("DB_NAME", c.Database.DatabaseConfig().Name)
+
+	switch receiptConfig := c.Receipt.ReceiptConfig(); receiptConfig.Backend {
+	case "", receipt.BackendOpenAI:
+		required("OPENAI_APIKEY", c.OpenAIAPIKey)
+	case receipt.BackendFixture:
+		required("RECEIPT_FIXTURE", receiptConfig.FixturePath)
+	default:
+		errs = append(errs, fmt.Errorf("RECEIPT_BACKEND: unknown backend %q", receiptConfig.Backend))
+	}
+
+	return errors.Join(errs...)
 }
@@ -0,0 +1,120 @@
+package serverenv
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setRequiredEnv(t *testing.T) {
+	t.Helper()
+
+	t.Setenv("LINE_CHANNEL_SECRET", "secret")
+	t.Setenv("LINE_CHANNEL_TOKEN", "token")
+	t.Setenv("DB_NAME", "momon")
+	t.Setenv("OPENAI_APIKEY", "sk-test")
+	for _, name := range []string{"HTTP_PORT", "MIGRATIONS_DIR", "SHUTDOWN_TIMEOUT", "WEBHOOK_WORKERS", "WEBHOOK_MAX_ATTEMPTS", "PROCESSED_EVENT_TTL", "RECEIPT_DRAFT_TTL", "DEFAULT_CURRENCY", "DEFAULT_TIMEZONE", "RECEIPT_BACKEND", "RECEIPT_FIXTURE", "TELEGRAM_BOT_TOKEN", "TELEGRAM_WEBHOOK_SECRET", "BUDGET_ALERT_THRESHOLDS"} {
+		t.Setenv(name, "")
+	}
+}
+
+func TestLoadEnv(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		setRequiredEnv(t)
+
+		config, err := LoadEnv()
+		if err != nil {
+			t.Fatalf("LoadEnv() unexpected error: %v", err)
+		}
+
+		tokyo, _ := time.LoadLocation("Asia/Tokyo")
+		assert.Equal(t, "8080", config.Host)
+		assert.Equal(t, "migrations", config.MigrationsDir)
+		assert.Equal(t, 25*time.Second, config.ShutdownTimeout)
+		assert.Equal(t, 4, config.WebhookWorkers)
+		assert.Equal(t, 5, config.WebhookMaxAttempts)
+		assert.Equal(t, 72*time.Hour, config.ProcessedEventTTL)
+		assert.Equal(t, 24*time.Hour, config.ReceiptDraftTTL)
+		assert.Equal(t, "sk-test", config.OpenAIAPIKey)
+		assert.Equal(t, "JPY", config.DefaultCurrency.String())
+		assert.Equal(t, tokyo, config.DefaultLocation)
+		assert.Equal(t, []int{80, 100}, config.BudgetAlertThresholds)
+	})
+
+	t.Run("budget alert thresholds", func(t *testing.T) {
+		setRequiredEnv(t)
+		t.Setenv("BUDGET_ALERT_THRESHOLDS", "100, 50%,80,100")
+
+		config, err := LoadEnv()
+		if assert.NoError(t, err) {
+			assert.Equal(t, []int{50, 80, 100}, config.BudgetAlertThresholds)
+		}
+
+		t.Setenv("BUDGET_ALERT_THRESHOLDS", "80,lots")
+		_, err = LoadEnv()
+		assert.EqualError(t, err, "invalid config: BUDGET_ALERT_THRESHOLDS: invalid percentage \"lots\"")
+	})
+
+	t.Run("fixture backend does not need an API key", func(t *testing.T) {
+		setRequiredEnv(t)
+		t.Setenv("OPENAI_APIKEY", "")
+		t.Setenv("RECEIPT_BACKEND", "fixture")
+		t.Setenv("RECEIPT_FIXTURE", "testdata/receipt.json")
+
+		_, err := LoadEnv()
+		assert.NoError(t, err)
+	})
+
+	t.Run("telegram needs a webhook secret", func(t *testing.T) {
+		setRequiredEnv(t)
+		t.Setenv("TELEGRAM_BOT_TOKEN", "123:token")
+
+		_, err := LoadEnv()
+		assert.EqualError(t, err, "invalid config: TELEGRAM_WEBHOOK_SECRET is required")
+
+		t.Setenv("TELEGRAM_WEBHOOK_SECRET", "secret")
+		config, err := LoadEnv()
+		if assert.NoError(t, err) {
+			assert.Equal(t, "123:token", config.Messaging.MessagingConfig().TelegramBotToken)
+		}
+	})
+
+	t.Run("reports every problem", func(t *testing.T) {
+		setRequiredEnv(t)
+		t.Setenv("LINE_CHANNEL_TOKEN", "")
+		t.Setenv("DB_NAME", "")
+		t.Setenv("OPENAI_APIKEY", "")
+		t.Setenv("DEFAULT_TIMEZONE", "Mars/Olympus")
+		t.Setenv("SHUTDOWN_TIMEOUT", "soon")
+		t.Setenv("PROCESSED_EVENT_TTL", "-1h")
+		t.Setenv("WEBHOOK_WORKERS", "0")
+
+		_, err := LoadEnv()
+		assert.EqualError(t, err, "invalid config: SHUTDOWN_TIMEOUT: invalid duration \"soon\"\n"+
+			"PROCESSED_EVENT_TTL: invalid duration \"-1h\"\n"+
+			"WEBHOOK_WORKERS: must be a positive integer, got \"0\"\n"+
+			"DEFAULT_TIMEZONE: invalid timezone \"Mars/Olympus\"\n"+
+			"LINE_CHANNEL_TOKEN is required\nDB_NAME is required\nOPENAI_APIKEY is required")
+	})
+}
+
+func TestLoadDatabaseEnv(t *testing.T) {
+	t.Setenv("DB_NAME", "momon")
+	t.Setenv("DB_HOST", "db")
+	t.Setenv("MIGRATIONS_DIR", "")
+	for _, name := range []string{"LINE_CHANNEL_SECRET", "LINE_CHANNEL_TOKEN", "OPENAI_APIKEY"} {
+		t.Setenv(name, "")
+	}
+
+	config, err := LoadDatabaseEnv()
+	if assert.NoError(t, err) {
+		assert.Equal(t, "momon", config.Database.DatabaseConfig().Name)
+		assert.Equal(t, "db", config.Database.DatabaseConfig().Host)
+		assert.Equal(t, "migrations", config.MigrationsDir)
+	}
+
+	t.Setenv("DB_NAME", "")
+	_, err = LoadDatabaseEnv()
+	assert.EqualError(t, err, "invalid config: DB_NAME is required")
+}
@@ -2,6 +2,7 @@ package serverenv
 
 import (
 	"context"
+	"github/shaolim/momon/internal/receipt"
 	"github/shaolim/momon/pkg/database"
 	"github/shaolim/momon/pkg/messaging"
 
@@ -13,7 +14,9 @@ type Option func(*ServerEnv) *ServerEnv
 type ServerEnv struct {
 	db               *database.DB
 	openaiClient     *openai.Client
-	lineMessagingAPI *messaging.LineMessaging
+	messenger        messaging.Messenger
+	telegram         messaging.Messenger
+	receiptExtractor receipt.ReceiptExtractor
 }
 
 func New(opts ...Option) *ServerEnv {
@@ -32,9 +35,17 @@ func WithOpenAIClient(openaiClient *openai.Client) Option {
 	}
 }
 
-func WithLineMessagingAPI(lineMessagingAPI *messaging.LineMessaging) Option {
+func WithMessenger(messenger messaging.Messenger) Option {
 	return func(s *ServerEnv) *ServerEnv {
-		s.lineMessagingAPI = lineMessagingAPI
+		s.messenger = messenger
+		return s
+	}
+}
+
+// WithTelegramMessenger enables the Telegram bot.
+func WithTelegramMessenger(telegram messaging.Messenger) Option {
+	return func(s *ServerEnv) *ServerEnv {
+		s.telegram = telegram
 		return s
 	}
 }
@@ -46,6 +57,13 @@ func WithDatabase(db *database.DB) Option {
 	}
 }
 
+func WithReceiptExtractor(receiptExtractor receipt.ReceiptExtractor) Option {
+	return func(s *ServerEnv) *ServerEnv {
+		s.receiptExtractor = receiptExtractor
+		return s
+	}
+}
+
 func (s *ServerEnv) GetOpenAIClient() *openai.Client {
 	return s.openaiClient
 }
@@ -54,8 +72,17 @@ func (s *ServerEnv) GetDatabase() *database.DB {
 	return s.db
 }
 
-func (s *ServerEnv) GetLineMessagingAPI() *messaging.LineMessaging {
-	return s.lineMessagingAPI
+func (s *ServerEnv) GetMessenger() messaging.Messenger {
+	return s.messenger
+}
+
+// GetTelegramMessenger returns nil when the Telegram bot is disabled.
+func (s *ServerEnv) GetTelegramMessenger() messaging.Messenger {
+	return s.telegram
+}
+
+func (s *ServerEnv) GetReceiptExtractor() receipt.ReceiptExtractor {
+	return s.receiptExtractor
 }
 
 func (s *ServerEnv) Close(ctx context.Context) error {
@@ -0,0 +1,181 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github/shaolim/momon/internal/transaction/model"
+	"github/shaolim/momon/pkg/database"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const draftColumns = `id, user_id, COALESCE(group_id, 0), amount, currency, category, merchant, occurred_at, time_unknown, tax, items, step, expires_at, created_at, updated_at`
+
+func scanDraft(row pgx.Row) (*model.Draft, error) {
+	var d model.Draft
+	if err := row.Scan(&d.ID, &d.UserID, &d.GroupID, &d.Amount, &d.Currency, &d.Category, &d.Merchant, &d.OccurredAt,
+		&d.TimeUnknown, &d.Tax, &d.Items, &d.Step, &d.ExpiresAt, &d.CreatedAt, &d.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (db *transactionDB) AddDraft(ctx context.Context, draft *model.Draft) error {
+	if draft.Step == "" {
+		draft.Step = model.DraftStepReview
+	}
+	if draft.Items == nil {
+		draft.Items = []model.Item{}
+	}
+
+	if err := draft.Validate(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	draft.CreatedAt = now
+	draft.UpdatedAt = now
+
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, `
+			INSERT INTO transaction_drafts (user_id, group_id, amount, currency, category, merchant, occurred_at, time_unknown, tax, items, step, expires_at, created_at, updated_at)
+			VALUES($1, NULLIF($2::bigint, 0), $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+			RETURNING id
+		`, draft.UserID, draft.GroupID, draft.Amount, draft.Currency, draft.Category, draft.Merchant, draft.OccurredAt,
+			draft.TimeUnknown, draft.Tax, draft.Items, draft.Step, draft.ExpiresAt, draft.CreatedAt, draft.UpdatedAt)
+
+		if err := row.Scan(&draft.ID); err != nil {
+			return fmt.Errorf("insert transaction_drafts: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// GetDraft returns the draft, including one that has expired but was not
+// deleted yet.
+func (db *transactionDB) GetDraft(ctx context.Context, id int64) (*model.Draft, error) {
+	row := db.db.Pool.QueryRow(ctx, `SELECT `+draftColumns+` FROM transaction_drafts WHERE id = $1`, id)
+
+	draft, err := scanDraft(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, database.ErrNotFound
+		}
+		return nil, fmt.Errorf("select transaction_drafts: %w", err)
+	}
+
+	return draft, nil
+}
+
+// GetPendingDraft returns the user's draft that was last changed after since
+// and waits for them to send a total or category.
+func (db *transactionDB) GetPendingDraft(ctx context.Context, userID int64, since time.Time) (*model.Draft, error) {
+	row := db.db.Pool.QueryRow(ctx, `
+		SELECT `+draftColumns+`
+		FROM transaction_drafts
+		WHERE user_id = $1 AND step <> $2 AND updated_at > $3
+		ORDER BY updated_at DESC, id DESC
+		LIMIT 1
+	`, userID, model.DraftStepReview, since)
+
+	draft, err := scanDraft(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, database.ErrNotFound
+		}
+		return nil, fmt.Errorf("select transaction_drafts: %w", err)
+	}
+
+	return draft, nil
+}
+
+// UpdateDraft changes the draft's amount, category and step.
+func (db *transactionDB) UpdateDraft(ctx context.Context, draft *model.Draft) error {
+	if err := draft.Validate(); err != nil {
+		return err
+	}
+
+	draft.UpdatedAt = time.Now()
+
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+			UPDATE transaction_drafts
+			SET amount = $2, category = $3, step = $4, updated_at = $5
+			WHERE id = $1
+		`, draft.ID, draft.Amount, draft.Category, draft.Step, draft.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("update transaction_drafts: %w", err)
+		}
+
+		if result.RowsAffected() == 0 {
+			return database.ErrNotFound
+		}
+
+		return nil
+	})
+}
+
+// SaveDraft records the draft as an expense and deletes it in one go, so a
+// draft is saved at most once however often the user taps save. It returns
+// database.ErrNotFound when the draft was saved or discarded already, or
+// expired before now.
+func (db *transactionDB) SaveDraft(ctx context.Context, id int64, now time.Time) (*model.Transaction, error) {
+	var transaction *model.Transaction
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, `
+			DELETE FROM transaction_drafts
+			WHERE id = $1 AND expires_at > $2
+			RETURNING `+draftColumns,
+			id, now)
+
+		draft, err := scanDraft(row)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return database.ErrNotFound
+			}
+			return fmt.Errorf("delete transaction_drafts: %w", err)
+		}
+
+		transaction = draft.Transaction()
+		if err := transaction.Validate(); err != nil {
+			return err
+		}
+		transaction.CreatedAt = time.Now()
+		transaction.UpdatedAt = transaction.CreatedAt
+
+		return insertTransaction(ctx, tx, transaction)
+	}); err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+func (db *transactionDB) DeleteDraft(ctx context.Context, id int64) error {
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `DELETE FROM transaction_drafts WHERE id = $1`, id)
+		if err != nil {
+			return fmt.Errorf("delete transaction_drafts: %w", err)
+		}
+
+		if result.RowsAffected() == 0 {
+			return database.ErrNotFound
+		}
+
+		return nil
+	})
+}
+
+// DeleteExpiredDrafts drops drafts that expired before now and returns how
+// many were deleted.
+func (db *transactionDB) DeleteExpiredDrafts(ctx context.Context, now time.Time) (int64, error) {
+	result, err := db.db.Pool.Exec(ctx, `DELETE FROM transaction_drafts WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, fmt.Errorf("delete transaction_drafts: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
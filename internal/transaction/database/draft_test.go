@@ -0,0 +1,124 @@
+package database
+
+import (
+	"context"
+	"github/shaolim/momon/internal/transaction/model"
+	usermodel "github/shaolim/momon/internal/user/model"
+	"github/shaolim/momon/internal/user/usertest"
+	"github/shaolim/momon/pkg/database"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newDraft(userID int64, expiresAt time.Time) *model.Draft {
+	return &model.Draft{
+		UserID:     userID,
+		Amount:     2310,
+		Currency:   "JPY",
+		Merchant:   "Lawson",
+		OccurredAt: time.Date(2024, 1, 15, 14, 30, 0, 0, time.UTC),
+		Tax:        210,
+		Items: []model.Item{
+			{Name: "Bento", Quantity: 2, Price: 500, Tax: 100, TotalPrice: 1100},
+			{Name: "Tea", Quantity: 1, Price: 1100, Tax: 110, TotalPrice: 1210},
+		},
+		ExpiresAt: expiresAt,
+	}
+}
+
+func TestDraft(t *testing.T) {
+	t.Parallel()
+
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	transactionDB := New(testDB)
+	ctx := context.Background()
+	user := usertest.MustAddUser(t, testDB, &usermodel.User{LineUserID: "line123"})
+	now := time.Now()
+
+	draft := newDraft(user.ID, now.Add(time.Hour))
+	if err := transactionDB.AddDraft(ctx, draft); err != nil {
+		t.Fatalf("failed to add draft: %v", err)
+	}
+	assert.NotZero(t, draft.ID)
+	assert.Equal(t, model.DraftStep(model.DraftStepReview), draft.Step)
+
+	got, err := transactionDB.GetDraft(ctx, draft.ID)
+	if err != nil {
+		t.Fatalf("failed to get draft: %v", err)
+	}
+	assert.Equal(t, draft.Items, got.Items)
+	assert.Equal(t, int64(210), got.Tax)
+	assert.False(t, got.TimeUnknown)
+
+	// Only drafts waiting for input are pending.
+	_, err = transactionDB.GetPendingDraft(ctx, user.ID, now.Add(-time.Minute))
+	assert.ErrorIs(t, err, database.ErrNotFound)
+
+	draft.Step = model.DraftStepTotal
+	assert.NoError(t, transactionDB.UpdateDraft(ctx, draft))
+
+	pending, err := transactionDB.GetPendingDraft(ctx, user.ID, now.Add(-time.Minute))
+	if assert.NoError(t, err) {
+		assert.Equal(t, draft.ID, pending.ID)
+	}
+	_, err = transactionDB.GetPendingDraft(ctx, user.ID, time.Now().Add(time.Minute))
+	assert.ErrorIs(t, err, database.ErrNotFound)
+
+	draft.Amount = 2130
+	draft.Category = "groceries"
+	draft.Step = model.DraftStepReview
+	assert.NoError(t, transactionDB.UpdateDraft(ctx, draft))
+
+	transaction, err := transactionDB.SaveDraft(ctx, draft.ID, time.Now())
+	if err != nil {
+		t.Fatalf("failed to save draft: %v", err)
+	}
+	saved, err := transactionDB.GetTransaction(ctx, transaction.ID)
+	if err != nil {
+		t.Fatalf("failed to get transaction: %v", err)
+	}
+	assert.Equal(t, int64(2130), saved.Amount)
+	assert.Equal(t, "groceries", saved.Category)
+	assert.Equal(t, model.TransactionSource(model.TransactionSourceReceipt), saved.Source)
+	assert.Len(t, saved.Items, 2)
+
+	// A second tap on save finds nothing.
+	_, err = transactionDB.SaveDraft(ctx, draft.ID, time.Now())
+	assert.ErrorIs(t, err, database.ErrNotFound)
+	_, err = transactionDB.GetDraft(ctx, draft.ID)
+	assert.ErrorIs(t, err, database.ErrNotFound)
+}
+
+func TestDraft_Expiry(t *testing.T) {
+	t.Parallel()
+
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	transactionDB := New(testDB)
+	ctx := context.Background()
+	user := usertest.MustAddUser(t, testDB, &usermodel.User{LineUserID: "line123"})
+	now := time.Now()
+
+	expired := newDraft(user.ID, now.Add(-time.Minute))
+	live := newDraft(user.ID, now.Add(time.Hour))
+	for _, d := range []*model.Draft{expired, live} {
+		if err := transactionDB.AddDraft(ctx, d); err != nil {
+			t.Fatalf("failed to add draft: %v", err)
+		}
+	}
+
+	_, err := transactionDB.SaveDraft(ctx, expired.ID, now)
+	assert.ErrorIs(t, err, database.ErrNotFound)
+
+	deleted, err := transactionDB.DeleteExpiredDrafts(ctx, now)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	assert.NoError(t, transactionDB.DeleteDraft(ctx, live.ID))
+	assert.ErrorIs(t, transactionDB.DeleteDraft(ctx, live.ID), database.ErrNotFound)
+
+	transactions, err := transactionDB.ListTransactions(ctx, &ListFilter{UserID: user.ID})
+	assert.NoError(t, err)
+	assert.Empty(t, transactions)
+}
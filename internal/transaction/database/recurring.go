@@ -0,0 +1,158 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github/shaolim/momon/internal/transaction/model"
+	"github/shaolim/momon/pkg/database"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const recurringColumns = `r.id, r.user_id, r.amount, r.currency, r.type, r.category, r.note, r.frequency, r.day, r.month,
+	r.next_run_at, u.timezone, r.created_at, r.updated_at`
+
+func scanRecurring(row pgx.Row) (*model.Recurring, error) {
+	var (
+		r     model.Recurring
+		month int
+	)
+	if err := row.Scan(&r.ID, &r.UserID, &r.Amount, &r.Currency, &r.Type, &r.Category, &r.Note, &r.Frequency, &r.Day,
+		&month, &r.NextRunAt, &r.Timezone, &r.CreatedAt, &r.UpdatedAt); err != nil {
+		return nil, err
+	}
+	r.Month = time.Month(month)
+	return &r, nil
+}
+
+func (db *transactionDB) AddRecurring(ctx context.Context, recurring *model.Recurring) error {
+	if err := recurring.Validate(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	recurring.CreatedAt = now
+	recurring.UpdatedAt = now
+
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, `
+			INSERT INTO recurring_transactions (user_id, amount, currency, type, category, note, frequency, day, month, next_run_at, created_at, updated_at)
+			VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+			RETURNING id
+		`, recurring.UserID, recurring.Amount, recurring.Currency, recurring.Type, recurring.Category, recurring.Note,
+			recurring.Frequency, recurring.Day, int(recurring.Month), recurring.NextRunAt, recurring.CreatedAt, recurring.UpdatedAt)
+
+		if err := row.Scan(&recurring.ID); err != nil {
+			return fmt.Errorf("insert recurring_transactions: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// ListRecurring returns the user's recurring transactions, the next due
+// first.
+func (db *transactionDB) ListRecurring(ctx context.Context, userID int64) ([]*model.Recurring, error) {
+	return db.listRecurring(ctx, `
+		SELECT `+recurringColumns+`
+		FROM recurring_transactions r
+		JOIN users u ON u.id = r.user_id
+		WHERE r.user_id = $1
+		ORDER BY r.next_run_at, r.id
+	`, userID)
+}
+
+// ListDueRecurring returns up to limit recurring transactions whose next run
+// is at or before now, the longest overdue first.
+func (db *transactionDB) ListDueRecurring(ctx context.Context, now time.Time, limit int) ([]*model.Recurring, error) {
+	return db.listRecurring(ctx, `
+		SELECT `+recurringColumns+`
+		FROM recurring_transactions r
+		JOIN users u ON u.id = r.user_id
+		WHERE r.next_run_at <= $1
+		ORDER BY r.next_run_at, r.id
+		LIMIT $2
+	`, now, limit)
+}
+
+func (db *transactionDB) listRecurring(ctx context.Context, query string, args ...any) ([]*model.Recurring, error) {
+	rows, err := db.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("select recurring_transactions: %w", err)
+	}
+	defer rows.Close()
+
+	var recurring []*model.Recurring
+	for rows.Next() {
+		r, err := scanRecurring(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan recurring_transactions: %w", err)
+		}
+		recurring = append(recurring, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate recurring_transactions: %w", err)
+	}
+
+	return recurring, nil
+}
+
+// DeleteRecurring stops the user's recurring transaction. Transactions it
+// posted are kept.
+func (db *transactionDB) DeleteRecurring(ctx context.Context, userID, id int64) error {
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `DELETE FROM recurring_transactions WHERE id = $1 AND user_id = $2`, id, userID)
+		if err != nil {
+			return fmt.Errorf("delete recurring_transactions: %w", err)
+		}
+
+		if result.RowsAffected() == 0 {
+			return database.ErrNotFound
+		}
+
+		return nil
+	})
+}
+
+// PostRecurring records the transaction due at recurring.NextRunAt and moves
+// the rule on to next in one go. It returns database.ErrNotFound when that run
+// was posted already, e.g. by another instance or before a restart, or the
+// rule was deleted, so every run is posted exactly once.
+func (db *transactionDB) PostRecurring(ctx context.Context, recurring *model.Recurring, next time.Time) (*model.Transaction, error) {
+	if !next.After(recurring.NextRunAt) {
+		return nil, errors.New("next run must be after the current one")
+	}
+
+	transaction := recurring.Transaction(recurring.NextRunAt)
+	if err := transaction.Validate(); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	transaction.CreatedAt = now
+	transaction.UpdatedAt = now
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+			UPDATE recurring_transactions
+			SET next_run_at = $3, updated_at = $4
+			WHERE id = $1 AND next_run_at = $2
+		`, recurring.ID, recurring.NextRunAt, next, now)
+		if err != nil {
+			return fmt.Errorf("update recurring_transactions: %w", err)
+		}
+
+		if result.RowsAffected() == 0 {
+			return database.ErrNotFound
+		}
+
+		return insertTransaction(ctx, tx, transaction)
+	}); err != nil {
+		return nil, err
+	}
+
+	recurring.NextRunAt = next
+	recurring.UpdatedAt = now
+	return transaction, nil
+}
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"github/shaolim/momon/internal/transaction/model"
+	usermodel "github/shaolim/momon/internal/user/model"
+	"github/shaolim/momon/internal/user/usertest"
+	"github/shaolim/momon/pkg/database"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecurring(t *testing.T) {
+	t.Parallel()
+
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	transactionDB := New(testDB)
+	ctx := context.Background()
+	user := usertest.MustAddUser(t, testDB, &usermodel.User{LineUserID: "line123"})
+	first := time.Date(2025, 10, 27, 9, 0, 0, 0, time.UTC)
+
+	rent := &model.Recurring{
+		UserID:    user.ID,
+		Amount:    85000,
+		Currency:  "JPY",
+		Type:      model.TransactionTypeExpense,
+		Category:  "Housing",
+		Note:      "rent",
+		Frequency: model.FrequencyMonthly,
+		Day:       27,
+		NextRunAt: first,
+	}
+	if err := transactionDB.AddRecurring(ctx, rent); err != nil {
+		t.Fatalf("failed to add recurring: %v", err)
+	}
+	assert.NotZero(t, rent.ID)
+
+	gym := &model.Recurring{
+		UserID:    user.ID,
+		Amount:    12000,
+		Currency:  "JPY",
+		Type:      model.TransactionTypeExpense,
+		Note:      "gym",
+		Frequency: model.FrequencyYearly,
+		Day:       14,
+		Month:     time.March,
+		NextRunAt: time.Date(2026, 3, 14, 9, 0, 0, 0, time.UTC),
+	}
+	assert.NoError(t, transactionDB.AddRecurring(ctx, gym))
+
+	all, err := transactionDB.ListRecurring(ctx, user.ID)
+	if assert.NoError(t, err) && assert.Len(t, all, 2) {
+		assert.Equal(t, rent.ID, all[0].ID)
+		assert.Equal(t, time.March, all[1].Month)
+	}
+
+	due, err := transactionDB.ListDueRecurring(ctx, first, 10)
+	if assert.NoError(t, err) && assert.Len(t, due, 1) {
+		assert.Equal(t, rent.ID, due[0].ID)
+	}
+
+	next := first.AddDate(0, 1, 0)
+	transaction, err := transactionDB.PostRecurring(ctx, due[0], next)
+	if assert.NoError(t, err) {
+		assert.Equal(t, model.TransactionSource(model.TransactionSourceRecurring), transaction.Source)
+		assert.True(t, first.Equal(transaction.OccurredAt))
+		assert.Equal(t, "Housing", transaction.Category)
+	}
+
+	// Posting the same run again, as another instance would, does nothing.
+	_, err = transactionDB.PostRecurring(ctx, rent, next)
+	assert.ErrorIs(t, err, database.ErrNotFound)
+
+	transactions, _ := transactionDB.ListTransactions(ctx, &ListFilter{UserID: user.ID})
+	assert.Len(t, transactions, 1)
+
+	due, _ = transactionDB.ListDueRecurring(ctx, first, 10)
+	assert.Empty(t, due)
+
+	// Deleting stops the rule but keeps what it posted.
+	assert.ErrorIs(t, transactionDB.DeleteRecurring(ctx, user.ID+1, rent.ID), database.ErrNotFound)
+	assert.NoError(t, transactionDB.DeleteRecurring(ctx, user.ID, rent.ID))
+	all, _ = transactionDB.ListRecurring(ctx, user.ID)
+	assert.Len(t, all, 1)
+	transactions, _ = transactionDB.ListTransactions(ctx, &ListFilter{UserID: user.ID})
+	assert.Len(t, transactions, 1)
+}
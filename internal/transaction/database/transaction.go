@@ -1 +1,299 @@
 package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github/shaolim/momon/internal/transaction/model"
+	"github/shaolim/momon/pkg/database"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type TransactionDB interface {
+	AddTransaction(ctx context.Context, transaction *model.Transaction) error
+	GetTransaction(ctx context.Context, id int64) (*model.Transaction, error)
+	UpdateTransaction(ctx context.Context, transaction *model.Transaction) error
+	DeleteTransaction(ctx context.Context, id int64) error
+	ListTransactions(ctx context.Context, filter *ListFilter) ([]*model.Transaction, error)
+	GetLatestTransaction(ctx context.Context, userID, groupID int64) (*model.Transaction, error)
+
+	AddDraft(ctx context.Context, draft *model.Draft) error
+	GetDraft(ctx context.Context, id int64) (*model.Draft, error)
+	GetPendingDraft(ctx context.Context, userID int64, since time.Time) (*model.Draft, error)
+	UpdateDraft(ctx context.Context, draft *model.Draft) error
+	SaveDraft(ctx context.Context, id int64, now time.Time) (*model.Transaction, error)
+	DeleteDraft(ctx context.Context, id int64) error
+	DeleteExpiredDrafts(ctx context.Context, now time.Time) (int64, error)
+
+	AddRecurring(ctx context.Context, recurring *model.Recurring) error
+	ListRecurring(ctx context.Context, userID int64) ([]*model.Recurring, error)
+	ListDueRecurring(ctx context.Context, now time.Time, limit int) ([]*model.Recurring, error)
+	DeleteRecurring(ctx context.Context, userID, id int64) error
+	PostRecurring(ctx context.Context, recurring *model.Recurring, next time.Time) (*model.Transaction, error)
+}
+
+// ListFilter narrows down the transactions returned by ListTransactions. Zero
+// values are ignored, except that entries in a group's ledger are only listed
+// when GroupID is set. From is inclusive and To is exclusive.
+type ListFilter struct {
+	UserID   int64
+	GroupID  int64
+	Type     model.TransactionType
+	Category string
+	From     time.Time
+	To       time.Time
+	Limit    int
+}
+
+type transactionDB struct {
+	db *database.DB
+}
+
+func New(db *database.DB) TransactionDB {
+	return &transactionDB{
+		db: db,
+	}
+}
+
+const transactionColumns = `id, user_id, COALESCE(group_id, 0), amount, currency, type, category, merchant, occurred_at, time_unknown, note, source, created_at, updated_at`
+
+func scanTransaction(row pgx.Row) (*model.Transaction, error) {
+	var t model.Transaction
+	if err := row.Scan(&t.ID, &t.UserID, &t.GroupID, &t.Amount, &t.Currency, &t.Type, &t.Category, &t.Merchant,
+		&t.OccurredAt, &t.TimeUnknown, &t.Note, &t.Source, &t.CreatedAt, &t.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (db *transactionDB) AddTransaction(ctx context.Context, transaction *model.Transaction) error {
+	if transaction.Source == "" {
+		transaction.Source = model.TransactionSourceManual
+	}
+
+	if err := transaction.Validate(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if transaction.CreatedAt.IsZero() {
+		transaction.CreatedAt = now
+	}
+	transaction.UpdatedAt = now
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		return insertTransaction(ctx, tx, transaction)
+	}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func insertTransaction(ctx context.Context, tx pgx.Tx, transaction *model.Transaction) error {
+	row := tx.QueryRow(ctx, `
+		INSERT INTO transactions (user_id, group_id, amount, currency, type, category, merchant, occurred_at, time_unknown, note, source, created_at, updated_at)
+		VALUES($1, NULLIF($2::bigint, 0), $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING id
+	`, transaction.UserID, transaction.GroupID, transaction.Amount, transaction.Currency, transaction.Type, transaction.Category,
+		transaction.Merchant, transaction.OccurredAt, transaction.TimeUnknown, transaction.Note, transaction.Source,
+		transaction.CreatedAt, transaction.UpdatedAt)
+
+	if err := row.Scan(&transaction.ID); err != nil {
+		return fmt.Errorf("insert transactions: %w", err)
+	}
+
+	return insertItems(ctx, tx, transaction)
+}
+
+func insertItems(ctx context.Context, tx pgx.Tx, transaction *model.Transaction) error {
+	for i := range transaction.Items {
+		item := &transaction.Items[i]
+		item.TransactionID = transaction.ID
+
+		row := tx.QueryRow(ctx, `
+			INSERT INTO transaction_items (transaction_id, name, quantity, price, tax, total_price)
+			VALUES($1, $2, $3, $4, $5, $6)
+			RETURNING id
+		`, item.TransactionID, item.Name, item.Quantity, item.Price, item.Tax, item.TotalPrice)
+
+		if err := row.Scan(&item.ID); err != nil {
+			return fmt.Errorf("insert transaction_items: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// GetTransaction returns the transaction with its items. Items are not loaded
+// by ListTransactions.
+func (db *transactionDB) GetTransaction(ctx context.Context, id int64) (*model.Transaction, error) {
+	row := db.db.Pool.QueryRow(ctx, `SELECT `+transactionColumns+` FROM transactions WHERE id = $1`, id)
+
+	transaction, err := scanTransaction(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, database.ErrNotFound
+		}
+		return nil, fmt.Errorf("select transactions: %w", err)
+	}
+
+	rows, err := db.db.Pool.Query(ctx, `
+		SELECT id, transaction_id, name, quantity, price, tax, total_price
+		FROM transaction_items
+		WHERE transaction_id = $1
+		ORDER BY id
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("select transaction_items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item model.Item
+		if err := rows.Scan(&item.ID, &item.TransactionID, &item.Name, &item.Quantity, &item.Price, &item.Tax, &item.TotalPrice); err != nil {
+			return nil, fmt.Errorf("scan transaction_items: %w", err)
+		}
+		transaction.Items = append(transaction.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate transaction_items: %w", err)
+	}
+
+	return transaction, nil
+}
+
+// GetLatestTransaction returns the transaction the user recorded last in the
+// group's ledger, or in their own when groupID is 0, regardless of when it
+// occurred.
+func (db *transactionDB) GetLatestTransaction(ctx context.Context, userID, groupID int64) (*model.Transaction, error) {
+	row := db.db.Pool.QueryRow(ctx, `
+		SELECT `+transactionColumns+`
+		FROM transactions
+		WHERE user_id = $1 AND COALESCE(group_id, 0) = $2
+		ORDER BY id DESC
+		LIMIT 1
+	`, userID, groupID)
+
+	transaction, err := scanTransaction(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, database.ErrNotFound
+		}
+		return nil, fmt.Errorf("select transactions: %w", err)
+	}
+
+	return transaction, nil
+}
+
+func (db *transactionDB) UpdateTransaction(ctx context.Context, transaction *model.Transaction) error {
+	if err := transaction.Validate(); err != nil {
+		return err
+	}
+
+	transaction.UpdatedAt = time.Now()
+
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+			UPDATE transactions
+			SET amount = $2, currency = $3, type = $4, category = $5, merchant = $6,
+				occurred_at = $7, time_unknown = $8, note = $9, source = $10, updated_at = $11
+			WHERE id = $1
+		`, transaction.ID, transaction.Amount, transaction.Currency, transaction.Type, transaction.Category,
+			transaction.Merchant, transaction.OccurredAt, transaction.TimeUnknown, transaction.Note, transaction.Source,
+			transaction.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("update transactions: %w", err)
+		}
+
+		if result.RowsAffected() == 0 {
+			return database.ErrNotFound
+		}
+
+		return nil
+	})
+}
+
+func (db *transactionDB) DeleteTransaction(ctx context.Context, id int64) error {
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `DELETE FROM transactions WHERE id = $1`, id)
+		if err != nil {
+			return fmt.Errorf("delete transactions: %w", err)
+		}
+
+		if result.RowsAffected() == 0 {
+			return database.ErrNotFound
+		}
+
+		return nil
+	})
+}
+
+func (db *transactionDB) ListTransactions(ctx context.Context, filter *ListFilter) ([]*model.Transaction, error) {
+	if filter == nil {
+		filter = &ListFilter{}
+	}
+
+	var (
+		conditions []string
+		args       []any
+	)
+	where := func(cond string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(cond, len(args)))
+	}
+
+	if filter.UserID != 0 {
+		where("user_id = $%d", filter.UserID)
+	}
+	if filter.GroupID != 0 {
+		where("group_id = $%d", filter.GroupID)
+	} else {
+		conditions = append(conditions, "group_id IS NULL")
+	}
+	if filter.Type != "" {
+		where("type = $%d", filter.Type)
+	}
+	if filter.Category != "" {
+		where("category = $%d", filter.Category)
+	}
+	if !filter.From.IsZero() {
+		where("occurred_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		where("occurred_at < $%d", filter.To)
+	}
+
+	query := `SELECT ` + transactionColumns + ` FROM transactions`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	query += ` ORDER BY occurred_at DESC, id DESC`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(` LIMIT $%d`, len(args))
+	}
+
+	rows, err := db.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("select transactions: %w", err)
+	}
+	defer rows.Close()
+
+	var transactions []*model.Transaction
+	for rows.Next() {
+		transaction, err := scanTransaction(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan transactions: %w", err)
+		}
+		transactions = append(transactions, transaction)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate transactions: %w", err)
+	}
+
+	return transactions, nil
+}
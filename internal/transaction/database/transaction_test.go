@@ -0,0 +1,305 @@
+package database
+
+import (
+	"context"
+	"errors"
+	groupdb "github/shaolim/momon/internal/group/database"
+	groupmodel "github/shaolim/momon/internal/group/model"
+	"github/shaolim/momon/internal/transaction/model"
+	usermodel "github/shaolim/momon/internal/user/model"
+	"github/shaolim/momon/internal/user/usertest"
+	"github/shaolim/momon/pkg/database"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mustAddGroup(t *testing.T, db *database.DB, lineGroupID string) *groupmodel.Group {
+	t.Helper()
+
+	group := &groupmodel.Group{LineGroupID: lineGroupID, Status: groupmodel.GroupStatusActive}
+	if err := groupdb.New(db).Upsert(context.Background(), group); err != nil {
+		t.Fatalf("failed to add group: %v", err)
+	}
+	return group
+}
+
+func TestAddTransaction(t *testing.T) {
+	t.Parallel()
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+
+		testDB, _ := testDatabaseInstance.NewDatabase(t)
+		transactionDB := New(testDB)
+		ctx := context.Background()
+		user := usertest.MustAddUser(t, testDB, &usermodel.User{LineUserID: "line123"})
+
+		transaction := &model.Transaction{
+			UserID:     user.ID,
+			Amount:     1200,
+			Currency:   "JPY",
+			Type:       model.TransactionTypeExpense,
+			Merchant:   "Lawson",
+			OccurredAt: time.Now(),
+		}
+
+		err := transactionDB.AddTransaction(ctx, transaction)
+		if err != nil {
+			t.Fatalf("failed to add transaction: %v", err)
+		}
+
+		if transaction.ID == 0 {
+			t.Error("expected transaction ID to be assigned")
+		}
+		assert.Equal(t, model.TransactionSource(model.TransactionSourceManual), transaction.Source)
+	})
+
+	t.Run("with_items", func(t *testing.T) {
+		t.Parallel()
+
+		testDB, _ := testDatabaseInstance.NewDatabase(t)
+		transactionDB := New(testDB)
+		ctx := context.Background()
+		user := usertest.MustAddUser(t, testDB, &usermodel.User{LineUserID: "line123"})
+
+		transaction := &model.Transaction{
+			UserID:     user.ID,
+			Amount:     231,
+			Currency:   "JPY",
+			Type:       model.TransactionTypeExpense,
+			Merchant:   "Grocery Market",
+			OccurredAt: time.Now(),
+			Source:     model.TransactionSourceReceipt,
+			Items: []model.Item{
+				{Name: "Bread", Quantity: 1, Price: 50, Tax: 5, TotalPrice: 55},
+				{Name: "Milk", Quantity: 2, Price: 80, Tax: 16, TotalPrice: 176},
+			},
+		}
+		if err := transactionDB.AddTransaction(ctx, transaction); err != nil {
+			t.Fatalf("failed to add transaction: %v", err)
+		}
+
+		got, err := transactionDB.GetTransaction(ctx, transaction.ID)
+		if err != nil {
+			t.Fatalf("failed to get transaction: %v", err)
+		}
+		if assert.Len(t, got.Items, 2) {
+			assert.Equal(t, "Bread", got.Items[0].Name)
+			assert.Equal(t, int64(176), got.Items[1].TotalPrice)
+			assert.Equal(t, transaction.ID, got.Items[1].TransactionID)
+		}
+	})
+
+	t.Run("invalid_amount", func(t *testing.T) {
+		t.Parallel()
+
+		testDB, _ := testDatabaseInstance.NewDatabase(t)
+		transactionDB := New(testDB)
+		ctx := context.Background()
+		user := usertest.MustAddUser(t, testDB, &usermodel.User{LineUserID: "line123"})
+
+		err := transactionDB.AddTransaction(ctx, &model.Transaction{
+			UserID:     user.ID,
+			Currency:   "JPY",
+			Type:       model.TransactionTypeExpense,
+			OccurredAt: time.Now(),
+		})
+		assert.EqualError(t, err, "amount must be greater than zero")
+	})
+}
+
+func TestGetUpdateDeleteTransaction(t *testing.T) {
+	t.Parallel()
+
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	transactionDB := New(testDB)
+	ctx := context.Background()
+	user := usertest.MustAddUser(t, testDB, &usermodel.User{LineUserID: "line123"})
+
+	transaction := &model.Transaction{
+		UserID:      user.ID,
+		Amount:      3400,
+		Currency:    "JPY",
+		Type:        model.TransactionTypeExpense,
+		Category:    "Transport",
+		OccurredAt:  time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC),
+		TimeUnknown: true,
+	}
+	if err := transactionDB.AddTransaction(ctx, transaction); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+
+	got, err := transactionDB.GetTransaction(ctx, transaction.ID)
+	if err != nil {
+		t.Fatalf("failed to get transaction: %v", err)
+	}
+	assert.Equal(t, transaction.Amount, got.Amount)
+	assert.Equal(t, transaction.Category, got.Category)
+	assert.True(t, transaction.OccurredAt.Equal(got.OccurredAt))
+	assert.True(t, got.TimeUnknown)
+
+	got.Amount = 3500
+	got.Note = "taxi home"
+	if err := transactionDB.UpdateTransaction(ctx, got); err != nil {
+		t.Fatalf("failed to update transaction: %v", err)
+	}
+
+	updated, err := transactionDB.GetTransaction(ctx, transaction.ID)
+	if err != nil {
+		t.Fatalf("failed to get transaction: %v", err)
+	}
+	assert.Equal(t, int64(3500), updated.Amount)
+	assert.Equal(t, "taxi home", updated.Note)
+
+	if err := transactionDB.DeleteTransaction(ctx, transaction.ID); err != nil {
+		t.Fatalf("failed to delete transaction: %v", err)
+	}
+
+	_, err = transactionDB.GetTransaction(ctx, transaction.ID)
+	if !errors.Is(err, database.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+
+	err = transactionDB.DeleteTransaction(ctx, transaction.ID)
+	if !errors.Is(err, database.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+}
+
+func TestListTransactions(t *testing.T) {
+	t.Parallel()
+
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	transactionDB := New(testDB)
+	ctx := context.Background()
+	user := usertest.MustAddUser(t, testDB, &usermodel.User{LineUserID: "line123"})
+	other := usertest.MustAddUser(t, testDB, &usermodel.User{LineUserID: "line456"})
+	group := mustAddGroup(t, testDB, "C123")
+
+	base := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	for i, tx := range []*model.Transaction{
+		{UserID: user.ID, Amount: 100, Type: model.TransactionTypeExpense, Category: "Food"},
+		{UserID: user.ID, Amount: 200, Type: model.TransactionTypeExpense, Category: "Transport"},
+		{UserID: user.ID, Amount: 50000, Type: model.TransactionTypeIncome, Category: "Salary"},
+		{UserID: other.ID, Amount: 300, Type: model.TransactionTypeExpense, Category: "Food"},
+		{UserID: user.ID, GroupID: group.ID, Amount: 400, Type: model.TransactionTypeExpense, Category: "Food"},
+		{UserID: other.ID, GroupID: group.ID, Amount: 500, Type: model.TransactionTypeExpense, Category: "Food"},
+	} {
+		tx.Currency = "JPY"
+		tx.OccurredAt = base.Add(time.Duration(i) * 24 * time.Hour)
+		if err := transactionDB.AddTransaction(ctx, tx); err != nil {
+			t.Fatalf("failed to add transaction: %v", err)
+		}
+	}
+
+	cases := []struct {
+		name    string
+		filter  *ListFilter
+		amounts []int64
+	}{
+		{
+			name:    "by_user",
+			filter:  &ListFilter{UserID: user.ID},
+			amounts: []int64{50000, 200, 100},
+		},
+		{
+			name:    "by_group",
+			filter:  &ListFilter{GroupID: group.ID},
+			amounts: []int64{500, 400},
+		},
+		{
+			name:    "by_group_member",
+			filter:  &ListFilter{UserID: user.ID, GroupID: group.ID},
+			amounts: []int64{400},
+		},
+		{
+			name:    "by_type",
+			filter:  &ListFilter{UserID: user.ID, Type: model.TransactionTypeExpense},
+			amounts: []int64{200, 100},
+		},
+		{
+			name:    "by_category",
+			filter:  &ListFilter{Category: "Food"},
+			amounts: []int64{300, 100},
+		},
+		{
+			name:    "by_range",
+			filter:  &ListFilter{UserID: user.ID, From: base.Add(24 * time.Hour), To: base.Add(48 * time.Hour)},
+			amounts: []int64{200},
+		},
+		{
+			name:    "limit",
+			filter:  &ListFilter{UserID: user.ID, Limit: 1},
+			amounts: []int64{50000},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			transactions, err := transactionDB.ListTransactions(ctx, tc.filter)
+			if err != nil {
+				t.Fatalf("failed to list transactions: %v", err)
+			}
+
+			var amounts []int64
+			for _, tx := range transactions {
+				amounts = append(amounts, tx.Amount)
+			}
+			assert.Equal(t, tc.amounts, amounts)
+		})
+	}
+}
+
+func TestGetLatestTransaction(t *testing.T) {
+	t.Parallel()
+
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	transactionDB := New(testDB)
+	ctx := context.Background()
+	user := usertest.MustAddUser(t, testDB, &usermodel.User{LineUserID: "line123"})
+
+	_, err := transactionDB.GetLatestTransaction(ctx, user.ID, 0)
+	if !errors.Is(err, database.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+
+	recent := &model.Transaction{
+		UserID: user.ID, Amount: 100, Currency: "JPY", Type: model.TransactionTypeExpense,
+		OccurredAt: time.Now(),
+	}
+	backdated := &model.Transaction{
+		UserID: user.ID, Amount: 200, Currency: "JPY", Type: model.TransactionTypeExpense,
+		OccurredAt: time.Now().AddDate(0, 0, -7),
+	}
+	for _, tx := range []*model.Transaction{recent, backdated} {
+		if err := transactionDB.AddTransaction(ctx, tx); err != nil {
+			t.Fatalf("failed to add transaction: %v", err)
+		}
+	}
+
+	got, err := transactionDB.GetLatestTransaction(ctx, user.ID, 0)
+	if err != nil {
+		t.Fatalf("failed to get latest transaction: %v", err)
+	}
+	assert.Equal(t, backdated.ID, got.ID)
+
+	// Entries in a group's ledger are separate from the user's own.
+	group := mustAddGroup(t, testDB, "C123")
+	shared := &model.Transaction{
+		UserID: user.ID, GroupID: group.ID, Amount: 300, Currency: "JPY", Type: model.TransactionTypeExpense,
+		OccurredAt: time.Now(),
+	}
+	if err := transactionDB.AddTransaction(ctx, shared); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+
+	got, _ = transactionDB.GetLatestTransaction(ctx, user.ID, 0)
+	assert.Equal(t, backdated.ID, got.ID)
+	got, err = transactionDB.GetLatestTransaction(ctx, user.ID, group.ID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, shared.ID, got.ID)
+		assert.Equal(t, group.ID, got.GroupID)
+	}
+}
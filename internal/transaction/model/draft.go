@@ -0,0 +1,100 @@
+package model
+
+import (
+	"errors"
+	"github/shaolim/momon/pkg/money"
+	"time"
+)
+
+// Draft is an expense read from a receipt photo that waits for the user to
+// save, edit or discard it. Nothing is recorded until it is saved.
+type Draft struct {
+	ID         int64
+	UserID     int64
+	Amount     int64
+	Currency   money.Currency
+	Category   string
+	Merchant   string
+	OccurredAt time.Time
+	// TimeUnknown is set when the receipt prints a date but no time.
+	TimeUnknown bool
+	// Tax is the tax printed on the receipt. It is only shown to the user.
+	Tax   int64
+	Items []Item
+	// GroupID is the group the receipt was sent in, or 0 outside of groups.
+	GroupID int64
+	// Step is what the draft is waiting for.
+	Step      DraftStep
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (d *Draft) Validate() error {
+	if d.UserID == 0 {
+		return errors.New("user id must not be empty")
+	}
+
+	if d.Amount <= 0 {
+		return errors.New("amount must be greater than zero")
+	}
+
+	if len(d.Currency) != 3 {
+		return errors.New("currency must be a 3-letter ISO 4217 code")
+	}
+
+	if d.OccurredAt.IsZero() {
+		return errors.New("occurred at must not be empty")
+	}
+
+	switch d.Step {
+	case DraftStepReview, DraftStepTotal, DraftStepCategory:
+	default:
+		return errors.New("step must be one of REVIEW, TOTAL or CATEGORY")
+	}
+
+	if d.ExpiresAt.IsZero() {
+		return errors.New("expires at must not be empty")
+	}
+
+	return nil
+}
+
+// Money returns the amount together with its currency.
+func (d *Draft) Money() money.Money {
+	return money.New(d.Amount, d.Currency)
+}
+
+// Transaction returns the expense recorded when the draft is saved.
+func (d *Draft) Transaction() *Transaction {
+	items := make([]Item, 0, len(d.Items))
+	for _, item := range d.Items {
+		item.ID, item.TransactionID = 0, 0
+		items = append(items, item)
+	}
+
+	return &Transaction{
+		UserID:      d.UserID,
+		GroupID:     d.GroupID,
+		Amount:      d.Amount,
+		Currency:    d.Currency,
+		Type:        TransactionTypeExpense,
+		Category:    d.Category,
+		Merchant:    d.Merchant,
+		OccurredAt:  d.OccurredAt,
+		TimeUnknown: d.TimeUnknown,
+		Source:      TransactionSourceReceipt,
+		Items:       items,
+	}
+}
+
+type DraftStep string
+
+const (
+	// DraftStepReview waits for the user to tap save, edit or discard.
+	DraftStepReview = "REVIEW"
+	// DraftStepTotal waits for the user to send the correct total.
+	DraftStepTotal = "TOTAL"
+	// DraftStepCategory waits for the user to send a category.
+	DraftStepCategory = "CATEGORY"
+)
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDraft_Validate(t *testing.T) {
+	valid := func() *Draft {
+		return &Draft{
+			UserID:     1,
+			Amount:     1200,
+			Currency:   "JPY",
+			OccurredAt: time.Now(),
+			Step:       DraftStepReview,
+			ExpiresAt:  time.Now().Add(time.Hour),
+		}
+	}
+
+	assert.NoError(t, valid().Validate())
+
+	d := valid()
+	d.Amount = 0
+	assert.EqualError(t, d.Validate(), "amount must be greater than zero")
+
+	d = valid()
+	d.Step = "DONE"
+	assert.EqualError(t, d.Validate(), "step must be one of REVIEW, TOTAL or CATEGORY")
+
+	d = valid()
+	d.ExpiresAt = time.Time{}
+	assert.EqualError(t, d.Validate(), "expires at must not be empty")
+}
+
+func TestDraft_Transaction(t *testing.T) {
+	d := &Draft{
+		ID:          7,
+		UserID:      1,
+		GroupID:     3,
+		Amount:      2130,
+		Currency:    "JPY",
+		Category:    "groceries",
+		Merchant:    "Lawson",
+		OccurredAt:  time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC),
+		TimeUnknown: true,
+		Items:       []Item{{ID: 3, TransactionID: 9, Name: "Bento", Quantity: 2, TotalPrice: 1100}},
+	}
+
+	got := d.Transaction()
+
+	assert.Equal(t, &Transaction{
+		UserID:      1,
+		GroupID:     3,
+		Amount:      2130,
+		Currency:    "JPY",
+		Type:        TransactionTypeExpense,
+		Category:    "groceries",
+		Merchant:    "Lawson",
+		OccurredAt:  d.OccurredAt,
+		TimeUnknown: true,
+		Source:      TransactionSourceReceipt,
+		Items:       []Item{{Name: "Bento", Quantity: 2, TotalPrice: 1100}},
+	}, got)
+	assert.NoError(t, got.Validate())
+}
@@ -0,0 +1,213 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"github/shaolim/momon/pkg/money"
+	"time"
+)
+
+type Frequency string
+
+const (
+	FrequencyWeekly  = "WEEKLY"
+	FrequencyMonthly = "MONTHLY"
+	FrequencyYearly  = "YEARLY"
+)
+
+// LastDay is the Day of monthly and yearly rules that run on the last day of
+// the month.
+const LastDay = -1
+
+// RunHour is the hour of the day, in the user's time zone, recurring
+// transactions are posted at, so their notification doesn't arrive at
+// midnight.
+const RunHour = 9
+
+// Recurring is a transaction such as rent or a subscription that is posted
+// on a schedule.
+type Recurring struct {
+	ID       int64
+	UserID   int64
+	Amount   int64
+	Currency money.Currency
+	Type     TransactionType
+	Category string
+	Note     string
+
+	Frequency Frequency
+	// Day is the weekday (0 is Sunday) of weekly rules. For monthly and
+	// yearly rules it is the day of the month, or LastDay; days past the end
+	// of a short month fall on its last day.
+	Day int
+	// Month is the month of yearly rules.
+	Month time.Month
+	// NextRunAt is when the next transaction is due.
+	NextRunAt time.Time
+	// Timezone is the user's. It is read with the rule and ignored when
+	// saving it.
+	Timezone string
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (r *Recurring) Validate() error {
+	if r.UserID == 0 {
+		return errors.New("user id must not be empty")
+	}
+
+	if r.Amount <= 0 {
+		return errors.New("amount must be greater than zero")
+	}
+
+	if len(r.Currency) != 3 {
+		return errors.New("currency must be a 3-letter ISO 4217 code")
+	}
+
+	switch r.Type {
+	case TransactionTypeIncome, TransactionTypeExpense:
+	default:
+		return errors.New("type must be either INCOME or EXPENSE")
+	}
+
+	switch r.Frequency {
+	case FrequencyWeekly:
+		if r.Day < int(time.Sunday) || r.Day > int(time.Saturday) {
+			return errors.New("day must be a weekday")
+		}
+	case FrequencyMonthly, FrequencyYearly:
+		if r.Day != LastDay && (r.Day < 1 || r.Day > 31) {
+			return errors.New("day must be between 1 and 31")
+		}
+		if r.Frequency == FrequencyYearly && (r.Month < time.January || r.Month > time.December) {
+			return errors.New("month must be between 1 and 12")
+		}
+	default:
+		return errors.New("frequency must be one of WEEKLY, MONTHLY or YEARLY")
+	}
+
+	if r.NextRunAt.IsZero() {
+		return errors.New("next run at must not be empty")
+	}
+
+	return nil
+}
+
+// Money returns the amount together with its currency.
+func (r *Recurring) Money() money.Money {
+	return money.New(r.Amount, r.Currency)
+}
+
+// Location returns the user's time zone, or fallback when they have not set
+// one.
+func (r *Recurring) Location(fallback *time.Location) *time.Location {
+	if r.Timezone == "" {
+		return fallback
+	}
+
+	loc, err := time.LoadLocation(r.Timezone)
+	if err != nil {
+		return fallback
+	}
+
+	return loc
+}
+
+// Next returns the first time the rule runs after after, at RunHour in loc.
+func (r *Recurring) Next(after time.Time, loc *time.Location) time.Time {
+	after = after.In(loc)
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, RunHour, 0, 0, 0, loc)
+	}
+
+	switch r.Frequency {
+	case FrequencyWeekly:
+		for i := 0; ; i++ {
+			t := at(after.Year(), after.Month(), after.Day()+i)
+			if int(t.Weekday()) == r.Day && t.After(after) {
+				return t
+			}
+		}
+	case FrequencyYearly:
+		for year := after.Year(); ; year++ {
+			if t := at(year, r.Month, r.dayIn(year, r.Month)); t.After(after) {
+				return t
+			}
+		}
+	default:
+		for i := 0; ; i++ {
+			first := time.Date(after.Year(), after.Month()+time.Month(i), 1, 0, 0, 0, 0, loc)
+			if t := at(first.Year(), first.Month(), r.dayIn(first.Year(), first.Month())); t.After(after) {
+				return t
+			}
+		}
+	}
+}
+
+// dayIn is the day of the month the rule falls on in month.
+func (r *Recurring) dayIn(year int, month time.Month) int {
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if r.Day == LastDay || r.Day > last {
+		return last
+	}
+	return r.Day
+}
+
+// Schedule describes when the rule runs, e.g. "monthly on the 25th".
+func (r *Recurring) Schedule() string {
+	switch r.Frequency {
+	case FrequencyWeekly:
+		return "weekly on " + time.Weekday(r.Day).String()
+	case FrequencyYearly:
+		if r.Day == LastDay {
+			return "yearly on the last day of " + r.Month.String()
+		}
+		return fmt.Sprintf("yearly on %s %d", r.Month, r.Day)
+	default:
+		if r.Day == LastDay {
+			return "monthly on the last day"
+		}
+		return "monthly on the " + ordinal(r.Day)
+	}
+}
+
+// Monthly is roughly what the rule costs or earns a month.
+func (r *Recurring) Monthly() money.Money {
+	switch r.Frequency {
+	case FrequencyWeekly:
+		return money.New(r.Amount*52/12, r.Currency)
+	case FrequencyYearly:
+		return money.New(r.Amount/12, r.Currency)
+	default:
+		return r.Money()
+	}
+}
+
+// Transaction is the transaction the rule posts when it runs at occurredAt.
+func (r *Recurring) Transaction(occurredAt time.Time) *Transaction {
+	return &Transaction{
+		UserID:     r.UserID,
+		Amount:     r.Amount,
+		Currency:   r.Currency,
+		Type:       r.Type,
+		Category:   r.Category,
+		OccurredAt: occurredAt,
+		Note:       r.Note,
+		Source:     TransactionSourceRecurring,
+	}
+}
+
+func ordinal(n int) string {
+	suffix := "th"
+	switch {
+	case n%100 >= 11 && n%100 <= 13:
+	case n%10 == 1:
+		suffix = "st"
+	case n%10 == 2:
+		suffix = "nd"
+	case n%10 == 3:
+		suffix = "rd"
+	}
+	return fmt.Sprintf("%d%s", n, suffix)
+}
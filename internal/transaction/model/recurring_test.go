@@ -0,0 +1,160 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecurring_Validate(t *testing.T) {
+	valid := func() *Recurring {
+		return &Recurring{
+			UserID:    1,
+			Amount:    85000,
+			Currency:  "JPY",
+			Type:      TransactionTypeExpense,
+			Frequency: FrequencyMonthly,
+			Day:       27,
+			NextRunAt: time.Date(2025, 10, 27, 9, 0, 0, 0, time.UTC),
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(r *Recurring)
+		wantErr string
+	}{
+		{name: "valid", modify: func(*Recurring) {}},
+		{name: "last day", modify: func(r *Recurring) { r.Day = LastDay }},
+		{name: "weekly", modify: func(r *Recurring) { r.Frequency = FrequencyWeekly; r.Day = int(time.Saturday) }},
+		{name: "yearly", modify: func(r *Recurring) { r.Frequency = FrequencyYearly; r.Month = time.March }},
+		{name: "no user", modify: func(r *Recurring) { r.UserID = 0 }, wantErr: "user id must not be empty"},
+		{name: "zero amount", modify: func(r *Recurring) { r.Amount = 0 }, wantErr: "amount must be greater than zero"},
+		{name: "bad type", modify: func(r *Recurring) { r.Type = "TRANSFER" }, wantErr: "type must be either INCOME or EXPENSE"},
+		{name: "bad frequency", modify: func(r *Recurring) { r.Frequency = "DAILY" }, wantErr: "frequency must be one of WEEKLY, MONTHLY or YEARLY"},
+		{name: "bad day", modify: func(r *Recurring) { r.Day = 32 }, wantErr: "day must be between 1 and 31"},
+		{name: "bad weekday", modify: func(r *Recurring) { r.Frequency = FrequencyWeekly; r.Day = 7 }, wantErr: "day must be a weekday"},
+		{name: "yearly without month", modify: func(r *Recurring) { r.Frequency = FrequencyYearly }, wantErr: "month must be between 1 and 12"},
+		{name: "no next run", modify: func(r *Recurring) { r.NextRunAt = time.Time{} }, wantErr: "next run at must not be empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := valid()
+			tt.modify(r)
+
+			err := r.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRecurring_Next(t *testing.T) {
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	at := func(year int, month time.Month, day, hour int) time.Time {
+		return time.Date(year, month, day, hour, 0, 0, 0, tokyo)
+	}
+
+	tests := []struct {
+		name     string
+		rule     Recurring
+		after    time.Time
+		want     time.Time
+		schedule string
+	}{
+		{
+			name:     "monthly later this month",
+			rule:     Recurring{Frequency: FrequencyMonthly, Day: 27},
+			after:    at(2025, 10, 15, 12),
+			want:     at(2025, 10, 27, 9),
+			schedule: "monthly on the 27th",
+		},
+		{
+			name:     "monthly same day before the run hour",
+			rule:     Recurring{Frequency: FrequencyMonthly, Day: 15},
+			after:    at(2025, 10, 15, 8),
+			want:     at(2025, 10, 15, 9),
+			schedule: "monthly on the 15th",
+		},
+		{
+			name:     "monthly once it ran",
+			rule:     Recurring{Frequency: FrequencyMonthly, Day: 15},
+			after:    at(2025, 10, 15, 9),
+			want:     at(2025, 11, 15, 9),
+			schedule: "monthly on the 15th",
+		},
+		{
+			name:     "day 31 in a short month",
+			rule:     Recurring{Frequency: FrequencyMonthly, Day: 31},
+			after:    at(2026, 1, 31, 9),
+			want:     at(2026, 2, 28, 9),
+			schedule: "monthly on the 31st",
+		},
+		{
+			name:     "end of month",
+			rule:     Recurring{Frequency: FrequencyMonthly, Day: LastDay},
+			after:    at(2028, 1, 31, 10),
+			want:     at(2028, 2, 29, 9),
+			schedule: "monthly on the last day",
+		},
+		{
+			name:     "across the year",
+			rule:     Recurring{Frequency: FrequencyMonthly, Day: 1},
+			after:    at(2025, 12, 1, 9),
+			want:     at(2026, 1, 1, 9),
+			schedule: "monthly on the 1st",
+		},
+		{
+			name:     "weekly",
+			rule:     Recurring{Frequency: FrequencyWeekly, Day: int(time.Monday)},
+			after:    at(2025, 10, 15, 12), // a Wednesday
+			want:     at(2025, 10, 20, 9),
+			schedule: "weekly on Monday",
+		},
+		{
+			name:     "weekly a week later",
+			rule:     Recurring{Frequency: FrequencyWeekly, Day: int(time.Monday)},
+			after:    at(2025, 10, 20, 9),
+			want:     at(2025, 10, 27, 9),
+			schedule: "weekly on Monday",
+		},
+		{
+			name:     "yearly",
+			rule:     Recurring{Frequency: FrequencyYearly, Month: time.March, Day: 14},
+			after:    at(2025, 10, 15, 12),
+			want:     at(2026, 3, 14, 9),
+			schedule: "yearly on March 14",
+		},
+		{
+			name:     "yearly leap day",
+			rule:     Recurring{Frequency: FrequencyYearly, Month: time.February, Day: 29},
+			after:    at(2028, 2, 29, 9),
+			want:     at(2029, 2, 28, 9),
+			schedule: "yearly on February 29",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.rule.Next(tt.after, tokyo))
+			assert.Equal(t, tt.schedule, tt.rule.Schedule())
+		})
+	}
+}
+
+func TestRecurring_Monthly(t *testing.T) {
+	assert.Equal(t, "1,490 JPY", (&Recurring{Frequency: FrequencyMonthly, Amount: 1490, Currency: "JPY"}).Monthly().String())
+	assert.Equal(t, "3,466 JPY", (&Recurring{Frequency: FrequencyWeekly, Amount: 800, Currency: "JPY"}).Monthly().String())
+	assert.Equal(t, "1,000 JPY", (&Recurring{Frequency: FrequencyYearly, Amount: 12000, Currency: "JPY"}).Monthly().String())
+}
+
+func TestOrdinal(t *testing.T) {
+	for n, want := range map[int]string{1: "1st", 2: "2nd", 3: "3rd", 4: "4th", 11: "11th", 12: "12th", 13: "13th", 21: "21st", 22: "22nd", 31: "31st"} {
+		assert.Equal(t, want, ordinal(n))
+	}
+}
@@ -1,4 +1,101 @@
 package model
 
+import (
+	"errors"
+	"github/shaolim/momon/pkg/money"
+	"time"
+)
+
 type Transaction struct {
+	ID int64
+	// UserID is who recorded the transaction. In a group's ledger that is
+	// the member who sent it.
+	UserID int64
+	// GroupID is the group whose shared ledger the transaction is in, or 0
+	// for the user's own.
+	GroupID int64
+	// Amount is always positive and expressed in the currency's minor unit
+	// (e.g. yen for JPY, cents for USD). Whether the money came in or went out
+	// is decided by Type.
+	Amount     int64
+	Currency   money.Currency
+	Type       TransactionType
+	Category   string
+	Merchant   string
+	OccurredAt time.Time
+	// TimeUnknown is set when only the date is known, e.g. from a receipt
+	// that prints no time. OccurredAt is then noon on that date.
+	TimeUnknown bool
+	Note        string
+	Source      TransactionSource
+	Items       []Item
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (t *Transaction) Validate() error {
+	if t.UserID == 0 {
+		return errors.New("user id must not be empty")
+	}
+
+	if t.Amount <= 0 {
+		return errors.New("amount must be greater than zero")
+	}
+
+	if len(t.Currency) != 3 {
+		return errors.New("currency must be a 3-letter ISO 4217 code")
+	}
+
+	switch t.Type {
+	case TransactionTypeIncome, TransactionTypeExpense:
+	default:
+		return errors.New("type must be either INCOME or EXPENSE")
+	}
+
+	if t.OccurredAt.IsZero() {
+		return errors.New("occurred at must not be empty")
+	}
+
+	return nil
+}
+
+// Item is a single line of a transaction, typically read from a receipt.
+// Monetary values use the same minor unit as the parent transaction.
+type Item struct {
+	ID            int64
+	TransactionID int64
+	Name          string
+	Quantity      float64
+	Price         int64
+	Tax           int64
+	TotalPrice    int64
+}
+
+// Money returns the amount together with its currency.
+func (t *Transaction) Money() money.Money {
+	return money.New(t.Amount, t.Currency)
+}
+
+// SignedAmount returns the amount as a signed value, negative for expenses.
+func (t *Transaction) SignedAmount() int64 {
+	if t.Type == TransactionTypeExpense {
+		return -t.Amount
+	}
+	return t.Amount
 }
+
+type TransactionType string
+
+const (
+	TransactionTypeIncome  = "INCOME"
+	TransactionTypeExpense = "EXPENSE"
+)
+
+type TransactionSource string
+
+const (
+	TransactionSourceManual    = "MANUAL"
+	TransactionSourceText      = "TEXT"
+	TransactionSourceReceipt   = "RECEIPT"
+	TransactionSourceRecurring = "RECURRING"
+)
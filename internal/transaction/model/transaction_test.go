@@ -0,0 +1,85 @@
+package model_test
+
+import (
+	"github/shaolim/momon/internal/transaction/model"
+	"testing"
+	"time"
+)
+
+func TestTransaction_Validate(t *testing.T) {
+	valid := func() model.Transaction {
+		return model.Transaction{
+			UserID:     1,
+			Amount:     1200,
+			Currency:   "JPY",
+			Type:       model.TransactionTypeExpense,
+			OccurredAt: time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC),
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(*model.Transaction)
+		wantErr string
+	}{
+		{
+			name:   "valid",
+			modify: func(*model.Transaction) {},
+		},
+		{
+			name:    "missing user",
+			modify:  func(tx *model.Transaction) { tx.UserID = 0 },
+			wantErr: "user id must not be empty",
+		},
+		{
+			name:    "negative amount",
+			modify:  func(tx *model.Transaction) { tx.Amount = -1 },
+			wantErr: "amount must be greater than zero",
+		},
+		{
+			name:    "invalid currency",
+			modify:  func(tx *model.Transaction) { tx.Currency = "YEN!" },
+			wantErr: "currency must be a 3-letter ISO 4217 code",
+		},
+		{
+			name:    "invalid type",
+			modify:  func(tx *model.Transaction) { tx.Type = "TRANSFER" },
+			wantErr: "type must be either INCOME or EXPENSE",
+		},
+		{
+			name:    "missing occurred at",
+			modify:  func(tx *model.Transaction) { tx.OccurredAt = time.Time{} },
+			wantErr: "occurred at must not be empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := valid()
+			tt.modify(&tx)
+
+			err := tx.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Validate() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTransaction_SignedAmount(t *testing.T) {
+	expense := model.Transaction{Amount: 1200, Type: model.TransactionTypeExpense}
+	if got := expense.SignedAmount(); got != -1200 {
+		t.Errorf("SignedAmount() = %v, want -1200", got)
+	}
+
+	income := model.Transaction{Amount: 50000, Type: model.TransactionTypeIncome}
+	if got := income.SignedAmount(); got != 50000 {
+		t.Errorf("SignedAmount() = %v, want 50000", got)
+	}
+}
@@ -0,0 +1,117 @@
+package parser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github/shaolim/momon/internal/transaction/model"
+	"github/shaolim/momon/pkg/money"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+)
+
+const openAIPrompt = `You turn chat messages sent to a money tracking bot into transactions.
+
+Today is %s. Amounts are in %s unless the message says otherwise.
+
+Return ONLY a JSON object with these fields:
+{
+    "isTransaction": true,
+    "type": "EXPENSE or INCOME",
+    "amount": 12.5,
+    "description": "Short description such as lunch or salary",
+    "date": "YYYY-MM-DD"
+}
+
+RULES:
+- amount is a positive number as written by people, e.g. 12.5 for $12.50 or 1200 for 1,200 yen
+- date is the day the money was spent or received; use today when not mentioned
+- If the message is not about spending or receiving money, return {"isTransaction": false}`
+
+type openAIEntry struct {
+	IsTransaction bool    `json:"isTransaction"`
+	Type          string  `json:"type"`
+	Amount        float64 `json:"amount"`
+	Description   string  `json:"description"`
+	Date          string  `json:"date"`
+}
+
+// OpenAI asks the model to interpret messages the deterministic parser does
+// not understand, such as "paid 3 thousand for a haircut".
+type OpenAI struct {
+	client *openai.Client
+}
+
+func NewOpenAI(client *openai.Client) *OpenAI {
+	return &OpenAI{
+		client: client,
+	}
+}
+
+func (p *OpenAI) Parse(ctx context.Context, text string, currency money.Currency, now time.Time) (*Entry, error) {
+	req := openai.ChatCompletionNewParams{
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.SystemMessage(fmt.Sprintf(openAIPrompt, now.Format("2006-01-02 (Monday)"), currency)),
+			openai.UserMessage(text),
+		},
+		Model: openai.ChatModelGPT4oMini,
+		ResponseFormat: openai.ChatCompletionNewParamsResponseFormatUnion{
+			OfJSONObject: &openai.ResponseFormatJSONObjectParam{},
+		},
+	}
+
+	resp, err := p.client.Chat.Completions.New(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get response from OpenAI: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("no choices returned from OpenAI")
+	}
+	content := resp.Choices[0].Message.Content
+
+	var result openAIEntry
+	if err := json.Unmarshal([]byte(content), &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal entry: %w, content: %s", err, content)
+	}
+
+	return result.entry(currency, now)
+}
+
+func (e *openAIEntry) entry(currency money.Currency, now time.Time) (*Entry, error) {
+	if !e.IsTransaction || e.Amount <= 0 {
+		return nil, ErrNoMatch
+	}
+
+	// The model answers in major units; round to the currency's minor unit.
+	amount, err := money.Parse(strconv.FormatFloat(e.Amount, 'f', currency.Exponent(), 64), currency)
+	if err != nil {
+		return nil, fmt.Errorf("invalid amount %v: %w", e.Amount, err)
+	}
+	if amount.IsZero() {
+		return nil, ErrNoMatch
+	}
+
+	entry := &Entry{
+		Type:        model.TransactionTypeExpense,
+		Amount:      amount,
+		Description: strings.TrimSpace(e.Description),
+		OccurredAt:  now,
+	}
+
+	if strings.EqualFold(e.Type, model.TransactionTypeIncome) {
+		entry.Type = model.TransactionTypeIncome
+	}
+
+	if e.Date != "" && e.Date != now.Format("2006-01-02") {
+		date, err := time.ParseInLocation("2006-01-02", e.Date, now.Location())
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q: %w", e.Date, err)
+		}
+		entry.OccurredAt = noon(date)
+	}
+
+	return entry, nil
+}
@@ -0,0 +1,133 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"github/shaolim/momon/internal/transaction/model"
+	"github/shaolim/momon/pkg/money"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoMatch is returned when a message does not describe a transaction.
+var ErrNoMatch = errors.New("message does not describe a transaction")
+
+// Entry is a transaction described in a chat message.
+type Entry struct {
+	Type        model.TransactionType
+	Amount      money.Money
+	Description string
+	OccurredAt  time.Time
+}
+
+// Parser turns free text into an Entry when the deterministic Parse does not
+// understand it. Amounts are read in currency. now is the time the message was
+// received and is used to resolve relative dates such as "yesterday".
+type Parser interface {
+	Parse(ctx context.Context, text string, currency money.Currency, now time.Time) (*Entry, error)
+}
+
+var amountPattern = regexp.MustCompile(`^([+-])?[¥$€]?((?:\d{1,3}(?:,\d{3})+|\d+)(?:\.\d+)?)(?:円|yen)?$`)
+
+var (
+	isoDatePattern   = regexp.MustCompile(`^(\d{4})-(\d{1,2})-(\d{1,2})$`)
+	slashDatePattern = regexp.MustCompile(`^(?:(\d{4})/)?(\d{1,2})/(\d{1,2})$`)
+)
+
+// Parse is the deterministic parser for the common shapes "lunch 1200",
+// "1200 lunch", "taxi 3,400 yesterday" and "+50000 salary". It accepts exactly
+// one amount, at most one date and treats the remaining words as the
+// description. A leading "+" marks income, anything else is an expense.
+// Amounts are read in currency, so "4.50" is accepted for USD but not for JPY.
+func Parse(text string, currency money.Currency, now time.Time) (*Entry, error) {
+	var (
+		entry       = &Entry{Type: model.TransactionTypeExpense}
+		description []string
+		hasAmount   bool
+		hasDate     bool
+	)
+
+	for _, word := range strings.Fields(text) {
+		if m := amountPattern.FindStringSubmatch(strings.ToLower(word)); m != nil {
+			if hasAmount {
+				return nil, ErrNoMatch
+			}
+			amount, err := money.Parse(m[2], currency)
+			if err != nil || amount.IsZero() {
+				return nil, ErrNoMatch
+			}
+			if m[1] == "+" {
+				entry.Type = model.TransactionTypeIncome
+			}
+			entry.Amount = amount
+			hasAmount = true
+			continue
+		}
+
+		if date, ok := parseDate(word, now); ok {
+			if hasDate {
+				return nil, ErrNoMatch
+			}
+			entry.OccurredAt = date
+			hasDate = true
+			continue
+		}
+
+		description = append(description, word)
+	}
+
+	if !hasAmount {
+		return nil, ErrNoMatch
+	}
+
+	if !hasDate {
+		entry.OccurredAt = now
+	}
+	entry.Description = strings.Join(description, " ")
+
+	return entry, nil
+}
+
+// parseDate understands "today", "yesterday", "YYYY-MM-DD", "YYYY/M/D" and
+// "M/D". Dates other than today are placed at noon so they stay on the same
+// day in nearby time zones.
+func parseDate(word string, now time.Time) (time.Time, bool) {
+	switch strings.ToLower(word) {
+	case "today":
+		return now, true
+	case "yesterday":
+		return noon(now.AddDate(0, 0, -1)), true
+	}
+
+	m := isoDatePattern.FindStringSubmatch(word)
+	if m == nil {
+		m = slashDatePattern.FindStringSubmatch(word)
+	}
+	if m == nil {
+		return time.Time{}, false
+	}
+
+	year := now.Year()
+	if m[1] != "" {
+		year, _ = strconv.Atoi(m[1])
+	}
+	month, _ := strconv.Atoi(m[2])
+	day, _ := strconv.Atoi(m[3])
+	if month < 1 || month > 12 || day < 1 || day > 31 {
+		return time.Time{}, false
+	}
+
+	date := time.Date(year, time.Month(month), day, 12, 0, 0, 0, now.Location())
+	if date.Day() != day {
+		// e.g. 2/30 rolled over into March.
+		return time.Time{}, false
+	}
+
+	return date, true
+}
+
+func noon(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 12, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,213 @@
+package parser
+
+import (
+	"errors"
+	"github/shaolim/momon/internal/transaction/model"
+	"github/shaolim/momon/pkg/money"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	now := time.Date(2025, 10, 15, 19, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		input    string
+		currency money.Currency
+		want     *Entry
+		wantErr  error
+	}{
+		{
+			name:  "description then amount",
+			input: "lunch 1200",
+			want:  &Entry{Type: model.TransactionTypeExpense, Amount: money.New(1200, "JPY"), Description: "lunch", OccurredAt: now},
+		},
+		{
+			name:  "amount then description",
+			input: "1200 lunch",
+			want:  &Entry{Type: model.TransactionTypeExpense, Amount: money.New(1200, "JPY"), Description: "lunch", OccurredAt: now},
+		},
+		{
+			name:  "thousands separator and yesterday",
+			input: "taxi 3,400 yesterday",
+			want: &Entry{
+				Type:        model.TransactionTypeExpense,
+				Amount:      money.New(3400, "JPY"),
+				Description: "taxi",
+				OccurredAt:  time.Date(2025, 10, 14, 12, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:  "income",
+			input: "+50000 salary",
+			want:  &Entry{Type: model.TransactionTypeIncome, Amount: money.New(50000, "JPY"), Description: "salary", OccurredAt: now},
+		},
+		{
+			name:  "explicit expense sign and yen suffix",
+			input: "-800円 coffee beans",
+			want:  &Entry{Type: model.TransactionTypeExpense, Amount: money.New(800, "JPY"), Description: "coffee beans", OccurredAt: now},
+		},
+		{
+			name:  "currency symbol",
+			input: "¥1,500 haircut",
+			want:  &Entry{Type: model.TransactionTypeExpense, Amount: money.New(1500, "JPY"), Description: "haircut", OccurredAt: now},
+		},
+		{
+			name:  "iso date",
+			input: "rent 85000 2025-10-01",
+			want: &Entry{
+				Type:        model.TransactionTypeExpense,
+				Amount:      money.New(85000, "JPY"),
+				Description: "rent",
+				OccurredAt:  time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:  "month and day",
+			input: "10/3 dinner 4200",
+			want: &Entry{
+				Type:        model.TransactionTypeExpense,
+				Amount:      money.New(4200, "JPY"),
+				Description: "dinner",
+				OccurredAt:  time.Date(2025, 10, 3, 12, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:  "amount only",
+			input: "500",
+			want:  &Entry{Type: model.TransactionTypeExpense, Amount: money.New(500, "JPY"), OccurredAt: now},
+		},
+		{
+			name:     "cents",
+			input:    "coffee $4.50",
+			currency: "USD",
+			want:     &Entry{Type: model.TransactionTypeExpense, Amount: money.New(450, "USD"), Description: "coffee", OccurredAt: now},
+		},
+		{
+			name:     "cents with thousands separator",
+			input:    "+1,234.5 refund",
+			currency: "USD",
+			want:     &Entry{Type: model.TransactionTypeIncome, Amount: money.New(123450, "USD"), Description: "refund", OccurredAt: now},
+		},
+		{
+			name:    "decimals in a zero-decimal currency",
+			input:   "lunch 12.5",
+			wantErr: ErrNoMatch,
+		},
+		{
+			name:    "no amount",
+			input:   "hello there",
+			wantErr: ErrNoMatch,
+		},
+		{
+			name:    "two amounts",
+			input:   "2 coffees 800",
+			wantErr: ErrNoMatch,
+		},
+		{
+			name:    "zero amount",
+			input:   "lunch 0",
+			wantErr: ErrNoMatch,
+		},
+		{
+			name:    "empty",
+			input:   "",
+			wantErr: ErrNoMatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			currency := tt.currency
+			if currency == "" {
+				currency = "JPY"
+			}
+
+			got, err := Parse(tt.input, currency, now)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Parse(%q) error = %v, want %v", tt.input, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q) unexpected error: %v", tt.input, err)
+			}
+
+			if got.Type != tt.want.Type {
+				t.Errorf("Type = %v, want %v", got.Type, tt.want.Type)
+			}
+			if got.Amount != tt.want.Amount {
+				t.Errorf("Amount = %v, want %v", got.Amount, tt.want.Amount)
+			}
+			if got.Description != tt.want.Description {
+				t.Errorf("Description = %q, want %q", got.Description, tt.want.Description)
+			}
+			if !got.OccurredAt.Equal(tt.want.OccurredAt) {
+				t.Errorf("OccurredAt = %v, want %v", got.OccurredAt, tt.want.OccurredAt)
+			}
+		})
+	}
+}
+
+func TestParseDate_Invalid(t *testing.T) {
+	now := time.Date(2025, 10, 15, 19, 30, 0, 0, time.UTC)
+
+	for _, word := range []string{"2/30", "13/1", "7-11", "tomorrowish"} {
+		if _, ok := parseDate(word, now); ok {
+			t.Errorf("parseDate(%q) ok = true, want false", word)
+		}
+	}
+}
+
+func TestOpenAIEntry(t *testing.T) {
+	now := time.Date(2025, 10, 15, 19, 30, 0, 0, time.UTC)
+
+	t.Run("expense", func(t *testing.T) {
+		e := &openAIEntry{IsTransaction: true, Type: "EXPENSE", Amount: 3000, Description: " haircut ", Date: "2025-10-15"}
+
+		got, err := e.entry("JPY", now)
+		if err != nil {
+			t.Fatalf("entry() unexpected error: %v", err)
+		}
+		if got.Amount != money.New(3000, "JPY") || got.Description != "haircut" || !got.OccurredAt.Equal(now) {
+			t.Errorf("entry() = %+v", got)
+		}
+	})
+
+	t.Run("income on another day", func(t *testing.T) {
+		e := &openAIEntry{IsTransaction: true, Type: "income", Amount: 50000, Description: "bonus", Date: "2025-10-10"}
+
+		got, err := e.entry("JPY", now)
+		if err != nil {
+			t.Fatalf("entry() unexpected error: %v", err)
+		}
+		if got.Type != model.TransactionTypeIncome {
+			t.Errorf("Type = %v, want INCOME", got.Type)
+		}
+		if want := time.Date(2025, 10, 10, 12, 0, 0, 0, time.UTC); !got.OccurredAt.Equal(want) {
+			t.Errorf("OccurredAt = %v, want %v", got.OccurredAt, want)
+		}
+	})
+
+	t.Run("major units are converted to cents", func(t *testing.T) {
+		e := &openAIEntry{IsTransaction: true, Type: "EXPENSE", Amount: 12.499, Description: "lunch"}
+
+		got, err := e.entry("USD", now)
+		if err != nil {
+			t.Fatalf("entry() unexpected error: %v", err)
+		}
+		if want := money.New(1250, "USD"); got.Amount != want {
+			t.Errorf("Amount = %v, want %v", got.Amount, want)
+		}
+	})
+
+	t.Run("not a transaction", func(t *testing.T) {
+		e := &openAIEntry{IsTransaction: false}
+
+		if _, err := e.entry("JPY", now); !errors.Is(err, ErrNoMatch) {
+			t.Errorf("entry() error = %v, want ErrNoMatch", err)
+		}
+	})
+}
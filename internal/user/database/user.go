@@ -2,9 +2,11 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github/shaolim/momon/internal/user/model"
 	"github/shaolim/momon/pkg/database"
+	"github/shaolim/momon/pkg/money"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -12,8 +14,34 @@ import (
 
 type UserDB interface {
 	AddUser(ctx context.Context, user *model.User) error
+	GetUser(ctx context.Context, id int64) (*model.User, error)
+	GetByLineUserID(ctx context.Context, lineUserID string) (*model.User, error)
+	Upsert(ctx context.Context, user *model.User) error
+	UpdateStatus(ctx context.Context, lineUserID string, status model.UserStatus) error
+	UpdateTimezone(ctx context.Context, userID int64, timezone string) error
+	UpdateCurrency(ctx context.Context, userID int64, currency money.Currency) error
+
+	GetByIdentity(ctx context.Context, channel model.Channel, externalID string) (*model.User, error)
+	AddUserWithIdentity(ctx context.Context, user *model.User, identity *model.Identity) error
+	ListIdentities(ctx context.Context, userID int64) ([]*model.Identity, error)
+	AddLinkCode(ctx context.Context, code *model.LinkCode) error
+	LinkIdentity(ctx context.Context, code string, identity *model.Identity, now time.Time) (*model.User, error)
 }
 
+var (
+	errEmptyLineUserID = errors.New("line user id must not be empty")
+
+	// ErrInvalidLinkCode is returned for link codes that do not exist, were
+	// used or expired.
+	ErrInvalidLinkCode = errors.New("invalid link code")
+	// ErrAlreadyLinked is returned when the identity belongs to the account
+	// it is linked to already.
+	ErrAlreadyLinked = errors.New("identity is already linked to this account")
+	// ErrChannelLinked is returned when the account has another identity on
+	// the identity's channel.
+	ErrChannelLinked = errors.New("account already has an identity on this channel")
+)
+
 type userDB struct {
 	db *database.DB
 }
@@ -24,7 +52,22 @@ func New(db *database.DB) UserDB {
 	}
 }
 
+const userColumns = `id, line_user_id, display_name, status, timezone, home_currency, created_at, updated_at`
+
+func scanUser(row pgx.Row) (*model.User, error) {
+	var user model.User
+	if err := row.Scan(&user.ID, &user.LineUserID, &user.DisplayName, &user.Status, &user.Timezone, &user.Currency,
+		&user.CreatedAt, &user.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// AddUser inserts a LINE user together with their LINE identity.
 func (db *userDB) AddUser(ctx context.Context, user *model.User) error {
+	if user.LineUserID == "" {
+		return errEmptyLineUserID
+	}
 	if err := user.Validate(); err != nil {
 		return err
 	}
@@ -37,15 +80,95 @@ func (db *userDB) AddUser(ctx context.Context, user *model.User) error {
 
 	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
 		row := tx.QueryRow(ctx, `
-			INSERT INTO users (line_user_id, display_name, status, created_at, updated_at)
-			VALUES($1, $2, $3, $4, $5)
+			INSERT INTO users (line_user_id, display_name, status, timezone, home_currency, created_at, updated_at)
+			VALUES($1, $2, $3, $4, $5, $6, $7)
 			RETURNING id
-		`, user.LineUserID, user.DisplayName, user.Status, user.CreatedAt, user.UpdatedAt)
+		`, user.LineUserID, user.DisplayName, user.Status, user.Timezone, user.Currency, user.CreatedAt, user.UpdatedAt)
 
 		if err := row.Scan(&user.ID); err != nil {
 			return fmt.Errorf("insert users: %w", err)
 		}
 
+		return addIdentity(ctx, tx, &model.Identity{UserID: user.ID, Channel: model.ChannelLine, ExternalID: user.LineUserID, CreatedAt: user.CreatedAt})
+	}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (db *userDB) GetUser(ctx context.Context, id int64) (*model.User, error) {
+	row := db.db.Pool.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1`, id)
+
+	user, err := scanUser(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, database.ErrNotFound
+		}
+		return nil, fmt.Errorf("select users: %w", err)
+	}
+
+	return user, nil
+}
+
+func (db *userDB) GetByLineUserID(ctx context.Context, lineUserID string) (*model.User, error) {
+	if lineUserID == "" {
+		return nil, database.ErrNotFound
+	}
+
+	row := db.db.Pool.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE line_user_id = $1`, lineUserID)
+
+	user, err := scanUser(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, database.ErrNotFound
+		}
+		return nil, fmt.Errorf("select users: %w", err)
+	}
+
+	return user, nil
+}
+
+// Upsert inserts the user or, when the LINE user ID is already known, updates
+// the display name and status of the existing row. The user's ID, Timezone,
+// Currency and CreatedAt are set from the stored row, so settings the user
+// chose are kept.
+func (db *userDB) Upsert(ctx context.Context, user *model.User) error {
+	if user.LineUserID == "" {
+		return errEmptyLineUserID
+	}
+	if err := user.Validate(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = now
+	}
+	user.UpdatedAt = now
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, `
+			INSERT INTO users (line_user_id, display_name, status, timezone, home_currency, created_at, updated_at)
+			VALUES($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (line_user_id) WHERE line_user_id <> '' DO UPDATE
+			SET display_name = EXCLUDED.display_name, status = EXCLUDED.status, updated_at = EXCLUDED.updated_at
+			RETURNING id, timezone, home_currency, created_at
+		`, user.LineUserID, user.DisplayName, user.Status, user.Timezone, user.Currency, user.CreatedAt, user.UpdatedAt)
+
+		if err := row.Scan(&user.ID, &user.Timezone, &user.Currency, &user.CreatedAt); err != nil {
+			return fmt.Errorf("upsert users: %w", err)
+		}
+
+		_, err := tx.Exec(ctx, `
+			INSERT INTO user_identities (user_id, channel, external_id, created_at)
+			VALUES($1, $2, $3, $4)
+			ON CONFLICT (channel, external_id) DO NOTHING
+		`, user.ID, model.ChannelLine, user.LineUserID, user.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("insert user_identities: %w", err)
+		}
+
 		return nil
 	}); err != nil {
 		return err
@@ -53,3 +176,345 @@ func (db *userDB) AddUser(ctx context.Context, user *model.User) error {
 
 	return nil
 }
+
+func (db *userDB) UpdateStatus(ctx context.Context, lineUserID string, status model.UserStatus) error {
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+			UPDATE users
+			SET status = $2, updated_at = $3
+			WHERE line_user_id = $1
+		`, lineUserID, status, time.Now())
+		if err != nil {
+			return fmt.Errorf("update users: %w", err)
+		}
+
+		if result.RowsAffected() == 0 {
+			return database.ErrNotFound
+		}
+
+		return nil
+	})
+}
+
+// UpdateTimezone sets the user's IANA time zone. An empty timezone reverts to
+// the server default.
+func (db *userDB) UpdateTimezone(ctx context.Context, userID int64, timezone string) error {
+	if timezone != "" {
+		if _, err := time.LoadLocation(timezone); err != nil {
+			return fmt.Errorf("invalid timezone %q", timezone)
+		}
+	}
+
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+			UPDATE users
+			SET timezone = $2, updated_at = $3
+			WHERE id = $1
+		`, userID, timezone, time.Now())
+		if err != nil {
+			return fmt.Errorf("update users: %w", err)
+		}
+
+		if result.RowsAffected() == 0 {
+			return database.ErrNotFound
+		}
+
+		return nil
+	})
+}
+
+// UpdateCurrency sets the user's home currency. An empty currency reverts to
+// the server default.
+func (db *userDB) UpdateCurrency(ctx context.Context, userID int64, currency money.Currency) error {
+	if err := (&model.User{Currency: currency}).Validate(); err != nil {
+		return err
+	}
+
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+			UPDATE users
+			SET home_currency = $2, updated_at = $3
+			WHERE id = $1
+		`, userID, currency, time.Now())
+		if err != nil {
+			return fmt.Errorf("update users: %w", err)
+		}
+
+		if result.RowsAffected() == 0 {
+			return database.ErrNotFound
+		}
+
+		return nil
+	})
+}
+
+func addIdentity(ctx context.Context, tx pgx.Tx, identity *model.Identity) error {
+	if err := identity.Validate(); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO user_identities (user_id, channel, external_id, created_at)
+		VALUES($1, $2, $3, $4)
+	`, identity.UserID, identity.Channel, identity.ExternalID, identity.CreatedAt); err != nil {
+		return fmt.Errorf("insert user_identities: %w", err)
+	}
+
+	return nil
+}
+
+// GetByIdentity returns the user whose identity on channel is externalID.
+func (db *userDB) GetByIdentity(ctx context.Context, channel model.Channel, externalID string) (*model.User, error) {
+	row := db.db.Pool.QueryRow(ctx, `
+		SELECT `+userColumns+`
+		FROM users
+		WHERE id = (SELECT user_id FROM user_identities WHERE channel = $1 AND external_id = $2)
+	`, channel, externalID)
+
+	user, err := scanUser(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, database.ErrNotFound
+		}
+		return nil, fmt.Errorf("select users: %w", err)
+	}
+
+	return user, nil
+}
+
+// AddUserWithIdentity inserts a user known by their identity on a channel
+// other than LINE.
+func (db *userDB) AddUserWithIdentity(ctx context.Context, user *model.User, identity *model.Identity) error {
+	if err := user.Validate(); err != nil {
+		return err
+	}
+	if err := identity.Validate(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = now
+	}
+	user.UpdatedAt = now
+
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, `
+			INSERT INTO users (line_user_id, display_name, status, timezone, home_currency, created_at, updated_at)
+			VALUES($1, $2, $3, $4, $5, $6, $7)
+			RETURNING id
+		`, user.LineUserID, user.DisplayName, user.Status, user.Timezone, user.Currency, user.CreatedAt, user.UpdatedAt)
+
+		if err := row.Scan(&user.ID); err != nil {
+			return fmt.Errorf("insert users: %w", err)
+		}
+
+		identity.UserID = user.ID
+		identity.CreatedAt = user.CreatedAt
+		return addIdentity(ctx, tx, identity)
+	})
+}
+
+func (db *userDB) ListIdentities(ctx context.Context, userID int64) ([]*model.Identity, error) {
+	rows, err := db.db.Pool.Query(ctx, `
+		SELECT user_id, channel, external_id, created_at
+		FROM user_identities
+		WHERE user_id = $1
+		ORDER BY created_at, id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("select user_identities: %w", err)
+	}
+	defer rows.Close()
+
+	var identities []*model.Identity
+	for rows.Next() {
+		var i model.Identity
+		if err := rows.Scan(&i.UserID, &i.Channel, &i.ExternalID, &i.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan user_identities: %w", err)
+		}
+		identities = append(identities, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("select user_identities: %w", err)
+	}
+
+	return identities, nil
+}
+
+func (db *userDB) AddLinkCode(ctx context.Context, code *model.LinkCode) error {
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO link_codes (code, user_id, expires_at)
+			VALUES($1, $2, $3)
+		`, code.Code, code.UserID, code.ExpiresAt); err != nil {
+			return fmt.Errorf("insert link_codes: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// LinkIdentity uses up the link code and moves the identity to the account
+// that created it. When the identity's previous account has no identities
+// left it is merged into the account and deleted; an account that keeps
+// other identities keeps its data too. It returns the account the identity
+// now belongs to.
+func (db *userDB) LinkIdentity(ctx context.Context, code string, identity *model.Identity, now time.Time) (*model.User, error) {
+	if err := identity.Validate(); err != nil {
+		return nil, err
+	}
+
+	var user *model.User
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		var targetID int64
+		row := tx.QueryRow(ctx, `
+			DELETE FROM link_codes
+			WHERE code = $1 AND expires_at > $2
+			RETURNING user_id
+		`, code, now)
+		if err := row.Scan(&targetID); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrInvalidLinkCode
+			}
+			return fmt.Errorf("delete link_codes: %w", err)
+		}
+
+		var previousID int64
+		row = tx.QueryRow(ctx, `
+			SELECT user_id FROM user_identities
+			WHERE channel = $1 AND external_id = $2
+			FOR UPDATE
+		`, identity.Channel, identity.ExternalID)
+		if err := row.Scan(&previousID); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return database.ErrNotFound
+			}
+			return fmt.Errorf("select user_identities: %w", err)
+		}
+		if previousID == targetID {
+			return ErrAlreadyLinked
+		}
+
+		var taken bool
+		row = tx.QueryRow(ctx, `
+			SELECT EXISTS (SELECT 1 FROM user_identities WHERE user_id = $1 AND channel = $2)
+		`, targetID, identity.Channel)
+		if err := row.Scan(&taken); err != nil {
+			return fmt.Errorf("select user_identities: %w", err)
+		}
+		if taken {
+			return ErrChannelLinked
+		}
+
+		if _, err := tx.Exec(ctx, `
+			UPDATE user_identities SET user_id = $3
+			WHERE channel = $1 AND external_id = $2
+		`, identity.Channel, identity.ExternalID, targetID); err != nil {
+			return fmt.Errorf("update user_identities: %w", err)
+		}
+
+		if identity.Channel == model.ChannelLine {
+			if _, err := tx.Exec(ctx, `UPDATE users SET line_user_id = '', updated_at = $2 WHERE id = $1`, previousID, now); err != nil {
+				return fmt.Errorf("update users: %w", err)
+			}
+			if _, err := tx.Exec(ctx, `UPDATE users SET line_user_id = $2, updated_at = $3 WHERE id = $1`, targetID, identity.ExternalID, now); err != nil {
+				return fmt.Errorf("update users: %w", err)
+			}
+		}
+
+		var orphaned bool
+		row = tx.QueryRow(ctx, `
+			SELECT NOT EXISTS (SELECT 1 FROM user_identities WHERE user_id = $1)
+		`, previousID)
+		if err := row.Scan(&orphaned); err != nil {
+			return fmt.Errorf("select user_identities: %w", err)
+		}
+		if orphaned {
+			if err := mergeUser(ctx, tx, previousID, targetID); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		if user, err = scanUser(tx.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1`, targetID)); err != nil {
+			return fmt.Errorf("select users: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// mergeUser hands the transactions, drafts and recurring entries of the
+// account previousID over to targetID, merges its categories, learned rules,
+// budgets, digests and group memberships into the target's own, which wins
+// where both have one, and deletes it.
+func mergeUser(ctx context.Context, tx pgx.Tx, previousID, targetID int64) error {
+	// Entries recorded before linking stay with the person who made them.
+	if _, err := tx.Exec(ctx, `UPDATE transactions SET user_id = $2 WHERE user_id = $1`, previousID, targetID); err != nil {
+		return fmt.Errorf("update transactions: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE transaction_drafts SET user_id = $2 WHERE user_id = $1`, previousID, targetID); err != nil {
+		return fmt.Errorf("update transaction_drafts: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE recurring_transactions SET user_id = $2 WHERE user_id = $1`, previousID, targetID); err != nil {
+		return fmt.Errorf("update recurring_transactions: %w", err)
+	}
+
+	// Categories are merged by name, and rules point at the account's
+	// category of the same name unless it has a rule for the keyword.
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO categories (user_id, name, created_at)
+		SELECT $2, name, created_at FROM categories WHERE user_id = $1
+		ON CONFLICT (user_id, LOWER(name)) DO NOTHING
+	`, previousID, targetID); err != nil {
+		return fmt.Errorf("insert categories: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO category_rules (user_id, category_id, keyword, created_at, updated_at)
+		SELECT $2, target.id, r.keyword, r.created_at, r.updated_at
+		FROM category_rules r
+		JOIN categories c ON c.id = r.category_id
+		JOIN categories target ON target.user_id = $2 AND LOWER(target.name) = LOWER(c.name)
+		WHERE r.user_id = $1
+		ON CONFLICT (user_id, keyword) DO NOTHING
+	`, previousID, targetID); err != nil {
+		return fmt.Errorf("insert category_rules: %w", err)
+	}
+
+	// Budget alerts follow their budget, so thresholds already pushed
+	// this month aren't pushed again.
+	if _, err := tx.Exec(ctx, `
+		UPDATE budgets b SET user_id = $2
+		WHERE b.user_id = $1 AND NOT EXISTS (
+			SELECT 1 FROM budgets t WHERE t.user_id = $2 AND LOWER(t.category) = LOWER(b.category)
+		)
+	`, previousID, targetID); err != nil {
+		return fmt.Errorf("update budgets: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE digests d SET user_id = $2
+		WHERE d.user_id = $1 AND NOT EXISTS (
+			SELECT 1 FROM digests t WHERE t.user_id = $2 AND t.period = d.period
+		)
+	`, previousID, targetID); err != nil {
+		return fmt.Errorf("update digests: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO group_members (group_id, user_id, joined_at)
+		SELECT group_id, $2, joined_at FROM group_members WHERE user_id = $1
+		ON CONFLICT (group_id, user_id) DO NOTHING
+	`, previousID, targetID); err != nil {
+		return fmt.Errorf("insert group_members: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM users WHERE id = $1`, previousID); err != nil {
+		return fmt.Errorf("delete users: %w", err)
+	}
+
+	return nil
+}
@@ -3,9 +3,13 @@ package database
 import (
 	"context"
 	"github/shaolim/momon/internal/user/model"
+	"github/shaolim/momon/pkg/database"
+	"github/shaolim/momon/pkg/money"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -90,3 +94,564 @@ func TestAddUser(t *testing.T) {
 		assert.EqualError(t, err, "line user id must not be empty")
 	})
 }
+
+func TestGetByLineUserID(t *testing.T) {
+	t.Parallel()
+
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	userDB := New(testDB)
+	ctx := context.Background()
+
+	user := &model.User{
+		LineUserID:  "line789",
+		DisplayName: "surti",
+		Status:      model.UserStatusActive,
+	}
+	if err := userDB.AddUser(ctx, user); err != nil {
+		t.Fatalf("failed to add user: %v", err)
+	}
+
+	got, err := userDB.GetByLineUserID(ctx, "line789")
+	if err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+	assert.Equal(t, user.ID, got.ID)
+	assert.Equal(t, "surti", got.DisplayName)
+	assert.Equal(t, model.UserStatus(model.UserStatusActive), got.Status)
+
+	_, err = userDB.GetByLineUserID(ctx, "unknown")
+	assert.ErrorIs(t, err, database.ErrNotFound)
+
+	got, err = userDB.GetUser(ctx, user.ID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "line789", got.LineUserID)
+	}
+
+	_, err = userDB.GetUser(ctx, user.ID+1)
+	assert.ErrorIs(t, err, database.ErrNotFound)
+}
+
+func TestUpsert(t *testing.T) {
+	t.Parallel()
+
+	t.Run("insert", func(t *testing.T) {
+		t.Parallel()
+
+		testDB, _ := testDatabaseInstance.NewDatabase(t)
+		userDB := New(testDB)
+		ctx := context.Background()
+
+		user := &model.User{
+			LineUserID:  "line123",
+			DisplayName: "surti",
+			Status:      model.UserStatusActive,
+		}
+		if err := userDB.Upsert(ctx, user); err != nil {
+			t.Fatalf("failed to upsert user: %v", err)
+		}
+
+		if user.ID == 0 {
+			t.Error("expected user ID to be assigned")
+		}
+	})
+
+	t.Run("reactivate", func(t *testing.T) {
+		t.Parallel()
+
+		testDB, _ := testDatabaseInstance.NewDatabase(t)
+		userDB := New(testDB)
+		ctx := context.Background()
+
+		user := &model.User{
+			LineUserID:  "line456",
+			DisplayName: "Old Name",
+			Status:      model.UserStatusInActive,
+		}
+		if err := userDB.AddUser(ctx, user); err != nil {
+			t.Fatalf("failed to add user: %v", err)
+		}
+
+		refollowed := &model.User{
+			LineUserID:  "line456",
+			DisplayName: "New Name",
+			Status:      model.UserStatusActive,
+		}
+		if err := userDB.Upsert(ctx, refollowed); err != nil {
+			t.Fatalf("failed to upsert user: %v", err)
+		}
+		assert.Equal(t, user.ID, refollowed.ID)
+
+		got, err := userDB.GetByLineUserID(ctx, "line456")
+		if err != nil {
+			t.Fatalf("failed to get user: %v", err)
+		}
+		assert.Equal(t, "New Name", got.DisplayName)
+		assert.Equal(t, model.UserStatus(model.UserStatusActive), got.Status)
+	})
+
+	t.Run("empty_line_user_id", func(t *testing.T) {
+		t.Parallel()
+
+		testDB, _ := testDatabaseInstance.NewDatabase(t)
+		userDB := New(testDB)
+
+		err := userDB.Upsert(context.Background(), &model.User{DisplayName: "Test User"})
+		assert.EqualError(t, err, "line user id must not be empty")
+	})
+}
+
+func TestUpdateStatus(t *testing.T) {
+	t.Parallel()
+
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	userDB := New(testDB)
+	ctx := context.Background()
+
+	user := &model.User{
+		LineUserID:  "line123",
+		DisplayName: "surti",
+		Status:      model.UserStatusActive,
+	}
+	if err := userDB.AddUser(ctx, user); err != nil {
+		t.Fatalf("failed to add user: %v", err)
+	}
+
+	if err := userDB.UpdateStatus(ctx, "line123", model.UserStatusInActive); err != nil {
+		t.Fatalf("failed to update status: %v", err)
+	}
+
+	got, err := userDB.GetByLineUserID(ctx, "line123")
+	if err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+	assert.Equal(t, model.UserStatus(model.UserStatusInActive), got.Status)
+
+	err = userDB.UpdateStatus(ctx, "unknown", model.UserStatusInActive)
+	assert.ErrorIs(t, err, database.ErrNotFound)
+}
+
+func TestUpdateTimezone(t *testing.T) {
+	t.Parallel()
+
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	userDB := New(testDB)
+	ctx := context.Background()
+
+	user := &model.User{
+		LineUserID:  "line123",
+		DisplayName: "surti",
+		Status:      model.UserStatusActive,
+	}
+	if err := userDB.AddUser(ctx, user); err != nil {
+		t.Fatalf("failed to add user: %v", err)
+	}
+
+	if err := userDB.UpdateTimezone(ctx, user.ID, "America/New_York"); err != nil {
+		t.Fatalf("failed to update timezone: %v", err)
+	}
+
+	// Following again must not reset the chosen timezone.
+	refollowed := &model.User{
+		LineUserID:  "line123",
+		DisplayName: "surti",
+		Status:      model.UserStatusActive,
+	}
+	if err := userDB.Upsert(ctx, refollowed); err != nil {
+		t.Fatalf("failed to upsert user: %v", err)
+	}
+	assert.Equal(t, "America/New_York", refollowed.Timezone)
+
+	got, err := userDB.GetByLineUserID(ctx, "line123")
+	if err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+	assert.Equal(t, "America/New_York", got.Timezone)
+
+	err = userDB.UpdateTimezone(ctx, user.ID, "Mars/Olympus")
+	assert.EqualError(t, err, `invalid timezone "Mars/Olympus"`)
+
+	err = userDB.UpdateTimezone(ctx, 0, "Asia/Tokyo")
+	assert.ErrorIs(t, err, database.ErrNotFound)
+}
+
+func TestUpdateCurrency(t *testing.T) {
+	t.Parallel()
+
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	userDB := New(testDB)
+	ctx := context.Background()
+
+	user := &model.User{
+		LineUserID:  "line123",
+		DisplayName: "surti",
+		Status:      model.UserStatusActive,
+	}
+	if err := userDB.AddUser(ctx, user); err != nil {
+		t.Fatalf("failed to add user: %v", err)
+	}
+
+	if err := userDB.UpdateCurrency(ctx, user.ID, "USD"); err != nil {
+		t.Fatalf("failed to update currency: %v", err)
+	}
+
+	// Following again must not reset the chosen currency.
+	refollowed := &model.User{
+		LineUserID:  "line123",
+		DisplayName: "surti",
+		Status:      model.UserStatusActive,
+	}
+	if err := userDB.Upsert(ctx, refollowed); err != nil {
+		t.Fatalf("failed to upsert user: %v", err)
+	}
+	assert.Equal(t, money.Currency("USD"), refollowed.Currency)
+
+	got, err := userDB.GetUser(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+	assert.Equal(t, money.Currency("USD"), got.Currency)
+
+	err = userDB.UpdateCurrency(ctx, user.ID, "dollars")
+	assert.EqualError(t, err, `invalid currency "dollars"`)
+
+	err = userDB.UpdateCurrency(ctx, 0, "EUR")
+	assert.ErrorIs(t, err, database.ErrNotFound)
+}
+
+func TestAddUserWithIdentity(t *testing.T) {
+	t.Parallel()
+
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	userDB := New(testDB)
+	ctx := context.Background()
+
+	user := &model.User{DisplayName: "surti", Status: model.UserStatusActive}
+	identity := &model.Identity{Channel: model.ChannelTelegram, ExternalID: "12345"}
+	if err := userDB.AddUserWithIdentity(ctx, user, identity); err != nil {
+		t.Fatalf("failed to add user: %v", err)
+	}
+	assert.NotZero(t, user.ID)
+	assert.Equal(t, user.ID, identity.UserID)
+
+	got, err := userDB.GetByIdentity(ctx, model.ChannelTelegram, "12345")
+	if err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+	assert.Equal(t, user.ID, got.ID)
+	assert.Empty(t, got.LineUserID)
+
+	// LINE users get their LINE identity when they are added.
+	lineUser := &model.User{LineUserID: "line123", DisplayName: "asep", Status: model.UserStatusActive}
+	if err := userDB.AddUser(ctx, lineUser); err != nil {
+		t.Fatalf("failed to add user: %v", err)
+	}
+	got, err = userDB.GetByIdentity(ctx, model.ChannelLine, "line123")
+	if err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+	assert.Equal(t, lineUser.ID, got.ID)
+
+	// A second user without LINE does not clash on the empty LINE user ID.
+	other := &model.User{DisplayName: "budi", Status: model.UserStatusActive}
+	assert.NoError(t, userDB.AddUserWithIdentity(ctx, other, &model.Identity{Channel: model.ChannelTelegram, ExternalID: "67890"}))
+
+	err = userDB.AddUserWithIdentity(ctx, &model.User{DisplayName: "dup"}, &model.Identity{Channel: model.ChannelTelegram, ExternalID: "12345"})
+	assert.Error(t, err)
+
+	_, err = userDB.GetByIdentity(ctx, model.ChannelTelegram, "unknown")
+	assert.ErrorIs(t, err, database.ErrNotFound)
+}
+
+func TestLinkIdentity(t *testing.T) {
+	t.Parallel()
+
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	userDB := New(testDB)
+	ctx := context.Background()
+	now := time.Now()
+
+	lineUser := &model.User{LineUserID: "line123", DisplayName: "surti", Status: model.UserStatusActive}
+	if err := userDB.AddUser(ctx, lineUser); err != nil {
+		t.Fatalf("failed to add user: %v", err)
+	}
+	telegramUser := &model.User{DisplayName: "surti", Status: model.UserStatusActive}
+	telegram := &model.Identity{Channel: model.ChannelTelegram, ExternalID: "12345"}
+	if err := userDB.AddUserWithIdentity(ctx, telegramUser, telegram); err != nil {
+		t.Fatalf("failed to add user: %v", err)
+	}
+
+	if _, err := testDB.Pool.Exec(ctx, `
+		INSERT INTO transactions (user_id, amount, currency, type, occurred_at)
+		VALUES($1, 1200, 'JPY', 'EXPENSE', $2)
+	`, telegramUser.ID, now); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+
+	for _, code := range []*model.LinkCode{
+		{Code: "ABC123", UserID: lineUser.ID, ExpiresAt: now.Add(10 * time.Minute)},
+		{Code: "OLD999", UserID: lineUser.ID, ExpiresAt: now.Add(-time.Minute)},
+	} {
+		if err := userDB.AddLinkCode(ctx, code); err != nil {
+			t.Fatalf("failed to add link code: %v", err)
+		}
+	}
+
+	_, err := userDB.LinkIdentity(ctx, "OLD999", telegram, now)
+	assert.ErrorIs(t, err, ErrInvalidLinkCode)
+
+	linked, err := userDB.LinkIdentity(ctx, "ABC123", telegram, now)
+	if err != nil {
+		t.Fatalf("failed to link identity: %v", err)
+	}
+	assert.Equal(t, lineUser.ID, linked.ID)
+
+	got, err := userDB.GetByIdentity(ctx, model.ChannelTelegram, "12345")
+	if err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+	assert.Equal(t, lineUser.ID, got.ID)
+
+	identities, err := userDB.ListIdentities(ctx, lineUser.ID)
+	if err != nil {
+		t.Fatalf("failed to list identities: %v", err)
+	}
+	assert.Len(t, identities, 2)
+
+	var count int
+	if err := testDB.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM transactions WHERE user_id = $1`, lineUser.ID).Scan(&count); err != nil {
+		t.Fatalf("failed to count transactions: %v", err)
+	}
+	assert.Equal(t, 1, count, "transactions move to the linked account")
+
+	if err := testDB.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM users WHERE id = $1`, telegramUser.ID).Scan(&count); err != nil {
+		t.Fatalf("failed to count users: %v", err)
+	}
+	assert.Zero(t, count, "the empty account is deleted")
+
+	// Codes are single use.
+	_, err = userDB.LinkIdentity(ctx, "ABC123", telegram, now)
+	assert.ErrorIs(t, err, ErrInvalidLinkCode)
+
+	if err := userDB.AddLinkCode(ctx, &model.LinkCode{Code: "DEF456", UserID: lineUser.ID, ExpiresAt: now.Add(time.Minute)}); err != nil {
+		t.Fatalf("failed to add link code: %v", err)
+	}
+	_, err = userDB.LinkIdentity(ctx, "DEF456", telegram, now)
+	assert.ErrorIs(t, err, ErrAlreadyLinked)
+}
+
+// addLinkedUsers adds a LINE account with the link code "ABC123" and a
+// Telegram account that can be linked to it with the returned identity.
+func addLinkedUsers(t *testing.T, userDB UserDB, now time.Time) (*model.User, *model.User, *model.Identity) {
+	t.Helper()
+
+	ctx := context.Background()
+	lineUser := &model.User{LineUserID: "line123", DisplayName: "surti", Status: model.UserStatusActive}
+	if err := userDB.AddUser(ctx, lineUser); err != nil {
+		t.Fatalf("failed to add user: %v", err)
+	}
+	telegramUser := &model.User{DisplayName: "surti", Status: model.UserStatusActive}
+	telegram := &model.Identity{Channel: model.ChannelTelegram, ExternalID: "12345"}
+	if err := userDB.AddUserWithIdentity(ctx, telegramUser, telegram); err != nil {
+		t.Fatalf("failed to add user: %v", err)
+	}
+	if err := userDB.AddLinkCode(ctx, &model.LinkCode{Code: "ABC123", UserID: lineUser.ID, ExpiresAt: now.Add(10 * time.Minute)}); err != nil {
+		t.Fatalf("failed to add link code: %v", err)
+	}
+	return lineUser, telegramUser, telegram
+}
+
+func mustExec(t *testing.T, db *database.DB, query string, args ...any) {
+	t.Helper()
+
+	if _, err := db.Pool.Exec(context.Background(), query, args...); err != nil {
+		t.Fatalf("failed to exec %q: %v", query, err)
+	}
+}
+
+func mustQueryStrings(t *testing.T, db *database.DB, query string, args ...any) []string {
+	t.Helper()
+
+	rows, err := db.Pool.Query(context.Background(), query, args...)
+	if err != nil {
+		t.Fatalf("failed to query %q: %v", query, err)
+	}
+	values, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		t.Fatalf("failed to query %q: %v", query, err)
+	}
+	return values
+}
+
+func TestLinkIdentity_Categories(t *testing.T) {
+	t.Parallel()
+
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	userDB := New(testDB)
+	ctx := context.Background()
+	now := time.Now()
+	lineUser, telegramUser, telegram := addLinkedUsers(t, userDB, now)
+
+	mustExec(t, testDB, `INSERT INTO categories (user_id, name) VALUES ($1, 'Food'), ($1, 'Transport')`, lineUser.ID)
+	mustExec(t, testDB, `INSERT INTO categories (user_id, name) VALUES ($1, 'food'), ($1, 'Pets')`, telegramUser.ID)
+	mustExec(t, testDB, `
+		INSERT INTO category_rules (user_id, category_id, keyword)
+		SELECT $1, id, 'taxi' FROM categories WHERE user_id = $1 AND name = 'Transport'
+	`, lineUser.ID)
+	mustExec(t, testDB, `
+		INSERT INTO category_rules (user_id, category_id, keyword)
+		SELECT $1, id, keyword FROM categories, (VALUES ('lawson'), ('taxi')) AS k(keyword)
+		WHERE user_id = $1 AND name = 'food'
+	`, telegramUser.ID)
+	mustExec(t, testDB, `
+		INSERT INTO category_rules (user_id, category_id, keyword)
+		SELECT $1, id, 'petco' FROM categories WHERE user_id = $1 AND name = 'Pets'
+	`, telegramUser.ID)
+
+	if _, err := userDB.LinkIdentity(ctx, "ABC123", telegram, now); err != nil {
+		t.Fatalf("failed to link identity: %v", err)
+	}
+
+	assert.Equal(t, []string{"Food", "Pets", "Transport"},
+		mustQueryStrings(t, testDB, `SELECT name FROM categories WHERE user_id = $1 ORDER BY name`, lineUser.ID))
+	// The account's own rule for a keyword wins.
+	assert.Equal(t, []string{"lawson Food", "petco Pets", "taxi Transport"}, mustQueryStrings(t, testDB, `
+		SELECT r.keyword || ' ' || c.name FROM category_rules r JOIN categories c ON c.id = r.category_id
+		WHERE r.user_id = $1 ORDER BY r.keyword
+	`, lineUser.ID))
+}
+
+func TestLinkIdentity_Budgets(t *testing.T) {
+	t.Parallel()
+
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	userDB := New(testDB)
+	ctx := context.Background()
+	now := time.Now()
+	lineUser, telegramUser, telegram := addLinkedUsers(t, userDB, now)
+
+	mustExec(t, testDB, `INSERT INTO budgets (user_id, category, amount, currency) VALUES ($1, 'Food', 30000, 'JPY')`, lineUser.ID)
+	mustExec(t, testDB, `
+		INSERT INTO budgets (user_id, category, amount, currency)
+		VALUES ($1, 'food', 20000, 'JPY'), ($1, '', 100000, 'JPY')
+	`, telegramUser.ID)
+	mustExec(t, testDB, `
+		INSERT INTO budget_alerts (budget_id, month, threshold)
+		SELECT id, '2025-10-01', 80 FROM budgets WHERE user_id = $1 AND category = ''
+	`, telegramUser.ID)
+
+	if _, err := userDB.LinkIdentity(ctx, "ABC123", telegram, now); err != nil {
+		t.Fatalf("failed to link identity: %v", err)
+	}
+
+	// The account's own budget for a category wins.
+	assert.Equal(t, []string{" 100000", "Food 30000"}, mustQueryStrings(t, testDB, `
+		SELECT category || ' ' || amount FROM budgets WHERE user_id = $1 ORDER BY category
+	`, lineUser.ID))
+	assert.Equal(t, []string{"80"}, mustQueryStrings(t, testDB, `
+		SELECT a.threshold::TEXT FROM budget_alerts a JOIN budgets b ON b.id = a.budget_id WHERE b.user_id = $1
+	`, lineUser.ID), "alerts move with their budget")
+}
+
+func TestLinkIdentity_Recurring(t *testing.T) {
+	t.Parallel()
+
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	userDB := New(testDB)
+	ctx := context.Background()
+	now := time.Now()
+	lineUser, telegramUser, telegram := addLinkedUsers(t, userDB, now)
+
+	mustExec(t, testDB, `
+		INSERT INTO recurring_transactions (user_id, amount, currency, type, note, frequency, day, next_run_at)
+		VALUES ($1, 85000, 'JPY', 'EXPENSE', 'rent', 'MONTHLY', 27, $2)
+	`, telegramUser.ID, now)
+
+	if _, err := userDB.LinkIdentity(ctx, "ABC123", telegram, now); err != nil {
+		t.Fatalf("failed to link identity: %v", err)
+	}
+
+	assert.Equal(t, []string{"rent"},
+		mustQueryStrings(t, testDB, `SELECT note FROM recurring_transactions WHERE user_id = $1`, lineUser.ID),
+		"recurring entries keep running for the linked account")
+}
+
+func TestLinkIdentity_Digests(t *testing.T) {
+	t.Parallel()
+
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	userDB := New(testDB)
+	ctx := context.Background()
+	now := time.Now()
+	lineUser, telegramUser, telegram := addLinkedUsers(t, userDB, now)
+
+	mustExec(t, testDB, `INSERT INTO digests (user_id, period, hour, next_run_at) VALUES ($1, 'DAILY', 21, $2)`, lineUser.ID, now)
+	mustExec(t, testDB, `
+		INSERT INTO digests (user_id, period, weekday, hour, next_run_at)
+		VALUES ($1, 'DAILY', 0, 8, $2), ($1, 'WEEKLY', 0, 20, $2)
+	`, telegramUser.ID, now)
+
+	if _, err := userDB.LinkIdentity(ctx, "ABC123", telegram, now); err != nil {
+		t.Fatalf("failed to link identity: %v", err)
+	}
+
+	// The account's own daily digest keeps its time.
+	assert.Equal(t, []string{"DAILY 21", "WEEKLY 20"}, mustQueryStrings(t, testDB, `
+		SELECT period || ' ' || hour FROM digests WHERE user_id = $1 ORDER BY period
+	`, lineUser.ID))
+}
+
+func TestLinkIdentity_GroupMembers(t *testing.T) {
+	t.Parallel()
+
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	userDB := New(testDB)
+	ctx := context.Background()
+	now := time.Now()
+	lineUser, telegramUser, telegram := addLinkedUsers(t, userDB, now)
+
+	mustExec(t, testDB, `INSERT INTO groups (line_group_id) VALUES ('G1'), ('G2')`)
+	mustExec(t, testDB, `INSERT INTO group_members (group_id, user_id) SELECT id, $1 FROM groups WHERE line_group_id = 'G1'`, lineUser.ID)
+	mustExec(t, testDB, `INSERT INTO group_members (group_id, user_id) SELECT id, $1 FROM groups`, telegramUser.ID)
+
+	if _, err := userDB.LinkIdentity(ctx, "ABC123", telegram, now); err != nil {
+		t.Fatalf("failed to link identity: %v", err)
+	}
+
+	assert.Equal(t, []string{"G1", "G2"}, mustQueryStrings(t, testDB, `
+		SELECT g.line_group_id FROM group_members m JOIN groups g ON g.id = m.group_id
+		WHERE m.user_id = $1 ORDER BY g.line_group_id
+	`, lineUser.ID))
+}
+
+func TestLinkIdentity_KeepsAccountWithOtherIdentities(t *testing.T) {
+	t.Parallel()
+
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	userDB := New(testDB)
+	ctx := context.Background()
+	now := time.Now()
+	lineUser, telegramUser, telegram := addLinkedUsers(t, userDB, now)
+
+	// The Telegram account is also used from another LINE account.
+	mustExec(t, testDB, `INSERT INTO user_identities (user_id, channel, external_id) VALUES ($1, 'LINE', 'line456')`, telegramUser.ID)
+	mustExec(t, testDB, `
+		INSERT INTO transactions (user_id, amount, currency, type, note, occurred_at)
+		VALUES ($1, 1200, 'JPY', 'EXPENSE', 'lunch', $2)
+	`, telegramUser.ID, now)
+	mustExec(t, testDB, `INSERT INTO categories (user_id, name) VALUES ($1, 'Food')`, telegramUser.ID)
+
+	if _, err := userDB.LinkIdentity(ctx, "ABC123", telegram, now); err != nil {
+		t.Fatalf("failed to link identity: %v", err)
+	}
+
+	got, err := userDB.GetByIdentity(ctx, model.ChannelLine, "line456")
+	if err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+	assert.Equal(t, telegramUser.ID, got.ID, "the account with identities left is kept")
+	assert.Equal(t, []string{"lunch"},
+		mustQueryStrings(t, testDB, `SELECT note FROM transactions WHERE user_id = $1`, telegramUser.ID),
+		"transactions stay with the account that is kept")
+	assert.Equal(t, []string{"Food"}, mustQueryStrings(t, testDB, `SELECT name FROM categories WHERE user_id = $1`, telegramUser.ID))
+	assert.Empty(t, mustQueryStrings(t, testDB, `SELECT name FROM categories WHERE user_id = $1`, lineUser.ID))
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+// Channel is a chat platform the bot is reachable on.
+type Channel string
+
+const (
+	ChannelLine     = "LINE"
+	ChannelTelegram = "TELEGRAM"
+)
+
+// Identity is a user's ID on one channel. A user has at most one identity
+// per channel; linking moves an identity to another user.
+type Identity struct {
+	UserID     int64
+	Channel    Channel
+	ExternalID string
+	CreatedAt  time.Time
+}
+
+func (i *Identity) Validate() error {
+	switch i.Channel {
+	case ChannelLine, ChannelTelegram:
+	default:
+		return errors.New("unknown channel")
+	}
+
+	if i.ExternalID == "" {
+		return errors.New("external id must not be empty")
+	}
+
+	return nil
+}
+
+// LinkCode lets a user attach the identity they use on another channel to
+// their account.
+type LinkCode struct {
+	Code      string
+	UserID    int64
+	ExpiresAt time.Time
+}
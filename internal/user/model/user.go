@@ -1,27 +1,67 @@
 package model
 
 import (
-	"errors"
+	"fmt"
+	"github/shaolim/momon/pkg/money"
 	"time"
 )
 
 type User struct {
-	ID          int64
+	ID int64
+	// LineUserID is empty for users who have not used the bot on LINE.
 	LineUserID  string
 	DisplayName string
 	Status      UserStatus
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	// Timezone is an IANA name such as "Asia/Tokyo". Empty means the server
+	// default.
+	Timezone string
+	// Currency is the home currency entries are recorded in and reports are
+	// converted to. Empty means the server default.
+	Currency  money.Currency
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
 
 func (u *User) Validate() error {
-	if u.LineUserID == "" {
-		return errors.New("line user id must not be empty")
+	if u.Timezone != "" {
+		if _, err := time.LoadLocation(u.Timezone); err != nil {
+			return fmt.Errorf("invalid timezone %q", u.Timezone)
+		}
+	}
+
+	if u.Currency != "" {
+		if c, err := money.ParseCurrency(string(u.Currency)); err != nil || c != u.Currency {
+			return fmt.Errorf("invalid currency %q", u.Currency)
+		}
 	}
 
 	return nil
 }
 
+// Location returns the user's time zone, or fallback when the user has not set
+// one or it can no longer be loaded.
+func (u *User) Location(fallback *time.Location) *time.Location {
+	if u.Timezone == "" {
+		return fallback
+	}
+
+	loc, err := time.LoadLocation(u.Timezone)
+	if err != nil {
+		return fallback
+	}
+
+	return loc
+}
+
+// HomeCurrency returns the user's home currency, or fallback when the user has
+// not set one.
+func (u *User) HomeCurrency(fallback money.Currency) money.Currency {
+	if u.Currency == "" {
+		return fallback
+	}
+	return u.Currency
+}
+
 type UserStatus string
 
 const (
@@ -0,0 +1,28 @@
+// Package usertest provides helpers for tests that need users in a test
+// database.
+package usertest
+
+import (
+	"context"
+	userdb "github/shaolim/momon/internal/user/database"
+	"github/shaolim/momon/internal/user/model"
+	"github/shaolim/momon/pkg/database"
+	"testing"
+)
+
+// MustAddUser adds the user to db, as an active user named "surti" unless it
+// says otherwise, and fails the test when that fails.
+func MustAddUser(t *testing.T, db *database.DB, user *model.User) *model.User {
+	t.Helper()
+
+	if user.DisplayName == "" {
+		user.DisplayName = "surti"
+	}
+	if user.Status == "" {
+		user.Status = model.UserStatusActive
+	}
+	if err := userdb.New(db).AddUser(context.Background(), user); err != nil {
+		t.Fatalf("failed to add user: %v", err)
+	}
+	return user
+}
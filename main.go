@@ -2,13 +2,24 @@ package main
 
 import (
 	"context"
+	"github/shaolim/momon/internal/fx"
+	fxdb "github/shaolim/momon/internal/fx/database"
 	"github/shaolim/momon/internal/messaging"
+	"github/shaolim/momon/internal/receipt"
 	"github/shaolim/momon/internal/serverenv"
+	"github/shaolim/momon/pkg/database"
 	msg "github/shaolim/momon/pkg/messaging"
 	"github/shaolim/momon/pkg/server"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	// Users pick their own time zone, so don't depend on the host's zoneinfo.
+	_ "time/tzdata"
 
 	"github.com/joho/godotenv"
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
 )
 
 func main() {
@@ -17,25 +28,115 @@ func main() {
 		log.Fatal("Error loading .env file")
 	}
 
-	// Start the server
-	port := "8080"
-	log.Printf("Starting HTTP server on port %s...", port)
-	s, err := server.New(port)
+	// Cancelled on SIGINT/SIGTERM, which stops the HTTP server.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// `momon import-rates FILE...` loads exchange rates and exits. It only
+	// needs the database, not the LINE or OpenAI settings.
+	if len(os.Args) > 1 && os.Args[1] == "import-rates" {
+		importRates(ctx, os.Args[2:])
+		return
+	}
+
+	config, err := serverenv.LoadEnv()
 	if err != nil {
-		log.Fatal("failed to initiate the server:", err)
+		log.Fatal(err)
+	}
+
+	db := openDatabase(ctx, config)
+
+	opts := []serverenv.Option{serverenv.WithDatabase(db)}
+
+	var openaiClient *openai.Client
+	if config.OpenAIAPIKey != "" {
+		client := openai.NewClient(option.WithAPIKey(config.OpenAIAPIKey))
+		openaiClient = &client
+		opts = append(opts, serverenv.WithOpenAIClient(openaiClient))
+	}
+
+	extractor, err := receipt.NewExtractor(config.Receipt.ReceiptConfig(), openaiClient)
+	if err != nil {
+		log.Fatal("failed to initiate the receipt extractor: ", err)
 	}
+	opts = append(opts, serverenv.WithReceiptExtractor(extractor))
 
-	config := serverenv.LoadEnv()
-	lineMessagingAPI, err := msg.NewLineMessaging(config.Messaging.MessagingConfig())
+	lineMessaging, err := msg.NewLineMessaging(config.Messaging.MessagingConfig())
 	if err != nil {
 		log.Fatal("failed to initiate line messaging API", err)
 	}
+	opts = append(opts, serverenv.WithMessenger(lineMessaging))
 
-	senv := serverenv.New(serverenv.WithLineMessagingAPI(lineMessagingAPI))
+	if config.Messaging.MessagingConfig().TelegramBotToken != "" {
+		telegramMessaging, err := msg.NewTelegramMessaging(config.Messaging.MessagingConfig())
+		if err != nil {
+			log.Fatal("failed to initiate telegram messaging API: ", err)
+		}
+		opts = append(opts, serverenv.WithTelegramMessenger(telegramMessaging))
+	}
+
+	senv := serverenv.New(opts...)
 
 	m := messaging.New(config, senv)
+	m.Start()
+
+	// Start the server
+	log.Printf("Starting HTTP server on port %s...", config.Host)
+	s, err := server.New(config.Host)
+	if err != nil {
+		log.Fatal("failed to initiate the server:", err)
+	}
 
-	if err := s.ServeHTTPHandler(context.Background(), m.Routes()); err != nil {
-		log.Fatal("Server failed to start:", err)
+	if err := s.ServeHTTPHandler(ctx, m.Routes()); err != nil {
+		log.Print("Server failed:", err)
+	}
+
+	// The server no longer accepts webhooks. Let the ones already acknowledged
+	// finish before closing the database they write to.
+	log.Printf("Shutting down, waiting up to %s for in-flight callbacks...", config.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+
+	if err := m.Drain(shutdownCtx); err != nil {
+		log.Print(err)
+	}
+	if err := senv.Close(shutdownCtx); err != nil {
+		log.Print("failed to close server env: ", err)
+	}
+	log.Print("Shutdown complete")
+}
+
+// openDatabase connects to the database and brings its schema up to date.
+func openDatabase(ctx context.Context, config *serverenv.Config) *database.DB {
+	dbConfig := config.Database.DatabaseConfig()
+	db, err := database.New(ctx, dbConfig)
+	if err != nil {
+		log.Fatal("failed to initiate the database: ", err)
+	}
+
+	log.Printf("Running migrations from %s...", config.MigrationsDir)
+	if err := database.Migrate(dbConfig.ConnectionURL(), config.MigrationsDir); err != nil {
+		log.Fatal("failed to migrate the database: ", err)
+	}
+
+	return db
+}
+
+func importRates(ctx context.Context, files []string) {
+	if len(files) == 0 {
+		log.Fatal("usage: momon import-rates FILE...")
+	}
+
+	config, err := serverenv.LoadDatabaseEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	db := openDatabase(ctx, config)
+	imported, err := fx.ImportFiles(ctx, fxdb.New(db), files...)
+	db.Close()
+	if err != nil {
+		log.Fatal("failed to import rates: ", err)
 	}
+	log.Printf("Imported %d rates", imported)
 }
@@ -0,0 +1,11 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_transactions_user_id_occurred_at;
+
+DROP TABLE IF EXISTS transactions;
+
+DROP TYPE IF EXISTS TransactionSource;
+
+DROP TYPE IF EXISTS TransactionType;
+
+END;
\ No newline at end of file
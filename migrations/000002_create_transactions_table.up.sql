@@ -0,0 +1,24 @@
+BEGIN;
+
+CREATE TYPE TransactionType AS ENUM ('INCOME', 'EXPENSE');
+
+CREATE TYPE TransactionSource AS ENUM ('MANUAL', 'TEXT', 'RECEIPT');
+
+CREATE TABLE IF NOT EXISTS transactions(
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    type TransactionType NOT NULL,
+    category VARCHAR(255) NOT NULL DEFAULT '',
+    merchant VARCHAR(255) NOT NULL DEFAULT '',
+    occurred_at TIMESTAMPTZ NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    source TransactionSource NOT NULL DEFAULT 'MANUAL',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_user_id_occurred_at ON transactions(user_id, occurred_at);
+
+END;
\ No newline at end of file
@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_transaction_items_transaction_id;
+
+DROP TABLE IF EXISTS transaction_items;
+
+END;
\ No newline at end of file
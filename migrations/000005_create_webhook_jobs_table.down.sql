@@ -0,0 +1,11 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_webhook_jobs_status_run_at;
+
+DROP INDEX IF EXISTS idx_webhook_jobs_event_id;
+
+DROP TABLE IF EXISTS webhook_jobs;
+
+DROP TYPE IF EXISTS WebhookJobStatus;
+
+END;
\ No newline at end of file
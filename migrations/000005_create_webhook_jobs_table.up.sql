@@ -0,0 +1,22 @@
+BEGIN;
+
+CREATE TYPE WebhookJobStatus AS ENUM ('PENDING', 'RUNNING', 'DONE', 'DEAD');
+
+CREATE TABLE IF NOT EXISTS webhook_jobs(
+    id BIGSERIAL PRIMARY KEY,
+    event_id VARCHAR(255) NOT NULL,
+    payload JSONB NOT NULL,
+    status WebhookJobStatus NOT NULL DEFAULT 'PENDING',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    run_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    locked_until TIMESTAMPTZ,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_jobs_event_id ON webhook_jobs(event_id);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_jobs_status_run_at ON webhook_jobs(status, run_at);
+
+END;
\ No newline at end of file
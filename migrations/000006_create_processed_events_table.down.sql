@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_processed_events_processed_at;
+
+DROP TABLE IF EXISTS processed_events;
+
+END;
\ No newline at end of file
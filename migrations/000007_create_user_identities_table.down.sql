@@ -0,0 +1,19 @@
+BEGIN;
+
+DROP TABLE IF EXISTS link_codes;
+
+DELETE FROM users WHERE line_user_id = '';
+
+DROP INDEX IF EXISTS idx_users_line_user_id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_line_user_id ON users(line_user_id);
+
+DROP INDEX IF EXISTS idx_user_identities_user_id_channel;
+
+DROP INDEX IF EXISTS idx_user_identities_channel_external_id;
+
+DROP TABLE IF EXISTS user_identities;
+
+DROP TYPE IF EXISTS Channel;
+
+END;
\ No newline at end of file
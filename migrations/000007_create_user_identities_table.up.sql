@@ -0,0 +1,31 @@
+BEGIN;
+
+CREATE TYPE Channel AS ENUM ('LINE', 'TELEGRAM');
+
+CREATE TABLE IF NOT EXISTS user_identities(
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    channel Channel NOT NULL,
+    external_id VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_identities_channel_external_id ON user_identities(channel, external_id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_identities_user_id_channel ON user_identities(user_id, channel);
+
+INSERT INTO user_identities (user_id, channel, external_id, created_at)
+SELECT id, 'LINE', line_user_id, created_at FROM users;
+
+-- Users who only use another channel have no LINE user ID.
+DROP INDEX IF EXISTS idx_users_line_user_id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_line_user_id ON users(line_user_id) WHERE line_user_id <> '';
+
+CREATE TABLE IF NOT EXISTS link_codes(
+    code VARCHAR(16) PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+END;
\ No newline at end of file
@@ -0,0 +1,11 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_transaction_drafts_expires_at;
+
+DROP INDEX IF EXISTS idx_transaction_drafts_user_id_updated_at;
+
+DROP TABLE IF EXISTS transaction_drafts;
+
+DROP TYPE IF EXISTS DraftStep;
+
+END;
\ No newline at end of file
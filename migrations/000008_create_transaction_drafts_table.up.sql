@@ -0,0 +1,25 @@
+BEGIN;
+
+CREATE TYPE DraftStep AS ENUM ('REVIEW', 'TOTAL', 'CATEGORY');
+
+CREATE TABLE IF NOT EXISTS transaction_drafts(
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    category VARCHAR(255) NOT NULL DEFAULT '',
+    merchant VARCHAR(255) NOT NULL DEFAULT '',
+    occurred_at TIMESTAMPTZ NOT NULL,
+    tax BIGINT NOT NULL DEFAULT 0,
+    items JSONB NOT NULL DEFAULT '[]',
+    step DraftStep NOT NULL DEFAULT 'REVIEW',
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_drafts_user_id_updated_at ON transaction_drafts(user_id, updated_at);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_drafts_expires_at ON transaction_drafts(expires_at);
+
+END;
\ No newline at end of file
@@ -0,0 +1,11 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_category_rules_user_id_keyword;
+
+DROP TABLE IF EXISTS category_rules;
+
+DROP INDEX IF EXISTS idx_categories_user_id_name;
+
+DROP TABLE IF EXISTS categories;
+
+END;
\ No newline at end of file
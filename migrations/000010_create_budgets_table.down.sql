@@ -0,0 +1,9 @@
+BEGIN;
+
+DROP TABLE IF EXISTS budget_alerts;
+
+DROP INDEX IF EXISTS idx_budgets_user_id_category;
+
+DROP TABLE IF EXISTS budgets;
+
+END;
\ No newline at end of file
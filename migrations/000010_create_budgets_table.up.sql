@@ -0,0 +1,26 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS budgets(
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- An empty category is a budget for all spending.
+    category VARCHAR(50) NOT NULL DEFAULT '',
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_budgets_user_id_category ON budgets(user_id, LOWER(category));
+
+-- budget_alerts records which thresholds were pushed for a budget in a month,
+-- so every alert is sent once.
+CREATE TABLE IF NOT EXISTS budget_alerts(
+    budget_id BIGINT NOT NULL REFERENCES budgets(id) ON DELETE CASCADE,
+    month DATE NOT NULL,
+    threshold INTEGER NOT NULL,
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (budget_id, month, threshold)
+);
+
+END;
\ No newline at end of file
@@ -0,0 +1,14 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_recurring_transactions_user_id;
+
+DROP INDEX IF EXISTS idx_recurring_transactions_next_run_at;
+
+DROP TABLE IF EXISTS recurring_transactions;
+
+DROP TYPE IF EXISTS RecurrenceFrequency;
+
+-- Postgres can't drop an enum value, so RECURRING stays in TransactionSource.
+UPDATE transactions SET source = 'MANUAL' WHERE source = 'RECURRING';
+
+END;
\ No newline at end of file
@@ -0,0 +1,30 @@
+BEGIN;
+
+ALTER TYPE TransactionSource ADD VALUE IF NOT EXISTS 'RECURRING';
+
+CREATE TYPE RecurrenceFrequency AS ENUM ('WEEKLY', 'MONTHLY', 'YEARLY');
+
+CREATE TABLE IF NOT EXISTS recurring_transactions(
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    type TransactionType NOT NULL,
+    category VARCHAR(255) NOT NULL DEFAULT '',
+    note TEXT NOT NULL DEFAULT '',
+    frequency RecurrenceFrequency NOT NULL,
+    -- The weekday (0 is Sunday) for weekly rules, otherwise the day of the
+    -- month, with -1 for the last day.
+    day INTEGER NOT NULL,
+    -- The month of yearly rules, otherwise 0.
+    month INTEGER NOT NULL DEFAULT 0,
+    next_run_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_recurring_transactions_next_run_at ON recurring_transactions(next_run_at);
+
+CREATE INDEX IF NOT EXISTS idx_recurring_transactions_user_id ON recurring_transactions(user_id);
+
+END;
\ No newline at end of file
@@ -0,0 +1,11 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_digests_next_run_at;
+
+DROP INDEX IF EXISTS idx_digests_user_id_period;
+
+DROP TABLE IF EXISTS digests;
+
+DROP TYPE IF EXISTS DigestPeriod;
+
+END;
\ No newline at end of file
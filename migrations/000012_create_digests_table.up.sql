@@ -0,0 +1,24 @@
+BEGIN;
+
+CREATE TYPE DigestPeriod AS ENUM ('DAILY', 'WEEKLY');
+
+CREATE TABLE IF NOT EXISTS digests(
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    period DigestPeriod NOT NULL,
+    -- The weekday (0 is Sunday) weekly digests are sent on, otherwise 0.
+    weekday INTEGER NOT NULL DEFAULT 0,
+    -- The time of day in the user's time zone.
+    hour INTEGER NOT NULL,
+    minute INTEGER NOT NULL DEFAULT 0,
+    -- Moved on before a digest is pushed, so a restart never sends it twice.
+    next_run_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_digests_user_id_period ON digests(user_id, period);
+
+CREATE INDEX IF NOT EXISTS idx_digests_next_run_at ON digests(next_run_at);
+
+END;
\ No newline at end of file
@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP TABLE IF EXISTS fx_rates;
+
+ALTER TABLE users DROP COLUMN IF EXISTS home_currency;
+
+END;
\ No newline at end of file
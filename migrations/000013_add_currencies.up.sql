@@ -0,0 +1,17 @@
+BEGIN;
+
+-- The currency reports are converted to. Empty means the server default.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS home_currency VARCHAR(3) NOT NULL DEFAULT '';
+
+-- One unit of base was worth rate units of quote on date. Rates are imported,
+-- not fetched, and either direction of a pair is used.
+CREATE TABLE IF NOT EXISTS fx_rates(
+    date DATE NOT NULL,
+    base CHAR(3) NOT NULL,
+    quote CHAR(3) NOT NULL,
+    rate NUMERIC(24, 12) NOT NULL CHECK (rate > 0),
+    imported_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (base, quote, date)
+);
+
+END;
\ No newline at end of file
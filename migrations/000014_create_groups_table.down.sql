@@ -0,0 +1,17 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_transactions_group_id_occurred_at;
+
+ALTER TABLE transaction_drafts DROP COLUMN IF EXISTS group_id;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS group_id;
+
+DROP INDEX IF EXISTS idx_groups_line_group_id;
+
+DROP TABLE IF EXISTS group_members;
+
+DROP TABLE IF EXISTS groups;
+
+DROP TYPE IF EXISTS GroupStatus;
+
+END;
\ No newline at end of file
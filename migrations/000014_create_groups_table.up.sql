@@ -0,0 +1,31 @@
+BEGIN;
+
+CREATE TYPE GroupStatus AS ENUM ('ACTIVE', 'INACTIVE');
+
+-- A LINE group chat the bot is in. Its members share one ledger.
+CREATE TABLE IF NOT EXISTS groups(
+    id BIGSERIAL PRIMARY KEY,
+    line_group_id VARCHAR(255) NOT NULL,
+    status GroupStatus NOT NULL DEFAULT 'ACTIVE',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_groups_line_group_id ON groups(line_group_id);
+
+CREATE TABLE IF NOT EXISTS group_members(
+    group_id BIGINT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    joined_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (group_id, user_id)
+);
+
+-- Entries recorded in a group belong to its ledger, and user_id is the member
+-- who sent them. Personal entries have no group.
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS group_id BIGINT REFERENCES groups(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_group_id_occurred_at ON transactions(group_id, occurred_at) WHERE group_id IS NOT NULL;
+
+ALTER TABLE transaction_drafts ADD COLUMN IF NOT EXISTS group_id BIGINT REFERENCES groups(id) ON DELETE CASCADE;
+
+END;
\ No newline at end of file
@@ -0,0 +1,7 @@
+BEGIN;
+
+ALTER TABLE transaction_drafts DROP COLUMN IF EXISTS time_unknown;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS time_unknown;
+
+END;
\ No newline at end of file
@@ -0,0 +1,9 @@
+BEGIN;
+
+-- Receipts that print a date but no time are recorded at noon. time_unknown
+-- tells them apart from entries that really happened at noon.
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS time_unknown BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE transaction_drafts ADD COLUMN IF NOT EXISTS time_unknown BOOLEAN NOT NULL DEFAULT FALSE;
+
+END;
\ No newline at end of file
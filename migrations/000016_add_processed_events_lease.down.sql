@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE processed_events DROP COLUMN IF EXISTS locked_until;
+
+END;
\ No newline at end of file
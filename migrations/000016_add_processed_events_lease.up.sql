@@ -0,0 +1,8 @@
+BEGIN;
+
+-- locked_until is set while an event is being handled. When the handler dies
+-- before finishing, the event can be claimed again once it has passed; it is
+-- NULL once the event has been processed.
+ALTER TABLE processed_events ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ;
+
+END;
\ No newline at end of file
@@ -10,6 +10,9 @@ type Config struct {
 	Host     string `json:"host"`
 	Port     string `json:"port"`
 	Password string `json:"-"`
+	// SSLMode is passed through as the sslmode connection parameter, e.g.
+	// "disable" for a local database. Empty leaves the driver default.
+	SSLMode string `json:"sslMode"`
 }
 
 func (c *Config) DatabaseConfig() *Config {
@@ -41,5 +44,9 @@ func (c *Config) ConnectionURL() string {
 		u.User = url.UserPassword(c.User, c.Password)
 	}
 
+	if c.SSLMode != "" {
+		u.RawQuery = url.Values{"sslmode": {c.SSLMode}}.Encode()
+	}
+
 	return u.String()
 }
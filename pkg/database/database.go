@@ -2,12 +2,17 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ErrNotFound is returned by repositories when the requested record does not
+// exist.
+var ErrNotFound = errors.New("record not found")
+
 type DB struct {
 	Pool *pgxpool.Pool
 }
@@ -18,6 +23,13 @@ func New(ctx context.Context, cfg *Config) (*DB, error) {
 		return nil, fmt.Errorf("failed to create connection pool: %w", err)
 	}
 
+	// The pool connects lazily; ping so a wrong host or password is reported
+	// at startup rather than on the first query.
+	if err := dbpool.Ping(ctx); err != nil {
+		dbpool.Close()
+		return nil, fmt.Errorf("failed to connect to database %s:%s/%s: %w", cfg.Host, cfg.Port, cfg.Name, err)
+	}
+
 	return &DB{
 		Pool: dbpool,
 	}, nil
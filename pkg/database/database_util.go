@@ -4,7 +4,6 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
-	"errors"
 	"fmt"
 	"net"
 	"net/url"
@@ -14,9 +13,6 @@ import (
 	"sync"
 	"testing"
 
-	"github.com/golang-migrate/migrate/v4"
-	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/ory/dockertest"
@@ -116,23 +112,7 @@ func NewTestInstance() (*TestInstance, error) {
 // dbMigrate runs the migrations. u is the connection URL string (e.g.
 // postgres://...).
 func dbMigrate(u string) error {
-	// Run the migrations
-	migrationsDir := fmt.Sprintf("file://%s", dbMigrationsDir())
-	m, err := migrate.New(migrationsDir, u)
-	if err != nil {
-		return fmt.Errorf("failed create migrate: %w", err)
-	}
-	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
-		return fmt.Errorf("failed run migrate: %w", err)
-	}
-	srcErr, dbErr := m.Close()
-	if srcErr != nil {
-		return fmt.Errorf("migrate source error: %w", srcErr)
-	}
-	if dbErr != nil {
-		return fmt.Errorf("migrate database error: %w", dbErr)
-	}
-	return nil
+	return Migrate(u, dbMigrationsDir())
 }
 
 // dbMigrationsDir returns the path on disk to the migrations. It uses
@@ -0,0 +1,30 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+)
+
+// Migrate applies the pending migrations in dir to the database at
+// connectionURL (e.g. postgres://...).
+func Migrate(connectionURL, dir string) error {
+	m, err := migrate.New(fmt.Sprintf("file://%s", dir), connectionURL)
+	if err != nil {
+		return fmt.Errorf("failed create migrate: %w", err)
+	}
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("failed run migrate: %w", err)
+	}
+	srcErr, dbErr := m.Close()
+	if srcErr != nil {
+		return fmt.Errorf("migrate source error: %w", srcErr)
+	}
+	if dbErr != nil {
+		return fmt.Errorf("migrate database error: %w", dbErr)
+	}
+	return nil
+}
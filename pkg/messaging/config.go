@@ -3,6 +3,10 @@ package messaging
 type Config struct {
 	LineChannelSecret string
 	LineChannelToken  string
+	// TelegramBotToken enables the Telegram bot. TelegramWebhookSecret is
+	// the secret_token set with setWebhook, sent back on every update.
+	TelegramBotToken      string
+	TelegramWebhookSecret string
 }
 
 func (c *Config) MessagingConfig() *Config {
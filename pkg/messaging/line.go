@@ -1,22 +1,208 @@
 package messaging
 
 import (
+	"context"
 	"fmt"
+	"io"
 
 	messagingapi "github.com/line/line-bot-sdk-go/v8/linebot/messaging_api"
 )
 
+// LineMessaging is the Messenger for the LINE Messaging API.
 type LineMessaging struct {
-	*messagingapi.MessagingApiAPI
+	api  *messagingapi.MessagingApiAPI
+	blob *messagingapi.MessagingApiBlobAPI
 }
 
-func NewLineMessaging(config *Config) (*LineMessaging, error) {
-	api, err := messagingapi.NewMessagingApiAPI(config.LineChannelToken)
+var _ Messenger = (*LineMessaging)(nil)
+
+type lineOptions struct {
+	baseURL string
+}
+
+type LineOption func(*lineOptions) *lineOptions
+
+// WithBaseURL sends every API and content request to baseURL instead of the
+// LINE platform, e.g. to a linetest.Server.
+func WithBaseURL(baseURL string) LineOption {
+	return func(o *lineOptions) *lineOptions {
+		o.baseURL = baseURL
+		return o
+	}
+}
+
+func NewLineMessaging(config *Config, opts ...LineOption) (*LineMessaging, error) {
+	o := &lineOptions{}
+	for _, f := range opts {
+		o = f(o)
+	}
+
+	var (
+		apiOpts  []messagingapi.MessagingApiAPIOption
+		blobOpts []messagingapi.MessagingApiBlobAPIOption
+	)
+	if o.baseURL != "" {
+		apiOpts = append(apiOpts, messagingapi.WithEndpoint(o.baseURL))
+		blobOpts = append(blobOpts, messagingapi.WithBlobEndpoint(o.baseURL))
+	}
+
+	api, err := messagingapi.NewMessagingApiAPI(config.LineChannelToken, apiOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initiate line messaging API: %w", err)
 	}
 
+	blob, err := messagingapi.NewMessagingApiBlobAPI(config.LineChannelToken, blobOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initiate line messaging blob API: %w", err)
+	}
+
 	return &LineMessaging{
-		api,
+		api:  api,
+		blob: blob,
+	}, nil
+}
+
+// apiWithContext returns a copy of the client bound to ctx. The SDK's
+// WithContext sets the context on the client itself, which would race between
+// events handled at the same time.
+func (l *LineMessaging) apiWithContext(ctx context.Context) *messagingapi.MessagingApiAPI {
+	api := *l.api
+	return api.WithContext(ctx)
+}
+
+func (l *LineMessaging) blobWithContext(ctx context.Context) *messagingapi.MessagingApiBlobAPI {
+	blob := *l.blob
+	return blob.WithContext(ctx)
+}
+
+// maxAltText is the longest alternative text LINE accepts for a Flex
+// Message.
+const maxAltText = 400
+
+func lineMessages(messages []Message) []messagingapi.MessageInterface {
+	result := make([]messagingapi.MessageInterface, 0, len(messages))
+	for _, m := range messages {
+		if m.Flex != nil {
+			result = append(result, &messagingapi.FlexMessage{
+				AltText:    altText(m.Text),
+				Contents:   m.Flex,
+				QuickReply: lineQuickReply(m.QuickReplies),
+			})
+			continue
+		}
+		result = append(result, &messagingapi.TextMessage{
+			Text:       m.Text,
+			QuickReply: lineQuickReply(m.QuickReplies),
+		})
+	}
+	return result
+}
+
+// lineQuickReply turns the buttons into postback actions. LINE only shows
+// the quick replies of the last message in a reply.
+func lineQuickReply(quickReplies []QuickReply) *messagingapi.QuickReply {
+	if len(quickReplies) == 0 {
+		return nil
+	}
+
+	items := make([]messagingapi.QuickReplyItem, 0, len(quickReplies))
+	for _, q := range quickReplies {
+		items = append(items, messagingapi.QuickReplyItem{
+			Type: "action",
+			Action: &messagingapi.PostbackAction{
+				Label:       q.Label,
+				Data:        q.Data,
+				DisplayText: q.Label,
+			},
+		})
+	}
+	return &messagingapi.QuickReply{Items: items}
+}
+
+func altText(text string) string {
+	runes := []rune(text)
+	if len(runes) <= maxAltText {
+		return text
+	}
+	return string(runes[:maxAltText-1]) + "…"
+}
+
+func (l *LineMessaging) Reply(ctx context.Context, replyToken string, messages ...Message) error {
+	if _, err := l.apiWithContext(ctx).ReplyMessage(&messagingapi.ReplyMessageRequest{
+		ReplyToken: replyToken,
+		Messages:   lineMessages(messages),
+	}); err != nil {
+		return fmt.Errorf("failed to reply message: %w", err)
+	}
+
+	return nil
+}
+
+// Push sends messages to a user, group or room ID. LINE requires retryKey to
+// be a UUID when set.
+func (l *LineMessaging) Push(ctx context.Context, to, retryKey string, messages ...Message) error {
+	if _, err := l.apiWithContext(ctx).PushMessage(&messagingapi.PushMessageRequest{
+		To:       to,
+		Messages: lineMessages(messages),
+	}, retryKey); err != nil {
+		return fmt.Errorf("failed to push message: %w", err)
+	}
+
+	return nil
+}
+
+func (l *LineMessaging) GetProfile(ctx context.Context, userID string) (*Profile, error) {
+	profile, err := l.apiWithContext(ctx).GetProfile(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get profile: %w", err)
+	}
+
+	return &Profile{
+		UserID:      profile.UserId,
+		DisplayName: profile.DisplayName,
+		Language:    profile.Language,
 	}, nil
 }
+
+func (l *LineMessaging) GetGroupMemberProfile(ctx context.Context, groupID, userID string) (*Profile, error) {
+	profile, err := l.apiWithContext(ctx).GetGroupMemberProfile(groupID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group member profile: %w", err)
+	}
+
+	return &Profile{
+		UserID:      profile.UserId,
+		DisplayName: profile.DisplayName,
+	}, nil
+}
+
+// GetContent downloads the image, video or audio sent by a user and returns
+// its bytes together with the content type reported by LINE.
+func (l *LineMessaging) GetContent(ctx context.Context, messageID string) ([]byte, string, error) {
+	resp, err := l.blobWithContext(ctx).GetMessageContent(messageID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get message content: %w", err)
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read message content: %w", err)
+	}
+
+	return content, resp.Header.Get("Content-Type"), nil
+}
+
+// ShowLoading shows the loading animation in a one-on-one chat. LINE accepts
+// 5 to 60 seconds in steps of 5, so seconds is rounded up to fit.
+func (l *LineMessaging) ShowLoading(ctx context.Context, chatID string, seconds int) error {
+	seconds = min(max((seconds+4)/5*5, 5), 60)
+	if _, err := l.apiWithContext(ctx).ShowLoadingAnimation(&messagingapi.ShowLoadingAnimationRequest{
+		ChatId:         chatID,
+		LoadingSeconds: int32(seconds),
+	}); err != nil {
+		return fmt.Errorf("failed to show loading animation: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package messaging
+
+import (
+	"context"
+	"github/shaolim/momon/pkg/messaging/linetest"
+	"strings"
+	"testing"
+
+	messagingapi "github.com/line/line-bot-sdk-go/v8/linebot/messaging_api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineMessaging(t *testing.T) {
+	ctx := context.Background()
+	line := linetest.NewServer("secret")
+	defer line.Close()
+
+	api, err := NewLineMessaging(&Config{LineChannelToken: "token"}, WithBaseURL(line.URL))
+	if err != nil {
+		t.Fatalf("failed to create LINE client: %v", err)
+	}
+
+	t.Run("reply", func(t *testing.T) {
+		assert.NoError(t, api.Reply(ctx, "token-1", TextMessage("hello")))
+
+		// Reply tokens are single use.
+		assert.Error(t, api.Reply(ctx, "token-1", TextMessage("again")))
+
+		replies := line.Replies()
+		if assert.Len(t, replies, 1) {
+			assert.Equal(t, "token-1", replies[0].ReplyToken)
+			assert.Equal(t, "text", replies[0].Messages[0].Type)
+			assert.Equal(t, "hello", replies[0].Messages[0].Text)
+		}
+	})
+
+	t.Run("flex", func(t *testing.T) {
+		card := &messagingapi.FlexBubble{
+			Body: &messagingapi.FlexBox{
+				Layout:   messagingapi.FlexBoxLAYOUT_VERTICAL,
+				Contents: []messagingapi.FlexComponentInterface{&messagingapi.FlexText{Text: "Lawson"}},
+			},
+		}
+		assert.NoError(t, api.Reply(ctx, "token-2", Message{Text: strings.Repeat("a", 500), Flex: card}))
+
+		replies := line.Replies()
+		if assert.Len(t, replies, 2) {
+			message := replies[1].Messages[0]
+			assert.Equal(t, "flex", message.Type)
+			// LINE rejects alternative texts over 400 characters.
+			assert.Equal(t, strings.Repeat("a", 399)+"…", message.AltText)
+			assert.Contains(t, string(message.Raw), `"text":"Lawson"`)
+		}
+	})
+
+	t.Run("quick replies", func(t *testing.T) {
+		message := Message{Text: "Save it?", QuickReplies: []QuickReply{
+			{Label: "Save", Data: "action=save&draft=1"},
+			{Label: "Discard", Data: "action=discard&draft=1"},
+		}}
+		assert.NoError(t, api.Reply(ctx, "token-3", message))
+
+		replies := line.Replies()
+		if assert.Len(t, replies, 3) {
+			assert.Equal(t, []linetest.QuickReply{
+				{Label: "Save", Data: "action=save&draft=1"},
+				{Label: "Discard", Data: "action=discard&draft=1"},
+			}, replies[2].Messages[0].QuickReplies)
+		}
+	})
+
+	t.Run("push", func(t *testing.T) {
+		retryKey := "3f8e4a3c-0b8f-4a5e-9b2c-6d1f2e3a4b5c"
+		assert.NoError(t, api.Push(ctx, "U1", retryKey, TextMessage("budget exceeded")))
+		assert.Error(t, api.Push(ctx, "U1", retryKey, TextMessage("budget exceeded")))
+
+		pushes := line.Pushes()
+		if assert.Len(t, pushes, 1) {
+			assert.Equal(t, "U1", pushes[0].To)
+			assert.Equal(t, "budget exceeded", pushes[0].Messages[0].Text)
+		}
+	})
+
+	t.Run("profile", func(t *testing.T) {
+		line.SetProfile("U1", "Alice")
+
+		profile, err := api.GetProfile(ctx, "U1")
+		if err != nil {
+			t.Fatalf("failed to get profile: %v", err)
+		}
+		assert.Equal(t, "Alice", profile.DisplayName)
+
+		_, err = api.GetProfile(ctx, "U2")
+		assert.Error(t, err)
+
+		profile, err = api.GetGroupMemberProfile(ctx, "C1", "U1")
+		if err != nil {
+			t.Fatalf("failed to get group member profile: %v", err)
+		}
+		assert.Equal(t, "Alice", profile.DisplayName)
+	})
+
+	t.Run("content", func(t *testing.T) {
+		line.SetContent("m1", []byte("jpeg bytes"), "image/jpeg")
+
+		content, contentType, err := api.GetContent(ctx, "m1")
+		if err != nil {
+			t.Fatalf("failed to get content: %v", err)
+		}
+		assert.Equal(t, []byte("jpeg bytes"), content)
+		assert.Equal(t, "image/jpeg", contentType)
+	})
+
+	t.Run("loading animation", func(t *testing.T) {
+		assert.NoError(t, api.ShowLoading(ctx, "U1", 60))
+		assert.Equal(t, []string{"U1"}, line.Loading())
+	})
+}
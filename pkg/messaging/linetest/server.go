@@ -0,0 +1,406 @@
+// Package linetest provides an in-process stand-in for the LINE platform, so
+// the webhook handlers can be tested end to end without a LINE channel.
+package linetest
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+)
+
+// Message is a message the bot sent. Text is only set for text messages and
+// AltText for Flex Messages; Raw holds the message as the bot sent it.
+type Message struct {
+	Type         string
+	Text         string
+	AltText      string
+	QuickReplies []QuickReply
+	Raw          json.RawMessage
+}
+
+// QuickReply is a postback quick reply button. Data is sent back in a
+// postback event when it is tapped, see PostbackEvent.
+type QuickReply struct {
+	Label string
+	Data  string
+}
+
+type Reply struct {
+	ReplyToken string
+	Messages   []Message
+}
+
+type Push struct {
+	To       string
+	RetryKey string
+	Messages []Message
+}
+
+type content struct {
+	data        []byte
+	contentType string
+}
+
+// Server serves the parts of the Messaging API the bot uses and records what
+// the bot sent. Point LineMessaging at it with messaging.WithBaseURL(s.URL).
+type Server struct {
+	*httptest.Server
+
+	channelSecret string
+
+	mu       sync.Mutex
+	nextID   int
+	profiles map[string]string
+	contents map[string]content
+	replies  []Reply
+	pushes   []Push
+	loading  []string
+}
+
+// NewServer starts a server that signs webhooks with channelSecret. Close it
+// when done.
+func NewServer(channelSecret string) *Server {
+	s := &Server{
+		channelSecret: channelSecret,
+		profiles:      map[string]string{},
+		contents:      map[string]content{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v2/bot/message/reply", s.handleReply)
+	mux.HandleFunc("POST /v2/bot/message/push", s.handlePush)
+	mux.HandleFunc("POST /v2/bot/chat/loading/start", s.handleLoading)
+	mux.HandleFunc("GET /v2/bot/profile/{userId}", s.handleProfile)
+	mux.HandleFunc("GET /v2/bot/group/{groupId}/member/{userId}", s.handleProfile)
+	mux.HandleFunc("GET /v2/bot/message/{messageId}/content", s.handleContent)
+	s.Server = httptest.NewServer(mux)
+
+	return s
+}
+
+// SetProfile registers a LINE user so their profile can be fetched, also as a
+// member of any group.
+func (s *Server) SetProfile(userID, displayName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.profiles[userID] = displayName
+}
+
+// SetContent stores the bytes returned for an image or other media message.
+func (s *Server) SetContent(messageID string, data []byte, contentType string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.contents[messageID] = content{data: data, contentType: contentType}
+}
+
+func (s *Server) Replies() []Reply {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Reply(nil), s.replies...)
+}
+
+func (s *Server) Pushes() []Push {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Push(nil), s.pushes...)
+}
+
+// Loading returns the chats a loading animation was shown in.
+func (s *Server) Loading() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.loading...)
+}
+
+// WaitForReplies waits until the bot replied at least n times, since webhook
+// events are processed after the webhook is acknowledged.
+func (s *Server) WaitForReplies(n int, timeout time.Duration) ([]Reply, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		replies := s.Replies()
+		if len(replies) >= n {
+			return replies, nil
+		}
+		if time.Now().After(deadline) {
+			return replies, fmt.Errorf("got %d replies after %s, want %d", len(replies), timeout, n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Event is a webhook event as LINE sends it.
+type Event map[string]any
+
+// Redelivered marks the event as a redelivery of an event LINE sent before.
+func (e Event) Redelivered() Event {
+	e["deliveryContext"] = map[string]any{"isRedelivery": true}
+	return e
+}
+
+// ReplyToken returns the token the bot must reply with, if the event has one.
+func (e Event) ReplyToken() string {
+	token, _ := e["replyToken"].(string)
+	return token
+}
+
+func (s *Server) newEvent(eventType, userID string) Event {
+	s.mu.Lock()
+	s.nextID++
+	id := s.nextID
+	s.mu.Unlock()
+
+	return Event{
+		"type":            eventType,
+		"mode":            "active",
+		"timestamp":       time.Now().UnixMilli(),
+		"webhookEventId":  fmt.Sprintf("01TESTEVENT%06d", id),
+		"deliveryContext": map[string]any{"isRedelivery": false},
+		"replyToken":      fmt.Sprintf("reply-token-%d", id),
+		"source":          map[string]any{"type": "user", "userId": userID},
+	}
+}
+
+func (s *Server) FollowEvent(userID string) Event {
+	e := s.newEvent("follow", userID)
+	e["follow"] = map[string]any{"isUnblocked": false}
+	return e
+}
+
+func (s *Server) UnfollowEvent(userID string) Event {
+	e := s.newEvent("unfollow", userID)
+	delete(e, "replyToken")
+	return e
+}
+
+func (s *Server) TextMessageEvent(userID, text string) Event {
+	e := s.newEvent("message", userID)
+	e["message"] = map[string]any{
+		"type":       "text",
+		"id":         e["webhookEventId"],
+		"quoteToken": "quote-token",
+		"text":       text,
+	}
+	return e
+}
+
+// ImageMessageEvent sends an image whose bytes are served for messageID, see
+// SetContent.
+func (s *Server) ImageMessageEvent(userID, messageID string) Event {
+	e := s.newEvent("message", userID)
+	e["message"] = map[string]any{
+		"type":            "image",
+		"id":              messageID,
+		"quoteToken":      "quote-token",
+		"contentProvider": map[string]any{"type": "line"},
+	}
+	return e
+}
+
+// PostbackEvent is the user tapping a postback button carrying data.
+func (s *Server) PostbackEvent(userID, data string) Event {
+	e := s.newEvent("postback", userID)
+	e["postback"] = map[string]any{"data": data}
+	return e
+}
+
+// SendWebhook posts the events to the bot's webhook URL, signed with the
+// channel secret like LINE does.
+func (s *Server) SendWebhook(url string, events ...Event) (*http.Response, error) {
+	if events == nil {
+		events = []Event{}
+	}
+	body, err := json.Marshal(map[string]any{
+		"destination": "Utestbot",
+		"events":      events,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal webhook: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Line-Signature", s.Sign(body))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send webhook: %w", err)
+	}
+	resp.Body.Close()
+
+	return resp, nil
+}
+
+// Sign returns the X-Line-Signature of body.
+func (s *Server) Sign(body []byte) string {
+	mac := hmac.New(sha256.New, []byte(s.channelSecret))
+	mac.Write(body)
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func decodeMessages(raw []json.RawMessage) ([]Message, error) {
+	messages := make([]Message, 0, len(raw))
+	for _, r := range raw {
+		var m struct {
+			Type       string `json:"type"`
+			Text       string `json:"text"`
+			AltText    string `json:"altText"`
+			QuickReply *struct {
+				Items []struct {
+					Action struct {
+						Label string `json:"label"`
+						Data  string `json:"data"`
+					} `json:"action"`
+				} `json:"items"`
+			} `json:"quickReply"`
+		}
+		if err := json.Unmarshal(r, &m); err != nil {
+			return nil, err
+		}
+
+		message := Message{Type: m.Type, Text: m.Text, AltText: m.AltText, Raw: r}
+		if m.QuickReply != nil {
+			for _, item := range m.QuickReply.Items {
+				message.QuickReplies = append(message.QuickReplies, QuickReply{Label: item.Action.Label, Data: item.Action.Data})
+			}
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+func (s *Server) handleReply(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ReplyToken string            `json:"replyToken"`
+		Messages   []json.RawMessage `json:"messages"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "The request body has 1 error(s)")
+		return
+	}
+	messages, err := decodeMessages(req.Messages)
+	if err != nil || req.ReplyToken == "" || len(messages) == 0 {
+		writeError(w, http.StatusBadRequest, "The request body has 1 error(s)")
+		return
+	}
+
+	s.mu.Lock()
+	for _, reply := range s.replies {
+		if reply.ReplyToken == req.ReplyToken {
+			s.mu.Unlock()
+			writeError(w, http.StatusBadRequest, "Invalid reply token")
+			return
+		}
+	}
+	s.replies = append(s.replies, Reply{ReplyToken: req.ReplyToken, Messages: messages})
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]any{"sentMessages": []any{}})
+}
+
+func (s *Server) handlePush(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		To       string            `json:"to"`
+		Messages []json.RawMessage `json:"messages"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "The request body has 1 error(s)")
+		return
+	}
+	messages, err := decodeMessages(req.Messages)
+	if err != nil || req.To == "" || len(messages) == 0 {
+		writeError(w, http.StatusBadRequest, "The request body has 1 error(s)")
+		return
+	}
+
+	retryKey := r.Header.Get("X-Line-Retry-Key")
+
+	s.mu.Lock()
+	if retryKey != "" {
+		for _, push := range s.pushes {
+			if push.RetryKey == retryKey {
+				s.mu.Unlock()
+				writeError(w, http.StatusConflict, "The retry key is already accepted")
+				return
+			}
+		}
+	}
+	s.pushes = append(s.pushes, Push{To: req.To, RetryKey: retryKey, Messages: messages})
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]any{"sentMessages": []any{}})
+}
+
+func (s *Server) handleLoading(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ChatID string `json:"chatId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ChatID == "" {
+		writeError(w, http.StatusBadRequest, "The request body has 1 error(s)")
+		return
+	}
+
+	s.mu.Lock()
+	s.loading = append(s.loading, req.ChatID)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusAccepted, map[string]any{})
+}
+
+func (s *Server) handleProfile(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("userId")
+
+	s.mu.Lock()
+	displayName, ok := s.profiles[userID]
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"userId":      userID,
+		"displayName": displayName,
+		"language":    "en",
+	})
+}
+
+func (s *Server) handleContent(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	c, ok := s.contents[r.PathValue("messageId")]
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", c.contentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(c.data)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]any{"message": message})
+}
@@ -0,0 +1,189 @@
+// Package messagingtest provides a Messenger fake that records what the bot
+// sends.
+package messagingtest
+
+import (
+	"context"
+	"errors"
+	"github/shaolim/momon/pkg/messaging"
+	"sync"
+)
+
+// ErrNotFound is returned for unknown profiles and message contents.
+var ErrNotFound = errors.New("not found")
+
+type Reply struct {
+	ReplyToken string
+	Messages   []messaging.Message
+}
+
+type Push struct {
+	To       string
+	RetryKey string
+	Messages []messaging.Message
+}
+
+type content struct {
+	data        []byte
+	contentType string
+}
+
+// Messenger is an in-memory messaging.Messenger. It is safe for concurrent
+// use.
+type Messenger struct {
+	mu       sync.Mutex
+	profiles map[string]*messaging.Profile
+	contents map[string]content
+	replies  []Reply
+	pushes   []Push
+	loading  []string
+	err      error
+	replyErr error
+}
+
+var _ messaging.Messenger = (*Messenger)(nil)
+
+func NewMessenger() *Messenger {
+	return &Messenger{
+		profiles: map[string]*messaging.Profile{},
+		contents: map[string]content{},
+	}
+}
+
+// SetProfile registers a user so their profile can be fetched.
+func (f *Messenger) SetProfile(userID, displayName string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.profiles[userID] = &messaging.Profile{UserID: userID, DisplayName: displayName}
+}
+
+// SetContent stores the bytes returned for a media message.
+func (f *Messenger) SetContent(messageID string, data []byte, contentType string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.contents[messageID] = content{data: data, contentType: contentType}
+}
+
+// FailWith makes every following call fail with err, or succeed again when
+// err is nil.
+func (f *Messenger) FailWith(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.err = err
+}
+
+// FailRepliesWith makes every following reply fail with err, e.g. for an
+// expired reply token, while other calls still succeed.
+func (f *Messenger) FailRepliesWith(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.replyErr = err
+}
+
+func (f *Messenger) Reply(_ context.Context, replyToken string, messages ...messaging.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return f.err
+	}
+	if f.replyErr != nil {
+		return f.replyErr
+	}
+	f.replies = append(f.replies, Reply{ReplyToken: replyToken, Messages: messages})
+	return nil
+}
+
+func (f *Messenger) Push(_ context.Context, to, retryKey string, messages ...messaging.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return f.err
+	}
+	f.pushes = append(f.pushes, Push{To: to, RetryKey: retryKey, Messages: messages})
+	return nil
+}
+
+func (f *Messenger) GetProfile(_ context.Context, userID string) (*messaging.Profile, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return nil, f.err
+	}
+	profile, ok := f.profiles[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *profile
+	return &copied, nil
+}
+
+// GetGroupMemberProfile returns the profile set with SetProfile, whatever the
+// group.
+func (f *Messenger) GetGroupMemberProfile(ctx context.Context, _, userID string) (*messaging.Profile, error) {
+	return f.GetProfile(ctx, userID)
+}
+
+func (f *Messenger) GetContent(_ context.Context, messageID string) ([]byte, string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return nil, "", f.err
+	}
+	c, ok := f.contents[messageID]
+	if !ok {
+		return nil, "", ErrNotFound
+	}
+	return c.data, c.contentType, nil
+}
+
+func (f *Messenger) ShowLoading(_ context.Context, chatID string, _ int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return f.err
+	}
+	f.loading = append(f.loading, chatID)
+	return nil
+}
+
+func (f *Messenger) Replies() []Reply {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]Reply(nil), f.replies...)
+}
+
+func (f *Messenger) Pushes() []Push {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]Push(nil), f.pushes...)
+}
+
+// Loading returns the chats a loading animation was shown in.
+func (f *Messenger) Loading() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]string(nil), f.loading...)
+}
+
+// ReplyTexts returns the text of every message replied, in order.
+func (f *Messenger) ReplyTexts() []string {
+	var texts []string
+	for _, r := range f.Replies() {
+		for _, m := range r.Messages {
+			texts = append(texts, m.Text)
+		}
+	}
+	return texts
+}
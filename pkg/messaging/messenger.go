@@ -0,0 +1,56 @@
+package messaging
+
+import (
+	"context"
+
+	messagingapi "github.com/line/line-bot-sdk-go/v8/linebot/messaging_api"
+)
+
+// Messenger is what the bot needs from a chat platform. Handlers depend on it
+// rather than on a platform's SDK so they can be tested with a fake.
+type Messenger interface {
+	// Reply answers the event the reply token came with. Reply tokens are
+	// single use.
+	Reply(ctx context.Context, replyToken string, messages ...Message) error
+	// Push sends messages to a user or chat outside of a reply. Pushes with
+	// the same non-empty retryKey are only delivered once.
+	Push(ctx context.Context, to, retryKey string, messages ...Message) error
+	GetProfile(ctx context.Context, userID string) (*Profile, error)
+	// GetGroupMemberProfile returns the profile of a member of a group chat,
+	// who may not have added the bot as a friend.
+	GetGroupMemberProfile(ctx context.Context, groupID, userID string) (*Profile, error)
+	// GetContent downloads the image, video or audio sent in a message and
+	// returns it with its content type.
+	GetContent(ctx context.Context, messageID string) ([]byte, string, error)
+	// ShowLoading shows a loading animation in a chat for up to seconds, or
+	// until the next message is sent.
+	ShowLoading(ctx context.Context, chatID string, seconds int) error
+}
+
+// Message is a message sent by the bot.
+type Message struct {
+	Text string
+	// Flex is a LINE Flex Message shown instead of Text on LINE, which
+	// still uses Text for notifications. Other platforms only send Text.
+	Flex messagingapi.FlexContainerInterface
+	// QuickReplies are buttons offered with the message. Tapping one sends
+	// its Data back to the bot as a postback.
+	QuickReplies []QuickReply
+}
+
+// QuickReply is a button that answers a message.
+type QuickReply struct {
+	Label string
+	Data  string
+}
+
+// TextMessage returns a plain text message.
+func TextMessage(text string) Message {
+	return Message{Text: text}
+}
+
+type Profile struct {
+	UserID      string
+	DisplayName string
+	Language    string
+}
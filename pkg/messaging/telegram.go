@@ -0,0 +1,311 @@
+package messaging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const telegramBaseURL = "https://api.telegram.org"
+
+// TelegramUpdate is an incoming update from the Telegram Bot API. Only the
+// fields the bot uses are decoded.
+type TelegramUpdate struct {
+	UpdateID      int64                  `json:"update_id"`
+	Message       *TelegramMessage       `json:"message,omitempty"`
+	CallbackQuery *TelegramCallbackQuery `json:"callback_query,omitempty"`
+}
+
+// TelegramCallbackQuery is a tap on a button under one of the bot's messages.
+// Data is the QuickReply's Data.
+type TelegramCallbackQuery struct {
+	ID      string           `json:"id"`
+	From    *TelegramUser    `json:"from"`
+	Message *TelegramMessage `json:"message,omitempty"`
+	Data    string           `json:"data,omitempty"`
+}
+
+type TelegramMessage struct {
+	MessageID int64               `json:"message_id"`
+	From      *TelegramUser       `json:"from,omitempty"`
+	Chat      TelegramChat        `json:"chat"`
+	Date      int64               `json:"date"`
+	Text      string              `json:"text,omitempty"`
+	Caption   string              `json:"caption,omitempty"`
+	Photo     []TelegramPhotoSize `json:"photo,omitempty"`
+}
+
+type TelegramUser struct {
+	ID           int64  `json:"id"`
+	IsBot        bool   `json:"is_bot"`
+	FirstName    string `json:"first_name"`
+	LastName     string `json:"last_name,omitempty"`
+	Username     string `json:"username,omitempty"`
+	LanguageCode string `json:"language_code,omitempty"`
+}
+
+// DisplayName is the user's full name as shown in Telegram.
+func (u *TelegramUser) DisplayName() string {
+	return strings.TrimSpace(u.FirstName + " " + u.LastName)
+}
+
+type TelegramChat struct {
+	ID        int64  `json:"id"`
+	Type      string `json:"type"`
+	FirstName string `json:"first_name,omitempty"`
+	LastName  string `json:"last_name,omitempty"`
+}
+
+// TelegramPhotoSize is one resolution of a photo. Telegram sends every
+// resolution, smallest first.
+type TelegramPhotoSize struct {
+	FileID   string `json:"file_id"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	FileSize int    `json:"file_size,omitempty"`
+}
+
+// TelegramReplyToken is the reply token of a Telegram message. Telegram has no
+// reply tokens; replies are sent to the chat quoting the message instead.
+func TelegramReplyToken(chatID, messageID int64) string {
+	return fmt.Sprintf("%d:%d", chatID, messageID)
+}
+
+// TelegramMessaging is the Messenger for the Telegram Bot API.
+type TelegramMessaging struct {
+	token   string
+	baseURL string
+	client  *http.Client
+}
+
+var _ Messenger = (*TelegramMessaging)(nil)
+
+type telegramOptions struct {
+	baseURL string
+}
+
+type TelegramOption func(*telegramOptions) *telegramOptions
+
+// WithTelegramBaseURL sends every request to baseURL instead of the Telegram
+// Bot API, e.g. to a telegramtest.Server.
+func WithTelegramBaseURL(baseURL string) TelegramOption {
+	return func(o *telegramOptions) *telegramOptions {
+		o.baseURL = baseURL
+		return o
+	}
+}
+
+func NewTelegramMessaging(config *Config, opts ...TelegramOption) (*TelegramMessaging, error) {
+	if config.TelegramBotToken == "" {
+		return nil, errors.New("telegram bot token must not be empty")
+	}
+
+	o := &telegramOptions{baseURL: telegramBaseURL}
+	for _, f := range opts {
+		o = f(o)
+	}
+
+	return &TelegramMessaging{
+		token:   config.TelegramBotToken,
+		baseURL: strings.TrimRight(o.baseURL, "/"),
+		client:  http.DefaultClient,
+	}, nil
+}
+
+// call invokes a Bot API method and decodes its result into result, which
+// may be nil.
+func (t *TelegramMessaging) call(ctx context.Context, method string, params any, result any) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s request: %w", method, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/bot%s/%s", t.baseURL, t.token, method), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", method, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	var apiResp struct {
+		OK          bool            `json:"ok"`
+		Description string          `json:"description"`
+		Result      json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", method, err)
+	}
+	if !apiResp.OK {
+		return fmt.Errorf("%s failed with status %d: %s", method, resp.StatusCode, apiResp.Description)
+	}
+
+	if result != nil {
+		if err := json.Unmarshal(apiResp.Result, result); err != nil {
+			return fmt.Errorf("failed to decode %s result: %w", method, err)
+		}
+	}
+
+	return nil
+}
+
+func (t *TelegramMessaging) send(ctx context.Context, chatID string, replyTo int64, messages []Message) error {
+	for _, m := range messages {
+		params := map[string]any{
+			"chat_id": chatID,
+			"text":    m.Text,
+		}
+		if replyTo != 0 {
+			params["reply_parameters"] = map[string]any{
+				"message_id":                  replyTo,
+				"allow_sending_without_reply": true,
+			}
+		}
+		if len(m.QuickReplies) > 0 {
+			params["reply_markup"] = map[string]any{"inline_keyboard": inlineKeyboard(m.QuickReplies)}
+		}
+
+		if err := t.call(ctx, "sendMessage", params, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// inlineKeyboard lays out the buttons two to a row.
+func inlineKeyboard(quickReplies []QuickReply) [][]map[string]string {
+	var rows [][]map[string]string
+	for i, q := range quickReplies {
+		if i%2 == 0 {
+			rows = append(rows, nil)
+		}
+		rows[len(rows)-1] = append(rows[len(rows)-1], map[string]string{"text": q.Label, "callback_data": q.Data})
+	}
+	return rows
+}
+
+// AnswerCallback stops the loading indicator on the button the user tapped.
+func (t *TelegramMessaging) AnswerCallback(ctx context.Context, callbackQueryID string) error {
+	if err := t.call(ctx, "answerCallbackQuery", map[string]any{"callback_query_id": callbackQueryID}, nil); err != nil {
+		return fmt.Errorf("failed to answer callback query: %w", err)
+	}
+
+	return nil
+}
+
+// Reply sends messages to the chat of the message replyToken was made from,
+// see TelegramReplyToken.
+func (t *TelegramMessaging) Reply(ctx context.Context, replyToken string, messages ...Message) error {
+	chatID, messageID, ok := strings.Cut(replyToken, ":")
+	if !ok {
+		return fmt.Errorf("invalid telegram reply token %q", replyToken)
+	}
+	replyTo, err := strconv.ParseInt(messageID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid telegram reply token %q", replyToken)
+	}
+
+	if err := t.send(ctx, chatID, replyTo, messages); err != nil {
+		return fmt.Errorf("failed to reply message: %w", err)
+	}
+
+	return nil
+}
+
+// Push sends messages to a chat. Telegram has no idempotent sends, so
+// retryKey is ignored.
+func (t *TelegramMessaging) Push(ctx context.Context, to, _ string, messages ...Message) error {
+	if err := t.send(ctx, to, 0, messages); err != nil {
+		return fmt.Errorf("failed to push message: %w", err)
+	}
+
+	return nil
+}
+
+// GetProfile returns the name of a user who started a chat with the bot.
+// Telegram does not share the user's language outside of updates.
+func (t *TelegramMessaging) GetProfile(ctx context.Context, userID string) (*Profile, error) {
+	var chat TelegramChat
+	if err := t.call(ctx, "getChat", map[string]any{"chat_id": userID}, &chat); err != nil {
+		return nil, fmt.Errorf("failed to get profile: %w", err)
+	}
+
+	return &Profile{
+		UserID:      userID,
+		DisplayName: strings.TrimSpace(chat.FirstName + " " + chat.LastName),
+	}, nil
+}
+
+// GetGroupMemberProfile returns the name of a member of a group chat.
+func (t *TelegramMessaging) GetGroupMemberProfile(ctx context.Context, groupID, userID string) (*Profile, error) {
+	var member struct {
+		User TelegramUser `json:"user"`
+	}
+	if err := t.call(ctx, "getChatMember", map[string]any{"chat_id": groupID, "user_id": userID}, &member); err != nil {
+		return nil, fmt.Errorf("failed to get group member profile: %w", err)
+	}
+
+	return &Profile{
+		UserID:      userID,
+		DisplayName: strings.TrimSpace(member.User.FirstName + " " + member.User.LastName),
+	}, nil
+}
+
+// GetContent downloads a file by its file ID, e.g. a photo's largest size.
+func (t *TelegramMessaging) GetContent(ctx context.Context, fileID string) ([]byte, string, error) {
+	var file struct {
+		FilePath string `json:"file_path"`
+	}
+	if err := t.call(ctx, "getFile", map[string]any{"file_id": fileID}, &file); err != nil {
+		return nil, "", fmt.Errorf("failed to get message content: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/file/bot%s/%s", t.baseURL, t.token, file.FilePath), nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create download request: %w", err)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get message content: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("failed to get message content: status %d", resp.StatusCode)
+	}
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read message content: %w", err)
+	}
+
+	// Telegram serves files as application/octet-stream.
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" || contentType == "application/octet-stream" {
+		contentType = http.DetectContentType(content)
+	}
+
+	return content, contentType, nil
+}
+
+// ShowLoading shows "typing..." in the chat. Telegram shows it for about five
+// seconds whatever seconds is.
+func (t *TelegramMessaging) ShowLoading(ctx context.Context, chatID string, _ int) error {
+	if err := t.call(ctx, "sendChatAction", map[string]any{"chat_id": chatID, "action": "typing"}, nil); err != nil {
+		return fmt.Errorf("failed to show loading animation: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package messaging
+
+import (
+	"context"
+	"github/shaolim/momon/pkg/messaging/telegramtest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTelegramMessaging(t *testing.T) {
+	ctx := context.Background()
+	bot := telegramtest.NewServer("123:token", "secret")
+	defer bot.Close()
+
+	api, err := NewTelegramMessaging(&Config{TelegramBotToken: "123:token"}, WithTelegramBaseURL(bot.URL))
+	if err != nil {
+		t.Fatalf("failed to create Telegram client: %v", err)
+	}
+
+	t.Run("reply quotes the message", func(t *testing.T) {
+		assert.NoError(t, api.Reply(ctx, TelegramReplyToken(42, 7), TextMessage("hello"), TextMessage("again")))
+		assert.Error(t, api.Reply(ctx, "not-a-token", TextMessage("hello")))
+
+		assert.Equal(t, []telegramtest.Message{
+			{ID: 1, ChatID: "42", Text: "hello", ReplyTo: 7},
+			{ID: 2, ChatID: "42", Text: "again", ReplyTo: 7},
+		}, bot.Messages())
+	})
+
+	t.Run("push", func(t *testing.T) {
+		assert.NoError(t, api.Push(ctx, "43", "", TextMessage("budget exceeded")))
+		messages := bot.Messages()
+		assert.Equal(t, telegramtest.Message{ID: 3, ChatID: "43", Text: "budget exceeded"}, messages[len(messages)-1])
+	})
+
+	t.Run("quick replies are inline buttons", func(t *testing.T) {
+		message := Message{Text: "Save it?", QuickReplies: []QuickReply{
+			{Label: "Save", Data: "action=save"},
+			{Label: "Edit total", Data: "action=edit_total"},
+			{Label: "Discard", Data: "action=discard"},
+		}}
+		assert.NoError(t, api.Push(ctx, "42", "", message))
+
+		messages := bot.Messages()
+		assert.Equal(t, []telegramtest.Button{
+			{Text: "Save", Data: "action=save"},
+			{Text: "Edit total", Data: "action=edit_total"},
+			{Text: "Discard", Data: "action=discard"},
+		}, messages[len(messages)-1].Buttons)
+
+		assert.NoError(t, api.AnswerCallback(ctx, "query-1"))
+		assert.Equal(t, []string{"query-1"}, bot.Answered())
+	})
+
+	t.Run("profile", func(t *testing.T) {
+		bot.SetUser(42, "Alice")
+
+		profile, err := api.GetProfile(ctx, "42")
+		if err != nil {
+			t.Fatalf("failed to get profile: %v", err)
+		}
+		assert.Equal(t, "Alice", profile.DisplayName)
+
+		_, err = api.GetProfile(ctx, "44")
+		assert.ErrorContains(t, err, "chat not found")
+
+		profile, err = api.GetGroupMemberProfile(ctx, "-100", "42")
+		if err != nil {
+			t.Fatalf("failed to get group member profile: %v", err)
+		}
+		assert.Equal(t, "Alice", profile.DisplayName)
+	})
+
+	t.Run("content", func(t *testing.T) {
+		jpeg := []byte("\xff\xd8\xff\xe0 jpeg bytes")
+		bot.SetFile("photo-1", jpeg, "application/octet-stream")
+
+		content, contentType, err := api.GetContent(ctx, "photo-1")
+		if err != nil {
+			t.Fatalf("failed to get content: %v", err)
+		}
+		assert.Equal(t, jpeg, content)
+		assert.Equal(t, "image/jpeg", contentType)
+	})
+
+	t.Run("loading", func(t *testing.T) {
+		assert.NoError(t, api.ShowLoading(ctx, "42", 60))
+		assert.Equal(t, []string{"42"}, bot.Actions())
+	})
+
+	t.Run("wrong token", func(t *testing.T) {
+		other, _ := NewTelegramMessaging(&Config{TelegramBotToken: "999:other"}, WithTelegramBaseURL(bot.URL))
+		assert.ErrorContains(t, other.Push(ctx, "42", "", TextMessage("hello")), "Unauthorized")
+	})
+}
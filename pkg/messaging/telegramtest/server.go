@@ -0,0 +1,330 @@
+// Package telegramtest provides an in-process stand-in for the Telegram Bot
+// API, so the Telegram webhook can be tested end to end without a bot.
+package telegramtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Message is a message the bot sent. ReplyTo is the message it quoted, if
+// any, and Buttons its inline keyboard.
+type Message struct {
+	ID      int64
+	ChatID  string
+	Text    string
+	ReplyTo int64
+	Buttons []Button
+}
+
+// Button is an inline keyboard button. Data is sent back in a callback query
+// when it is tapped, see CallbackUpdate.
+type Button struct {
+	Text string
+	Data string
+}
+
+type file struct {
+	path        string
+	data        []byte
+	contentType string
+}
+
+// Server serves the Bot API methods the bot uses and records what the bot
+// sent. Point TelegramMessaging at it with
+// messaging.WithTelegramBaseURL(s.URL).
+type Server struct {
+	*httptest.Server
+
+	token         string
+	webhookSecret string
+
+	mu       sync.Mutex
+	nextID   int64
+	users    map[string]string
+	files    map[string]file
+	messages []Message
+	actions  []string
+	answered []string
+}
+
+// NewServer starts a server that accepts requests for the bot token and sends
+// updates with webhookSecret. Close it when done.
+func NewServer(token, webhookSecret string) *Server {
+	s := &Server{
+		token:         token,
+		webhookSecret: webhookSecret,
+		users:         map[string]string{},
+		files:         map[string]file{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /{bot}/{method}", s.handleMethod)
+	mux.HandleFunc("GET /file/{bot}/{path...}", s.handleFile)
+	s.Server = httptest.NewServer(mux)
+
+	return s
+}
+
+// SetUser registers a user so getChat and getChatMember can find them.
+func (s *Server) SetUser(userID int64, firstName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.users[strconv.FormatInt(userID, 10)] = firstName
+}
+
+// SetFile stores the bytes downloaded for fileID.
+func (s *Server) SetFile(fileID string, data []byte, contentType string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.files[fileID] = file{path: "photos/" + fileID + ".jpg", data: data, contentType: contentType}
+}
+
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Message(nil), s.messages...)
+}
+
+// Actions returns the chats a chat action such as "typing" was sent to.
+func (s *Server) Actions() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.actions...)
+}
+
+// Answered returns the IDs of the callback queries the bot answered.
+func (s *Server) Answered() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.answered...)
+}
+
+// WaitForMessages waits until the bot sent at least n messages, since updates
+// are processed after the webhook is acknowledged.
+func (s *Server) WaitForMessages(n int, timeout time.Duration) ([]Message, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		messages := s.Messages()
+		if len(messages) >= n {
+			return messages, nil
+		}
+		if time.Now().After(deadline) {
+			return messages, fmt.Errorf("got %d messages after %s, want %d", len(messages), timeout, n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Update is an update as the Bot API sends it.
+type Update map[string]any
+
+func (s *Server) newMessage(userID int64) (Update, map[string]any) {
+	s.mu.Lock()
+	s.nextID++
+	id := s.nextID
+	firstName := s.users[strconv.FormatInt(userID, 10)]
+	s.mu.Unlock()
+
+	message := map[string]any{
+		"message_id": id,
+		"from":       map[string]any{"id": userID, "is_bot": false, "first_name": firstName},
+		"chat":       map[string]any{"id": userID, "type": "private", "first_name": firstName},
+		"date":       time.Now().Unix(),
+	}
+	return Update{"update_id": 100000 + id, "message": message}, message
+}
+
+// TextUpdate is a private text message from userID.
+func (s *Server) TextUpdate(userID int64, text string) Update {
+	u, message := s.newMessage(userID)
+	message["text"] = text
+	return u
+}
+
+// PhotoUpdate is a private photo from userID whose largest size is served as
+// fileID, see SetFile.
+func (s *Server) PhotoUpdate(userID int64, fileID string) Update {
+	u, message := s.newMessage(userID)
+	message["photo"] = []map[string]any{
+		{"file_id": fileID + "-small", "file_unique_id": fileID + "-small", "width": 90, "height": 120},
+		{"file_id": fileID, "file_unique_id": fileID, "width": 900, "height": 1200},
+	}
+	return u
+}
+
+// CallbackUpdate is userID tapping the button with data under the bot's
+// message.
+func (s *Server) CallbackUpdate(userID int64, message Message, data string) Update {
+	s.mu.Lock()
+	s.nextID++
+	id := s.nextID
+	firstName := s.users[strconv.FormatInt(userID, 10)]
+	s.mu.Unlock()
+
+	return Update{
+		"update_id": 100000 + id,
+		"callback_query": map[string]any{
+			"id":   strconv.FormatInt(id, 10),
+			"from": map[string]any{"id": userID, "is_bot": false, "first_name": firstName},
+			"message": map[string]any{
+				"message_id": message.ID,
+				"chat":       map[string]any{"id": userID, "type": "private", "first_name": firstName},
+				"date":       time.Now().Unix(),
+				"text":       message.Text,
+			},
+			"data": data,
+		},
+	}
+}
+
+// SendUpdate posts the update to the bot's webhook URL with the webhook
+// secret, like Telegram does.
+func (s *Server) SendUpdate(url string, update Update) (*http.Response, error) {
+	body, err := json.Marshal(update)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal update: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create update request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Telegram-Bot-Api-Secret-Token", s.webhookSecret)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send update: %w", err)
+	}
+	resp.Body.Close()
+
+	return resp, nil
+}
+
+func (s *Server) handleMethod(w http.ResponseWriter, r *http.Request) {
+	if r.PathValue("bot") != "bot"+s.token {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var params struct {
+		ChatID          json.Number `json:"chat_id"`
+		UserID          json.Number `json:"user_id"`
+		Text            string      `json:"text"`
+		Action          string      `json:"action"`
+		FileID          string      `json:"file_id"`
+		CallbackQueryID string      `json:"callback_query_id"`
+		ReplyParameters *struct {
+			MessageID int64 `json:"message_id"`
+		} `json:"reply_parameters"`
+		ReplyMarkup *struct {
+			InlineKeyboard [][]struct {
+				Text         string `json:"text"`
+				CallbackData string `json:"callback_data"`
+			} `json:"inline_keyboard"`
+		} `json:"reply_markup"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&params); err != nil {
+		writeError(w, http.StatusBadRequest, "Bad Request: can't parse JSON")
+		return
+	}
+	chatID := params.ChatID.String()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.PathValue("method") {
+	case "sendMessage":
+		if chatID == "" || params.Text == "" {
+			writeError(w, http.StatusBadRequest, "Bad Request: message text is empty")
+			return
+		}
+		s.nextID++
+		m := Message{ID: s.nextID, ChatID: chatID, Text: params.Text}
+		if params.ReplyParameters != nil {
+			m.ReplyTo = params.ReplyParameters.MessageID
+		}
+		if params.ReplyMarkup != nil {
+			for _, row := range params.ReplyMarkup.InlineKeyboard {
+				for _, b := range row {
+					m.Buttons = append(m.Buttons, Button{Text: b.Text, Data: b.CallbackData})
+				}
+			}
+		}
+		s.messages = append(s.messages, m)
+		writeResult(w, map[string]any{"message_id": m.ID, "chat": map[string]any{"id": params.ChatID}, "text": params.Text})
+	case "answerCallbackQuery":
+		s.answered = append(s.answered, params.CallbackQueryID)
+		writeResult(w, true)
+	case "sendChatAction":
+		s.actions = append(s.actions, chatID)
+		writeResult(w, true)
+	case "getChat":
+		firstName, ok := s.users[chatID]
+		if !ok {
+			writeError(w, http.StatusBadRequest, "Bad Request: chat not found")
+			return
+		}
+		writeResult(w, map[string]any{"id": params.ChatID, "type": "private", "first_name": firstName})
+	case "getChatMember":
+		firstName, ok := s.users[params.UserID.String()]
+		if !ok {
+			writeError(w, http.StatusBadRequest, "Bad Request: member not found")
+			return
+		}
+		writeResult(w, map[string]any{"status": "member", "user": map[string]any{"id": params.UserID, "is_bot": false, "first_name": firstName}})
+	case "getFile":
+		f, ok := s.files[params.FileID]
+		if !ok {
+			writeError(w, http.StatusBadRequest, "Bad Request: invalid file_id")
+			return
+		}
+		writeResult(w, map[string]any{"file_id": params.FileID, "file_size": len(f.data), "file_path": f.path})
+	default:
+		writeError(w, http.StatusNotFound, "Not Found: method not found")
+	}
+}
+
+func (s *Server) handleFile(w http.ResponseWriter, r *http.Request) {
+	if r.PathValue("bot") != "bot"+s.token {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, f := range s.files {
+		if f.path == r.PathValue("path") {
+			w.Header().Set("Content-Type", f.contentType)
+			w.Write(f.data)
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
+
+func writeResult(w http.ResponseWriter, result any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": result})
+}
+
+func writeError(w http.ResponseWriter, status int, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"ok": false, "error_code": status, "description": description})
+}
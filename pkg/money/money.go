@@ -0,0 +1,219 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	ErrOverflow         = errors.New("amount overflows int64")
+)
+
+// Currency is an ISO 4217 currency code such as "JPY" or "USD".
+type Currency string
+
+// exponents lists the currencies whose minor unit is not 1/100 of the major
+// unit. Every other currency is assumed to have 2 decimals.
+var exponents = map[Currency]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0,
+	"PYG": 0, "RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+// ParseCurrency validates and normalizes a currency code.
+func ParseCurrency(s string) (Currency, error) {
+	code := strings.ToUpper(strings.TrimSpace(s))
+	if len(code) != 3 {
+		return "", fmt.Errorf("invalid currency %q", s)
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return "", fmt.Errorf("invalid currency %q", s)
+		}
+	}
+	return Currency(code), nil
+}
+
+// Exponent is the number of decimals of the currency, e.g. 0 for JPY and 2
+// for USD.
+func (c Currency) Exponent() int {
+	if e, ok := exponents[c]; ok {
+		return e
+	}
+	return 2
+}
+
+func (c Currency) String() string {
+	return string(c)
+}
+
+// Money is an amount in the currency's minor unit, e.g. yen for JPY and cents
+// for USD.
+type Money struct {
+	Amount   int64
+	Currency Currency
+}
+
+func New(amount int64, currency Currency) Money {
+	return Money{
+		Amount:   amount,
+		Currency: currency,
+	}
+}
+
+// Parse reads a major-unit amount such as "1,234.56" or "-1200" in the given
+// currency. It rejects more decimals than the currency has.
+func Parse(s string, currency Currency) (Money, error) {
+	str := strings.ReplaceAll(strings.TrimSpace(s), ",", "")
+
+	negative := false
+	switch {
+	case strings.HasPrefix(str, "-"):
+		negative = true
+		str = str[1:]
+	case strings.HasPrefix(str, "+"):
+		str = str[1:]
+	}
+
+	whole, fraction, hasPoint := strings.Cut(str, ".")
+	exponent := currency.Exponent()
+	if whole == "" || (hasPoint && fraction == "") || len(fraction) > exponent {
+		return Money{}, fmt.Errorf("invalid %s amount %q", currency, s)
+	}
+
+	digits := whole + fraction + strings.Repeat("0", exponent-len(fraction))
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return Money{}, fmt.Errorf("invalid %s amount %q", currency, s)
+		}
+	}
+
+	amount, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("invalid %s amount %q: %w", currency, s, ErrOverflow)
+	}
+	if negative {
+		amount = -amount
+	}
+
+	return New(amount, currency), nil
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+func (m Money) Neg() Money {
+	return New(-m.Amount, m.Currency)
+}
+
+func (m Money) Abs() Money {
+	if m.Amount < 0 {
+		return m.Neg()
+	}
+	return m
+}
+
+// Add returns m + o. Both must be in the same currency.
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, o.Currency)
+	}
+
+	sum := m.Amount + o.Amount
+	if (o.Amount > 0 && sum < m.Amount) || (o.Amount < 0 && sum > m.Amount) {
+		return Money{}, ErrOverflow
+	}
+
+	return New(sum, m.Currency), nil
+}
+
+// Sub returns m - o. Both must be in the same currency.
+func (m Money) Sub(o Money) (Money, error) {
+	if o.Amount == math.MinInt64 {
+		return Money{}, ErrOverflow
+	}
+	return m.Add(o.Neg())
+}
+
+// Mul multiplies the amount by a quantity such as 1.5 (kg), rounding half away
+// from zero to the nearest minor unit.
+func (m Money) Mul(quantity float64) (Money, error) {
+	product := math.Round(float64(m.Amount) * quantity)
+	if product >= math.MaxInt64 || product < math.MinInt64 {
+		return Money{}, ErrOverflow
+	}
+	return New(int64(product), m.Currency), nil
+}
+
+// Convert returns the amount in currency to at rate, the number of major
+// units of to that one major unit of m's currency is worth, rounding half away
+// from zero to the nearest minor unit.
+func (m Money) Convert(to Currency, rate float64) (Money, error) {
+	if rate <= 0 || math.IsInf(rate, 0) || math.IsNaN(rate) {
+		return Money{}, fmt.Errorf("invalid rate %v", rate)
+	}
+
+	converted := math.Round(float64(m.Amount) * rate * math.Pow10(to.Exponent()-m.Currency.Exponent()))
+	if converted >= math.MaxInt64 || converted < math.MinInt64 {
+		return Money{}, ErrOverflow
+	}
+	return New(int64(converted), to), nil
+}
+
+// Major formats the amount in major units with thousands separators and
+// without the currency, e.g. "1,234.56".
+func (m Money) Major() string {
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+	}
+
+	// Work on the string form so math.MinInt64 does not overflow on negation.
+	digits := strings.TrimPrefix(strconv.FormatInt(amount, 10), "-")
+	exponent := m.Currency.Exponent()
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+	whole, fraction := digits[:len(digits)-exponent], digits[len(digits)-exponent:]
+
+	var b strings.Builder
+	b.WriteString(sign)
+	for i, d := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(d)
+	}
+	if fraction != "" {
+		b.WriteString("." + fraction)
+	}
+
+	return b.String()
+}
+
+// String formats the amount followed by its currency, e.g. "1,234.56 USD".
+func (m Money) String() string {
+	return m.Major() + " " + string(m.Currency)
+}
+
+// Sum adds up amounts in the same currency.
+func Sum(currency Currency, amounts ...Money) (Money, error) {
+	total := New(0, currency)
+	for _, a := range amounts {
+		var err error
+		if total, err = total.Add(a); err != nil {
+			return Money{}, err
+		}
+	}
+	return total, nil
+}
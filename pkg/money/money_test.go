@@ -0,0 +1,162 @@
+package money
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParseCurrency(t *testing.T) {
+	cases := []struct {
+		input   string
+		want    Currency
+		wantErr bool
+	}{
+		{input: "JPY", want: "JPY"},
+		{input: " usd ", want: "USD"},
+		{input: "YEN!", wantErr: true},
+		{input: "US", wantErr: true},
+		{input: "U5D", wantErr: true},
+	}
+
+	for _, tc := range cases {
+		got, err := ParseCurrency(tc.input)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("ParseCurrency(%q) error = %v, wantErr %v", tc.input, err, tc.wantErr)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("ParseCurrency(%q) = %q, want %q", tc.input, got, tc.want)
+		}
+	}
+}
+
+func TestCurrency_Exponent(t *testing.T) {
+	cases := map[Currency]int{"JPY": 0, "KRW": 0, "USD": 2, "THB": 2, "EUR": 2, "KWD": 3}
+
+	for currency, want := range cases {
+		if got := currency.Exponent(); got != want {
+			t.Errorf("%s.Exponent() = %d, want %d", currency, got, want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		currency Currency
+		want     int64
+		wantErr  bool
+	}{
+		{name: "yen", input: "1200", currency: "JPY", want: 1200},
+		{name: "yen_separators", input: "3,400", currency: "JPY", want: 3400},
+		{name: "yen_decimals", input: "12.5", currency: "JPY", wantErr: true},
+		{name: "dollars", input: "12.50", currency: "USD", want: 1250},
+		{name: "dollars_one_decimal", input: "12.5", currency: "USD", want: 1250},
+		{name: "dollars_whole", input: "1,234", currency: "USD", want: 123400},
+		{name: "dollars_too_precise", input: "1.234", currency: "USD", wantErr: true},
+		{name: "dinar", input: "1.234", currency: "KWD", want: 1234},
+		{name: "negative", input: "-5.25", currency: "EUR", want: -525},
+		{name: "plus", input: "+50000", currency: "JPY", want: 50000},
+		{name: "dangling_point", input: "5.", currency: "USD", wantErr: true},
+		{name: "no_whole", input: ".5", currency: "USD", wantErr: true},
+		{name: "letters", input: "12a", currency: "JPY", wantErr: true},
+		{name: "empty", input: "", currency: "JPY", wantErr: true},
+		{name: "overflow", input: "99999999999999999999", currency: "JPY", wantErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Parse(tc.input, tc.currency)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Parse(%q) error = %v, wantErr %v", tc.input, err, tc.wantErr)
+			}
+			if err == nil && (got.Amount != tc.want || got.Currency != tc.currency) {
+				t.Errorf("Parse(%q) = %+v, want %d %s", tc.input, got, tc.want, tc.currency)
+			}
+		})
+	}
+}
+
+func TestMoney_String(t *testing.T) {
+	cases := []struct {
+		money Money
+		want  string
+	}{
+		{money: New(0, "JPY"), want: "0 JPY"},
+		{money: New(2310, "JPY"), want: "2,310 JPY"},
+		{money: New(1234567, "JPY"), want: "1,234,567 JPY"},
+		{money: New(-1200, "JPY"), want: "-1,200 JPY"},
+		{money: New(5, "USD"), want: "0.05 USD"},
+		{money: New(123456, "USD"), want: "1,234.56 USD"},
+		{money: New(-50, "EUR"), want: "-0.50 EUR"},
+		{money: New(1234, "KWD"), want: "1.234 KWD"},
+		{money: New(math.MinInt64, "JPY"), want: "-9,223,372,036,854,775,808 JPY"},
+	}
+
+	for _, tc := range cases {
+		if got := tc.money.String(); got != tc.want {
+			t.Errorf("%+v.String() = %q, want %q", tc.money, got, tc.want)
+		}
+	}
+}
+
+func TestMoney_Arithmetic(t *testing.T) {
+	a := New(1200, "JPY")
+	b := New(800, "JPY")
+
+	sum, err := a.Add(b)
+	if err != nil || sum != New(2000, "JPY") {
+		t.Errorf("Add() = %v, %v", sum, err)
+	}
+
+	diff, err := b.Sub(a)
+	if err != nil || diff != New(-400, "JPY") {
+		t.Errorf("Sub() = %v, %v", diff, err)
+	}
+
+	if _, err := a.Add(New(100, "USD")); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Add() across currencies error = %v, want ErrCurrencyMismatch", err)
+	}
+
+	if _, err := New(math.MaxInt64, "JPY").Add(New(1, "JPY")); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Add() overflow error = %v, want ErrOverflow", err)
+	}
+
+	if _, err := New(0, "JPY").Sub(New(math.MinInt64, "JPY")); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Sub() overflow error = %v, want ErrOverflow", err)
+	}
+
+	product, err := New(9999, "USD").Mul(1.5)
+	if err != nil || product != New(14999, "USD") {
+		t.Errorf("Mul() = %v, %v", product, err)
+	}
+
+	if _, err := New(math.MaxInt64, "JPY").Mul(2); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Mul() overflow error = %v, want ErrOverflow", err)
+	}
+
+	converted, err := New(1550, "USD").Convert("JPY", 151.23)
+	if err != nil || converted != New(2344, "JPY") {
+		t.Errorf("Convert() = %v, %v", converted, err)
+	}
+
+	converted, err = New(2344, "JPY").Convert("USD", 1/151.23)
+	if err != nil || converted != New(1550, "USD") {
+		t.Errorf("Convert() = %v, %v", converted, err)
+	}
+
+	if _, err := a.Convert("USD", 0); err == nil {
+		t.Error("Convert() with a zero rate succeeded")
+	}
+
+	total, err := Sum("JPY", a, b, New(-500, "JPY"))
+	if err != nil || total != New(1500, "JPY") {
+		t.Errorf("Sum() = %v, %v", total, err)
+	}
+
+	if got := New(-300, "JPY").Abs(); got != New(300, "JPY") {
+		t.Errorf("Abs() = %v", got)
+	}
+}
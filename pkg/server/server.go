@@ -46,6 +46,11 @@ func (s *Server) ServeHTTP(ctx context.Context, srv *http.Server) error {
 		return fmt.Errorf("failed to serve: %w", err)
 	}
 
+	// Serve returns as soon as Shutdown starts; wait for in-flight requests.
+	if err := <-errCh; err != nil {
+		return fmt.Errorf("failed to shutdown: %w", err)
+	}
+
 	return nil
 }
 